@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"net"
 	"net/http"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/startdusk/finance-app-backend/internal/api"
 	"github.com/startdusk/finance-app-backend/internal/config"
 	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/worker"
 )
 
 var (
@@ -38,6 +40,12 @@ func main() {
 
 	logrus.Debug("Database is ready to use")
 
+	if *config.TrashRetentionDays > 0 {
+		go worker.NewTrashRetention(db, *config.TrashRetentionDays).Run(context.Background())
+	}
+
+	go worker.NewErasure(db).Run(context.Background())
+
 	var addr = net.JoinHostPort(*host, *port)
 	server := http.Server{
 		Handler: router,
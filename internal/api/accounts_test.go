@@ -0,0 +1,259 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestAccounts(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	h.deny(http.MethodPost, userPath(user, "accounts"), user.Token, map[string]interface{}{
+		"name": "Wallet",
+	}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, userPath(user, "accounts"), user.Token, map[string]interface{}{
+		"name":         "Wallet",
+		"type":         "piggy-bank",
+		"startBalance": "0",
+		"currency":     "dollars",
+	}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, userPath(user, "accounts"), other.Token, map[string]interface{}{
+		"name":         "Wallet",
+		"type":         "cash",
+		"startBalance": "0",
+		"currency":     "USD",
+	}, http.StatusUnauthorized)
+
+	accountID := h.createAccount(user, "Wallet", "USD")
+	accountPath := userPath(user, "accounts", string(accountID))
+
+	var accounts []*model.Account
+	h.call(http.MethodGet, userPath(user, "accounts"), user.Token, nil, http.StatusOK, &accounts)
+	if len(accounts) != 1 || accounts[0].ID != accountID {
+		t.Fatalf("expected created account, got %+v", accounts)
+	}
+	h.call(http.MethodGet, userPath(user, "accounts"), admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodGet, userPath(user, "accounts"), other.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodGet, userPath(user, "accounts"), "", nil, http.StatusUnauthorized)
+
+	// partial update keeps other fields
+	h.call(http.MethodPatch, accountPath, user.Token, map[string]interface{}{
+		"name": "Pocket",
+	}, http.StatusOK, nil)
+
+	var account model.Account
+	h.call(http.MethodGet, accountPath, user.Token, nil, http.StatusOK, &account)
+	if *account.Name != "Pocket" || *account.Currency != "USD" || *account.Type != model.Cash || account.StartBalance.String() != "0.00" {
+		t.Fatalf("expected renamed account, got %+v", account)
+	}
+
+	// yen has no minor units, start balance can't be rounded silently
+	h.deny(http.MethodPatch, accountPath, user.Token, map[string]interface{}{
+		"startBalance": "10.50",
+		"currency":     "JPY",
+	}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodGet, accountPath, other.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodPatch, accountPath, other.Token, map[string]interface{}{"name": "Stolen"}, http.StatusUnauthorized)
+
+	// user in path has to own account in path
+	h.deny(http.MethodGet, userPath(other, "accounts", string(accountID)), other.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodGet, userPath(user, "accounts", "unknown"), user.Token, nil, http.StatusUnauthorized)
+
+	var history []*model.Revision
+	h.call(http.MethodGet, accountPath+"/history", user.Token, nil, http.StatusOK, &history)
+	if len(history) != 2 {
+		t.Fatalf("expected 2 revisions, got %d", len(history))
+	}
+	h.deny(http.MethodGet, accountPath+"/history", other.Token, nil, http.StatusUnauthorized)
+
+	h.call(http.MethodPost, accountPath+"/restore", user.Token, map[string]int{"revision": 1}, http.StatusOK, nil)
+	h.call(http.MethodGet, accountPath, user.Token, nil, http.StatusOK, &account)
+	if *account.Name != "Wallet" {
+		t.Fatalf("expected name of first revision, got %s", *account.Name)
+	}
+	h.deny(http.MethodPost, accountPath+"/restore", user.Token, map[string]int{"revision": 99}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, accountPath+"/restore", other.Token, nil, http.StatusUnauthorized)
+
+	h.deny(http.MethodDelete, accountPath, other.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodDelete, accountPath+"?strategy=unknown", user.Token, nil, http.StatusUnprocessableEntity)
+	h.call(http.MethodDelete, accountPath, user.Token, nil, http.StatusOK, nil)
+
+	h.call(http.MethodGet, userPath(user, "accounts"), user.Token, nil, http.StatusOK, &accounts)
+	if len(accounts) != 0 {
+		t.Fatalf("expected no accounts after delete, got %d", len(accounts))
+	}
+
+	// restore without revision undeletes account
+	h.call(http.MethodPost, accountPath+"/restore", user.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodGet, userPath(user, "accounts"), user.Token, nil, http.StatusOK, &accounts)
+	if len(accounts) != 1 {
+		t.Fatalf("expected restored account, got %d", len(accounts))
+	}
+}
+
+func TestCreditAccount(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+
+	card := map[string]interface{}{
+		"name":         "Card",
+		"type":         "credit",
+		"startBalance": "0",
+		"currency":     "USD",
+		"institution":  "Bank",
+		"maskedNumber": "4111 1111 1111 1234",
+		"creditLimit":  "1000",
+		"statementDay": 15,
+	}
+
+	// details which type doesn't have are refused
+	savings := map[string]interface{}{}
+	for field, value := range card {
+		savings[field] = value
+	}
+	savings["type"] = "savings"
+	h.deny(http.MethodPost, userPath(user, "accounts"), user.Token, savings, http.StatusUnprocessableEntity)
+	card["statementDay"] = 31
+	h.deny(http.MethodPost, userPath(user, "accounts"), user.Token, card, http.StatusUnprocessableEntity)
+	card["statementDay"] = 15
+
+	var account model.Account
+	h.call(http.MethodPost, userPath(user, "accounts"), user.Token, card, http.StatusCreated, &account)
+	if *account.MaskedNumber != "****1234" || account.CreditLimit.String() != "1000.00" {
+		t.Fatalf("expected masked number and limit in cents, got %+v", account)
+	}
+	accountPath := userPath(user, "accounts", string(account.ID))
+
+	h.createTransaction(user, account.ID, h.createCategory(user, "Food", ""))
+
+	h.call(http.MethodGet, accountPath, user.Token, nil, http.StatusOK, &account)
+	if account.Credit == nil || account.Credit.Balance.String() != "-25.00" || account.Credit.AvailableCredit.String() != "975.00" || account.Credit.Utilization != 2.5 {
+		t.Fatalf("expected 2.5%% of limit used, got %+v", account.Credit)
+	}
+
+	// changing type drops credit details
+	h.call(http.MethodPatch, accountPath, user.Token, map[string]interface{}{"type": "savings", "interestRate": 4.5}, http.StatusOK, nil)
+	var changed model.Account
+	h.call(http.MethodGet, accountPath, user.Token, nil, http.StatusOK, &changed)
+	if changed.CreditLimit != nil || changed.StatementDay != nil || changed.Credit != nil || *changed.InterestRate != 4.5 || *changed.Institution != "Bank" {
+		t.Fatalf("expected savings account without credit details, got %+v", changed)
+	}
+}
+
+func TestAccountDeleteStrategies(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+
+	categoryID := h.createCategory(user, "Food", "")
+	accountID := h.createAccount(user, "Wallet", "USD")
+	targetID := h.createAccount(user, "Bank", "USD")
+	euroID := h.createAccount(user, "Euro", "EUR")
+	h.createTransaction(user, accountID, categoryID)
+
+	accountPath := userPath(user, "accounts", string(accountID))
+
+	var result model.DeleteResult
+	h.deny(http.MethodDelete, accountPath, user.Token, nil, http.StatusConflict)
+	h.deny(http.MethodDelete, accountPath+"?strategy=reassign", user.Token, nil, http.StatusUnprocessableEntity)
+	h.deny(http.MethodDelete, accountPath+"?strategy=reassign&target="+string(euroID), user.Token, nil, http.StatusUnprocessableEntity)
+	h.deny(http.MethodDelete, accountPath+"?strategy=reassign&target="+string(accountID), user.Token, nil, http.StatusUnprocessableEntity)
+
+	other := h.signUp()
+	otherAccountID := h.createAccount(other, "Other", "USD")
+	h.deny(http.MethodDelete, accountPath+"?strategy=reassign&target="+string(otherAccountID), user.Token, nil, http.StatusUnprocessableEntity)
+
+	h.call(http.MethodDelete, accountPath+"?strategy=reassign&target="+string(targetID), user.Token, nil, http.StatusOK, &result)
+	if !result.Deleted || result.Transactions != 1 {
+		t.Fatalf("expected 1 moved transaction, got %+v", result)
+	}
+
+	h.call(http.MethodDelete, userPath(user, "accounts", string(targetID))+"?strategy=cascade", user.Token, nil, http.StatusOK, &result)
+	if !result.Deleted || result.Transactions != 1 {
+		t.Fatalf("expected 1 deleted transaction, got %+v", result)
+	}
+}
+
+func TestAccountMembers(t *testing.T) {
+	h := newHarness(t)
+	owner := h.signUp()
+	member := h.signUp()
+	stranger := h.signUp()
+
+	accountID := h.createAccount(owner, "Family", "USD")
+	categoryID := h.createCategory(owner, "Food", "")
+	memberCategoryID := h.createCategory(member, "Food", "")
+	membersPath := userPath(owner, "accounts", string(accountID), "members")
+	memberPath := membersPath + "/" + string(member.ID)
+	transactionsPath := "/api/v1/accounts/" + string(accountID) + "/transactions"
+
+	h.deny(http.MethodPost, membersPath, owner.Token, map[string]string{"email": member.Email, "access": "owner"}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, membersPath, owner.Token, map[string]string{"email": newEmail(), "access": "viewer"}, http.StatusNotFound)
+	h.deny(http.MethodPost, membersPath, owner.Token, map[string]string{"email": owner.Email, "access": "viewer"}, http.StatusBadRequest)
+	h.deny(http.MethodPost, membersPath, stranger.Token, map[string]string{"email": member.Email, "access": "viewer"}, http.StatusUnauthorized)
+
+	invitation := map[string]string{"email": member.Email, "access": "viewer"}
+	h.call(http.MethodPost, membersPath, owner.Token, invitation, http.StatusCreated, nil)
+	h.deny(http.MethodPost, membersPath, owner.Token, invitation, http.StatusConflict)
+
+	// pending invitation gives no access
+	h.deny(http.MethodGet, transactionsPath+dayWindow(), member.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodGet, userPath(owner, "accounts", string(accountID)), member.Token, nil, http.StatusUnauthorized)
+
+	var invitations []*model.AccountMember
+	h.call(http.MethodGet, userPath(member, "invitations"), member.Token, nil, http.StatusOK, &invitations)
+	if len(invitations) != 1 || invitations[0].AccountID != accountID {
+		t.Fatalf("expected invitation to account, got %+v", invitations)
+	}
+	h.deny(http.MethodGet, userPath(member, "invitations"), stranger.Token, nil, http.StatusUnauthorized)
+
+	h.deny(http.MethodPost, userPath(stranger, "invitations", string(accountID), "accept"), stranger.Token, nil, http.StatusNotFound)
+	h.deny(http.MethodPost, userPath(member, "invitations", string(accountID), "accept"), stranger.Token, nil, http.StatusUnauthorized)
+	h.call(http.MethodPost, userPath(member, "invitations", string(accountID), "accept"), member.Token, nil, http.StatusOK, nil)
+
+	var members []*model.AccountMember
+	h.call(http.MethodGet, membersPath, owner.Token, nil, http.StatusOK, &members)
+	if len(members) != 1 || members[0].Status != model.MemberAccepted {
+		t.Fatalf("expected accepted member, got %+v", members)
+	}
+	h.deny(http.MethodGet, membersPath, member.Token, nil, http.StatusUnauthorized)
+
+	// viewer can read account and transactions but not change them
+	accountPath := userPath(owner, "accounts", string(accountID))
+	h.call(http.MethodGet, accountPath, member.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodGet, accountPath+"/history", member.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodPatch, accountPath, member.Token, map[string]interface{}{"name": "Mine"}, http.StatusUnauthorized)
+	h.deny(http.MethodGet, accountPath, stranger.Token, nil, http.StatusUnauthorized)
+	h.call(http.MethodGet, transactionsPath+dayWindow(), member.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodPost, transactionsPath, member.Token, transactionBody(accountID, categoryID), http.StatusUnauthorized)
+	h.deny(http.MethodGet, transactionsPath+dayWindow(), stranger.Token, nil, http.StatusUnauthorized)
+
+	h.deny(http.MethodPatch, memberPath, owner.Token, map[string]string{"access": "owner"}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPatch, membersPath+"/"+string(stranger.ID), owner.Token, map[string]string{"access": "editor"}, http.StatusNotFound)
+	h.deny(http.MethodPatch, memberPath, member.Token, map[string]string{"access": "editor"}, http.StatusUnauthorized)
+	h.call(http.MethodPatch, memberPath, owner.Token, map[string]string{"access": "editor"}, http.StatusOK, nil)
+
+	// transactions of shared account belong to owner, so they use owner's categories
+	h.deny(http.MethodPost, transactionsPath, member.Token, transactionBody(accountID, memberCategoryID), http.StatusUnprocessableEntity)
+
+	var transaction model.Transaction
+	h.call(http.MethodPost, transactionsPath, member.Token, transactionBody(accountID, categoryID), http.StatusCreated, &transaction)
+	transactionPath := transactionsPath + "/" + string(transaction.ID)
+	h.call(http.MethodPatch, transactionPath, member.Token, map[string]interface{}{"notes": "dinner"}, http.StatusOK, nil)
+	h.deny(http.MethodPatch, transactionPath, stranger.Token, map[string]interface{}{"notes": "dinner"}, http.StatusUnauthorized)
+
+	// member leaves account
+	h.call(http.MethodPost, userPath(member, "invitations", string(accountID), "decline"), member.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodPost, userPath(member, "invitations", string(accountID), "decline"), member.Token, nil, http.StatusNotFound)
+	h.deny(http.MethodGet, transactionsPath+dayWindow(), member.Token, nil, http.StatusUnauthorized)
+
+	// owner removes invitation
+	h.call(http.MethodPost, membersPath, owner.Token, map[string]string{"email": stranger.Email, "access": "viewer"}, http.StatusCreated, nil)
+	h.deny(http.MethodDelete, membersPath+"/"+string(stranger.ID), stranger.Token, nil, http.StatusUnauthorized)
+	h.call(http.MethodDelete, membersPath+"/"+string(stranger.ID), owner.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodDelete, membersPath+"/"+string(stranger.ID), owner.Token, nil, http.StatusNotFound)
+}
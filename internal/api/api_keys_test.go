@@ -0,0 +1,111 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+type apiKeyCreated struct {
+	model.APIKey
+	Key string `json:"key"`
+}
+
+func TestAPIKeys(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	h.deny(http.MethodPost, userPath(user, "api-keys"), user.Token, map[string]interface{}{
+		"name": "no scopes",
+	}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, userPath(user, "api-keys"), user.Token, map[string]interface{}{
+		"name":   "bad scope",
+		"scopes": []string{"accounts:delete"},
+	}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, userPath(user, "api-keys"), other.Token, map[string]interface{}{
+		"name":   "key",
+		"scopes": []string{"accounts:read"},
+	}, http.StatusUnauthorized)
+
+	var created apiKeyCreated
+	h.call(http.MethodPost, userPath(user, "api-keys"), user.Token, map[string]interface{}{
+		"name":   "budget app",
+		"scopes": []string{"accounts:read"},
+	}, http.StatusCreated, &created)
+	if !strings.HasPrefix(created.Key, model.APIKeyPrefix) {
+		t.Fatalf("expected key with prefix %s, got %q", model.APIKeyPrefix, created.Key)
+	}
+	keyPath := userPath(user, "api-keys", string(created.ID))
+
+	var keys []*model.APIKey
+	h.call(http.MethodGet, userPath(user, "api-keys"), user.Token, nil, http.StatusOK, &keys)
+	if len(keys) != 1 {
+		t.Fatalf("expected 1 key, got %d", len(keys))
+	}
+	h.call(http.MethodGet, userPath(user, "api-keys"), admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodGet, userPath(user, "api-keys"), other.Token, nil, http.StatusUnauthorized)
+
+	h.call(http.MethodGet, keyPath, user.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodGet, keyPath, other.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodGet, userPath(user, "api-keys", "unknown"), user.Token, nil, http.StatusNotFound)
+
+	// key is limited by its scopes
+	h.call(http.MethodGet, userPath(user, "accounts"), created.Key, nil, http.StatusOK, nil)
+	h.deny(http.MethodPost, userPath(user, "accounts"), created.Key, map[string]interface{}{
+		"name":         "Wallet",
+		"type":         "cash",
+		"startBalance": "0",
+		"currency":     "USD",
+	}, http.StatusForbidden)
+	h.deny(http.MethodGet, userPath(user), created.Key, nil, http.StatusForbidden)
+	h.deny(http.MethodGet, userPath(user, "api-keys"), created.Key, nil, http.StatusForbidden)
+
+	// scopes don't give more than user has
+	h.deny(http.MethodGet, userPath(other, "accounts"), created.Key, nil, http.StatusUnauthorized)
+
+	h.call(http.MethodPatch, keyPath, user.Token, map[string]interface{}{
+		"scopes": []string{"accounts:write"},
+	}, http.StatusOK, nil)
+	h.deny(http.MethodPatch, keyPath, user.Token, map[string]interface{}{
+		"scopes": []string{"unknown:write"},
+	}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPatch, keyPath, other.Token, map[string]interface{}{
+		"name": "stolen",
+	}, http.StatusUnauthorized)
+	h.createAccount(&testUser{ID: user.ID, Token: created.Key}, "Wallet", "USD")
+
+	h.deny(http.MethodDelete, keyPath, other.Token, nil, http.StatusUnauthorized)
+	h.call(http.MethodDelete, keyPath, user.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodDelete, keyPath, user.Token, nil, http.StatusNotFound)
+
+	// revoked key isn't accepted at all
+	h.deny(http.MethodGet, userPath(user, "accounts"), created.Key, nil, http.StatusUnauthorized)
+}
+
+func TestAPIKeyCantTakeOverUser(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	admin := h.admin()
+
+	var created apiKeyCreated
+	h.call(http.MethodPost, userPath(user, "api-keys"), user.Token, map[string]interface{}{
+		"name":   "everything",
+		"scopes": []string{"*:write"},
+	}, http.StatusCreated, &created)
+	key := &testUser{ID: user.ID, Token: created.Key}
+
+	h.call(http.MethodGet, userPath(key), key.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodPatch, userPath(key), key.Token, map[string]interface{}{
+		"password": "Correct-Horse-Battery-42",
+	}, http.StatusForbidden)
+	h.deny(http.MethodPost, userPath(key, "erasure"), key.Token, nil, http.StatusForbidden)
+	h.deny(http.MethodDelete, userPath(key), key.Token, nil, http.StatusForbidden)
+
+	// the same is allowed with user's token
+	h.call(http.MethodPost, userPath(user, "erasure"), user.Token, nil, http.StatusCreated, nil)
+	h.call(http.MethodDelete, userPath(user), admin.Token, nil, http.StatusOK, nil)
+}
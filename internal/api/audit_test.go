@@ -0,0 +1,48 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestAudit(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	accountID := h.createAccount(user, "Wallet", "USD")
+	h.call(http.MethodPost, userPath(user, "impersonate"), admin.Token, nil, http.StatusOK, nil)
+
+	var entries []*model.AuditEntry
+	h.call(http.MethodGet, "/api/v1/audit?action="+string(model.AuditUserImpersonated), admin.Token, nil, http.StatusOK, &entries)
+	if len(entries) != 1 || entries[0].ActorID == nil || *entries[0].ActorID != admin.ID {
+		t.Fatalf("expected impersonation by admin, got %+v", entries)
+	}
+
+	h.call(http.MethodGet, "/api/v1/audit?entityType=account&entityID="+string(accountID), admin.Token, nil, http.StatusOK, &entries)
+	if len(entries) != 1 {
+		t.Fatalf("expected account creation, got %d entries", len(entries))
+	}
+
+	h.deny(http.MethodGet, "/api/v1/audit?limit=many", admin.Token, nil, http.StatusUnprocessableEntity)
+	h.deny(http.MethodGet, "/api/v1/audit?from=yesterday", admin.Token, nil, http.StatusUnprocessableEntity)
+	h.deny(http.MethodGet, "/api/v1/audit", user.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodGet, "/api/v1/audit", "", nil, http.StatusUnauthorized)
+
+	// user sees only entries about his data, even if he asks for other user
+	h.call(http.MethodGet, userPath(user, "audit")+"?userID="+string(other.ID), user.Token, nil, http.StatusOK, &entries)
+	if len(entries) == 0 {
+		t.Fatal("expected entries of user")
+	}
+	for _, entry := range entries {
+		if entry.UserID == nil || *entry.UserID != user.ID {
+			t.Fatalf("expected only entries of user, got %+v", entry)
+		}
+	}
+
+	h.call(http.MethodGet, userPath(user, "audit"), admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodGet, userPath(user, "audit"), other.Token, nil, http.StatusUnauthorized)
+}
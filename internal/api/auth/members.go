@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bluele/gcache"
+
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+type memberKey struct {
+	accountID model.AccountID
+	userID    model.UserID
+}
+
+// members is cache of access levels of shared accounts members
+type members struct {
+	DB    database.Database
+	cache gcache.Cache
+}
+
+func newMembers(db database.Database) *members {
+	m := &members{
+		DB: db,
+	}
+
+	m.cache = gcache.New(1000).
+		LRU().
+		LoaderExpireFunc(func(key interface{}) (interface{}, *time.Duration, error) {
+			k, ok := key.(memberKey)
+			if !ok {
+				return nil, nil, fmt.Errorf("unknow key type: %v", key)
+			}
+			expire := 1 * time.Minute
+
+			// user without accepted invitation has no access
+			var access model.AccountAccess
+			member, err := m.DB.GetAccountMember(context.Background(), k.accountID, k.userID)
+			if err != nil && err != database.ErrMemberNotFound {
+				return nil, nil, err
+			}
+			if err == nil && member.Status == model.MemberAccepted {
+				access = member.Access
+			}
+			return access, &expire, nil
+		}).
+		Build()
+
+	return m
+}
+
+// hasAccess checks if user is member of account with required access
+func (m *members) hasAccess(userID model.UserID, accountID model.AccountID, required model.AccountAccess) bool {
+	a, err := m.cache.Get(memberKey{accountID: accountID, userID: userID})
+	if err != nil {
+		return false
+	}
+
+	access, ok := a.(model.AccountAccess)
+	return ok && access.Allows(required)
+}
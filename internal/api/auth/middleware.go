@@ -5,8 +5,12 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
 
 	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
 	"github.com/startdusk/finance-app-backend/internal/model"
 )
 
@@ -19,19 +23,31 @@ type principalContextKeyType struct{}
 
 var principalContextKey principalContextKeyType
 
-func AutherizationToken(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		req, err := CheckToken(r)
-		if err != nil {
-			utils.WriteError(w, http.StatusUnauthorized, err.Error(), nil)
-			return
-		}
-
-		next.ServeHTTP(w, req)
-	})
+// AutherizationToken returns middleware which puts principal from JWT or API key to request context.
+// It also puts audit actor, so database logs who made changes, from which IP and device.
+func AutherizationToken(db database.Database) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req, err := CheckToken(db, r)
+			if err != nil {
+				utils.WriteError(w, http.StatusUnauthorized, err.Error(), nil)
+				return
+			}
+
+			principal := GetPrincipal(req)
+			req = req.WithContext(database.WithAuditActor(req.Context(), database.AuditActor{
+				UserID:         principal.UserID,
+				IP:             utils.ClientIP(req),
+				DeviceID:       principal.DeviceID,
+				ImpersonatorID: principal.ImpersonatorID,
+			}))
+
+			next.ServeHTTP(w, req)
+		})
+	}
 }
 
-func CheckToken(r *http.Request) (*http.Request, error) {
+func CheckToken(db database.Database, r *http.Request) (*http.Request, error) {
 	// extract token from header
 	token, err := GetToken(r)
 	if err != nil {
@@ -43,7 +59,12 @@ func CheckToken(r *http.Request) (*http.Request, error) {
 		return r, nil
 	}
 
-	principal, err := VerifyToken(token)
+	var principal model.Principal
+	if model.IsAPIKey(token) {
+		principal, err = VerifyAPIKey(r.Context(), db, token)
+	} else {
+		principal, err = VerifyToken(token)
+	}
 	if err != nil {
 		return r, err
 	}
@@ -51,6 +72,32 @@ func CheckToken(r *http.Request) (*http.Request, error) {
 	return r.WithContext(WithPrincipalContext(r.Context(), principal)), nil
 }
 
+// VerifyAPIKey finds key in database and returns principal with key's scopes
+func VerifyAPIKey(ctx context.Context, db database.Database, token string) (model.Principal, error) {
+	key, err := db.GetAPIKeyByHash(ctx, model.HashAPIKey(token))
+	if err != nil {
+		if err != database.ErrAPIKeyNotFound {
+			logrus.WithError(err).Warn("could not get api key")
+		}
+		return model.NilPrincipal, errors.New("invalid api key")
+	}
+
+	if key.IsExpired(time.Now()) {
+		return model.NilPrincipal, errors.New("api key expired")
+	}
+
+	// last use is only informative, request can continue without it
+	if err := db.TouchAPIKey(ctx, key.ID); err != nil {
+		logrus.WithError(err).Warn("could not update api key last use")
+	}
+
+	return model.Principal{
+		UserID:   key.UserID,
+		APIKeyID: key.ID,
+		Scopes:   key.Scopes,
+	}, nil
+}
+
 func WithPrincipalContext(ctx context.Context, principal model.Principal) context.Context {
 	return context.WithValue(ctx, principalContextKey, principal)
 }
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bluele/gcache"
+
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// resource is path variable with ID of resource owned by user
+type resource string
+
+const (
+	accountResource     resource = "accountID"
+	categoryResource    resource = "categoryID"
+	merchantResource    resource = "merchantID"
+	transactionResource resource = "transactionID"
+)
+
+// ownedResources are all path variables MemberIsOwner checks
+var ownedResources = []resource{accountResource, categoryResource, merchantResource, transactionResource}
+
+type ownerKey struct {
+	resource resource
+	id       string
+}
+
+// owners is cache of resource owners. Owner of resource never changes so we can keep it longer than roles.
+type owners struct {
+	DB    database.Database
+	cache gcache.Cache
+}
+
+func newOwners(db database.Database) *owners {
+	o := &owners{
+		DB: db,
+	}
+
+	o.cache = gcache.New(1000).
+		LRU().
+		LoaderExpireFunc(func(key interface{}) (interface{}, *time.Duration, error) {
+			k, ok := key.(ownerKey)
+			if !ok {
+				return nil, nil, fmt.Errorf("unknow key type: %v", key)
+			}
+			userID, err := o.load(context.Background(), k)
+			if err != nil {
+				return nil, nil, err
+			}
+			expire := 10 * time.Minute
+			return userID, &expire, nil
+		}).
+		Build()
+
+	return o
+}
+
+// resourceEntities maps path variables to entities in database
+var resourceEntities = map[resource]model.AuditEntity{
+	accountResource:     model.AuditEntityAccount,
+	categoryResource:    model.AuditEntityCategory,
+	merchantResource:    model.AuditEntityMerchant,
+	transactionResource: model.AuditEntityTransaction,
+}
+
+// load gets owner of resource from database, deleted resources are owned too (history, restore)
+func (o *owners) load(ctx context.Context, key ownerKey) (*model.UserID, error) {
+	entity, ok := resourceEntities[key.resource]
+	if !ok {
+		return nil, fmt.Errorf("unknown resource: %s", key.resource)
+	}
+	return o.DB.GetResourceOwner(ctx, entity, key.id)
+}
+
+// isOwner checks if user owns resource, unknown resource isn't owned by anyone
+func (o *owners) isOwner(userID model.UserID, resource resource, id string) bool {
+	owner, err := o.cache.Get(ownerKey{resource: resource, id: id})
+	if err != nil {
+		return false
+	}
+
+	ownerID, ok := owner.(*model.UserID)
+	return ok && ownerID != nil && *ownerID == userID
+}
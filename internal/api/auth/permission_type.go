@@ -4,7 +4,8 @@ import (
 	"github.com/startdusk/finance-app-backend/internal/model"
 )
 
-// We will have 3 permission type for now.
+// PermissionType is one of builtin types below or name of permission (model.Permission)
+// which user must have through one of his roles.
 type PermissionType string
 
 const (
@@ -14,8 +15,37 @@ const (
 	Member PermissionType = "member"
 	// User is loged in and user id passed to API is the same
 	MemberIsTarget PermissionType = "memberIsTarget"
+	// User is loged in, user id passed to API is the same and user owns resources passed to API
+	// (accountID, categoryID, merchantID, transactionID)
+	MemberIsOwner PermissionType = "memberIsOwner"
+	// User is owner of account passed to API or member of shared account (viewer or editor)
+	AccountViewer PermissionType = "accountViewer"
+	// User is owner of account passed to API or editor of shared account
+	AccountEditor PermissionType = "accountEditor"
 	// Any one can access
 	Any PermissionType = "anonym"
+
+	// RefuseImpersonation doesn't allow anything, it marks sensitive API (password, deletion, login methods)
+	// which admin must not call with impersonation token. Route needs other permission types too.
+	RefuseImpersonation PermissionType = "refuseImpersonation"
+	// RefuseAPIKey doesn't allow anything, it marks API which takes over or destroys user,
+	// API key must not call it even with users:write scope. Route needs other permission types too.
+	RefuseAPIKey PermissionType = "refuseAPIKey"
+)
+
+// Named permissions, admin has all of them, other roles get them in /roles API
+const (
+	UsersRead         = PermissionType(model.PermissionUsersRead)
+	UsersWrite        = PermissionType(model.PermissionUsersWrite)
+	RolesRead         = PermissionType(model.PermissionRolesRead)
+	AccountsRead      = PermissionType(model.PermissionAccountsRead)
+	AccountsWrite     = PermissionType(model.PermissionAccountsWrite)
+	CategoriesRead    = PermissionType(model.PermissionCategoriesRead)
+	CategoriesWrite   = PermissionType(model.PermissionCategoriesWrite)
+	MerchantsRead     = PermissionType(model.PermissionMerchantsRead)
+	MerchantsWrite    = PermissionType(model.PermissionMerchantsWrite)
+	TransactionsRead  = PermissionType(model.PermissionTransactionsRead)
+	TransactionsWrite = PermissionType(model.PermissionTransactionsWrite)
 )
 
 // We will create function for each type
@@ -49,6 +79,35 @@ var memberIsTarget = func(userID model.UserID, principal model.Principal) bool {
 	return true
 }
 
+// refusesImpersonation - route has RefuseImpersonation between its permission types
+var refusesImpersonation = func(permissionTypes []PermissionType) bool {
+	for _, permissionType := range permissionTypes {
+		if permissionType == RefuseImpersonation {
+			return true
+		}
+	}
+	return false
+}
+
+// refusesAPIKey - route has RefuseAPIKey between its permission types
+var refusesAPIKey = func(permissionTypes []PermissionType) bool {
+	for _, permissionType := range permissionTypes {
+		if permissionType == RefuseAPIKey {
+			return true
+		}
+	}
+	return false
+}
+
+var hasPermission = func(permissions []model.Permission, permission model.Permission) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
 var any = func() bool {
 	return true
 }
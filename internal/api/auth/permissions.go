@@ -17,16 +17,21 @@ import (
 type Permissions interface {
 	Wrap(next http.HandlerFunc, permissionTypes ...PermissionType) http.HandlerFunc
 	Check(r *http.Request, permissionTypes ...PermissionType) bool
+	Purge()
 }
 
 type permissions struct {
-	DB    database.Database
-	cache gcache.Cache
+	DB      database.Database
+	cache   gcache.Cache
+	owners  *owners
+	members *members
 }
 
 func NewPermissions(db database.Database) Permissions {
 	p := &permissions{
-		DB: db,
+		DB:      db,
+		owners:  newOwners(db),
+		members: newMembers(db),
 	}
 
 	p.cache = gcache.New(20).
@@ -36,30 +41,47 @@ func NewPermissions(db database.Database) Permissions {
 			if !ok {
 				return nil, nil, fmt.Errorf("unknow key type: %v", key)
 			}
-			roles, err := p.DB.GetRolesByUser(context.Background(), userID)
+			ctx := context.Background()
+			roles, err := p.DB.GetRolesByUser(ctx, userID)
+			if err != nil {
+				return nil, nil, err
+			}
+			permissions, err := p.DB.GetPermissionsByUser(ctx, userID)
 			if err != nil {
 				return nil, nil, err
 			}
 			expire := 1 * time.Minute
-			return roles, &expire, nil
+			return &grants{roles: roles, permissions: permissions}, &expire, nil
 		}).
 		Build()
 
 	return p
 }
 
-// get user's roles from cache (if we wont have roles in cache it will get it from database)
-func (p *permissions) getRoles(userID model.UserID) ([]*model.UserRole, error) {
-	r, err := p.cache.Get(userID)
+// Purge forgets cached roles, permissions and account members, call it when they are changed
+func (p *permissions) Purge() {
+	p.cache.Purge()
+	p.members.cache.Purge()
+}
+
+// grants are user's roles and permissions of these roles
+type grants struct {
+	roles       []*model.UserRole
+	permissions []model.Permission
+}
+
+// get user's roles and permissions from cache (if we wont have them in cache it will get it from database)
+func (p *permissions) getGrants(userID model.UserID) (*grants, error) {
+	g, err := p.cache.Get(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	roles, ok := r.([]*model.UserRole)
+	userGrants, ok := g.(*grants)
 	if !ok {
-		return nil, fmt.Errorf("cannot get roles: %v", roles)
+		return nil, fmt.Errorf("cannot get roles: %v", g)
 	}
-	return roles, nil
+	return userGrants, nil
 }
 
 func (p *permissions) withRoles(principal model.Principal, roleFunc func([]*model.UserRole) bool) (bool, error) {
@@ -68,17 +90,50 @@ func (p *permissions) withRoles(principal model.Principal, roleFunc func([]*mode
 	}
 
 	// Load roles
-	roles, err := p.getRoles(principal.UserID)
+	userGrants, err := p.getGrants(principal.UserID)
 	if err != nil {
 		return false, err
 	}
 
-	return roleFunc(roles), nil
+	return roleFunc(userGrants.roles), nil
+}
+
+// withPermission checks if one of user's roles has permission, admin has all permissions
+func (p *permissions) withPermission(principal model.Principal, permission model.Permission) (bool, error) {
+	if principal.UserID == model.NilUserID {
+		return false, nil
+	}
+
+	userGrants, err := p.getGrants(principal.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	if adminOnly(userGrants.roles) {
+		return true, nil
+	}
+
+	return hasPermission(userGrants.permissions, permission), nil
 }
 
 // we need see if we have principal on Request in this point...
 func (p *permissions) Wrap(next http.HandlerFunc, permissionTypes ...PermissionType) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if allowed := allowedByScopes(r, GetPrincipal(r)); !allowed {
+			utils.WriteError(w, http.StatusForbidden, "api key scopes don't allow this", nil)
+			return
+		}
+
+		if principal := GetPrincipal(r); principal.IsImpersonated() && refusesImpersonation(permissionTypes) {
+			utils.WriteError(w, http.StatusForbidden, "not allowed while impersonating user", nil)
+			return
+		}
+
+		if principal := GetPrincipal(r); principal.IsAPIKey() && refusesAPIKey(permissionTypes) {
+			utils.WriteError(w, http.StatusForbidden, "not allowed with api key", nil)
+			return
+		}
+
 		if allowed := p.Check(r, permissionTypes...); !allowed {
 			utils.WriteError(w, http.StatusUnauthorized, "permission denied", nil)
 			return
@@ -118,11 +173,78 @@ func (p *permissions) Check(r *http.Request, permissionTypes ...PermissionType)
 			if allowed := memberIsTarget(targetUserID, principal); allowed {
 				return true
 			}
+		case MemberIsOwner:
+			if allowed := p.memberIsOwner(mux.Vars(r), principal); allowed {
+				return true
+			}
+		case AccountViewer:
+			if allowed := p.accountMember(mux.Vars(r), principal, model.AccessViewer); allowed {
+				return true
+			}
+		case AccountEditor:
+			if allowed := p.accountMember(mux.Vars(r), principal, model.AccessEditor); allowed {
+				return true
+			}
 		case Any:
 			if allowed := any(); allowed {
 				return true
 			}
+		case RefuseImpersonation, RefuseAPIKey:
+			// checked in Wrap, it never allows anything
+			continue
+		default:
+			// not builtin type is name of permission, like "users:read"
+			if allowed, _ := p.withPermission(principal, model.Permission(permissionType)); allowed {
+				return true
+			}
 		}
 	}
 	return false
 }
+
+// memberIsOwner checks that user in path is principal and principal owns all resources in path.
+// At least one of them has to be in path.
+func (p *permissions) memberIsOwner(vars map[string]string, principal model.Principal) bool {
+	if principal.UserID == model.NilUserID {
+		return false
+	}
+
+	checked := false
+	if userID, ok := vars["userID"]; ok {
+		if !memberIsTarget(model.UserID(userID), principal) {
+			return false
+		}
+		checked = true
+	}
+
+	for _, resource := range ownedResources {
+		id, ok := vars[string(resource)]
+		if !ok {
+			continue
+		}
+		if !p.owners.isOwner(principal.UserID, resource, id) {
+			return false
+		}
+		checked = true
+	}
+
+	return checked
+}
+
+// accountMember checks that principal is owner of account in path or its member with required access
+func (p *permissions) accountMember(vars map[string]string, principal model.Principal, required model.AccountAccess) bool {
+	if principal.UserID == model.NilUserID {
+		return false
+	}
+
+	accountID, ok := vars[string(accountResource)]
+	if !ok {
+		return false
+	}
+
+	if p.owners.isOwner(principal.UserID, accountResource, accountID) {
+		return true
+	}
+
+	return p.members.hasAccess(principal.UserID, model.AccountID(accountID), required)
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// rolesDB returns roles and permissions of users, other methods of Database are not used
+type rolesDB struct {
+	database.Database
+	roles       map[model.UserID][]model.Role
+	permissions map[model.Role][]model.Permission
+}
+
+func (db *rolesDB) GetRolesByUser(ctx context.Context, userID model.UserID) ([]*model.UserRole, error) {
+	roles := make([]*model.UserRole, 0)
+	for _, role := range db.roles[userID] {
+		roles = append(roles, &model.UserRole{Role: role})
+	}
+	return roles, nil
+}
+
+func (db *rolesDB) GetPermissionsByUser(ctx context.Context, userID model.UserID) ([]model.Permission, error) {
+	permissions := make([]model.Permission, 0)
+	for _, role := range db.roles[userID] {
+		permissions = append(permissions, db.permissions[role]...)
+	}
+	return permissions, nil
+}
+
+func TestCheck(t *testing.T) {
+	p := NewPermissions(&rolesDB{
+		roles: map[model.UserID][]model.Role{
+			"admin":   {model.RoleAdmin},
+			"support": {model.RoleSupport},
+			"user":    {},
+		},
+		permissions: map[model.Role][]model.Permission{
+			model.RoleSupport: {model.PermissionUsersRead},
+		},
+	})
+
+	check := func(userID model.UserID, pathUserID string, permissionTypes ...PermissionType) bool {
+		r := httptest.NewRequest("GET", "/", nil)
+		r = r.WithContext(WithPrincipalContext(r.Context(), model.Principal{UserID: userID}))
+		r = mux.SetURLVars(r, map[string]string{"userID": pathUserID})
+		return p.Check(r, permissionTypes...)
+	}
+
+	for _, test := range []struct {
+		userID          model.UserID
+		pathUserID      string
+		permissionTypes []PermissionType
+		want            bool
+	}{
+		{"admin", "user", []PermissionType{Admin}, true},
+		{"support", "user", []PermissionType{Admin}, false},
+		// admin has all named permissions, other roles only their own
+		{"admin", "user", []PermissionType{UsersWrite}, true},
+		{"support", "user", []PermissionType{UsersRead}, true},
+		{"support", "user", []PermissionType{UsersWrite}, false},
+		{"user", "user", []PermissionType{UsersRead}, false},
+		{"user", "user", []PermissionType{Admin, MemberIsTarget, UsersRead}, true},
+		{"user", "other", []PermissionType{Admin, MemberIsTarget, UsersRead}, false},
+		{"support", "other", []PermissionType{Admin, MemberIsTarget, UsersRead}, true},
+		{"", "", []PermissionType{Member}, false},
+		{"", "", []PermissionType{Any}, true},
+	} {
+		if got := check(test.userID, test.pathUserID, test.permissionTypes...); got != test.want {
+			t.Errorf("Check(%q on %q, %v) = %v; want %v", test.userID, test.pathUserID, test.permissionTypes, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// scopeResources maps last static segment of route to resource of API key scopes.
+// Routes which are not here (api-keys, 2fa, roles, login...) can't be used with API key at all,
+// key must not be able to create other keys or change how user logs in.
+var scopeResources = map[string]string{
+	"users":           "users",
+	"accounts":        "accounts",
+	"members":         "accounts",
+	"invitations":     "accounts",
+	"statements":      "accounts",
+	"upcoming":        "accounts",
+	"amortization":    "accounts",
+	"loan":            "accounts",
+	"holdings":        "accounts",
+	"prices":          "accounts",
+	"networth":        "accounts",
+	"reconciliations": "accounts",
+	"categories":      "categories",
+	"merchants":       "merchants",
+	"transactions":    "transactions",
+}
+
+// routeActions are last segments of routes which act on resource before them,
+// "/users/{userID}/transactions/{transactionID}/history" is read of transactions.
+// Actions which are true change resource, they need write scope whatever method route has.
+var routeActions = map[string]bool{
+	"history": false,
+	"restore": true,
+	"import":  true,
+	"clear":   true,
+	"lock":    true,
+}
+
+// routeSegments returns segments of route template, "/users/{userID}/accounts" -> ["users", "{userID}", "accounts"]
+func routeSegments(r *http.Request) ([]string, bool) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return nil, false
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return nil, false
+	}
+
+	return strings.Split(strings.Trim(template, "/"), "/"), true
+}
+
+// routeResource returns resource of route, "/users/{userID}/accounts/{accountID}" -> "accounts"
+func routeResource(segments []string) (string, bool) {
+	for i := len(segments) - 1; i >= 0; i-- {
+		if _, action := routeActions[segments[i]]; strings.HasPrefix(segments[i], "{") || action {
+			continue
+		}
+		resource, ok := scopeResources[segments[i]]
+		return resource, ok
+	}
+	return "", false
+}
+
+// isWrite - everything except GET changes data, so do actions which change resource
+func isWrite(r *http.Request, segments []string) bool {
+	if len(segments) > 0 && routeActions[segments[len(segments)-1]] {
+		return true
+	}
+	return r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions
+}
+
+// allowedByScopes checks scopes of API key, principal from JWT isn't limited by scopes
+func allowedByScopes(r *http.Request, principal model.Principal) bool {
+	if !principal.IsAPIKey() {
+		return true
+	}
+
+	segments, ok := routeSegments(r)
+	if !ok {
+		return false
+	}
+
+	resource, ok := routeResource(segments)
+	if !ok {
+		return false
+	}
+
+	return principal.Scopes.Allows(resource, isWrite(r, segments))
+}
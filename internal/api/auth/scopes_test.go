@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// serveWithPrincipal routes request like API router does and returns status of response
+func serveWithPrincipal(router *mux.Router, principal model.Principal, method, path string) int {
+	r := httptest.NewRequest(method, path, nil)
+	r = r.WithContext(WithPrincipalContext(r.Context(), principal))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestAllowedByScopes(t *testing.T) {
+	router := mux.NewRouter()
+	for _, path := range []string{
+		"/users/{userID}",
+		"/users/{userID}/accounts",
+		"/users/{userID}/accounts/{accountID}/members",
+		"/users/{userID}/accounts/{accountID}/history",
+		"/users/{userID}/accounts/{accountID}/restore",
+		"/users/{userID}/transactions",
+		"/users/{userID}/api-keys",
+	} {
+		router.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if !allowedByScopes(r, GetPrincipal(r)) {
+				w.WriteHeader(http.StatusForbidden)
+			}
+		})
+	}
+
+	key := func(scopes ...model.Scope) model.Principal {
+		return model.Principal{UserID: "user", APIKeyID: "key", Scopes: scopes}
+	}
+
+	for _, test := range []struct {
+		principal model.Principal
+		method    string
+		path      string
+		want      int
+	}{
+		{key("accounts:read"), http.MethodGet, "/users/user/accounts", http.StatusOK},
+		{key("accounts:read"), http.MethodPost, "/users/user/accounts", http.StatusForbidden},
+		{key("accounts:write"), http.MethodPost, "/users/user/accounts", http.StatusOK},
+		{key("accounts:read"), http.MethodGet, "/users/user/accounts/account/members", http.StatusOK},
+		{key("accounts:read"), http.MethodGet, "/users/user/accounts/account/history", http.StatusOK},
+		{key("accounts:read"), http.MethodPost, "/users/user/accounts/account/restore", http.StatusForbidden},
+		{key("accounts:write"), http.MethodPost, "/users/user/accounts/account/restore", http.StatusOK},
+		{key("accounts:read"), http.MethodGet, "/users/user/transactions", http.StatusForbidden},
+		{key("*:read"), http.MethodGet, "/users/user/transactions", http.StatusOK},
+		{key("*:read"), http.MethodPatch, "/users/user", http.StatusForbidden},
+		{key("users:write"), http.MethodPatch, "/users/user", http.StatusOK},
+		// key can't manage keys at all, user with JWT can
+		{key("*:write"), http.MethodGet, "/users/user/api-keys", http.StatusForbidden},
+		{model.Principal{UserID: "user"}, http.MethodPost, "/users/user/api-keys", http.StatusOK},
+	} {
+		if got := serveWithPrincipal(router, test.principal, test.method, test.path); got != test.want {
+			t.Errorf("%s %s with %v = %d; want %d", test.method, test.path, test.principal.Scopes, got, test.want)
+		}
+	}
+}
+
+func TestWrapRefusesAPIKey(t *testing.T) {
+	p := NewPermissions(&rolesDB{})
+	router := mux.NewRouter()
+	router.HandleFunc("/users/{userID}", p.Wrap(func(w http.ResponseWriter, r *http.Request) {}, MemberIsTarget, RefuseAPIKey))
+
+	if got := serveWithPrincipal(router, model.Principal{UserID: "user"}, http.MethodDelete, "/users/user"); got != http.StatusOK {
+		t.Fatalf("DELETE with token = %d; want %d", got, http.StatusOK)
+	}
+
+	key := model.Principal{UserID: "user", APIKeyID: "key", Scopes: model.Scopes{"*:write"}}
+	if got := serveWithPrincipal(router, key, http.MethodDelete, "/users/user"); got != http.StatusForbidden {
+		t.Fatalf("DELETE with api key = %d; want %d", got, http.StatusForbidden)
+	}
+}
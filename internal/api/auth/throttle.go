@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/namsral/flag"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// Login brute-force protection.
+// We count failed logins per account and per IP address. After few free attempts every
+// next failure doubles waiting time (1s, 2s, 4s...) and after max attempts key is locked.
+var (
+	loginFreeAttempts   = flag.Int("login-free-attempts", 3, "Failed logins allowed before backoff starts.")
+	loginMaxAttempts    = flag.Int("login-max-attempts", 10, "Failed logins of account before it is locked.")
+	loginIPMaxAttempts  = flag.Int("login-ip-max-attempts", 50, "Failed logins from one IP address before it is locked.")
+	loginLockout        = flag.Duration("login-lockout", 15*time.Minute, "How long account or IP address stays locked.")
+	loginFailuresWindow = flag.Duration("login-failures-window", 24*time.Hour, "Failed logins older than this are forgotten.")
+)
+
+// first backoff delay, it is doubled with every next failure
+const loginBackoffBase = 1 * time.Second
+
+// ThrottleKey identifies what we are counting failed logins for
+type ThrottleKey struct {
+	kind  string
+	value string
+}
+
+func (k ThrottleKey) String() string {
+	return k.kind + ":" + k.value
+}
+
+// AccountKey - failed password logins of email (email doesn't have to exist)
+func AccountKey(email string) ThrottleKey {
+	return ThrottleKey{kind: "account", value: strings.ToLower(strings.TrimSpace(email))}
+}
+
+// TwoFactorKey - failed two-factor codes of user
+func TwoFactorKey(userID model.UserID) ThrottleKey {
+	return ThrottleKey{kind: "2fa", value: string(userID)}
+}
+
+// IPKey - failed logins from IP address (any account)
+func IPKey(ip string) ThrottleKey {
+	return ThrottleKey{kind: "ip", value: ip}
+}
+
+// maxAttempts - one IP can be shared by many users (office, mobile network) so it has bigger limit
+func (k ThrottleKey) maxAttempts() int {
+	if k.kind == "ip" {
+		return *loginIPMaxAttempts
+	}
+	return *loginMaxAttempts
+}
+
+// delay returns how long key will be locked after number of failures and if it is lockout
+func (k ThrottleKey) delay(failures int) (time.Duration, bool) {
+	if failures >= k.maxAttempts() {
+		return *loginLockout, true
+	}
+
+	if failures <= *loginFreeAttempts {
+		return 0, false
+	}
+
+	delay := loginBackoffBase << uint(failures-*loginFreeAttempts-1)
+	if delay <= 0 || delay > *loginLockout {
+		delay = *loginLockout
+	}
+	return delay, false
+}
+
+// LoginThrottle keeps failed login attempts in database
+type LoginThrottle struct {
+	DB database.Database
+}
+
+func NewLoginThrottle(db database.Database) *LoginThrottle {
+	return &LoginThrottle{
+		DB: db,
+	}
+}
+
+// Check returns how long client has to wait before it can try to login again (0 - can try now)
+func (t *LoginThrottle) Check(ctx context.Context, keys ...ThrottleKey) (time.Duration, error) {
+	return t.DB.GetLoginRetryAfter(ctx, keyStrings(keys))
+}
+
+// Failure records failed login for all keys and locks them if needed.
+// userID is empty when email doesn't belong to any user.
+func (t *LoginThrottle) Failure(ctx context.Context, userID model.UserID, ip string, keys ...ThrottleKey) error {
+	for _, key := range keys {
+		attempt, err := t.DB.RecordLoginFailure(ctx, key.String(), *loginFailuresWindow)
+		if err != nil {
+			return err
+		}
+
+		delay, lockout := key.delay(attempt.Failures)
+		if delay == 0 {
+			continue
+		}
+
+		if err := t.DB.LockLogin(ctx, key.String(), delay); err != nil {
+			return err
+		}
+
+		// we log only the first failure which locks key, not every next one
+		if lockout && attempt.Failures == key.maxAttempts() {
+			t.audit(ctx, model.AuditLoginLocked, userID, model.NilUserID, ip, map[string]interface{}{
+				"key":      key.String(),
+				"failures": attempt.Failures,
+				"lockout":  delay.String(),
+			})
+		}
+	}
+
+	return nil
+}
+
+// Success forgets failed logins of keys. Don't reset IP key here,
+// attacker could reset it by logging in to his own account between attempts.
+func (t *LoginThrottle) Success(ctx context.Context, keys ...ThrottleKey) error {
+	return t.DB.ResetLoginAttempts(ctx, keyStrings(keys))
+}
+
+// Unlock removes lock of user account, admin does it when user proves identity
+func (t *LoginThrottle) Unlock(ctx context.Context, user *model.User, admin model.Principal, ip string) error {
+	keys := []ThrottleKey{AccountKey(*user.Email), TwoFactorKey(user.ID)}
+	if err := t.DB.ResetLoginAttempts(ctx, keyStrings(keys)); err != nil {
+		return err
+	}
+
+	t.audit(ctx, model.AuditLoginUnlocked, user.ID, admin.UserID, ip, nil)
+	return nil
+}
+
+// audit writes entry to audit log, error is only logged, it must not break login
+func (t *LoginThrottle) audit(ctx context.Context, action model.AuditAction, userID, actorID model.UserID, ip string, details map[string]interface{}) {
+	entry := &model.AuditEntry{
+		Action: action,
+		IP:     &ip,
+	}
+
+	if userID != model.NilUserID {
+		entry.UserID = &userID
+	}
+
+	if actorID != model.NilUserID {
+		entry.ActorID = &actorID
+	}
+
+	if details != nil {
+		data, err := json.Marshal(details)
+		if err != nil {
+			logrus.WithError(err).Warn("could not encode audit details")
+		}
+		entry.Details = data
+	}
+
+	if err := t.DB.CreateAuditEntry(ctx, entry); err != nil {
+		logrus.WithError(err).WithField("action", action).Warn("could not write audit entry")
+	}
+}
+
+func keyStrings(keys []ThrottleKey) []string {
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, key.String())
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleKeys(t *testing.T) {
+	if got := AccountKey(" User@Example.COM ").String(); got != "account:user@example.com" {
+		t.Fatalf("AccountKey() = %q", got)
+	}
+	if got := TwoFactorKey("user-id").String(); got != "2fa:user-id" {
+		t.Fatalf("TwoFactorKey() = %q", got)
+	}
+	if got := IPKey("10.0.0.1").String(); got != "ip:10.0.0.1" {
+		t.Fatalf("IPKey() = %q", got)
+	}
+}
+
+func TestThrottleDelay(t *testing.T) {
+	for _, test := range []struct {
+		key      ThrottleKey
+		failures int
+		delay    time.Duration
+		lockout  bool
+	}{
+		{AccountKey("a"), 1, 0, false},
+		{AccountKey("a"), 3, 0, false},
+		{AccountKey("a"), 4, 1 * time.Second, false},
+		{AccountKey("a"), 5, 2 * time.Second, false},
+		{AccountKey("a"), 9, 32 * time.Second, false},
+		{AccountKey("a"), 10, 15 * time.Minute, true},
+		{AccountKey("a"), 11, 15 * time.Minute, true},
+		{TwoFactorKey("u"), 10, 15 * time.Minute, true},
+		{IPKey("ip"), 10, 64 * time.Second, false},
+		// backoff never waits longer than lockout
+		{IPKey("ip"), 20, 15 * time.Minute, false},
+		{IPKey("ip"), 49, 15 * time.Minute, false},
+		{IPKey("ip"), 50, 15 * time.Minute, true},
+	} {
+		delay, lockout := test.key.delay(test.failures)
+		if delay != test.delay || lockout != test.lockout {
+			t.Errorf("%v.delay(%d) = %v, %v; want %v, %v", test.key, test.failures, delay, lockout, test.delay, test.lockout)
+		}
+	}
+}
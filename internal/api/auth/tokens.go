@@ -18,8 +18,19 @@ var accessTokenDuration = time.Duration(30) * time.Minute // 30 min
 var refreshTokenDuration = time.Duration(30*24) * time.Hour // 30 days
 // var refreshTokenDuration = time.Duration(120) * time.Second // TEST: after access token fails we send this token and it should return new one. we will wait 1 miniute and we should get expried error
 
+var challengeTokenDuration = time.Duration(5) * time.Minute // user has 5 min to enter two-factor code
+
+var impersonationTokenDuration = time.Duration(15) * time.Minute // admin has to ask for new token, there is no refresh token
+
+// challengePurpose is set in claims of token which can be used only to finish two-factor login
+const challengePurpose = "2fa"
+
 type Claims struct {
-	UserID model.UserID `json:"userID"`
+	UserID   model.UserID   `json:"userID"`
+	DeviceID model.DeviceID `json:"deviceID,omitempty"`
+	Purpose  string         `json:"purpose,omitempty"` // empty for access and refresh tokens
+	// admin who impersonates UserID, only in impersonation tokens
+	ImpersonatorID model.UserID `json:"impersonatorID,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -41,13 +52,13 @@ func IssueToken(principal model.Principal) (*Tokens, error) {
 		return nil, errors.New("invalid principal")
 	}
 	// Generate Access token
-	accessToken, accessTokenExpiresAt, err := generateToken(principal, accessTokenDuration)
+	accessToken, accessTokenExpiresAt, err := generateToken(principal, accessTokenDuration, "")
 	if err != nil {
 		return nil, err
 	}
 
 	// Generate Refresh token
-	refreshToken, refreshTokenExpiresAt, err := generateToken(principal, refreshTokenDuration)
+	refreshToken, refreshTokenExpiresAt, err := generateToken(principal, refreshTokenDuration, "")
 	if err != nil {
 		return nil, err
 	}
@@ -62,10 +73,73 @@ func IssueToken(principal model.Principal) (*Tokens, error) {
 	return &tokens, nil
 }
 
-func generateToken(principal model.Principal, duration time.Duration) (string, int64, error) {
+// Challenge is returned by login instead of Tokens when user has two-factor authentication enabled
+type Challenge struct {
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// IssueChallengeToken generates short lived token which proves that user already passed password check
+func IssueChallengeToken(userID model.UserID) (*Challenge, error) {
+	if userID == model.NilUserID {
+		return nil, errors.New("invalid user")
+	}
+
+	token, expiresAt, err := generateToken(model.Principal{UserID: userID}, challengeTokenDuration, challengePurpose)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Challenge{
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// VerifyChallengeToken returns user of valid challenge token
+func VerifyChallengeToken(token string) (model.UserID, error) {
+	claims, err := parseToken(token)
+	if err != nil {
+		return model.NilUserID, err
+	}
+
+	if claims.Purpose != challengePurpose {
+		return model.NilUserID, errors.New("not a challenge token")
+	}
+
+	return claims.UserID, nil
+}
+
+// IssueImpersonationToken generates short lived access token of user for admin.
+// Token carries both users, everything done with it is audited with admin as impersonator.
+func IssueImpersonationToken(adminID, userID model.UserID) (*Tokens, error) {
+	if adminID == model.NilUserID || userID == model.NilUserID {
+		return nil, errors.New("invalid principal")
+	}
+
+	principal := model.Principal{
+		UserID:         userID,
+		ImpersonatorID: adminID,
+	}
+
+	accessToken, accessTokenExpiresAt, err := generateToken(principal, impersonationTokenDuration, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessTokenExpiresAt,
+	}, nil
+}
+
+func generateToken(principal model.Principal, duration time.Duration, purpose string) (string, int64, error) {
 	now := time.Now()
 	claims := &Claims{
-		UserID: principal.UserID,
+		UserID:         principal.UserID,
+		DeviceID:       principal.DeviceID,
+		Purpose:        purpose,
+		ImpersonatorID: principal.ImpersonatorID,
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt:  now.Unix(),
 			ExpiresAt: now.Add(duration).Unix(),
@@ -83,27 +157,38 @@ func generateToken(principal model.Principal, duration time.Duration) (string, i
 }
 
 func VerifyToken(token string) (model.Principal, error) {
+	claims, err := parseToken(token)
+	if err != nil {
+		return model.NilPrincipal, err
+	}
+
+	// challenge token must not work as access token, user didn't finish login yet
+	if claims.Purpose != "" {
+		return model.NilPrincipal, errors.New("invalid token")
+	}
+
+	principal := model.Principal{
+		UserID:         claims.UserID,
+		DeviceID:       claims.DeviceID,
+		ImpersonatorID: claims.ImpersonatorID,
+	}
+
+	return principal, nil
+}
+
+func parseToken(token string) (*Claims, error) {
 	claims := &Claims{}
 	tkn, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
 		return jwtKey, nil
 	})
 
 	if err != nil {
-		if err == jwt.ErrSignatureInvalid {
-			return model.NilPrincipal, err
-		}
-
-		return model.NilPrincipal, err
-	}
-
-	principal := model.Principal{
-		UserID: claims.UserID,
+		return nil, err
 	}
 
-	// we want to return principal even token invalid becase we need to get userID
 	if !tkn.Valid {
-		return model.NilPrincipal, err
+		return nil, errors.New("invalid token")
 	}
 
-	return principal, nil
+	return claims, nil
 }
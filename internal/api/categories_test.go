@@ -0,0 +1,81 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestCategories(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	h.deny(http.MethodPost, userPath(user, "categories"), user.Token, map[string]string{}, http.StatusBadRequest)
+	h.deny(http.MethodPost, userPath(user, "categories"), other.Token, map[string]string{"name": "Food"}, http.StatusUnauthorized)
+
+	parentID := h.createCategory(user, "Food", "")
+	childID := h.createCategory(user, "Restaurants", parentID)
+	otherCategoryID := h.createCategory(other, "Travel", "")
+
+	// parent has to be own category
+	h.deny(http.MethodPost, userPath(user, "categories"), user.Token, map[string]interface{}{
+		"name":     "Flights",
+		"parentID": otherCategoryID,
+	}, http.StatusUnprocessableEntity)
+
+	var categories []*model.Category
+	h.call(http.MethodGet, userPath(user, "categories"), user.Token, nil, http.StatusOK, &categories)
+	if len(categories) != 2 {
+		t.Fatalf("expected 2 categories, got %d", len(categories))
+	}
+	h.call(http.MethodGet, userPath(user, "categories"), admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodGet, userPath(user, "categories"), other.Token, nil, http.StatusUnauthorized)
+
+	childPath := userPath(user, "categories", string(childID))
+
+	var category model.Category
+	h.call(http.MethodGet, childPath, user.Token, nil, http.StatusOK, &category)
+	if category.ParentID != parentID {
+		t.Fatalf("expected parent %s, got %s", parentID, category.ParentID)
+	}
+	h.deny(http.MethodGet, childPath, other.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodGet, userPath(other, "categories", string(childID)), other.Token, nil, http.StatusUnauthorized)
+
+	h.call(http.MethodPatch, childPath, user.Token, map[string]string{"name": "Cafes"}, http.StatusOK, nil)
+	h.deny(http.MethodPatch, childPath, user.Token, map[string]interface{}{"parentID": childID}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPatch, childPath, user.Token, map[string]interface{}{"parentID": otherCategoryID}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPatch, childPath, other.Token, map[string]string{"name": "Stolen"}, http.StatusUnauthorized)
+
+	var history []*model.Revision
+	h.call(http.MethodGet, childPath+"/history", user.Token, nil, http.StatusOK, &history)
+	if len(history) != 2 {
+		t.Fatalf("expected 2 revisions, got %d", len(history))
+	}
+	h.deny(http.MethodGet, childPath+"/history", other.Token, nil, http.StatusUnauthorized)
+
+	h.call(http.MethodPost, childPath+"/restore", user.Token, map[string]int{"revision": 1}, http.StatusOK, &category)
+	if *category.Name != "Restaurants" {
+		t.Fatalf("expected name of first revision, got %s", *category.Name)
+	}
+	h.deny(http.MethodPost, childPath+"/restore", other.Token, nil, http.StatusUnauthorized)
+
+	// parent with child is refused by default
+	parentPath := userPath(user, "categories", string(parentID))
+	h.deny(http.MethodDelete, parentPath, user.Token, nil, http.StatusConflict)
+	h.deny(http.MethodDelete, parentPath+"?strategy=reassign&target="+string(childID), user.Token, nil, http.StatusUnprocessableEntity)
+	h.deny(http.MethodDelete, parentPath, other.Token, nil, http.StatusUnauthorized)
+
+	var result model.DeleteResult
+	h.call(http.MethodDelete, parentPath+"?strategy=cascade", user.Token, nil, http.StatusOK, &result)
+	if !result.Deleted || result.Categories != 1 {
+		t.Fatalf("expected 1 deleted child, got %+v", result)
+	}
+
+	h.call(http.MethodGet, userPath(user, "categories"), user.Token, nil, http.StatusOK, &categories)
+	if len(categories) != 0 {
+		t.Fatalf("expected no categories, got %d", len(categories))
+	}
+}
@@ -0,0 +1,267 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/startdusk/finance-app-backend/internal/api"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// API tests send requests to router built by api.NewRouter over in-memory database,
+// so they go through the same middleware and permission checks as real requests.
+
+// testPassword passes password policy (length, strength) and isn't in breached list
+const testPassword = "correct-horse-battery-staple-42"
+
+// testDevice is device id every test user logs in from
+const testDevice = "test-device"
+
+var userCounter int64
+
+type harness struct {
+	t      *testing.T
+	db     database.Database
+	router http.Handler
+
+	// remoteAddr of requests, httptest uses 192.0.2.1 when it is empty
+	remoteAddr string
+}
+
+// testUser is signed up user with his tokens
+type testUser struct {
+	ID           model.UserID
+	Email        string
+	Token        string
+	RefreshToken string
+}
+
+type tokenResponse struct {
+	Tokens struct {
+		AccessToken  string `json:"accessToken"`
+		ExpiresAt    int64  `json:"expiresAt"`
+		RefreshToken string `json:"refreshToken"`
+	} `json:"tokens"`
+	User      model.User `json:"user"`
+	Challenge *struct {
+		Token string `json:"token"`
+	} `json:"challenge"`
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+
+	db := database.NewMemory()
+	router, err := api.NewRouter(db)
+	if err != nil {
+		t.Fatalf("could not create router: %v", err)
+	}
+
+	return &harness{
+		t:      t,
+		db:     db,
+		router: router,
+	}
+}
+
+// do sends request with JSON body, token is sent as bearer token when it isn't empty
+func (h *harness) do(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatalf("could not encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if h.remoteAddr != "" {
+		req.RemoteAddr = h.remoteAddr
+	}
+
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// call sends request, checks status and decodes response into result (if it isn't nil)
+func (h *harness) call(method, path, token string, body interface{}, status int, result interface{}) *httptest.ResponseRecorder {
+	h.t.Helper()
+
+	rec := h.do(method, path, token, body)
+	if rec.Code != status {
+		h.t.Fatalf("%s %s: expected status %d, got %d: %s", method, path, status, rec.Code, rec.Body.String())
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), result); err != nil {
+			h.t.Fatalf("%s %s: could not decode response: %v: %s", method, path, err, rec.Body.String())
+		}
+	}
+	return rec
+}
+
+// deny checks that request is refused with status without decoding anything
+func (h *harness) deny(method, path, token string, body interface{}, status int) {
+	h.t.Helper()
+	h.call(method, path, token, body, status, nil)
+}
+
+func newEmail() string {
+	return fmt.Sprintf("user%d@example.com", atomic.AddInt64(&userCounter, 1))
+}
+
+// signUp creates new user with POST /users and returns him with his tokens
+func (h *harness) signUp() *testUser {
+	h.t.Helper()
+
+	email := newEmail()
+	var response tokenResponse
+	h.call(http.MethodPost, "/api/v1/users", "", map[string]string{
+		"email":    email,
+		"password": testPassword,
+		"deviceID": testDevice,
+	}, http.StatusCreated, &response)
+
+	return &testUser{
+		ID:           response.User.ID,
+		Email:        email,
+		Token:        response.Tokens.AccessToken,
+		RefreshToken: response.Tokens.RefreshToken,
+	}
+}
+
+// login sends POST /login, response is returned as is (tokens, challenge or error)
+func (h *harness) login(email, password string) *httptest.ResponseRecorder {
+	h.t.Helper()
+
+	return h.do(http.MethodPost, "/api/v1/login", "", map[string]string{
+		"email":    email,
+		"password": password,
+		"deviceID": testDevice,
+	})
+}
+
+// admin signs up new user with admin role. Role is granted before user sends any request,
+// roles are cached by permissions.
+func (h *harness) admin() *testUser {
+	h.t.Helper()
+
+	user := h.signUp()
+	if err := h.db.GrantRole(context.Background(), user.ID, model.RoleAdmin); err != nil {
+		h.t.Fatalf("could not grant admin role: %v", err)
+	}
+	return user
+}
+
+// apiKey creates API key of user with scopes, returned user calls API with the key
+func (h *harness) apiKey(user *testUser, scopes ...string) *testUser {
+	h.t.Helper()
+
+	var created apiKeyCreated
+	h.call(http.MethodPost, userPath(user, "api-keys"), user.Token, map[string]interface{}{
+		"name":   "test key",
+		"scopes": scopes,
+	}, http.StatusCreated, &created)
+	return &testUser{ID: user.ID, Token: created.Key}
+}
+
+// shareAccount invites member to account of owner with access ("viewer" or "editor") and accepts invitation
+func (h *harness) shareAccount(owner *testUser, accountID model.AccountID, member *testUser, access string) {
+	h.t.Helper()
+
+	h.call(http.MethodPost, userPath(owner, "accounts", string(accountID), "members"), owner.Token, map[string]string{
+		"email":  member.Email,
+		"access": access,
+	}, http.StatusCreated, nil)
+	h.call(http.MethodPost, userPath(member, "invitations", string(accountID), "accept"), member.Token, nil, http.StatusOK, nil)
+}
+
+// createAccount creates account of user and returns its id
+func (h *harness) createAccount(user *testUser, name, currency string) model.AccountID {
+	h.t.Helper()
+
+	var account model.Account
+	h.call(http.MethodPost, userPath(user, "accounts"), user.Token, map[string]interface{}{
+		"name":         name,
+		"type":         "cash",
+		"startBalance": "0",
+		"currency":     currency,
+	}, http.StatusCreated, &account)
+	return account.ID
+}
+
+// createCategory creates category of user, parentID can be empty
+func (h *harness) createCategory(user *testUser, name string, parentID model.CategoryID) model.CategoryID {
+	h.t.Helper()
+
+	body := map[string]interface{}{
+		"name": name,
+	}
+	if parentID != "" {
+		body["parentID"] = parentID
+	}
+
+	var category model.Category
+	h.call(http.MethodPost, userPath(user, "categories"), user.Token, body, http.StatusCreated, &category)
+	return category.ID
+}
+
+// createTransaction creates expense of user dated an hour ago
+func (h *harness) createTransaction(user *testUser, accountID model.AccountID, categoryID model.CategoryID) model.TransactionID {
+	h.t.Helper()
+
+	var transaction model.Transaction
+	h.call(http.MethodPost, userPath(user, "transactions"), user.Token,
+		transactionBody(accountID, categoryID), http.StatusCreated, &transaction)
+	return transaction.ID
+}
+
+func transactionBody(accountID model.AccountID, categoryID model.CategoryID) map[string]interface{} {
+	return map[string]interface{}{
+		"accountID":  accountID,
+		"categoryID": categoryID,
+		"date":       hourAgo(),
+		"type":       "expense",
+		"amount":     "25",
+		"notes":      "lunch",
+	}
+}
+
+// userPath returns API path of user, parts are appended with "/"
+func userPath(user *testUser, parts ...string) string {
+	path := "/api/v1/users/" + string(user.ID)
+	for _, part := range parts {
+		path += "/" + part
+	}
+	return path
+}
+
+// hourAgo is date of test transactions, lists have to be asked with from before it
+func hourAgo() string {
+	return time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+}
+
+// dayWindow is query of transaction lists which contains transactions dated hourAgo
+func dayWindow() string {
+	now := time.Now().UTC()
+	return "?from=" + now.Add(-24*time.Hour).Format(time.RFC3339) + "&to=" + now.Add(time.Minute).Format(time.RFC3339)
+}
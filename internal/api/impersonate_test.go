@@ -0,0 +1,43 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestImpersonate(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+	otherAdmin := h.admin()
+
+	h.deny(http.MethodPost, userPath(user, "impersonate"), "", nil, http.StatusUnauthorized)
+	h.deny(http.MethodPost, userPath(user, "impersonate"), other.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodPost, userPath(admin, "impersonate"), admin.Token, nil, http.StatusBadRequest)
+	h.deny(http.MethodPost, userPath(otherAdmin, "impersonate"), admin.Token, nil, http.StatusForbidden)
+
+	var response tokenResponse
+	h.call(http.MethodPost, userPath(user, "impersonate"), admin.Token, nil, http.StatusOK, &response)
+	token := response.Tokens.AccessToken
+	if token == "" || response.Tokens.RefreshToken != "" {
+		t.Fatalf("expected only access token, got %+v", response.Tokens)
+	}
+
+	// token acts as user, not as admin
+	h.call(http.MethodGet, userPath(user), token, nil, http.StatusOK, nil)
+	h.createAccount(&testUser{ID: user.ID, Token: token}, "Wallet", "USD")
+	h.deny(http.MethodGet, userPath(other), token, nil, http.StatusUnauthorized)
+
+	// sensitive APIs refuse impersonation even if user could call them
+	h.deny(http.MethodPatch, userPath(user), token, map[string]string{"password": "another-long-passphrase-77"}, http.StatusForbidden)
+	h.deny(http.MethodPost, userPath(user, "api-keys"), token, map[string]interface{}{
+		"name":   "key",
+		"scopes": []string{"accounts:read"},
+	}, http.StatusForbidden)
+	h.deny(http.MethodPost, userPath(user, "2fa"), token, nil, http.StatusForbidden)
+	h.deny(http.MethodGet, userPath(user, "export"), token, nil, http.StatusForbidden)
+	h.deny(http.MethodPost, userPath(user, "erasure"), token, nil, http.StatusForbidden)
+	h.deny(http.MethodDelete, userPath(other), token, nil, http.StatusForbidden)
+	h.deny(http.MethodPost, userPath(other, "impersonate"), token, nil, http.StatusForbidden)
+}
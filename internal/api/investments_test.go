@@ -0,0 +1,141 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestInvestments(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+
+	var account model.Account
+	h.call(http.MethodPost, userPath(user, "accounts"), user.Token, map[string]interface{}{
+		"name":         "Broker",
+		"type":         "investment",
+		"startBalance": "1000",
+		"currency":     "USD",
+	}, http.StatusCreated, &account)
+	categoryID := h.createCategory(user, "Investments", "")
+
+	trade := func(accountID model.AccountID, transactionType, symbol, quantity, amount string) map[string]interface{} {
+		body := map[string]interface{}{
+			"accountID":  accountID,
+			"categoryID": categoryID,
+			"date":       hourAgo(),
+			"type":       transactionType,
+			"amount":     amount,
+			"symbol":     symbol,
+			"notes":      transactionType + " " + symbol,
+		}
+		if quantity != "" {
+			body["quantity"] = quantity
+		}
+		return body
+	}
+
+	var buy model.Transaction
+	h.call(http.MethodPost, userPath(user, "transactions"), user.Token, trade(account.ID, "buy", "aapl", "10", "500"), http.StatusCreated, &buy)
+	if buy.Symbol == nil || *buy.Symbol != "AAPL" || buy.Quantity == nil || buy.Quantity.String() != "10" {
+		t.Fatalf("expected buy of 10 AAPL, got %+v", buy)
+	}
+	h.call(http.MethodPost, userPath(user, "transactions"), user.Token, trade(account.ID, "dividend", "AAPL", "", "5"), http.StatusCreated, nil)
+
+	// can't sell more than account holds, buy needs quantity and investment account
+	h.deny(http.MethodPost, userPath(user, "transactions"), user.Token, trade(account.ID, "sell", "AAPL", "20", "1000"), http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, userPath(user, "transactions"), user.Token, trade(account.ID, "buy", "AAPL", "", "100"), http.StatusUnprocessableEntity)
+	cashID := h.createAccount(user, "Wallet", "USD")
+	h.deny(http.MethodPost, userPath(user, "transactions"), user.Token, trade(cashID, "buy", "AAPL", "1", "50"), http.StatusUnprocessableEntity)
+
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+	pricesPath := userPath(user, "prices")
+	h.call(http.MethodPost, pricesPath, user.Token, map[string]interface{}{
+		"symbol": "AAPL",
+		"date":   yesterday + "T00:00:00Z",
+		"price":  "55",
+	}, http.StatusOK, nil)
+	h.deny(http.MethodPost, pricesPath, user.Token, map[string]interface{}{
+		"symbol": "AAPL",
+		"date":   yesterday + "T00:00:00Z",
+		"price":  "-1",
+	}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, pricesPath, other.Token, map[string]interface{}{
+		"symbol": "AAPL",
+		"date":   yesterday + "T00:00:00Z",
+		"price":  "1",
+	}, http.StatusUnauthorized)
+
+	// import replaces price of the same day
+	importPrices := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, pricesPath+"/import", strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/csv")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.router.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := importPrices(user.Token, "symbol,date,price\nAAPL,"+yesterday+",60\nVTI,2020-01-02,200\n"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"imported":2`) {
+		t.Fatalf("expected 2 imported prices, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := importPrices(user.Token, "MSFT,2020-01-02,1\nMSFT,yesterday,1\n"); rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "prices[2].date") {
+		t.Fatalf("expected invalid date on line 2, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var prices []*model.Price
+	h.call(http.MethodGet, pricesPath+"?symbol=aapl", user.Token, nil, http.StatusOK, &prices)
+	if len(prices) != 1 || prices[0].Price.String() != "60" {
+		t.Fatalf("expected imported price of AAPL, got %+v", prices)
+	}
+
+	holdingsPath := userPath(user, "accounts", string(account.ID), "holdings")
+	var holdings model.Holdings
+	h.call(http.MethodGet, holdingsPath, user.Token, nil, http.StatusOK, &holdings)
+	if len(holdings.Positions) != 1 || holdings.Positions[0].MarketValue == nil || holdings.Positions[0].MarketValue.String() != "600.00" {
+		t.Fatalf("expected AAPL worth 600.00, got %+v", holdings.Positions)
+	}
+	if holdings.Cash.String() != "505.00" || holdings.Value.String() != "1105.00" || holdings.Dividends.String() != "5.00" {
+		t.Fatalf("unexpected holdings totals %+v", holdings)
+	}
+	h.deny(http.MethodGet, holdingsPath, other.Token, nil, http.StatusUnauthorized)
+
+	// viewer of shared account sees holdings valued with prices of owner
+	h.shareAccount(user, account.ID, other, "viewer")
+	var shared model.Holdings
+	h.call(http.MethodGet, holdingsPath, other.Token, nil, http.StatusOK, &shared)
+	if shared.Value.String() != "1105.00" {
+		t.Fatalf("expected viewer to see holdings worth 1105.00, got %+v", shared)
+	}
+	h.deny(http.MethodGet, userPath(user, "accounts", string(cashID), "holdings"), user.Token, nil, http.StatusConflict)
+
+	var netWorth model.NetWorth
+	h.call(http.MethodGet, userPath(user, "networth"), user.Token, nil, http.StatusOK, &netWorth)
+	if len(netWorth.Currencies) != 1 || netWorth.Currencies[0].NetWorth.String() != "1105.00" || len(netWorth.Accounts) != 2 {
+		t.Fatalf("expected net worth 1105.00 of 2 accounts, got %+v", netWorth)
+	}
+	h.deny(http.MethodGet, userPath(user, "networth"), other.Token, nil, http.StatusUnauthorized)
+
+	// holdings, prices and net worth are accounts for API keys
+	readKey := h.apiKey(user, "accounts:read")
+	h.call(http.MethodGet, holdingsPath, readKey.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodGet, pricesPath, readKey.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodGet, userPath(user, "networth"), readKey.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodPost, pricesPath, readKey.Token, map[string]interface{}{
+		"symbol": "AAPL",
+		"date":   yesterday + "T00:00:00Z",
+		"price":  "61",
+	}, http.StatusForbidden)
+	if rec := importPrices(readKey.Token, "AAPL,"+yesterday+",61\n"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected import refused for read key, got %d: %s", rec.Code, rec.Body.String())
+	}
+	writeKey := h.apiKey(user, "accounts:write")
+	if rec := importPrices(writeKey.Token, "AAPL,"+yesterday+",61\n"); rec.Code != http.StatusOK {
+		t.Fatalf("expected import with write key, got %d: %s", rec.Code, rec.Body.String())
+	}
+	h.deny(http.MethodGet, holdingsPath, h.apiKey(user, "transactions:read").Token, nil, http.StatusForbidden)
+}
@@ -0,0 +1,93 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestLoan(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	body := map[string]interface{}{
+		"name":           "Car",
+		"type":           "loan",
+		"startBalance":   "0",
+		"currency":       "USD",
+		"interestRate":   12,
+		"loanPrincipal":  "1200",
+		"loanTermMonths": 12,
+		"loanStartDate":  time.Now().AddDate(0, -2, -1).UTC().Format(time.RFC3339),
+	}
+
+	// loan terms are only for loans
+	body["type"] = "checking"
+	h.deny(http.MethodPost, userPath(user, "accounts"), user.Token, body, http.StatusUnprocessableEntity)
+	body["type"] = "loan"
+	body["loanTermMonths"] = 0
+	h.deny(http.MethodPost, userPath(user, "accounts"), user.Token, body, http.StatusUnprocessableEntity)
+	body["loanTermMonths"] = 12
+
+	var loan model.Account
+	h.call(http.MethodPost, userPath(user, "accounts"), user.Token, body, http.StatusCreated, &loan)
+	if loan.LoanPrincipal == nil || loan.LoanPrincipal.String() != "1200.00" || loan.LoanTermMonths == nil || *loan.LoanTermMonths != 12 {
+		t.Fatalf("expected loan terms, got %+v", loan)
+	}
+
+	schedulePath := userPath(user, "accounts", string(loan.ID), "amortization")
+	var schedule []*model.AmortizationEntry
+	h.call(http.MethodGet, schedulePath, user.Token, nil, http.StatusOK, &schedule)
+	if len(schedule) != 12 || schedule[0].Interest.String() != "12.00" || schedule[11].Balance.Sign() != 0 {
+		t.Fatalf("expected 12 payments paying off loan, got %+v", schedule)
+	}
+	h.call(http.MethodGet, schedulePath, admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodGet, schedulePath, other.Token, nil, http.StatusUnauthorized)
+
+	// payment of loan is income to loan account
+	h.call(http.MethodPost, userPath(user, "transactions"), user.Token, map[string]interface{}{
+		"accountID":  loan.ID,
+		"categoryID": h.createCategory(user, "Loan", ""),
+		"date":       hourAgo(),
+		"type":       "income",
+		"amount":     "200",
+		"notes":      "payment",
+	}, http.StatusCreated, nil)
+
+	loanPath := userPath(user, "accounts", string(loan.ID), "loan")
+	var status model.LoanStatus
+	h.call(http.MethodGet, loanPath+"?extra=50&extra=100", user.Token, nil, http.StatusOK, &status)
+	if status.PrincipalPaid.Sign() <= 0 || status.RemainingPrincipal.Cmp(*loan.LoanPrincipal) >= 0 || status.NextPaymentDate == nil {
+		t.Fatalf("expected payment to reduce principal, got %+v", status)
+	}
+	if len(status.Scenarios) != 3 || status.Scenarios[2].ExtraPayment.String() != "100.00" || status.Scenarios[2].InterestSaved.Sign() <= 0 {
+		t.Fatalf("expected base and 2 extra payment scenarios, got %+v", status.Scenarios)
+	}
+	h.deny(http.MethodGet, loanPath+"?extra=-5", user.Token, nil, http.StatusUnprocessableEntity)
+	h.deny(http.MethodGet, loanPath+"?extra=1.001", user.Token, nil, http.StatusUnprocessableEntity)
+	h.deny(http.MethodGet, loanPath, other.Token, nil, http.StatusUnauthorized)
+
+	// schedule and payoff are read of accounts for API keys
+	accountsKey := h.apiKey(user, "accounts:read")
+	h.call(http.MethodGet, schedulePath, accountsKey.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodGet, loanPath+"?extra=50", accountsKey.Token, nil, http.StatusOK, nil)
+	transactionsKey := h.apiKey(user, "transactions:write")
+	h.deny(http.MethodGet, schedulePath, transactionsKey.Token, nil, http.StatusForbidden)
+	h.deny(http.MethodGet, loanPath, transactionsKey.Token, nil, http.StatusForbidden)
+
+	// viewer of shared account reads schedule and payoff
+	viewer := h.signUp()
+	h.deny(http.MethodGet, schedulePath, viewer.Token, nil, http.StatusUnauthorized)
+	h.shareAccount(user, loan.ID, viewer, "viewer")
+	h.call(http.MethodGet, schedulePath, viewer.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodGet, loanPath, viewer.Token, nil, http.StatusOK, nil)
+
+	// account without loan terms has no schedule
+	cashID := h.createAccount(user, "Wallet", "USD")
+	h.deny(http.MethodGet, userPath(user, "accounts", string(cashID), "amortization"), user.Token, nil, http.StatusConflict)
+	h.deny(http.MethodGet, userPath(user, "accounts", string(cashID), "loan"), user.Token, nil, http.StatusConflict)
+}
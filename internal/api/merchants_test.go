@@ -0,0 +1,61 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestMerchants(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	h.deny(http.MethodPost, userPath(user, "merchants"), user.Token, map[string]string{}, http.StatusBadRequest)
+	h.deny(http.MethodPost, userPath(user, "merchants"), other.Token, map[string]string{"name": "Cafe"}, http.StatusUnauthorized)
+
+	var merchant model.Merchant
+	h.call(http.MethodPost, userPath(user, "merchants"), user.Token, map[string]string{"name": "Cafe"}, http.StatusCreated, &merchant)
+	merchantPath := userPath(user, "merchants", string(merchant.ID))
+
+	var merchants []*model.Merchant
+	h.call(http.MethodGet, userPath(user, "merchants"), user.Token, nil, http.StatusOK, &merchants)
+	if len(merchants) != 1 {
+		t.Fatalf("expected 1 merchant, got %d", len(merchants))
+	}
+	h.call(http.MethodGet, userPath(user, "merchants"), admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodGet, userPath(user, "merchants"), other.Token, nil, http.StatusUnauthorized)
+
+	// fields which aren't sent are kept
+	h.call(http.MethodPatch, merchantPath, user.Token, map[string]string{}, http.StatusOK, nil)
+	h.call(http.MethodPatch, merchantPath, user.Token, map[string]string{"name": "Bakery"}, http.StatusOK, nil)
+	h.deny(http.MethodPatch, merchantPath, other.Token, map[string]string{"name": "Stolen"}, http.StatusUnauthorized)
+
+	h.call(http.MethodGet, merchantPath, user.Token, nil, http.StatusOK, &merchant)
+	if *merchant.Name != "Bakery" {
+		t.Fatalf("expected renamed merchant, got %s", *merchant.Name)
+	}
+	h.deny(http.MethodGet, merchantPath, other.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodGet, userPath(other, "merchants", string(merchant.ID)), other.Token, nil, http.StatusUnauthorized)
+
+	var history []*model.Revision
+	h.call(http.MethodGet, merchantPath+"/history", user.Token, nil, http.StatusOK, &history)
+	if len(history) != 3 {
+		t.Fatalf("expected 3 revisions, got %d", len(history))
+	}
+	h.deny(http.MethodGet, merchantPath+"/history", other.Token, nil, http.StatusUnauthorized)
+
+	h.call(http.MethodPost, merchantPath+"/restore", user.Token, map[string]int{"revision": 1}, http.StatusOK, nil)
+	h.deny(http.MethodPost, merchantPath+"/restore", user.Token, map[string]int{"revision": 99}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, merchantPath+"/restore", other.Token, nil, http.StatusUnauthorized)
+
+	h.deny(http.MethodDelete, merchantPath, other.Token, nil, http.StatusUnauthorized)
+	h.call(http.MethodDelete, merchantPath, user.Token, nil, http.StatusOK, nil)
+
+	h.call(http.MethodGet, userPath(user, "merchants"), user.Token, nil, http.StatusOK, &merchants)
+	if len(merchants) != 0 {
+		t.Fatalf("expected no merchants, got %d", len(merchants))
+	}
+}
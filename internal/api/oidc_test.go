@@ -0,0 +1,258 @@
+package api_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/namsral/flag"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+	"github.com/startdusk/finance-app-backend/internal/oidc"
+)
+
+const (
+	fakeClientID = "finance-app"
+	fakeKeyID    = "test-key"
+)
+
+// fakeProvider is OpenID Connect provider which issues ID token for codes test authorized
+type fakeProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]fakeGrant
+}
+
+// fakeGrant is what user agreed to on provider login page
+type fakeGrant struct {
+	claims        jwt.MapClaims
+	codeChallenge string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+
+	p := &fakeProvider{
+		t:     t,
+		key:   key,
+		codes: make(map[string]fakeGrant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/keys", p.keys)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+func (p *fakeProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 p.server.URL,
+		"authorization_endpoint": p.server.URL + "/authorize",
+		"token_endpoint":         p.server.URL + "/token",
+		"jwks_uri":               p.server.URL + "/keys",
+	})
+}
+
+func (p *fakeProvider) keys(w http.ResponseWriter, r *http.Request) {
+	encoding := base64.RawURLEncoding
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": fakeKeyID,
+			"use": "sig",
+			"n":   encoding.EncodeToString(p.key.N.Bytes()),
+			"e":   encoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *fakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	grant, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	if !ok || r.PostForm.Get("client_id") != fakeClientID || oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != grant.codeChallenge {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+	token.Header["kid"] = fakeKeyID
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "provider-access-token",
+		"id_token":     idToken,
+		"token_type":   "Bearer",
+	})
+}
+
+// authorize plays user who logs in on provider page opened from authURL, it returns code
+func (p *fakeProvider) authorize(authURL, subject, email string, emailVerified bool) string {
+	p.t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatalf("invalid auth URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != fakeClientID || query.Get("code_challenge_method") != "S256" {
+		p.t.Fatalf("unexpected auth request: %s", authURL)
+	}
+
+	code, err := oidc.RandomString()
+	if err != nil {
+		p.t.Fatal(err)
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	p.codes[code] = fakeGrant{
+		claims: jwt.MapClaims{
+			"iss":            p.server.URL,
+			"sub":            subject,
+			"aud":            fakeClientID,
+			"exp":            now.Add(time.Hour).Unix(),
+			"iat":            now.Unix(),
+			"nonce":          query.Get("nonce"),
+			"email":          email,
+			"email_verified": emailVerified,
+		},
+		codeChallenge: query.Get("code_challenge"),
+	}
+	p.mu.Unlock()
+
+	return code
+}
+
+// useProviders configures providers file for routers created in test
+func useProviders(t *testing.T, configs ...oidc.ProviderConfig) {
+	data, err := json.Marshal(configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "providers.json")
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := flag.Set("oidc-providers-file", path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { flag.Set("oidc-providers-file", "") })
+}
+
+// startLogin opens login page of provider and returns URL user is redirected to
+func (h *harness) startLogin(provider string) string {
+	h.t.Helper()
+
+	rec := h.call(http.MethodGet, "/api/v1/login/"+provider+"?deviceID="+testDevice, "", nil, http.StatusFound, nil)
+	return rec.Header().Get("Location")
+}
+
+func callbackPath(provider, code, authURL string) string {
+	parsed, _ := url.Parse(authURL)
+	return "/api/v1/login/" + provider + "/callback?code=" + url.QueryEscape(code) + "&state=" + url.QueryEscape(parsed.Query().Get("state"))
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := newFakeProvider(t)
+	useProviders(t, oidc.ProviderConfig{
+		Name:        "test",
+		Issuer:      provider.server.URL,
+		ClientID:    fakeClientID,
+		RedirectURL: "http://localhost/api/v1/login/test/callback",
+	})
+
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+
+	h.deny(http.MethodGet, "/api/v1/login/unknown?deviceID="+testDevice, "", nil, http.StatusNotFound)
+	h.deny(http.MethodGet, "/api/v1/login/test", "", nil, http.StatusBadRequest)
+	h.deny(http.MethodGet, "/api/v1/login/unknown/callback?code=x&state=y", "", nil, http.StatusNotFound)
+	h.deny(http.MethodGet, "/api/v1/login/test/callback?error=access_denied", "", nil, http.StatusUnauthorized)
+	h.deny(http.MethodGet, "/api/v1/login/test/callback?code=x&state=unknown", "", nil, http.StatusUnauthorized)
+
+	// first login links identity to user with the same verified email
+	authURL := h.startLogin("test")
+	code := provider.authorize(authURL, "subject-1", user.Email, true)
+
+	var response tokenResponse
+	h.call(http.MethodGet, callbackPath("test", code, authURL), "", nil, http.StatusOK, &response)
+	if response.User.ID != user.ID || response.Tokens.AccessToken == "" {
+		t.Fatalf("expected tokens of user %s, got %+v", user.ID, response)
+	}
+
+	// state can be used only once
+	h.deny(http.MethodGet, callbackPath("test", code, authURL), "", nil, http.StatusUnauthorized)
+
+	// next login finds user by identity, even if email changed at provider
+	authURL = h.startLogin("test")
+	code = provider.authorize(authURL, "subject-1", newEmail(), false)
+	h.call(http.MethodGet, callbackPath("test", code, authURL), "", nil, http.StatusOK, &response)
+	if response.User.ID != user.ID {
+		t.Fatalf("expected user %s, got %s", user.ID, response.User.ID)
+	}
+
+	// unknown subject creates new user
+	email := newEmail()
+	authURL = h.startLogin("test")
+	code = provider.authorize(authURL, "subject-2", email, true)
+	h.call(http.MethodGet, callbackPath("test", code, authURL), "", nil, http.StatusOK, &response)
+	if response.User.ID == user.ID || response.User.Email == nil || *response.User.Email != email {
+		t.Fatalf("expected new user with email %s, got %+v", email, response.User)
+	}
+
+	// unverified email can't be trusted
+	authURL = h.startLogin("test")
+	code = provider.authorize(authURL, "subject-3", other.Email, false)
+	h.deny(http.MethodGet, callbackPath("test", code, authURL), "", nil, http.StatusConflict)
+
+	// code which provider didn't issue
+	authURL = h.startLogin("test")
+	h.deny(http.MethodGet, callbackPath("test", "forged", authURL), "", nil, http.StatusUnauthorized)
+
+	var identities []*model.UserIdentity
+	h.call(http.MethodGet, userPath(user, "identities"), user.Token, nil, http.StatusOK, &identities)
+	if len(identities) != 1 || identities[0].Provider != "test" || identities[0].Subject != "subject-1" {
+		t.Fatalf("expected identity of test provider, got %+v", identities)
+	}
+	h.call(http.MethodGet, userPath(other, "identities"), other.Token, nil, http.StatusOK, &identities)
+	if len(identities) != 0 {
+		t.Fatalf("expected no identities, got %d", len(identities))
+	}
+	h.deny(http.MethodGet, userPath(user, "identities"), other.Token, nil, http.StatusUnauthorized)
+}
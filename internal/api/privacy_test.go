@@ -0,0 +1,70 @@
+package api_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestExport(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	h.createAccount(user, "Wallet", "USD")
+
+	rec := h.call(http.MethodGet, userPath(user, "export"), user.Token, nil, http.StatusOK, nil)
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/zip" {
+		t.Fatalf("expected zip archive, got %s", contentType)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("could not read archive: %v", err)
+	}
+	if len(archive.File) == 0 {
+		t.Fatal("expected files in archive")
+	}
+
+	h.call(http.MethodGet, userPath(user, "export"), admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodGet, userPath(user, "export"), other.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodGet, userPath(user, "export"), "", nil, http.StatusUnauthorized)
+}
+
+func TestErasure(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	h.deny(http.MethodGet, userPath(user, "erasure"), user.Token, nil, http.StatusNotFound)
+	h.deny(http.MethodPost, userPath(user, "erasure"), other.Token, nil, http.StatusUnauthorized)
+
+	var request model.ErasureRequest
+	h.call(http.MethodPost, userPath(user, "erasure"), user.Token, nil, http.StatusCreated, &request)
+	if request.UserID != user.ID {
+		t.Fatalf("expected erasure of user %s, got %s", user.ID, request.UserID)
+	}
+	h.deny(http.MethodPost, userPath(user, "erasure"), admin.Token, nil, http.StatusConflict)
+
+	// user is deleted right away
+	if rec := h.login(user.Email, testPassword); rec.Code == http.StatusOK {
+		t.Fatal("user waiting for erasure must not log in")
+	}
+
+	h.call(http.MethodGet, userPath(user, "erasure"), admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodGet, userPath(user, "erasure"), other.Token, nil, http.StatusUnauthorized)
+
+	// only admin can cancel erasure
+	h.deny(http.MethodDelete, userPath(user, "erasure"), user.Token, nil, http.StatusUnauthorized)
+	h.call(http.MethodDelete, userPath(user, "erasure"), admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodDelete, userPath(user, "erasure"), admin.Token, nil, http.StatusNotFound)
+
+	if rec := h.login(user.Email, testPassword); rec.Code != http.StatusOK {
+		t.Fatalf("expected login after canceled erasure, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,191 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestReconciliation(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+
+	accountID := h.createAccount(user, "Checking", "USD")
+	categoryID := h.createCategory(user, "Food", "")
+
+	income := transactionBody(accountID, categoryID)
+	income["type"] = "income"
+	income["amount"] = "100"
+	var salary model.Transaction
+	h.call(http.MethodPost, userPath(user, "transactions"), user.Token, income, http.StatusCreated, &salary)
+	if salary.ClearedStatus == nil || *salary.ClearedStatus != model.Uncleared {
+		t.Fatalf("expected new transaction to be uncleared, got %v", salary.ClearedStatus)
+	}
+	lunchID := h.createTransaction(user, accountID, categoryID)
+
+	reconciliationsPath := userPath(user, "accounts", string(accountID), "reconciliations")
+	statement := map[string]interface{}{
+		"statementDate": time.Now().UTC().Format(time.RFC3339),
+		"endingBalance": "75",
+	}
+	var report model.ReconciliationReport
+	h.call(http.MethodPost, reconciliationsPath, user.Token, statement, http.StatusCreated, &report)
+	if report.Difference.String() != "75.00" || len(report.Uncleared) != 2 {
+		t.Fatalf("expected difference 75.00 with 2 uncleared transactions, got %+v", report)
+	}
+	h.deny(http.MethodPost, reconciliationsPath, user.Token, statement, http.StatusConflict)
+	h.deny(http.MethodPost, reconciliationsPath, other.Token, statement, http.StatusUnauthorized)
+
+	reconciliationPath := reconciliationsPath + "/" + string(report.ID)
+	h.deny(http.MethodPost, reconciliationPath+"/lock", user.Token, nil, http.StatusConflict)
+
+	h.call(http.MethodPost, reconciliationPath+"/clear", user.Token, map[string]interface{}{
+		"transactionIDs": []model.TransactionID{salary.ID},
+	}, http.StatusOK, &report)
+	if report.ClearedBalance.String() != "100.00" || report.Difference.String() != "-25.00" || len(report.Uncleared) != 1 {
+		t.Fatalf("expected cleared salary, got %+v", report)
+	}
+	h.deny(http.MethodPost, reconciliationPath+"/clear", user.Token, map[string]interface{}{
+		"transactionIDs": []model.TransactionID{"00000000-0000-0000-0000-000000000000"},
+	}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, reconciliationPath+"/clear", other.Token, map[string]interface{}{
+		"transactionIDs": []model.TransactionID{lunchID},
+	}, http.StatusUnauthorized)
+
+	// transaction can be cleared by update too
+	h.call(http.MethodPatch, userPath(user, "transactions", string(lunchID)), user.Token, map[string]interface{}{
+		"clearedStatus": "cleared",
+	}, http.StatusOK, nil)
+	h.deny(http.MethodPatch, userPath(user, "transactions", string(lunchID)), user.Token, map[string]interface{}{
+		"clearedStatus": "reconciled",
+	}, http.StatusUnprocessableEntity)
+
+	h.call(http.MethodPost, reconciliationPath+"/lock", user.Token, nil, http.StatusOK, &report)
+	if !report.IsLocked() || report.Difference.Sign() != 0 {
+		t.Fatalf("expected locked reconciliation, got %+v", report)
+	}
+	h.deny(http.MethodPost, reconciliationPath+"/lock", user.Token, nil, http.StatusConflict)
+	h.deny(http.MethodPost, reconciliationPath+"/clear", user.Token, map[string]interface{}{
+		"transactionIDs": []model.TransactionID{lunchID},
+		"status":         "uncleared",
+	}, http.StatusConflict)
+
+	var reconciled model.Transaction
+	h.call(http.MethodGet, userPath(user, "transactions", string(lunchID)), user.Token, nil, http.StatusOK, &reconciled)
+	if reconciled.ClearedStatus == nil || *reconciled.ClearedStatus != model.Reconciled {
+		t.Fatalf("expected reconciled transaction, got %v", reconciled.ClearedStatus)
+	}
+
+	// locked period can be changed only with force
+	lunchPath := userPath(user, "transactions", string(lunchID))
+	h.deny(http.MethodPatch, lunchPath, user.Token, map[string]interface{}{"amount": "30"}, http.StatusConflict)
+	h.deny(http.MethodDelete, lunchPath, user.Token, nil, http.StatusConflict)
+	h.deny(http.MethodPost, userPath(user, "transactions"), user.Token, transactionBody(accountID, categoryID), http.StatusConflict)
+	h.deny(http.MethodPatch, lunchPath+"?force=maybe", user.Token, map[string]interface{}{"amount": "30"}, http.StatusUnprocessableEntity)
+	h.call(http.MethodPatch, lunchPath+"?force=true", user.Token, map[string]interface{}{"amount": "30"}, http.StatusOK, nil)
+
+	// reconciled transaction can't be voided, unclearing it first needs force too
+	h.deny(http.MethodPatch, lunchPath, user.Token, map[string]interface{}{"status": "void"}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPatch, lunchPath, user.Token, map[string]interface{}{"status": "void", "clearedStatus": "uncleared"}, http.StatusConflict)
+
+	// next statement has to end after locked one
+	h.deny(http.MethodPost, reconciliationsPath, user.Token, statement, http.StatusUnprocessableEntity)
+
+	var reconciliations []*model.Reconciliation
+	h.call(http.MethodGet, reconciliationsPath, user.Token, nil, http.StatusOK, &reconciliations)
+	if len(reconciliations) != 1 || reconciliations[0].ID != report.ID {
+		t.Fatalf("expected one reconciliation, got %+v", reconciliations)
+	}
+	h.deny(http.MethodGet, reconciliationsPath, other.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodGet, reconciliationPath, other.Token, nil, http.StatusUnauthorized)
+
+	// viewer of shared account reads reconciliations but doesn't clear transactions
+	h.shareAccount(user, accountID, other, "viewer")
+	h.call(http.MethodGet, reconciliationsPath, other.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodGet, reconciliationPath, other.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodPost, reconciliationPath+"/clear", other.Token, map[string]interface{}{
+		"transactionIDs": []model.TransactionID{lunchID},
+	}, http.StatusUnauthorized)
+}
+
+func TestReconciliationAPIKeys(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+
+	accountID := h.createAccount(user, "Checking", "USD")
+	lunchID := h.createTransaction(user, accountID, h.createCategory(user, "Food", ""))
+
+	readKey := h.apiKey(user, "accounts:read")
+	writeKey := h.apiKey(user, "accounts:write")
+
+	reconciliationsPath := userPath(user, "accounts", string(accountID), "reconciliations")
+	statement := map[string]interface{}{
+		"statementDate": time.Now().UTC().Format(time.RFC3339),
+		"endingBalance": "-25",
+	}
+	h.deny(http.MethodPost, reconciliationsPath, readKey.Token, statement, http.StatusForbidden)
+	var report model.ReconciliationReport
+	h.call(http.MethodPost, reconciliationsPath, writeKey.Token, statement, http.StatusCreated, &report)
+	reconciliationPath := reconciliationsPath + "/" + string(report.ID)
+
+	h.call(http.MethodGet, reconciliationsPath, readKey.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodGet, reconciliationPath, readKey.Token, nil, http.StatusOK, nil)
+
+	// clear and lock change reconciliation, read scope isn't enough
+	clear := map[string]interface{}{
+		"transactionIDs": []model.TransactionID{lunchID},
+	}
+	h.deny(http.MethodPost, reconciliationPath+"/clear", readKey.Token, clear, http.StatusForbidden)
+	h.call(http.MethodPost, reconciliationPath+"/clear", writeKey.Token, clear, http.StatusOK, nil)
+	h.deny(http.MethodPost, reconciliationPath+"/lock", readKey.Token, nil, http.StatusForbidden)
+	h.call(http.MethodPost, reconciliationPath+"/lock", writeKey.Token, nil, http.StatusOK, nil)
+
+	h.deny(http.MethodGet, reconciliationsPath, h.apiKey(user, "transactions:write").Token, nil, http.StatusForbidden)
+}
+
+func TestReconciledAccountChanges(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+
+	accountID := h.createAccount(user, "Checking", "USD")
+	categoryID := h.createCategory(user, "Food", "")
+	lunchID := h.createTransaction(user, accountID, categoryID)
+	otherID := h.createAccount(user, "Savings", "USD")
+	h.createTransaction(user, otherID, categoryID)
+
+	var report model.ReconciliationReport
+	reconciliationsPath := userPath(user, "accounts", string(accountID), "reconciliations")
+	h.call(http.MethodPost, reconciliationsPath, user.Token, map[string]interface{}{
+		"statementDate": time.Now().UTC().Format(time.RFC3339),
+		"endingBalance": "-25",
+	}, http.StatusCreated, &report)
+	h.call(http.MethodPost, reconciliationsPath+"/"+string(report.ID)+"/clear", user.Token, map[string]interface{}{
+		"transactionIDs": []model.TransactionID{lunchID},
+	}, http.StatusOK, nil)
+
+	// cleared transactions are enough, start balance and currency are part of cleared balance
+	accountPath := userPath(user, "accounts", string(accountID))
+	h.deny(http.MethodPatch, accountPath, user.Token, map[string]interface{}{"startBalance": "10"}, http.StatusConflict)
+	h.deny(http.MethodPatch, accountPath, user.Token, map[string]interface{}{"currency": "EUR"}, http.StatusConflict)
+	h.call(http.MethodPatch, accountPath, user.Token, map[string]interface{}{"name": "Main", "startBalance": "0.00"}, http.StatusOK, nil)
+
+	h.call(http.MethodPost, reconciliationsPath+"/"+string(report.ID)+"/lock", user.Token, nil, http.StatusOK, nil)
+
+	// neither reconciled account nor account reassigned to it can be deleted without force
+	otherPath := userPath(user, "accounts", string(otherID))
+	h.deny(http.MethodDelete, accountPath+"?strategy=cascade", user.Token, nil, http.StatusConflict)
+	h.deny(http.MethodDelete, accountPath+"?strategy=reassign&target="+string(otherID), user.Token, nil, http.StatusConflict)
+	h.deny(http.MethodDelete, otherPath+"?strategy=reassign&target="+string(accountID), user.Token, nil, http.StatusConflict)
+	h.deny(http.MethodDelete, otherPath+"?strategy=reassign&target="+string(accountID)+"&force=maybe", user.Token, nil, http.StatusUnprocessableEntity)
+
+	h.call(http.MethodDelete, otherPath+"?strategy=reassign&target="+string(accountID)+"&force=true", user.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodPatch, accountPath+"?force=true", user.Token, map[string]interface{}{"startBalance": "10"}, http.StatusOK, nil)
+
+	// restore of revision with other start balance is a change of start balance too
+	h.deny(http.MethodPost, accountPath+"/restore", user.Token, map[string]int{"revision": 1}, http.StatusConflict)
+	h.call(http.MethodPost, accountPath+"/restore?force=true", user.Token, map[string]int{"revision": 1}, http.StatusOK, nil)
+	h.call(http.MethodDelete, accountPath+"?strategy=cascade&force=true", user.Token, nil, http.StatusOK, nil)
+}
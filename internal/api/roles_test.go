@@ -0,0 +1,110 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestRoles(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	admin := h.admin()
+
+	role := map[string]interface{}{
+		"name":        "auditor",
+		"permissions": []string{"users:read"},
+	}
+
+	h.deny(http.MethodPost, "/api/v1/roles", user.Token, role, http.StatusUnauthorized)
+	h.call(http.MethodPost, "/api/v1/roles", admin.Token, role, http.StatusCreated, nil)
+	h.deny(http.MethodPost, "/api/v1/roles", admin.Token, role, http.StatusConflict)
+	h.deny(http.MethodPost, "/api/v1/roles", admin.Token, map[string]interface{}{
+		"name":        "Bad Name",
+		"permissions": []string{"users:read"},
+	}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, "/api/v1/roles", admin.Token, map[string]interface{}{
+		"name":        "writer",
+		"permissions": []string{"everything:write"},
+	}, http.StatusUnprocessableEntity)
+
+	var roles []*model.RoleDefinition
+	h.call(http.MethodGet, "/api/v1/roles", admin.Token, nil, http.StatusOK, &roles)
+	if len(roles) != 3 {
+		t.Fatalf("expected admin, support and auditor roles, got %d", len(roles))
+	}
+	h.deny(http.MethodGet, "/api/v1/roles", user.Token, nil, http.StatusUnauthorized)
+
+	var got model.RoleDefinition
+	h.call(http.MethodGet, "/api/v1/roles/auditor", admin.Token, nil, http.StatusOK, &got)
+	if len(got.Permissions) != 1 || got.Permissions[0] != model.PermissionUsersRead {
+		t.Fatalf("expected users:read permission, got %v", got.Permissions)
+	}
+	h.deny(http.MethodGet, "/api/v1/roles/unknown", admin.Token, nil, http.StatusNotFound)
+
+	h.call(http.MethodPut, "/api/v1/roles/auditor", admin.Token, map[string]interface{}{
+		"permissions": []string{"users:read", "roles:read"},
+	}, http.StatusOK, nil)
+	h.deny(http.MethodPut, "/api/v1/roles/unknown", admin.Token, map[string]interface{}{
+		"permissions": []string{"users:read"},
+	}, http.StatusNotFound)
+	h.deny(http.MethodPut, "/api/v1/roles/admin", admin.Token, map[string]interface{}{
+		"permissions": []string{"users:read"},
+	}, http.StatusBadRequest)
+	h.deny(http.MethodPut, "/api/v1/roles/auditor", user.Token, map[string]interface{}{
+		"permissions": []string{"users:read"},
+	}, http.StatusUnauthorized)
+
+	var permissions []model.Permission
+	h.call(http.MethodGet, "/api/v1/permissions", admin.Token, nil, http.StatusOK, &permissions)
+	if len(permissions) != len(model.Permissions) {
+		t.Fatalf("expected %d permissions, got %d", len(model.Permissions), len(permissions))
+	}
+	h.deny(http.MethodGet, "/api/v1/permissions", user.Token, nil, http.StatusUnauthorized)
+
+	h.deny(http.MethodDelete, "/api/v1/roles/admin", admin.Token, nil, http.StatusBadRequest)
+	h.deny(http.MethodDelete, "/api/v1/roles/auditor", user.Token, nil, http.StatusUnauthorized)
+	h.call(http.MethodDelete, "/api/v1/roles/auditor", admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodDelete, "/api/v1/roles/auditor", admin.Token, nil, http.StatusNotFound)
+}
+
+func TestUserRoles(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	h.call(http.MethodPost, "/api/v1/roles", admin.Token, map[string]interface{}{
+		"name":        "reader",
+		"permissions": []string{"users:read", "roles:read"},
+	}, http.StatusCreated, nil)
+
+	// named permission of role is checked by permissions
+	h.deny(http.MethodGet, userPath(other), user.Token, nil, http.StatusUnauthorized)
+
+	grant := map[string]string{"role": "reader"}
+	h.deny(http.MethodPost, userPath(user, "roles"), user.Token, grant, http.StatusUnauthorized)
+	h.deny(http.MethodPost, userPath(user, "roles"), admin.Token, map[string]string{"role": "unknown"}, http.StatusBadRequest)
+	h.call(http.MethodPost, userPath(user, "roles"), admin.Token, grant, http.StatusCreated, nil)
+
+	var roles []*model.UserRole
+	h.call(http.MethodGet, userPath(user, "roles"), admin.Token, nil, http.StatusCreated, &roles)
+	if len(roles) != 1 || roles[0].Role != "reader" {
+		t.Fatalf("expected reader role, got %v", roles)
+	}
+	h.deny(http.MethodGet, userPath(user, "roles"), other.Token, nil, http.StatusUnauthorized)
+
+	h.call(http.MethodGet, userPath(other), user.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodGet, "/api/v1/users", user.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodGet, "/api/v1/roles", user.Token, nil, http.StatusOK, nil)
+
+	// read permission doesn't allow writes or admin only APIs
+	h.deny(http.MethodPatch, userPath(other), user.Token, map[string]string{}, http.StatusUnauthorized)
+	h.deny(http.MethodPost, "/api/v1/roles", user.Token, map[string]interface{}{"name": "other"}, http.StatusUnauthorized)
+	h.deny(http.MethodGet, "/api/v1/audit", user.Token, nil, http.StatusUnauthorized)
+
+	h.deny(http.MethodDelete, userPath(user, "roles"), user.Token, grant, http.StatusUnauthorized)
+	h.call(http.MethodDelete, userPath(user, "roles"), admin.Token, grant, http.StatusCreated, nil)
+	h.deny(http.MethodGet, userPath(other), user.Token, nil, http.StatusUnauthorized)
+}
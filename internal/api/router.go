@@ -8,23 +8,44 @@ import (
 	"github.com/startdusk/finance-app-backend/internal/api/auth"
 	v1 "github.com/startdusk/finance-app-backend/internal/api/v1"
 	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/oidc"
+	"github.com/startdusk/finance-app-backend/internal/password"
 )
 
 func NewRouter(db database.Database) (http.Handler, error) {
 	permissions := auth.NewPermissions(db)
 
+	providers, err := oidc.LoadProviders()
+	if err != nil {
+		return nil, err
+	}
+
+	passwords, err := password.NewPolicy()
+	if err != nil {
+		return nil, err
+	}
+
 	router := mux.NewRouter()
 	router.HandleFunc("/version", v1.VersionHandler)
 
 	apiRouter := router.PathPrefix("/api/v1").Subrouter()
 
-	v1.SetUserAPI(db, apiRouter, permissions)
+	v1.SetUserAPI(db, apiRouter, permissions, providers, passwords)
 	v1.SetUserRoleAPI(db, apiRouter, permissions)
+	v1.SetRoleAPI(db, apiRouter, permissions)
+	v1.SetAPIKeyAPI(db, apiRouter, permissions)
+	v1.SetAuditAPI(db, apiRouter, permissions)
 	v1.SetAccountAPI(db, apiRouter, permissions)
 	v1.SetCategoryAPI(db, apiRouter, permissions)
 	v1.SetMerchantAPI(db, apiRouter, permissions)
 	v1.SetTransactionAPI(db, apiRouter, permissions)
-	router.Use(auth.AutherizationToken)
+	v1.SetTrashAPI(db, apiRouter, permissions)
+	v1.SetStatementAPI(db, apiRouter, permissions)
+	v1.SetLoanAPI(db, apiRouter, permissions)
+	v1.SetInvestmentAPI(db, apiRouter, permissions)
+	v1.SetNetWorthAPI(db, apiRouter, permissions)
+	v1.SetReconciliationAPI(db, apiRouter, permissions)
+	router.Use(auth.AutherizationToken(db))
 
 	return router, nil
 }
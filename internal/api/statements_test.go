@@ -0,0 +1,69 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestStatements(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	var card model.Account
+	h.call(http.MethodPost, userPath(user, "accounts"), user.Token, map[string]interface{}{
+		"name":              "Card",
+		"type":              "credit",
+		"startBalance":      "0",
+		"currency":          "USD",
+		"creditLimit":       "1000",
+		"statementDay":      15,
+		"paymentDueDay":     10,
+		"minPaymentPercent": 2,
+		"minPaymentAmount":  "25",
+	}, http.StatusCreated, &card)
+	h.createTransaction(user, card.ID, h.createCategory(user, "Food", ""))
+
+	statementsPath := userPath(user, "accounts", string(card.ID), "statements")
+
+	var statements []*model.Statement
+	h.call(http.MethodGet, statementsPath, user.Token, nil, http.StatusOK, &statements)
+	if len(statements) == 0 || statements[0].Status != model.StatementCurrent || statements[0].ClosingBalance.String() != "-25.00" {
+		t.Fatalf("expected current statement with 25.00 charged, got %+v", statements)
+	}
+	if statements[0].MinimumPayment.String() != "25.00" {
+		t.Fatalf("expected minimum payment 25.00, got %s", statements[0].MinimumPayment)
+	}
+	h.call(http.MethodGet, statementsPath, admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodGet, statementsPath, other.Token, nil, http.StatusUnauthorized)
+
+	// cash account has no billing cycle
+	cashID := h.createAccount(user, "Wallet", "USD")
+	h.deny(http.MethodGet, userPath(user, "accounts", string(cashID), "statements"), user.Token, nil, http.StatusConflict)
+
+	// current statement isn't due yet
+	var upcoming []*model.Statement
+	h.call(http.MethodGet, userPath(user, "statements", "upcoming")+"?days=60", user.Token, nil, http.StatusOK, &upcoming)
+	if len(upcoming) != 0 {
+		t.Fatalf("expected no upcoming statements, got %+v", upcoming)
+	}
+	h.deny(http.MethodGet, userPath(user, "statements", "upcoming")+"?days=soon", user.Token, nil, http.StatusUnprocessableEntity)
+	h.deny(http.MethodGet, userPath(user, "statements", "upcoming"), other.Token, nil, http.StatusUnauthorized)
+
+	// statements are read of accounts for API keys
+	accountsKey := h.apiKey(user, "accounts:read")
+	h.call(http.MethodGet, statementsPath, accountsKey.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodGet, userPath(user, "statements", "upcoming"), accountsKey.Token, nil, http.StatusOK, nil)
+	transactionsKey := h.apiKey(user, "transactions:write")
+	h.deny(http.MethodGet, statementsPath, transactionsKey.Token, nil, http.StatusForbidden)
+	h.deny(http.MethodGet, userPath(user, "statements", "upcoming"), transactionsKey.Token, nil, http.StatusForbidden)
+
+	// viewer of shared account reads its statements
+	viewer := h.signUp()
+	h.deny(http.MethodGet, statementsPath, viewer.Token, nil, http.StatusUnauthorized)
+	h.shareAccount(user, card.ID, viewer, "viewer")
+	h.call(http.MethodGet, statementsPath, viewer.Token, nil, http.StatusOK, nil)
+}
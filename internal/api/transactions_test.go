@@ -0,0 +1,176 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestTransactions(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	accountID := h.createAccount(user, "Wallet", "USD")
+	categoryID := h.createCategory(user, "Food", "")
+	otherAccountID := h.createAccount(other, "Other", "USD")
+	otherCategoryID := h.createCategory(other, "Other", "")
+
+	h.deny(http.MethodPost, userPath(user, "transactions"), user.Token, map[string]interface{}{
+		"accountID": accountID,
+	}, http.StatusUnprocessableEntity)
+	for field, value := range map[string]interface{}{
+		"type":   "transfer",
+		"amount": "-25",
+		"date":   "0202-01-01T00:00:00Z",
+	} {
+		body := transactionBody(accountID, categoryID)
+		body[field] = value
+		h.deny(http.MethodPost, userPath(user, "transactions"), user.Token, body, http.StatusUnprocessableEntity)
+	}
+	// amount used to be JSON number of minor units, it's refused rather than read as 2500.00
+	numberBody := transactionBody(accountID, categoryID)
+	numberBody["amount"] = 2500
+	h.deny(http.MethodPost, userPath(user, "transactions"), user.Token, numberBody, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, userPath(user, "transactions"), user.Token, transactionBody(otherAccountID, categoryID), http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, userPath(user, "transactions"), user.Token, transactionBody(accountID, otherCategoryID), http.StatusUnprocessableEntity)
+	h.deny(http.MethodPost, userPath(user, "transactions"), other.Token, transactionBody(accountID, categoryID), http.StatusUnauthorized)
+
+	transactionID := h.createTransaction(user, accountID, categoryID)
+	transactionPath := userPath(user, "transactions", string(transactionID))
+
+	var transactions []*model.Transaction
+	h.call(http.MethodGet, userPath(user, "transactions")+dayWindow(), user.Token, nil, http.StatusOK, &transactions)
+	if len(transactions) != 1 || transactions[0].ID != transactionID {
+		t.Fatalf("expected created transaction, got %+v", transactions)
+	}
+	h.call(http.MethodGet, userPath(user, "transactions")+dayWindow(), admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodGet, userPath(user, "transactions")+dayWindow(), other.Token, nil, http.StatusUnauthorized)
+	h.deny(http.MethodGet, userPath(user, "transactions")+"?from=yesterday", user.Token, nil, http.StatusConflict)
+
+	accountTransactions := "/api/v1/accounts/" + string(accountID) + "/transactions"
+	h.call(http.MethodGet, accountTransactions+dayWindow(), user.Token, nil, http.StatusOK, &transactions)
+	if len(transactions) != 1 {
+		t.Fatalf("expected 1 transaction in account, got %d", len(transactions))
+	}
+	h.deny(http.MethodGet, accountTransactions+dayWindow(), other.Token, nil, http.StatusUnauthorized)
+
+	categoryTransactions := "/api/v1/categories/" + string(categoryID) + "/transactions"
+	h.call(http.MethodGet, categoryTransactions+dayWindow(), user.Token, nil, http.StatusOK, &transactions)
+	if len(transactions) != 1 {
+		t.Fatalf("expected 1 transaction in category, got %d", len(transactions))
+	}
+	h.deny(http.MethodGet, categoryTransactions+dayWindow(), other.Token, nil, http.StatusUnauthorized)
+
+	var transaction model.Transaction
+	h.call(http.MethodGet, transactionPath, user.Token, nil, http.StatusOK, &transaction)
+	if transaction.Amount.String() != "25.00" {
+		t.Fatalf("expected amount 25.00, got %s", transaction.Amount)
+	}
+	h.deny(http.MethodGet, transactionPath, other.Token, nil, http.StatusUnauthorized)
+
+	h.call(http.MethodPatch, transactionPath, user.Token, map[string]interface{}{"amount": "30"}, http.StatusOK, nil)
+	h.call(http.MethodPatch, transactionPath, user.Token, map[string]interface{}{"amount": "30.5"}, http.StatusOK, nil)
+	h.deny(http.MethodPatch, transactionPath, user.Token, map[string]interface{}{"amount": "30.505"}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPatch, transactionPath, user.Token, map[string]interface{}{"amount": "30,50"}, http.StatusBadRequest)
+	h.deny(http.MethodPatch, transactionPath, user.Token, map[string]interface{}{"accountID": otherAccountID}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPatch, transactionPath, other.Token, map[string]interface{}{"amount": "1"}, http.StatusUnauthorized)
+
+	// account routes check that transaction is in account
+	otherAccountPath := "/api/v1/accounts/" + string(h.createAccount(user, "Bank", "USD")) + "/transactions/" + string(transactionID)
+	h.deny(http.MethodPatch, otherAccountPath, user.Token, map[string]interface{}{"amount": "1"}, http.StatusNotFound)
+	h.deny(http.MethodDelete, otherAccountPath, user.Token, nil, http.StatusNotFound)
+	h.deny(http.MethodPatch, accountTransactions+"/"+string(transactionID), user.Token, map[string]interface{}{"accountID": otherAccountID}, http.StatusUnprocessableEntity)
+
+	var history []*model.Revision
+	h.call(http.MethodGet, transactionPath+"/history", user.Token, nil, http.StatusOK, &history)
+	if len(history) != 3 {
+		t.Fatalf("expected 3 revisions, got %d", len(history))
+	}
+	h.deny(http.MethodGet, transactionPath+"/history", other.Token, nil, http.StatusUnauthorized)
+
+	h.call(http.MethodPost, transactionPath+"/restore", user.Token, map[string]int{"revision": 1}, http.StatusOK, &transaction)
+	if transaction.Amount.String() != "25.00" {
+		t.Fatalf("expected amount of first revision, got %s", transaction.Amount)
+	}
+	h.deny(http.MethodPost, transactionPath+"/restore", other.Token, nil, http.StatusUnauthorized)
+
+	h.deny(http.MethodDelete, transactionPath, other.Token, nil, http.StatusUnauthorized)
+	h.call(http.MethodDelete, transactionPath, user.Token, nil, http.StatusOK, nil)
+
+	// the same through account route
+	transactionID = h.createTransaction(user, accountID, categoryID)
+	h.call(http.MethodPost, accountTransactions, user.Token, transactionBody(accountID, categoryID), http.StatusCreated, nil)
+	h.deny(http.MethodPost, accountTransactions, other.Token, transactionBody(accountID, categoryID), http.StatusUnauthorized)
+	h.call(http.MethodDelete, accountTransactions+"/"+string(transactionID), user.Token, nil, http.StatusOK, nil)
+
+	h.call(http.MethodGet, userPath(user, "transactions")+dayWindow(), user.Token, nil, http.StatusOK, &transactions)
+	if len(transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(transactions))
+	}
+}
+
+func TestTransactionStatus(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+
+	accountID := h.createAccount(user, "Card", "USD")
+	categoryID := h.createCategory(user, "Food", "")
+	h.createTransaction(user, accountID, categoryID)
+
+	netWorth := func(query string) string {
+		var netWorth model.NetWorth
+		h.call(http.MethodGet, userPath(user, "networth")+query, user.Token, nil, http.StatusOK, &netWorth)
+		return netWorth.Currencies[0].NetWorth.String()
+	}
+
+	body := transactionBody(accountID, categoryID)
+	body["status"] = "pending"
+	var pending model.Transaction
+	h.call(http.MethodPost, userPath(user, "transactions"), user.Token, body, http.StatusCreated, &pending)
+	if pending.Status == nil || *pending.Status != model.Pending {
+		t.Fatalf("expected pending transaction, got %v", pending.Status)
+	}
+	if got := netWorth(""); got != "-25.00" {
+		t.Fatalf("expected pending transaction excluded, got %s", got)
+	}
+	if got := netWorth("?pending=true"); got != "-50.00" {
+		t.Fatalf("expected pending transaction included, got %s", got)
+	}
+	h.deny(http.MethodGet, userPath(user, "networth")+"?pending=maybe", user.Token, nil, http.StatusUnprocessableEntity)
+
+	body["status"] = "void"
+	h.deny(http.MethodPost, userPath(user, "transactions"), user.Token, body, http.StatusUnprocessableEntity)
+
+	// pending transaction posts with its final date and amount
+	pendingPath := userPath(user, "transactions", string(pending.ID))
+	h.deny(http.MethodPatch, pendingPath, user.Token, map[string]interface{}{"clearedStatus": "cleared"}, http.StatusUnprocessableEntity)
+	h.call(http.MethodPatch, pendingPath, user.Token, map[string]interface{}{
+		"status": "posted",
+		"amount": "30",
+		"date":   time.Now().Add(-time.Minute).UTC().Format(time.RFC3339),
+	}, http.StatusOK, nil)
+	if got := netWorth(""); got != "-55.00" {
+		t.Fatalf("expected posted transaction counted, got %s", got)
+	}
+	h.deny(http.MethodPatch, pendingPath, user.Token, map[string]interface{}{"status": "pending"}, http.StatusUnprocessableEntity)
+
+	// cleared transaction is uncleared before it's voided
+	h.call(http.MethodPatch, pendingPath, user.Token, map[string]interface{}{"clearedStatus": "cleared"}, http.StatusOK, nil)
+	h.deny(http.MethodPatch, pendingPath, user.Token, map[string]interface{}{"status": "void"}, http.StatusUnprocessableEntity)
+
+	// void transaction stays visible but doesn't count
+	h.call(http.MethodPatch, pendingPath, user.Token, map[string]interface{}{"status": "void", "clearedStatus": "uncleared"}, http.StatusOK, nil)
+	if got := netWorth("?pending=true"); got != "-25.00" {
+		t.Fatalf("expected void transaction excluded, got %s", got)
+	}
+	var void model.Transaction
+	h.call(http.MethodGet, pendingPath, user.Token, nil, http.StatusOK, &void)
+	if void.Status == nil || *void.Status != model.Void {
+		t.Fatalf("expected void transaction, got %v", void.Status)
+	}
+	h.deny(http.MethodPatch, pendingPath, user.Token, map[string]interface{}{"status": "posted"}, http.StatusUnprocessableEntity)
+}
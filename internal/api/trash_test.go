@@ -0,0 +1,74 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestTrash(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	accountID := h.createAccount(user, "Wallet", "USD")
+	categoryID := h.createCategory(user, "Food", "")
+	transactionID := h.createTransaction(user, accountID, categoryID)
+
+	var merchant model.Merchant
+	h.call(http.MethodPost, userPath(user, "merchants"), user.Token, map[string]string{"name": "Cafe"}, http.StatusCreated, &merchant)
+
+	// only deleted items can be purged
+	h.deny(http.MethodDelete, userPath(user, "trash", "merchants", string(merchant.ID)), user.Token, nil, http.StatusNotFound)
+
+	h.call(http.MethodDelete, userPath(user, "accounts", string(accountID))+"?strategy=cascade", user.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodDelete, userPath(user, "categories", string(categoryID)), user.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodDelete, userPath(user, "merchants", string(merchant.ID)), user.Token, nil, http.StatusOK, nil)
+
+	var trash model.Trash
+	h.call(http.MethodGet, userPath(user, "trash"), user.Token, nil, http.StatusOK, &trash)
+	if len(trash.Accounts) != 1 || len(trash.Categories) != 1 || len(trash.Merchants) != 1 || len(trash.Transactions) != 1 {
+		t.Fatalf("expected one item of every type in trash, got %+v", trash)
+	}
+	if trash.Accounts[0].DeletedTransactions != 1 {
+		t.Fatalf("expected account with 1 deleted transaction, got %d", trash.Accounts[0].DeletedTransactions)
+	}
+	h.call(http.MethodGet, userPath(user, "trash"), admin.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodGet, userPath(user, "trash"), other.Token, nil, http.StatusUnauthorized)
+
+	for _, path := range []string{
+		userPath(user, "trash", "transactions", string(transactionID)),
+		userPath(user, "trash", "accounts", string(accountID)),
+		userPath(user, "trash", "categories", string(categoryID)),
+		userPath(user, "trash", "merchants", string(merchant.ID)),
+	} {
+		h.deny(http.MethodDelete, path, other.Token, nil, http.StatusUnauthorized)
+		h.call(http.MethodDelete, path, user.Token, nil, http.StatusOK, nil)
+	}
+
+	h.call(http.MethodGet, userPath(user, "trash"), user.Token, nil, http.StatusOK, &trash)
+	if len(trash.Accounts)+len(trash.Categories)+len(trash.Merchants)+len(trash.Transactions) != 0 {
+		t.Fatalf("expected empty trash, got %+v", trash)
+	}
+
+	// purged items can't be restored
+	h.deny(http.MethodPost, userPath(user, "merchants", string(merchant.ID), "restore"), user.Token, nil, http.StatusConflict)
+}
+
+func TestTrashCategoryWithChildren(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+
+	parentID := h.createCategory(user, "Food", "")
+	childID := h.createCategory(user, "Restaurants", parentID)
+
+	// child is restored alone, so parent in trash still has live child
+	h.call(http.MethodDelete, userPath(user, "categories", string(parentID))+"?strategy=cascade", user.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodPost, userPath(user, "categories", string(childID), "restore"), user.Token, nil, http.StatusOK, nil)
+	h.deny(http.MethodDelete, userPath(user, "trash", "categories", string(parentID)), user.Token, nil, http.StatusConflict)
+
+	h.call(http.MethodDelete, userPath(user, "categories", string(childID)), user.Token, nil, http.StatusOK, nil)
+	h.call(http.MethodDelete, userPath(user, "trash", "categories", string(parentID)), user.Token, nil, http.StatusOK, nil)
+}
@@ -0,0 +1,182 @@
+package api_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// totpCode is code authenticator app shows for secret, steps are relative to current time step
+func totpCode(t *testing.T, secret string, steps int64) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("invalid secret: %v", err)
+	}
+
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(time.Now().Unix()/30+steps))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+type recoveryCodes struct {
+	Enabled       bool     `json:"enabled"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// enableTwoFactor enrolls user and confirms it with code of current step
+func (h *harness) enableTwoFactor(user *testUser) {
+	h.t.Helper()
+
+	var enrollment struct {
+		Secret string `json:"secret"`
+	}
+	h.call(http.MethodPost, userPath(user, "2fa"), user.Token, nil, http.StatusCreated, &enrollment)
+
+	h.call(http.MethodPost, userPath(user, "2fa", "confirm"), user.Token, map[string]string{
+		"code": totpCode(h.t, enrollment.Secret, 0),
+	}, http.StatusOK, nil)
+}
+
+// challenge logs user in with password and returns challenge token
+func (h *harness) challenge(user *testUser) string {
+	h.t.Helper()
+
+	var response tokenResponse
+	h.call(http.MethodPost, "/api/v1/login", "", map[string]string{
+		"email":    user.Email,
+		"password": testPassword,
+		"deviceID": testDevice,
+	}, http.StatusOK, &response)
+	if response.Challenge == nil || response.Tokens.AccessToken != "" {
+		h.t.Fatalf("expected only challenge, got %+v", response)
+	}
+	return response.Challenge.Token
+}
+
+func TestTwoFactor(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+
+	var status model.TOTP
+	h.call(http.MethodGet, userPath(user, "2fa"), user.Token, nil, http.StatusOK, &status)
+	if status.Enabled {
+		t.Fatal("expected disabled two-factor authentication")
+	}
+	h.deny(http.MethodGet, userPath(user, "2fa"), other.Token, nil, http.StatusUnauthorized)
+
+	h.deny(http.MethodPost, userPath(user, "2fa", "confirm"), user.Token, map[string]string{"code": "123456"}, http.StatusNotFound)
+	h.deny(http.MethodPost, userPath(user, "2fa"), other.Token, nil, http.StatusUnauthorized)
+
+	var enrollment struct {
+		Secret string `json:"secret"`
+	}
+	h.call(http.MethodPost, userPath(user, "2fa"), user.Token, nil, http.StatusCreated, &enrollment)
+	h.deny(http.MethodPost, userPath(user, "2fa", "confirm"), user.Token, map[string]string{"code": "000000x"}, http.StatusUnauthorized)
+	h.deny(http.MethodPost, userPath(user, "2fa", "confirm"), other.Token, map[string]string{"code": totpCode(t, enrollment.Secret, 0)}, http.StatusUnauthorized)
+
+	confirmCode := totpCode(t, enrollment.Secret, 0)
+	var codes recoveryCodes
+	h.call(http.MethodPost, userPath(user, "2fa", "confirm"), user.Token, map[string]string{
+		"code": confirmCode,
+	}, http.StatusOK, &codes)
+	if !codes.Enabled || len(codes.RecoveryCodes) != model.RecoveryCodesCount {
+		t.Fatalf("expected %d recovery codes, got %+v", model.RecoveryCodesCount, codes)
+	}
+	h.deny(http.MethodPost, userPath(user, "2fa"), user.Token, nil, http.StatusConflict)
+
+	// password isn't enough to log in
+	challenge := h.challenge(user)
+	h.deny(http.MethodPost, "/api/v1/login/2fa", "", map[string]string{
+		"challengeToken": user.Token,
+		"code":           totpCode(t, enrollment.Secret, 1),
+		"deviceID":       testDevice,
+	}, http.StatusUnauthorized)
+	h.deny(http.MethodPost, "/api/v1/login/2fa", "", map[string]string{
+		"challengeToken": challenge,
+		"code":           "00000",
+		"deviceID":       testDevice,
+	}, http.StatusUnauthorized)
+	h.deny(http.MethodPost, "/api/v1/login/2fa", "", map[string]string{
+		"challengeToken": challenge,
+		"code":           totpCode(t, enrollment.Secret, 1),
+	}, http.StatusBadRequest)
+
+	// code used for confirmation can't be used again
+	h.deny(http.MethodPost, "/api/v1/login/2fa", "", map[string]string{
+		"challengeToken": challenge,
+		"code":           confirmCode,
+		"deviceID":       testDevice,
+	}, http.StatusUnauthorized)
+
+	var response tokenResponse
+	h.call(http.MethodPost, "/api/v1/login/2fa", "", map[string]string{
+		"challengeToken": challenge,
+		"code":           totpCode(t, enrollment.Secret, 1),
+		"deviceID":       testDevice,
+	}, http.StatusOK, &response)
+	if response.Tokens.AccessToken == "" {
+		t.Fatal("expected tokens after two-factor login")
+	}
+
+	// recovery code works only once
+	recoveryLogin := map[string]string{
+		"challengeToken": h.challenge(user),
+		"code":           codes.RecoveryCodes[0],
+		"deviceID":       testDevice,
+	}
+	h.call(http.MethodPost, "/api/v1/login/2fa", "", recoveryLogin, http.StatusOK, nil)
+	h.deny(http.MethodPost, "/api/v1/login/2fa", "", recoveryLogin, http.StatusUnauthorized)
+
+	h.deny(http.MethodPost, userPath(user, "2fa", "recovery-codes"), user.Token, map[string]string{"code": codes.RecoveryCodes[0]}, http.StatusUnauthorized)
+	h.deny(http.MethodPost, userPath(user, "2fa", "recovery-codes"), other.Token, map[string]string{"code": codes.RecoveryCodes[1]}, http.StatusUnauthorized)
+	h.deny(http.MethodPost, userPath(other, "2fa", "recovery-codes"), other.Token, map[string]string{"code": "123456"}, http.StatusNotFound)
+
+	var newCodes recoveryCodes
+	h.call(http.MethodPost, userPath(user, "2fa", "recovery-codes"), user.Token, map[string]string{
+		"code": codes.RecoveryCodes[1],
+	}, http.StatusOK, &newCodes)
+
+	// old codes are replaced
+	h.deny(http.MethodDelete, userPath(user, "2fa"), user.Token, map[string]string{"code": codes.RecoveryCodes[2]}, http.StatusUnauthorized)
+	h.deny(http.MethodDelete, userPath(user, "2fa"), other.Token, map[string]string{"code": newCodes.RecoveryCodes[0]}, http.StatusUnauthorized)
+	h.call(http.MethodDelete, userPath(user, "2fa"), user.Token, map[string]string{"code": newCodes.RecoveryCodes[0]}, http.StatusOK, nil)
+	h.deny(http.MethodDelete, userPath(user, "2fa"), user.Token, map[string]string{"code": newCodes.RecoveryCodes[1]}, http.StatusNotFound)
+
+	if rec := h.login(user.Email, testPassword); rec.Code != http.StatusOK {
+		t.Fatalf("expected login without second factor, got %d", rec.Code)
+	}
+}
+
+func TestTwoFactorAdminDisable(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	admin := h.admin()
+
+	h.enableTwoFactor(user)
+
+	// user lost his phone, admin disables 2FA without code
+	h.call(http.MethodDelete, userPath(user, "2fa"), admin.Token, nil, http.StatusOK, nil)
+
+	var status model.TOTP
+	h.call(http.MethodGet, userPath(user, "2fa"), admin.Token, nil, http.StatusOK, &status)
+	if status.Enabled {
+		t.Fatal("expected disabled two-factor authentication")
+	}
+}
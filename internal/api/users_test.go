@@ -0,0 +1,182 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestVersion(t *testing.T) {
+	h := newHarness(t)
+
+	h.call(http.MethodGet, "/version", "", nil, http.StatusOK, nil)
+}
+
+func TestCreateUser(t *testing.T) {
+	h := newHarness(t)
+
+	user := h.signUp()
+	if user.ID == "" || user.Token == "" || user.RefreshToken == "" {
+		t.Fatalf("expected user with tokens, got %+v", user)
+	}
+
+	// the same email
+	h.deny(http.MethodPost, "/api/v1/users", "", map[string]string{
+		"email":    user.Email,
+		"password": testPassword,
+		"deviceID": testDevice,
+	}, http.StatusConflict)
+
+	// weak password
+	h.deny(http.MethodPost, "/api/v1/users", "", map[string]string{
+		"email":    newEmail(),
+		"password": "12345",
+		"deviceID": testDevice,
+	}, http.StatusUnprocessableEntity)
+
+	// invalid email
+	h.deny(http.MethodPost, "/api/v1/users", "", map[string]string{
+		"email":    "not an email",
+		"password": testPassword,
+		"deviceID": testDevice,
+	}, http.StatusUnprocessableEntity)
+
+	// no device
+	h.deny(http.MethodPost, "/api/v1/users", "", map[string]string{
+		"email":    newEmail(),
+		"password": testPassword,
+	}, http.StatusBadRequest)
+
+	h.deny(http.MethodPost, "/api/v1/users", "", "not an object", http.StatusBadRequest)
+}
+
+func TestLogin(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+
+	rec := h.login(user.Email, testPassword)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected login, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := h.login(user.Email, "wrong-password"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected wrong password to be refused, got %d", rec.Code)
+	}
+
+	if rec := h.login(newEmail(), testPassword); rec.Code != http.StatusConflict {
+		t.Fatalf("expected unknown email to be refused, got %d", rec.Code)
+	}
+
+	h.deny(http.MethodPost, "/api/v1/login", "", map[string]string{
+		"email":    user.Email,
+		"password": testPassword,
+	}, http.StatusBadRequest)
+
+	// invalid token is refused before permissions are checked
+	h.deny(http.MethodGet, userPath(user), "invalid", nil, http.StatusUnauthorized)
+}
+
+func TestLoginThrottle(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	admin := h.admin()
+
+	// first failures are free, then account is locked for a while even for right password
+	for i := 0; i < 4; i++ {
+		if rec := h.login(user.Email, "wrong-password"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i, rec.Code)
+		}
+	}
+
+	rec := h.login(user.Email, testPassword)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+
+	h.deny(http.MethodDelete, userPath(user, "lockout"), user.Token, nil, http.StatusUnauthorized)
+	h.call(http.MethodDelete, userPath(user, "lockout"), admin.Token, nil, http.StatusOK, nil)
+
+	// unlock doesn't reset lock of IP address, user logs in from other network
+	h.remoteAddr = "198.51.100.7:40000"
+	if rec := h.login(user.Email, testPassword); rec.Code != http.StatusOK {
+		t.Fatalf("expected login after unlock, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+
+	var response tokenResponse
+	h.call(http.MethodPost, "/api/v1/refresh", "", map[string]string{
+		"refreshToken": user.RefreshToken,
+		"deviceID":     testDevice,
+	}, http.StatusOK, &response)
+	if response.Tokens.AccessToken == "" {
+		t.Fatal("expected new access token")
+	}
+
+	h.deny(http.MethodPost, "/api/v1/refresh", "", map[string]string{
+		"refreshToken": "invalid",
+		"deviceID":     testDevice,
+	}, http.StatusUnauthorized)
+
+	// session is bound to device
+	h.deny(http.MethodPost, "/api/v1/refresh", "", map[string]string{
+		"refreshToken": response.Tokens.RefreshToken,
+		"deviceID":     "other-device",
+	}, http.StatusUnauthorized)
+}
+
+func TestUsers(t *testing.T) {
+	h := newHarness(t)
+	user := h.signUp()
+	other := h.signUp()
+	admin := h.admin()
+
+	var got model.User
+	h.call(http.MethodGet, userPath(user), user.Token, nil, http.StatusOK, &got)
+	if got.ID != user.ID {
+		t.Fatalf("expected user %s, got %s", user.ID, got.ID)
+	}
+
+	h.deny(http.MethodGet, userPath(user), "", nil, http.StatusUnauthorized)
+	h.deny(http.MethodGet, userPath(user), other.Token, nil, http.StatusUnauthorized)
+	h.call(http.MethodGet, userPath(user), admin.Token, nil, http.StatusOK, nil)
+
+	var users []*model.User
+	h.call(http.MethodGet, "/api/v1/users", admin.Token, nil, http.StatusOK, &users)
+	if len(users) != 3 {
+		t.Fatalf("expected 3 users, got %d", len(users))
+	}
+	h.deny(http.MethodGet, "/api/v1/users", user.Token, nil, http.StatusUnauthorized)
+
+	// password change
+	const newPassword = "another-long-passphrase-77"
+	h.call(http.MethodPatch, userPath(user), user.Token, map[string]string{
+		"password": newPassword,
+	}, http.StatusOK, nil)
+	h.deny(http.MethodPatch, userPath(user), user.Token, map[string]string{
+		"password": "short",
+	}, http.StatusUnprocessableEntity)
+	h.deny(http.MethodPatch, userPath(user), other.Token, map[string]string{
+		"password": newPassword,
+	}, http.StatusUnauthorized)
+
+	if rec := h.login(user.Email, newPassword); rec.Code != http.StatusOK {
+		t.Fatalf("expected login with new password, got %d", rec.Code)
+	}
+
+	// user can't delete himself
+	h.deny(http.MethodDelete, userPath(user), user.Token, nil, http.StatusConflict)
+	h.deny(http.MethodDelete, userPath(user), other.Token, nil, http.StatusUnauthorized)
+	h.call(http.MethodDelete, userPath(user), admin.Token, nil, http.StatusOK, nil)
+
+	if rec := h.login(user.Email, newPassword); rec.Code == http.StatusOK {
+		t.Fatal("deleted user must not log in")
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -20,3 +21,23 @@ func TimeParam(query url.Values, name string) (time.Time, error) {
 
 	return parsed, nil
 }
+
+// IntParam returns def when parameter is not set
+func IntParam(query url.Values, name string, def int) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return def, nil
+	}
+
+	return strconv.Atoi(value)
+}
+
+// BoolParam returns def when parameter is not set
+func BoolParam(query url.Values, name string, def bool) (bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return def, nil
+	}
+
+	return strconv.ParseBool(value)
+}
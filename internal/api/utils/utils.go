@@ -2,9 +2,13 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"reflect"
 
 	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
 )
 
 // GenericError - represent error structure for generic error
@@ -35,3 +39,24 @@ func WriteJSON(w http.ResponseWriter, code int, data interface{}) {
 		logrus.WithError(err).Warn("Error writing response.")
 	}
 }
+
+// WriteValidationError returns list of invalid fields with 422 status code
+func WriteValidationError(w http.ResponseWriter, verr *model.ValidationError) {
+	WriteError(w, http.StatusUnprocessableEntity, "validation failed", verr)
+}
+
+// WriteDecodeError returns 422 when money in request body is JSON number instead of decimal string,
+// other errors of decoding body are 400
+func WriteDecodeError(w http.ResponseWriter, err error) {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" && typeErr.Type == reflect.TypeOf(model.Money{}) {
+		verr := &model.ValidationError{}
+		verr.Add(typeErr.Field, model.ErrCodeInvalid, model.ErrMoneyNumber.Error())
+		WriteValidationError(w, verr)
+		return
+	}
+
+	WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+		"error": err.Error(),
+	})
+}
@@ -15,20 +15,33 @@ import (
 
 // AccountAPI - provides REST for Account
 type AccountAPI struct {
-	DB database.Database // will represent all database interface
+	DB          database.Database // will represent all database interface
+	Permissions auth.Permissions  // to forget cached access of members when it is changed
 }
 
 func SetAccountAPI(db database.Database, router *mux.Router, permissions auth.Permissions) {
 	api := &AccountAPI{
-		DB: db,
+		DB:          db,
+		Permissions: permissions,
 	}
 
 	apis := []API{
-		NewAPI(http.MethodPost, "/users/{userID}/accounts", api.Create, auth.Admin, auth.MemberIsTarget),               // create account for user (Open for admin for now)
-		NewAPI(http.MethodGet, "/users/{userID}/accounts", api.List, auth.Admin, auth.MemberIsTarget),                  // get account for user (Open for admin for now)
-		NewAPI(http.MethodPatch, "/users/{userID}/accounts/{accountID}", api.Update, auth.Admin, auth.MemberIsTarget),  // update account for user (Open for admin for now)
-		NewAPI(http.MethodGet, "/users/{userID}/accounts/{accountID}", api.Get, auth.Admin, auth.MemberIsTarget),       // get account by account id for user (Open for admin for now)
-		NewAPI(http.MethodDelete, "/users/{userID}/accounts/{accountID}", api.Delete, auth.Admin, auth.MemberIsTarget), // delete account by account id for user (Open for admin for now)
+		NewAPI(http.MethodPost, "/users/{userID}/accounts", api.Create, auth.Admin, auth.MemberIsOwner, auth.AccountsWrite),                                        // create account for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/users/{userID}/accounts", api.List, auth.Admin, auth.MemberIsOwner, auth.AccountsRead),                                            // get account for user (Open for admin for now)
+		NewAPI(http.MethodPatch, "/users/{userID}/accounts/{accountID}", api.Update, auth.Admin, auth.MemberIsOwner, auth.AccountsWrite),                           // update account for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/users/{userID}/accounts/{accountID}", api.Get, auth.Admin, auth.MemberIsOwner, auth.AccountViewer, auth.AccountsRead),             // get own or shared account by account id
+		NewAPI(http.MethodDelete, "/users/{userID}/accounts/{accountID}", api.Delete, auth.Admin, auth.MemberIsOwner, auth.AccountsWrite),                          // delete account by account id for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/users/{userID}/accounts/{accountID}/history", api.History, auth.Admin, auth.MemberIsOwner, auth.AccountViewer, auth.AccountsRead), // list revisions of account
+		NewAPI(http.MethodPost, "/users/{userID}/accounts/{accountID}/restore", api.Restore, auth.Admin, auth.MemberIsOwner, auth.AccountsWrite),                   // restore revision or undelete account
+
+		// ---------------SHARING-----------------
+		NewAPI(http.MethodPost, "/users/{userID}/accounts/{accountID}/members", api.InviteMember, auth.Admin, auth.MemberIsOwner),                  // invite user to account
+		NewAPI(http.MethodGet, "/users/{userID}/accounts/{accountID}/members", api.ListMembers, auth.Admin, auth.MemberIsOwner, auth.AccountsRead), // list members and invitations of account
+		NewAPI(http.MethodPatch, "/users/{userID}/accounts/{accountID}/members/{memberID}", api.UpdateMember, auth.Admin, auth.MemberIsOwner),      // change access of member
+		NewAPI(http.MethodDelete, "/users/{userID}/accounts/{accountID}/members/{memberID}", api.RemoveMember, auth.Admin, auth.MemberIsOwner),     // remove member or cancel invitation
+		NewAPI(http.MethodGet, "/users/{userID}/invitations", api.ListInvitations, auth.Admin, auth.MemberIsTarget),                                // list pending invitations of user
+		NewAPI(http.MethodPost, "/users/{userID}/invitations/{accountID}/accept", api.AcceptInvitation, auth.MemberIsTarget),                       // accept invitation
+		NewAPI(http.MethodPost, "/users/{userID}/invitations/{accountID}/decline", api.DeclineInvitation, auth.MemberIsTarget),                     // decline invitation or leave shared account
 	}
 
 	for _, api := range apis {
@@ -37,7 +50,7 @@ func SetAccountAPI(db database.Database, router *mux.Router, permissions auth.Pe
 }
 
 // POST - /users/{userID}/accounts
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, AccountsWrite
 func (api *AccountAPI) Create(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "account.go -> Create()")
@@ -55,19 +68,15 @@ func (api *AccountAPI) Create(w http.ResponseWriter, r *http.Request) {
 	var account model.Account
 	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
 		logger.WithError(err).Warn("could not decode parameters")
-		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
-			"error": err.Error(),
-		})
+		utils.WriteDecodeError(w, err)
 		return
 	}
 
 	account.UserID = &userID
 
-	if err := account.Verify(); err != nil {
-		logger.WithError(err).Warn("not all fields found") // I will hide this error in future, it isn't secure to show what fields are missing...
-		utils.WriteError(w, http.StatusBadRequest, "not all fields found", map[string]string{
-			"error": err.Error(),
-		})
+	if verr := verifyAccount(&account); verr.Err() != nil {
+		logger.WithError(verr).Warn("invalid account")
+		utils.WriteValidationError(w, verr)
 		return
 	}
 
@@ -84,8 +93,9 @@ func (api *AccountAPI) Create(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusCreated, &account)
 }
 
-// PATCH - /users/{userID}/accounts/{accountID}
-// Permission - MemberIsTarget
+// PATCH - /users/{userID}/accounts/{accountID}?force={true|false}
+// Permission - MemberIsOwner, AccountsWrite
+// Start balance and currency of account with locked reconciliation or cleared transactions are changed only with force
 func (api *AccountAPI) Update(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "account.go -> Update()")
@@ -105,9 +115,7 @@ func (api *AccountAPI) Update(w http.ResponseWriter, r *http.Request) {
 	var accountRequest model.Account
 	if err := json.NewDecoder(r.Body).Decode(&accountRequest); err != nil {
 		logger.WithError(err).Warn("could not decode parameters")
-		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
-			"error": err.Error(),
-		})
+		utils.WriteDecodeError(w, err)
 		return
 	}
 
@@ -122,22 +130,83 @@ func (api *AccountAPI) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if accountRequest.Name != nil || len(*accountRequest.Name) != 0 {
+	before := *account
+
+	if accountRequest.Name != nil && len(*accountRequest.Name) != 0 {
 		account.Name = accountRequest.Name
 	}
 
-	if accountRequest.Type != nil || len(*accountRequest.Type) != 0 {
+	if accountRequest.Type != nil && len(*accountRequest.Type) != 0 {
 		account.Type = accountRequest.Type
+		account.ClearTypeDetails()
 	}
 
 	if accountRequest.StartBalance != nil {
 		account.StartBalance = accountRequest.StartBalance
 	}
 
-	if accountRequest.Currency != nil || len(*accountRequest.Currency) != 0 {
+	if accountRequest.Currency != nil && len(*accountRequest.Currency) != 0 {
 		account.Currency = accountRequest.Currency
 	}
 
+	if accountRequest.Institution != nil {
+		account.Institution = accountRequest.Institution
+	}
+
+	if accountRequest.MaskedNumber != nil {
+		account.MaskedNumber = accountRequest.MaskedNumber
+	}
+
+	if accountRequest.CreditLimit != nil {
+		account.CreditLimit = accountRequest.CreditLimit
+	}
+
+	if accountRequest.StatementDay != nil {
+		account.StatementDay = accountRequest.StatementDay
+	}
+
+	if accountRequest.InterestRate != nil {
+		account.InterestRate = accountRequest.InterestRate
+	}
+
+	if accountRequest.PaymentDueDay != nil {
+		account.PaymentDueDay = accountRequest.PaymentDueDay
+	}
+
+	if accountRequest.MinPaymentPercent != nil {
+		account.MinPaymentPercent = accountRequest.MinPaymentPercent
+	}
+
+	if accountRequest.MinPaymentAmount != nil {
+		account.MinPaymentAmount = accountRequest.MinPaymentAmount
+	}
+
+	if accountRequest.LoanPrincipal != nil {
+		account.LoanPrincipal = accountRequest.LoanPrincipal
+	}
+
+	if accountRequest.LoanTermMonths != nil {
+		account.LoanTermMonths = accountRequest.LoanTermMonths
+	}
+
+	if accountRequest.LoanStartDate != nil {
+		account.LoanStartDate = accountRequest.LoanStartDate
+	}
+
+	// start balance must fit currency also when only currency is changed
+	if verr := verifyAccount(account); verr.Err() != nil {
+		logger.WithError(verr).Warn("invalid account")
+		utils.WriteValidationError(w, verr)
+		return
+	}
+
+	// start balance and currency are part of reconciled balance
+	startBalanceChanged := before.StartBalance != nil && account.StartBalance != nil && before.StartBalance.Cmp(*account.StartBalance) != 0
+	currencyChanged := before.Currency != nil && account.Currency != nil && *before.Currency != *account.Currency
+	if (startBalanceChanged || currencyChanged) && !checkAccountUnlocked(w, r, api.DB, logger, accountID) {
+		return
+	}
+
 	if err := api.DB.UpdateAccount(ctx, account); err != nil {
 		logger.WithError(err).Warn("error updating account")
 		utils.WriteError(w, http.StatusInternalServerError, "error updating account", nil)
@@ -152,7 +221,7 @@ func (api *AccountAPI) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 // GET - /users/{userID}/accounts
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, AccountsRead
 func (api *AccountAPI) List(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "account.go -> List()")
@@ -175,13 +244,23 @@ func (api *AccountAPI) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// accounts of other users shared with user
+	shared, err := api.DB.ListSharedAccounts(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting shared accounts")
+		utils.WriteError(w, http.StatusConflict, "error getting accounts", nil)
+		return
+	}
+	accounts = append(accounts, shared...)
+
 	logger.Info("accounts returned")
 
 	utils.WriteJSON(w, http.StatusOK, &accounts)
 }
 
-// GET - /users/{userID}/accounts/{accountID}
-// Permission - MemberIsTarget
+// GET - /users/{userID}/accounts/{accountID}?pending={true|false}
+// Permission - MemberIsOwner, AccountViewer, AccountsRead
+// Credit account with limit has its balance, available credit and utilization in credit
 func (api *AccountAPI) Get(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "account.go -> Get()")
@@ -197,6 +276,11 @@ func (api *AccountAPI) Get(w http.ResponseWriter, r *http.Request) {
 		"accountID": accountID,
 	})
 
+	includePending, ok := readIncludePending(w, r)
+	if !ok {
+		return
+	}
+
 	ctx := r.Context()
 
 	account, err := api.DB.GetAccountByID(ctx, accountID)
@@ -206,13 +290,29 @@ func (api *AccountAPI) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if account.Type != nil && account.Type.HasCreditLimit() && account.CreditLimit != nil {
+		balance, err := api.DB.GetAccountBalance(ctx, accountID, includePending)
+		if err != nil {
+			logger.WithError(err).Warn("error getting account balance")
+			utils.WriteError(w, http.StatusInternalServerError, "error getting account", nil)
+			return
+		}
+
+		if err := account.SetCreditStatus(balance); err != nil {
+			logger.WithError(err).Warn("error computing credit status")
+			utils.WriteError(w, http.StatusInternalServerError, "error getting account", nil)
+			return
+		}
+	}
+
 	logger.Info("account returned")
 
 	utils.WriteJSON(w, http.StatusOK, &account)
 }
 
-// DELETE - /users/{userID}/accounts/{accountID}
-// Permission - MemberIsTarget
+// DELETE - /users/{userID}/accounts/{accountID}?strategy={refuse|cascade|reassign}&target={accountID}&force={true|false}
+// Permission - MemberIsOwner, AccountsWrite
+// Account with locked reconciliation or cleared transactions is deleted only with force, so is reassign to such account
 func (api *AccountAPI) Delete(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "account.go -> Delete()")
@@ -228,18 +328,139 @@ func (api *AccountAPI) Delete(w http.ResponseWriter, r *http.Request) {
 		"accountID": accountID,
 	})
 
+	options, ok := readDeleteOptions(w, r, logger)
+	if !ok {
+		return
+	}
+
 	ctx := r.Context()
 
-	ok, err := api.DB.DeleteAccount(ctx, accountID)
-	if !ok && err != nil {
-		logger.WithError(err).Warn("error deleting account")
-		utils.WriteError(w, http.StatusConflict, "error deleting account", nil)
+	// moved or deleted transactions would change reconciled balances of both accounts
+	reconciledAccounts := []model.AccountID{accountID}
+
+	if options.Strategy == model.DeleteReassign {
+		account, err := api.DB.GetAccountByID(ctx, accountID)
+		if err != nil {
+			logger.WithError(err).Warn("error getting account")
+			utils.WriteError(w, http.StatusConflict, "error getting account", nil)
+			return
+		}
+
+		// transactions can go only to other account of the same user with the same currency
+		target := model.AccountID(options.Target)
+		verr := &model.ValidationError{}
+		if _, err := checkAccountOwner(ctx, api.DB, verr, "target", *account.UserID, target); err != nil {
+			logger.WithError(err).Warn("error checking target account")
+			utils.WriteError(w, http.StatusInternalServerError, "error deleting account", nil)
+			return
+		}
+		if verr.Err() == nil {
+			targetAccount, err := api.DB.GetAccountByID(ctx, target)
+			if err != nil {
+				logger.WithError(err).Warn("error getting target account")
+				utils.WriteError(w, http.StatusInternalServerError, "error deleting account", nil)
+				return
+			}
+			if account.Currency != nil && targetAccount.Currency != nil && *account.Currency != *targetAccount.Currency {
+				verr.Add("target", model.ErrCodeInvalid, "target account has different currency")
+			}
+		}
+		if verr.Err() != nil {
+			logger.WithError(verr).Warn("invalid target account")
+			utils.WriteValidationError(w, verr)
+			return
+		}
+		reconciledAccounts = append(reconciledAccounts, target)
+	}
+
+	if !checkAccountUnlocked(w, r, api.DB, logger, reconciledAccounts...) {
+		return
+	}
+
+	result, err := api.DB.DeleteAccount(ctx, accountID, options)
+	writeDeleteResult(w, logger, "account", result, err)
+}
+
+// GET - /users/{userID}/accounts/{accountID}/history
+// Permission - MemberIsOwner, AccountViewer, AccountsRead
+func (api *AccountAPI) History(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "account.go -> History()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"accountID": accountID,
+	})
+
+	writeHistory(w, r, api.DB, logger, model.AuditEntityAccount, string(accountID))
+}
+
+// POST - /users/{userID}/accounts/{accountID}/restore?force={true|false}
+// Permission - MemberIsOwner, AccountsWrite
+// {"transactions": true} also restores transactions deleted together with account
+// Revision which changes start balance or currency of account with locked reconciliation is restored only with force
+func (api *AccountAPI) Restore(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "account.go -> Restore()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"accountID": accountID,
+	})
+
+	request, revision, ok := readRestoreRevision(w, r, api.DB, logger, model.AuditEntityAccount, string(accountID))
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	// nil state only undeletes account
+	var state *model.Account
+	if revision != nil {
+		state = &model.Account{}
+		if ok := decodeRevision(w, logger, revision, state); !ok {
+			return
+		}
+
+		current, err := api.DB.GetAccountByID(ctx, accountID)
+		if err != nil {
+			logger.WithError(err).Warn("error getting account")
+			utils.WriteError(w, http.StatusConflict, "error getting account", nil)
+			return
+		}
+
+		// start balance and currency are part of reconciled balance
+		startBalanceChanged := current.StartBalance != nil && state.StartBalance != nil && current.StartBalance.Cmp(*state.StartBalance) != 0
+		currencyChanged := current.Currency != nil && state.Currency != nil && *current.Currency != *state.Currency
+		if (startBalanceChanged || currencyChanged) && !checkAccountUnlocked(w, r, api.DB, logger, accountID) {
+			return
+		}
+	}
+
+	account, transactions, err := api.DB.RestoreAccount(ctx, accountID, state, request.Transactions)
+	if err != nil {
+		logger.WithError(err).Warn("error restoring account")
+		utils.WriteError(w, http.StatusConflict, "error restoring account", nil)
 		return
 	}
 
-	logger.Info("account deleted")
+	logger.WithField("transactions", transactions).Info("account restored")
 
-	utils.WriteJSON(w, http.StatusOK, &ActDeleted{
-		Deleted: true,
+	utils.WriteJSON(w, http.StatusOK, &model.AccountRestored{
+		Account:              account,
+		RestoredTransactions: transactions,
 	})
 }
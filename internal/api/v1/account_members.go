@@ -0,0 +1,308 @@
+package v1
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// AccountMemberRequest - new access level of member
+type AccountMemberRequest struct {
+	Access model.AccountAccess `json:"access"`
+}
+
+// POST - /users/{userID}/accounts/{accountID}/members
+// Permission - MemberIsOwner
+// Owner invites other user by email, user becomes member after accepting invitation
+func (api *AccountAPI) InviteMember(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "account_members.go -> InviteMember()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"accountID": accountID,
+	})
+
+	// Decode paramters
+	var invitation model.AccountInvitation
+	if err := json.NewDecoder(r.Body).Decode(&invitation); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := invitation.Verify(); err != nil {
+		logger.WithError(err).Warn("invalid invitation")
+		utils.WriteValidationError(w, err.(*model.ValidationError))
+		return
+	}
+
+	ctx := r.Context()
+
+	invitee, err := api.DB.GetUserByEmail(ctx, invitation.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, "user not found", nil)
+			return
+		}
+		logger.WithError(err).Warn("error getting user")
+		utils.WriteError(w, http.StatusInternalServerError, "error inviting member", nil)
+		return
+	}
+
+	if invitee.ID == userID {
+		utils.WriteError(w, http.StatusBadRequest, "owner can't be member of account", nil)
+		return
+	}
+
+	member := &model.AccountMember{
+		AccountID: accountID,
+		UserID:    invitee.ID,
+		Email:     invitee.Email,
+		Access:    invitation.Access,
+		InvitedBy: principal.UserID,
+	}
+
+	if err := api.DB.CreateAccountMember(ctx, member); err != nil {
+		if err == database.ErrMemberExists {
+			utils.WriteError(w, http.StatusConflict, "user is already invited", nil)
+			return
+		}
+		logger.WithError(err).Warn("error inviting member")
+		utils.WriteError(w, http.StatusInternalServerError, "error inviting member", nil)
+		return
+	}
+
+	logger.WithField("memberID", member.UserID).Info("member invited")
+
+	utils.WriteJSON(w, http.StatusCreated, member)
+}
+
+// GET - /users/{userID}/accounts/{accountID}/members
+// Permission - MemberIsOwner, AccountsRead
+func (api *AccountAPI) ListMembers(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "account_members.go -> ListMembers()")
+
+	vars := mux.Vars(r)
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"principal": principal,
+		"accountID": accountID,
+	})
+
+	ctx := r.Context()
+
+	members, err := api.DB.ListAccountMembers(ctx, accountID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting account members")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting account members", nil)
+		return
+	}
+	if members == nil {
+		members = make([]*model.AccountMember, 0)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, &members)
+}
+
+// PATCH - /users/{userID}/accounts/{accountID}/members/{memberID}
+// Permission - MemberIsOwner
+func (api *AccountAPI) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "account_members.go -> UpdateMember()")
+
+	vars := mux.Vars(r)
+	accountID := model.AccountID(vars["accountID"])
+	memberID := model.UserID(vars["memberID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"principal": principal,
+		"accountID": accountID,
+		"memberID":  memberID,
+	})
+
+	// Decode paramters
+	var request AccountMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if !request.Access.IsValid() {
+		verr := &model.ValidationError{}
+		verr.Add("access", model.ErrCodeInvalid, "access must be viewer or editor")
+		utils.WriteValidationError(w, verr)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := api.DB.UpdateAccountMemberAccess(ctx, accountID, memberID, request.Access); err != nil {
+		if err == database.ErrMemberNotFound {
+			utils.WriteError(w, http.StatusNotFound, "member not found", nil)
+			return
+		}
+		logger.WithError(err).Warn("error updating account member")
+		utils.WriteError(w, http.StatusInternalServerError, "error updating account member", nil)
+		return
+	}
+
+	api.Permissions.Purge()
+
+	logger.Info("account member updated")
+
+	utils.WriteJSON(w, http.StatusOK, &ActUpdated{
+		Updated: true,
+	})
+}
+
+// DELETE - /users/{userID}/accounts/{accountID}/members/{memberID}
+// Permission - MemberIsOwner
+func (api *AccountAPI) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "account_members.go -> RemoveMember()")
+
+	vars := mux.Vars(r)
+	accountID := model.AccountID(vars["accountID"])
+	memberID := model.UserID(vars["memberID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"principal": principal,
+		"accountID": accountID,
+		"memberID":  memberID,
+	})
+
+	api.deleteMember(w, r, logger, accountID, memberID)
+}
+
+// GET - /users/{userID}/invitations
+// Permission - MemberIsTarget
+func (api *AccountAPI) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "account_members.go -> ListInvitations()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	ctx := r.Context()
+
+	invitations, err := api.DB.ListInvitations(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting invitations")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting invitations", nil)
+		return
+	}
+	if invitations == nil {
+		invitations = make([]*model.AccountMember, 0)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, &invitations)
+}
+
+// POST - /users/{userID}/invitations/{accountID}/accept
+// Permission - MemberIsTarget
+func (api *AccountAPI) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "account_members.go -> AcceptInvitation()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"accountID": accountID,
+	})
+
+	ctx := r.Context()
+
+	if err := api.DB.AcceptAccountMember(ctx, accountID, userID); err != nil {
+		if err == database.ErrMemberNotFound {
+			utils.WriteError(w, http.StatusNotFound, "invitation not found", nil)
+			return
+		}
+		logger.WithError(err).Warn("error accepting invitation")
+		utils.WriteError(w, http.StatusInternalServerError, "error accepting invitation", nil)
+		return
+	}
+
+	api.Permissions.Purge()
+
+	logger.Info("invitation accepted")
+
+	utils.WriteJSON(w, http.StatusOK, &ActUpdated{
+		Updated: true,
+	})
+}
+
+// POST - /users/{userID}/invitations/{accountID}/decline
+// Permission - MemberIsTarget
+// Member can decline accepted invitation too, it means leaving shared account
+func (api *AccountAPI) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "account_members.go -> DeclineInvitation()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"accountID": accountID,
+	})
+
+	api.deleteMember(w, r, logger, accountID, userID)
+}
+
+func (api *AccountAPI) deleteMember(w http.ResponseWriter, r *http.Request, logger *logrus.Entry, accountID model.AccountID, userID model.UserID) {
+	if err := api.DB.DeleteAccountMember(r.Context(), accountID, userID); err != nil {
+		if err == database.ErrMemberNotFound {
+			utils.WriteError(w, http.StatusNotFound, "member not found", nil)
+			return
+		}
+		logger.WithError(err).Warn("error deleting account member")
+		utils.WriteError(w, http.StatusInternalServerError, "error deleting account member", nil)
+		return
+	}
+
+	api.Permissions.Purge()
+
+	logger.Info("account member deleted")
+
+	utils.WriteJSON(w, http.StatusOK, &ActDeleted{
+		Deleted: true,
+	})
+}
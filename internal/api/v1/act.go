@@ -14,3 +14,8 @@ type ActDeleted struct {
 type ActUpdated struct {
 	Updated bool `json:"updated"`
 }
+
+// ActImported is an act indicates that import action was finished, it tells how many items were imported.
+type ActImported struct {
+	Imported int `json:"imported"`
+}
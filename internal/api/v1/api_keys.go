@@ -0,0 +1,267 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// APIKeyAPI - provides REST for personal API keys
+type APIKeyAPI struct {
+	DB database.Database
+}
+
+func SetAPIKeyAPI(db database.Database, router *mux.Router, permissions auth.Permissions) {
+	api := &APIKeyAPI{
+		DB: db,
+	}
+
+	apis := []API{
+		NewAPI(http.MethodPost, "/users/{userID}/api-keys", api.Create, auth.MemberIsTarget, auth.RefuseImpersonation),                          // create api key
+		NewAPI(http.MethodGet, "/users/{userID}/api-keys", api.List, auth.Admin, auth.MemberIsTarget),                                           // list api keys
+		NewAPI(http.MethodGet, "/users/{userID}/api-keys/{apiKeyID}", api.Get, auth.Admin, auth.MemberIsTarget),                                 // get api key
+		NewAPI(http.MethodPatch, "/users/{userID}/api-keys/{apiKeyID}", api.Update, auth.MemberIsTarget, auth.RefuseImpersonation),              // change name, scopes or expiry
+		NewAPI(http.MethodDelete, "/users/{userID}/api-keys/{apiKeyID}", api.Delete, auth.Admin, auth.MemberIsTarget, auth.RefuseImpersonation), // revoke api key
+	}
+
+	for _, api := range apis {
+		router.HandleFunc(api.Path, permissions.Wrap(api.Func, api.permissionTypes...)).Methods(api.Method)
+	}
+}
+
+// APIKeyCreated is returned only once after key is created, we don't know the key later
+type APIKeyCreated struct {
+	*model.APIKey
+	Key string `json:"key"`
+}
+
+// POST - /users/{userID}/api-keys
+// Permission - MemberIsTarget, RefuseImpersonation
+func (api *APIKeyAPI) Create(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "api_keys.go -> Create()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	// Decode paramters
+	var key model.APIKey
+	if err := json.NewDecoder(r.Body).Decode(&key); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	key.UserID = userID
+
+	if err := key.Verify(); err != nil {
+		logger.WithError(err).Warn("invalid api key")
+		utils.WriteValidationError(w, err.(*model.ValidationError))
+		return
+	}
+
+	secret, err := key.GenerateAPIKey()
+	if err != nil {
+		logger.WithError(err).Warn("error generating api key")
+		utils.WriteError(w, http.StatusInternalServerError, "error creating api key", nil)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := api.DB.CreateAPIKey(ctx, &key); err != nil {
+		logger.WithError(err).Warn("error creating api key")
+		utils.WriteError(w, http.StatusInternalServerError, "error creating api key", nil)
+		return
+	}
+
+	logger.WithField("apiKeyID", key.ID).Info("api key created")
+
+	utils.WriteJSON(w, http.StatusCreated, &APIKeyCreated{
+		APIKey: &key,
+		Key:    secret,
+	})
+}
+
+// GET - /users/{userID}/api-keys
+// Permission - MemberIsTarget, Admin
+func (api *APIKeyAPI) List(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "api_keys.go -> List()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	ctx := r.Context()
+
+	keys, err := api.DB.ListAPIKeys(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting api keys")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting api keys", nil)
+		return
+	}
+	if keys == nil {
+		keys = make([]*model.APIKey, 0)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, &keys)
+}
+
+// GET - /users/{userID}/api-keys/{apiKeyID}
+// Permission - MemberIsTarget, Admin
+func (api *APIKeyAPI) Get(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "api_keys.go -> Get()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	keyID := model.APIKeyID(vars["apiKeyID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"apiKeyID":  keyID,
+	})
+
+	ctx := r.Context()
+
+	key, err := api.DB.GetAPIKey(ctx, userID, keyID)
+	if err != nil {
+		if err == database.ErrAPIKeyNotFound {
+			utils.WriteError(w, http.StatusNotFound, "api key not found", nil)
+			return
+		}
+		logger.WithError(err).Warn("error getting api key")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting api key", nil)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, key)
+}
+
+// PATCH - /users/{userID}/api-keys/{apiKeyID}
+// Permission - MemberIsTarget, RefuseImpersonation
+func (api *APIKeyAPI) Update(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "api_keys.go -> Update()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	keyID := model.APIKeyID(vars["apiKeyID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"apiKeyID":  keyID,
+	})
+
+	// Decode paramters
+	var request model.APIKey
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	ctx := r.Context()
+
+	key, err := api.DB.GetAPIKey(ctx, userID, keyID)
+	if err != nil {
+		if err == database.ErrAPIKeyNotFound {
+			utils.WriteError(w, http.StatusNotFound, "api key not found", nil)
+			return
+		}
+		logger.WithError(err).Warn("error getting api key")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting api key", nil)
+		return
+	}
+
+	if request.Name != nil {
+		key.Name = request.Name
+	}
+
+	if request.Scopes != nil {
+		key.Scopes = request.Scopes
+	}
+
+	if request.ExpiresAt != nil {
+		key.ExpiresAt = request.ExpiresAt
+	}
+
+	if err := key.Verify(); err != nil {
+		logger.WithError(err).Warn("invalid api key")
+		utils.WriteValidationError(w, err.(*model.ValidationError))
+		return
+	}
+
+	if err := api.DB.UpdateAPIKey(ctx, key); err != nil {
+		logger.WithError(err).Warn("error updating api key")
+		utils.WriteError(w, http.StatusInternalServerError, "error updating api key", nil)
+		return
+	}
+
+	logger.Info("api key updated")
+
+	utils.WriteJSON(w, http.StatusOK, &ActUpdated{
+		Updated: true,
+	})
+}
+
+// DELETE - /users/{userID}/api-keys/{apiKeyID}
+// Permission - MemberIsTarget, Admin, RefuseImpersonation
+func (api *APIKeyAPI) Delete(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "api_keys.go -> Delete()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	keyID := model.APIKeyID(vars["apiKeyID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"apiKeyID":  keyID,
+	})
+
+	ctx := r.Context()
+
+	if err := api.DB.DeleteAPIKey(ctx, userID, keyID); err != nil {
+		if err == database.ErrAPIKeyNotFound {
+			utils.WriteError(w, http.StatusNotFound, "api key not found", nil)
+			return
+		}
+		logger.WithError(err).Warn("error deleting api key")
+		utils.WriteError(w, http.StatusInternalServerError, "error deleting api key", nil)
+		return
+	}
+
+	logger.Info("api key deleted")
+
+	utils.WriteJSON(w, http.StatusOK, &ActDeleted{
+		Deleted: true,
+	})
+}
@@ -0,0 +1,135 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// AuditAPI - provides REST for audit log, log is read only
+type AuditAPI struct {
+	DB database.Database
+}
+
+func SetAuditAPI(db database.Database, router *mux.Router, permissions auth.Permissions) {
+	api := &AuditAPI{
+		DB: db,
+	}
+
+	apis := []API{
+		NewAPI(http.MethodGet, "/audit", api.List, auth.Admin),                                           // search whole audit log
+		NewAPI(http.MethodGet, "/users/{userID}/audit", api.ListByUser, auth.Admin, auth.MemberIsTarget), // changes of user's data
+	}
+
+	for _, api := range apis {
+		router.HandleFunc(api.Path, permissions.Wrap(api.Func, api.permissionTypes...)).Methods(api.Method)
+	}
+}
+
+// GET - /audit?userID=&actorID=&impersonatorID=&entityType=&entityID=&action=&from=&to=&limit=&offset=
+// Permission - Admin
+func (api *AuditAPI) List(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "audit.go -> List()")
+
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"principal": principal,
+	})
+
+	filter, verr := parseAuditFilter(r.URL.Query())
+	if err := verr.Err(); err != nil {
+		logger.WithError(err).Warn("invalid audit filter")
+		utils.WriteValidationError(w, verr)
+		return
+	}
+
+	api.writeEntries(w, r, logger, filter)
+}
+
+// GET - /users/{userID}/audit?actorID=&impersonatorID=&entityType=&entityID=&action=&from=&to=&limit=&offset=
+// Permission - MemberIsTarget, Admin
+func (api *AuditAPI) ListByUser(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "audit.go -> ListByUser()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	filter, verr := parseAuditFilter(r.URL.Query())
+	if err := verr.Err(); err != nil {
+		logger.WithError(err).Warn("invalid audit filter")
+		utils.WriteValidationError(w, verr)
+		return
+	}
+
+	// user sees only entries about own data
+	filter.UserID = userID
+
+	api.writeEntries(w, r, logger, filter)
+}
+
+func (api *AuditAPI) writeEntries(w http.ResponseWriter, r *http.Request, logger *logrus.Entry, filter model.AuditFilter) {
+	entries, err := api.DB.ListAuditEntries(r.Context(), filter)
+	if err != nil {
+		logger.WithError(err).Warn("error getting audit entries")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting audit entries", nil)
+		return
+	}
+	if entries == nil {
+		entries = make([]*model.AuditEntry, 0)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, &entries)
+}
+
+func parseAuditFilter(query url.Values) (model.AuditFilter, *model.ValidationError) {
+	verr := &model.ValidationError{}
+	filter := model.AuditFilter{
+		UserID:         model.UserID(query.Get("userID")),
+		ActorID:        model.UserID(query.Get("actorID")),
+		ImpersonatorID: model.UserID(query.Get("impersonatorID")),
+		EntityType:     model.AuditEntity(query.Get("entityType")),
+		EntityID:       query.Get("entityID"),
+		Action:         model.AuditAction(query.Get("action")),
+	}
+
+	// time filters are optional, TimeParam would default them to now
+	if query.Get("from") != "" {
+		from, err := utils.TimeParam(query, "from")
+		if err != nil {
+			verr.Add("from", model.ErrCodeInvalid, "from must be RFC 3339 time")
+		}
+		filter.From = from
+	}
+	if query.Get("to") != "" {
+		to, err := utils.TimeParam(query, "to")
+		if err != nil {
+			verr.Add("to", model.ErrCodeInvalid, "to must be RFC 3339 time")
+		}
+		filter.To = to
+	}
+
+	var err error
+	if filter.Limit, err = utils.IntParam(query, "limit", 0); err != nil {
+		verr.Add("limit", model.ErrCodeInvalid, "limit must be a number")
+	}
+	if filter.Offset, err = utils.IntParam(query, "offset", 0); err != nil {
+		verr.Add("offset", model.ErrCodeInvalid, "offset must be a number")
+	}
+
+	return filter, verr
+}
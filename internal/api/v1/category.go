@@ -24,11 +24,13 @@ func SetCategoryAPI(db database.Database, router *mux.Router, permissions auth.P
 	}
 
 	apis := []API{
-		NewAPI(http.MethodPost, "/users/{userID}/categories", api.Create, auth.Admin, auth.MemberIsTarget),                // create category for user (Open for admin for now)
-		NewAPI(http.MethodGet, "/users/{userID}/categories", api.List, auth.Admin, auth.MemberIsTarget),                   // get category for user (Open for admin for now)
-		NewAPI(http.MethodPatch, "/users/{userID}/categories/{categoryID}", api.Update, auth.Admin, auth.MemberIsTarget),  // update category for user (Open for admin for now)
-		NewAPI(http.MethodGet, "/users/{userID}/categories/{categoryID}", api.Get, auth.Admin, auth.MemberIsTarget),       // get category by category id for user (Open for admin for now)
-		NewAPI(http.MethodDelete, "/users/{userID}/categories/{categoryID}", api.Delete, auth.Admin, auth.MemberIsTarget), // delete category by category id for user (Open for admin for now)
+		NewAPI(http.MethodPost, "/users/{userID}/categories", api.Create, auth.Admin, auth.MemberIsOwner, auth.CategoriesWrite),                       // create category for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/users/{userID}/categories", api.List, auth.Admin, auth.MemberIsOwner, auth.CategoriesRead),                           // get category for user (Open for admin for now)
+		NewAPI(http.MethodPatch, "/users/{userID}/categories/{categoryID}", api.Update, auth.Admin, auth.MemberIsOwner, auth.CategoriesWrite),         // update category for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/users/{userID}/categories/{categoryID}", api.Get, auth.Admin, auth.MemberIsOwner, auth.CategoriesRead),               // get category by category id for user (Open for admin for now)
+		NewAPI(http.MethodDelete, "/users/{userID}/categories/{categoryID}", api.Delete, auth.Admin, auth.MemberIsOwner, auth.CategoriesWrite),        // delete category by category id for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/users/{userID}/categories/{categoryID}/history", api.History, auth.Admin, auth.MemberIsOwner, auth.CategoriesRead),   // list revisions of category
+		NewAPI(http.MethodPost, "/users/{userID}/categories/{categoryID}/restore", api.Restore, auth.Admin, auth.MemberIsOwner, auth.CategoriesWrite), // restore revision or undelete category
 	}
 
 	for _, api := range apis {
@@ -37,7 +39,7 @@ func SetCategoryAPI(db database.Database, router *mux.Router, permissions auth.P
 }
 
 // POST - /users/{userID}/categories
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, CategoriesWrite
 func (api *CategoryAPI) Create(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "category.go -> Create()")
@@ -73,6 +75,20 @@ func (api *CategoryAPI) Create(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
+	if category.ParentID != model.NilCategoryID {
+		verr := &model.ValidationError{}
+		if err := checkCategoryOwner(ctx, api.DB, verr, "parentID", userID, category.ParentID); err != nil {
+			logger.WithError(err).Warn("error checking parent category")
+			utils.WriteError(w, http.StatusInternalServerError, "error creating category", nil)
+			return
+		}
+		if verr.Err() != nil {
+			logger.WithError(verr).Warn("invalid parent category")
+			utils.WriteValidationError(w, verr)
+			return
+		}
+	}
+
 	if err := api.DB.CreateCategory(ctx, &category); err != nil {
 		logger.WithError(err).Warn("error creating category")
 		utils.WriteError(w, http.StatusInternalServerError, "error creating category", nil)
@@ -85,7 +101,7 @@ func (api *CategoryAPI) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 // PATCH - /users/{userID}/categories/{categoryID}
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, CategoriesWrite
 func (api *CategoryAPI) Update(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "category.go -> Update()")
@@ -123,10 +139,24 @@ func (api *CategoryAPI) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if categoryRequest.ParentID != model.NilCategoryID {
+		verr := &model.ValidationError{}
+		if categoryRequest.ParentID == categoryID {
+			verr.Add("parentID", model.ErrCodeInvalid, "category can't be its own parent")
+		} else if err := checkCategoryOwner(ctx, api.DB, verr, "parentID", *category.UserID, categoryRequest.ParentID); err != nil {
+			logger.WithError(err).Warn("error checking parent category")
+			utils.WriteError(w, http.StatusInternalServerError, "error updating category", nil)
+			return
+		}
+		if verr.Err() != nil {
+			logger.WithError(verr).Warn("invalid parent category")
+			utils.WriteValidationError(w, verr)
+			return
+		}
+
 		category.ParentID = categoryRequest.ParentID
 	}
 
-	if categoryRequest.Name != nil || len(*categoryRequest.Name) != 0 {
+	if categoryRequest.Name != nil && len(*categoryRequest.Name) != 0 {
 		category.Name = categoryRequest.Name
 	}
 
@@ -144,7 +174,7 @@ func (api *CategoryAPI) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 // GET - /users/{userID}/categories
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, CategoriesRead
 func (api *CategoryAPI) List(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "category.go -> List()")
@@ -173,7 +203,7 @@ func (api *CategoryAPI) List(w http.ResponseWriter, r *http.Request) {
 }
 
 // GET - /users/{userID}/categories/{categoryID}
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, CategoriesRead
 func (api *CategoryAPI) Get(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "category.go -> Get()")
@@ -203,8 +233,8 @@ func (api *CategoryAPI) Get(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, &category)
 }
 
-// DELETE - /users/{userID}/categories/{categoryID}
-// Permission - MemberIsTarget
+// DELETE - /users/{userID}/categories/{categoryID}?strategy={refuse|cascade|reassign}&target={categoryID}
+// Permission - MemberIsOwner, CategoriesWrite
 func (api *CategoryAPI) Delete(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "category.go -> Delete()")
@@ -220,18 +250,114 @@ func (api *CategoryAPI) Delete(w http.ResponseWriter, r *http.Request) {
 		"categoryID": categoryID,
 	})
 
+	options, ok := readDeleteOptions(w, r, logger)
+	if !ok {
+		return
+	}
+
 	ctx := r.Context()
 
-	ok, err := api.DB.DeleteCategory(ctx, categoryID)
-	if !ok && err != nil {
-		logger.WithError(err).Warn("error deleting category")
-		utils.WriteError(w, http.StatusConflict, "error deleting category", nil)
-		return
+	if options.Strategy == model.DeleteReassign {
+		category, err := api.DB.GetCategoryByID(ctx, categoryID)
+		if err != nil {
+			logger.WithError(err).Warn("error getting category")
+			utils.WriteError(w, http.StatusConflict, "error getting category", nil)
+			return
+		}
+
+		verr := &model.ValidationError{}
+		if err := checkCategoryOwner(ctx, api.DB, verr, "target", *category.UserID, model.CategoryID(options.Target)); err != nil {
+			logger.WithError(err).Warn("error checking target category")
+			utils.WriteError(w, http.StatusInternalServerError, "error deleting category", nil)
+			return
+		}
+		if verr.Err() != nil {
+			logger.WithError(verr).Warn("invalid target category")
+			utils.WriteValidationError(w, verr)
+			return
+		}
 	}
 
-	logger.Info("category deleted")
+	result, err := api.DB.DeleteCategory(ctx, categoryID, options)
+	writeDeleteResult(w, logger, "category", result, err)
+}
+
+// GET - /users/{userID}/categories/{categoryID}/history
+// Permission - MemberIsOwner, CategoriesRead
+func (api *CategoryAPI) History(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "category.go -> History()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	categoryID := model.CategoryID(vars["categoryID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":     userID,
+		"principal":  principal,
+		"categoryID": categoryID,
+	})
+
+	writeHistory(w, r, api.DB, logger, model.AuditEntityCategory, string(categoryID))
+}
+
+// POST - /users/{userID}/categories/{categoryID}/restore
+// Permission - MemberIsOwner, CategoriesWrite
+func (api *CategoryAPI) Restore(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "category.go -> Restore()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	categoryID := model.CategoryID(vars["categoryID"])
+	principal := auth.GetPrincipal(r)
 
-	utils.WriteJSON(w, http.StatusOK, &ActDeleted{
-		Deleted: true,
+	logger = logger.WithFields(logrus.Fields{
+		"userID":     userID,
+		"principal":  principal,
+		"categoryID": categoryID,
 	})
+
+	_, revision, ok := readRestoreRevision(w, r, api.DB, logger, model.AuditEntityCategory, string(categoryID))
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	// nil state only undeletes category
+	var state *model.Category
+	if revision != nil {
+		state = &model.Category{}
+		if ok := decodeRevision(w, logger, revision, state); !ok {
+			return
+		}
+
+		// parent could be deleted since
+		if state.ParentID != model.NilCategoryID && state.UserID != nil {
+			verr := &model.ValidationError{}
+			if err := checkCategoryOwner(ctx, api.DB, verr, "parentID", *state.UserID, state.ParentID); err != nil {
+				logger.WithError(err).Warn("error checking parent category")
+				utils.WriteError(w, http.StatusInternalServerError, "error restoring category", nil)
+				return
+			}
+			if verr.Err() != nil {
+				logger.WithError(verr).Warn("invalid parent category")
+				utils.WriteValidationError(w, verr)
+				return
+			}
+		}
+	}
+
+	category, err := api.DB.RestoreCategory(ctx, categoryID, state)
+	if err != nil {
+		logger.WithError(err).Warn("error restoring category")
+		utils.WriteError(w, http.StatusConflict, "error restoring category", nil)
+		return
+	}
+
+	logger.Info("category restored")
+
+	utils.WriteJSON(w, http.StatusOK, &category)
 }
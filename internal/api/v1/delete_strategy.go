@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// Accounts and categories are deleted with ?strategy=refuse|cascade|reassign&target={ID}:
+// refuse (default) fails when they have transactions or child categories, cascade deletes them too,
+// reassign moves them to target account or category. Response has counts of affected rows.
+
+// readDeleteOptions reads strategy from query, response is written when it returns false
+func readDeleteOptions(w http.ResponseWriter, r *http.Request, logger *logrus.Entry) (model.DeleteOptions, bool) {
+	query := r.URL.Query()
+	options := model.DeleteOptions{
+		Strategy: model.DeleteStrategy(query.Get("strategy")),
+		Target:   query.Get("target"),
+	}
+
+	if err := options.Verify(); err != nil {
+		logger.WithError(err).Warn("invalid delete options")
+		utils.WriteValidationError(w, err.(*model.ValidationError))
+		return options, false
+	}
+
+	return options, true
+}
+
+// writeDeleteResult writes counts of deleted or moved rows, or why delete failed
+func writeDeleteResult(w http.ResponseWriter, logger *logrus.Entry, name string, result *model.DeleteResult, err error) {
+	switch {
+	case err == database.ErrHasDependents:
+		logger.Warn(name + " has dependents")
+		utils.WriteError(w, http.StatusConflict, name+" has transactions or child categories, use strategy cascade or reassign", result)
+	case err == database.ErrInvalidTarget:
+		verr := &model.ValidationError{}
+		verr.Add("target", model.ErrCodeInvalid, name+" can't be reassigned to itself or its child")
+		utils.WriteValidationError(w, verr)
+	case err != nil:
+		logger.WithError(err).Warn("error deleting " + name)
+		utils.WriteError(w, http.StatusConflict, "error deleting "+name, nil)
+	default:
+		logger.WithField("result", result).Info(name + " deleted")
+		utils.WriteJSON(w, http.StatusOK, result)
+	}
+}
@@ -0,0 +1,299 @@
+package v1
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/finance"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// maxImportedPrices keeps CSV import in one reasonable database transaction
+const maxImportedPrices = 10000
+
+// InvestmentAPI - provides REST for prices of symbols and holdings of investment accounts, holdings are computed
+// from buy, sell and dividend transactions
+type InvestmentAPI struct {
+	DB database.Database
+}
+
+func SetInvestmentAPI(db database.Database, router *mux.Router, permissions auth.Permissions) {
+	api := &InvestmentAPI{
+		DB: db,
+	}
+
+	apis := []API{
+		NewAPI(http.MethodPost, "/users/{userID}/prices", api.SetPrice, auth.Admin, auth.MemberIsOwner, auth.AccountsWrite),                                          // enter price of symbol on date
+		NewAPI(http.MethodPost, "/users/{userID}/prices/import", api.ImportPrices, auth.Admin, auth.MemberIsOwner, auth.AccountsWrite),                               // import prices from CSV
+		NewAPI(http.MethodGet, "/users/{userID}/prices", api.ListPrices, auth.Admin, auth.MemberIsOwner, auth.AccountsRead),                                          // list price history
+		NewAPI(http.MethodGet, "/users/{userID}/accounts/{accountID}/holdings", api.Holdings, auth.Admin, auth.MemberIsOwner, auth.AccountViewer, auth.AccountsRead), // positions, gains and allocation of investment account
+	}
+
+	for _, api := range apis {
+		router.HandleFunc(api.Path, permissions.Wrap(api.Func, api.permissionTypes...)).Methods(api.Method)
+	}
+}
+
+// POST - /users/{userID}/prices
+// Permission - MemberIsOwner, AccountsWrite
+// Price of symbol on date which exists is replaced
+func (api *InvestmentAPI) SetPrice(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "investment.go -> SetPrice()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	// Decode paramters
+	var price model.Price
+	if err := json.NewDecoder(r.Body).Decode(&price); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	price.UserID = &userID
+
+	if err, ok := price.Verify().(*model.ValidationError); ok {
+		logger.WithError(err).Warn("invalid price")
+		utils.WriteValidationError(w, err)
+		return
+	}
+
+	if err := api.DB.SetPrices(r.Context(), []*model.Price{&price}); err != nil {
+		logger.WithError(err).Warn("error setting price")
+		utils.WriteError(w, http.StatusInternalServerError, "error setting price", nil)
+		return
+	}
+
+	logger.WithField("symbol", *price.Symbol).Info("price set")
+
+	utils.WriteJSON(w, http.StatusOK, &price)
+}
+
+// POST - /users/{userID}/prices/import
+// Permission - MemberIsOwner, AccountsWrite
+// Body is CSV with columns symbol, date (2006-01-02) and price, header line is optional.
+// Nothing is imported when one of lines is invalid.
+func (api *InvestmentAPI) ImportPrices(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "investment.go -> ImportPrices()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	prices, verr, err := readPricesCSV(r.Body, userID)
+	if err != nil {
+		logger.WithError(err).Warn("could not read CSV")
+		utils.WriteError(w, http.StatusBadRequest, "could not read CSV", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if verr.Err() != nil {
+		logger.WithError(verr).Warn("invalid prices")
+		utils.WriteValidationError(w, verr)
+		return
+	}
+
+	if err := api.DB.SetPrices(r.Context(), prices); err != nil {
+		logger.WithError(err).Warn("error importing prices")
+		utils.WriteError(w, http.StatusInternalServerError, "error importing prices", nil)
+		return
+	}
+
+	logger.WithField("prices", len(prices)).Info("prices imported")
+
+	utils.WriteJSON(w, http.StatusOK, &ActImported{
+		Imported: len(prices),
+	})
+}
+
+// readPricesCSV parses lines symbol,date,price. Fields of invalid line are reported as prices[line].field,
+// lines are counted from 1 like in editor.
+func readPricesCSV(body io.Reader, userID model.UserID) ([]*model.Price, *model.ValidationError, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	verr := &model.ValidationError{}
+	var prices []*model.Price
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if line == 1 && strings.EqualFold(record[0], "symbol") {
+			continue
+		}
+		if len(prices) == maxImportedPrices {
+			return nil, nil, errors.Errorf("at most %d prices can be imported at once", maxImportedPrices)
+		}
+
+		field := fmt.Sprintf("prices[%d].", line)
+		price := &model.Price{UserID: &userID, Symbol: &record[0]}
+		if date, err := time.Parse("2006-01-02", record[1]); err != nil {
+			verr.Add(field+"date", model.ErrCodeInvalid, "date must be like 2006-01-02")
+		} else {
+			price.Date = &date
+		}
+		if amount, err := model.ParseMoney(record[2]); err != nil {
+			verr.Add(field+"price", model.ErrCodeInvalid, err.Error())
+		} else {
+			price.Price = &amount
+		}
+		if price.Date == nil || price.Price == nil {
+			continue
+		}
+
+		if err, ok := price.Verify().(*model.ValidationError); ok {
+			for _, fieldError := range err.Fields {
+				verr.Add(field+fieldError.Field, fieldError.Code, fieldError.Message)
+			}
+			continue
+		}
+		prices = append(prices, price)
+	}
+
+	return prices, verr, nil
+}
+
+// GET - /users/{userID}/prices?symbol={symbol}
+// Permission - MemberIsOwner, AccountsRead
+// Prices are sorted by symbol and date, symbol filters one of them
+func (api *InvestmentAPI) ListPrices(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "investment.go -> ListPrices()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	symbol := model.NormalizeSymbol(r.URL.Query().Get("symbol"))
+
+	prices, err := api.DB.ListPrices(r.Context(), userID, symbol)
+	if err != nil {
+		logger.WithError(err).Warn("error getting prices")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting prices", nil)
+		return
+	}
+	if prices == nil {
+		prices = make([]*model.Price, 0)
+	}
+
+	logger.Info("prices returned")
+
+	utils.WriteJSON(w, http.StatusOK, prices)
+}
+
+// accountHoldings values positions of investment account with prices of its owner until now
+func accountHoldings(ctx context.Context, db database.Database, account *model.Account, now time.Time, includePending bool) (*model.Holdings, error) {
+	if account.Type == nil || !account.Type.HasHoldings() {
+		return nil, finance.ErrNoHoldings
+	}
+
+	balance, err := db.GetAccountBalance(ctx, account.ID, includePending)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := db.ListTransactionByAccountID(ctx, account.ID, time.Time{}, now.Add(time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	prices, err := db.ListLatestPrices(ctx, *account.UserID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return finance.Holdings(account, balance, model.CountedTransactions(transactions, includePending), prices, now)
+}
+
+// GET - /users/{userID}/accounts/{accountID}/holdings?pending={true|false}
+// Permission - MemberIsOwner, AccountViewer, AccountsRead
+func (api *InvestmentAPI) Holdings(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "investment.go -> Holdings()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"accountID": accountID,
+	})
+
+	includePending, ok := readIncludePending(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	account, err := api.DB.GetAccountByID(ctx, accountID)
+	if err != nil || account.DeletedAt != nil {
+		logger.WithError(err).Warn("error getting account")
+		utils.WriteError(w, http.StatusNotFound, "account not found", nil)
+		return
+	}
+
+	holdings, err := accountHoldings(ctx, api.DB, account, time.Now(), includePending)
+	if err == finance.ErrNoHoldings {
+		logger.Warn("account doesn't hold investments")
+		utils.WriteError(w, http.StatusConflict, "only investment accounts have holdings", nil)
+		return
+	}
+	if errors.Cause(err) == finance.ErrOversold {
+		logger.WithError(err).Warn("account sells more than it holds")
+		utils.WriteError(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Warn("error computing holdings")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting holdings", nil)
+		return
+	}
+
+	logger.Info("holdings returned")
+
+	utils.WriteJSON(w, http.StatusOK, holdings)
+}
@@ -0,0 +1,151 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/finance"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// LoanAPI - provides REST for amortization of loan accounts, it's computed from loan terms and transactions
+type LoanAPI struct {
+	DB database.Database
+}
+
+func SetLoanAPI(db database.Database, router *mux.Router, permissions auth.Permissions) {
+	api := &LoanAPI{
+		DB: db,
+	}
+
+	apis := []API{
+		NewAPI(http.MethodGet, "/users/{userID}/accounts/{accountID}/amortization", api.Schedule, auth.Admin, auth.MemberIsOwner, auth.AccountViewer, auth.AccountsRead), // amortization schedule of loan terms
+		NewAPI(http.MethodGet, "/users/{userID}/accounts/{accountID}/loan", api.Status, auth.Admin, auth.MemberIsOwner, auth.AccountViewer, auth.AccountsRead),           // payments made against schedule and payoff scenarios
+	}
+
+	for _, api := range apis {
+		router.HandleFunc(api.Path, permissions.Wrap(api.Func, api.permissionTypes...)).Methods(api.Method)
+	}
+}
+
+// GET - /users/{userID}/accounts/{accountID}/amortization
+// Permission - MemberIsOwner, AccountViewer, AccountsRead
+func (api *LoanAPI) Schedule(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "loan.go -> Schedule()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"accountID": accountID,
+	})
+
+	account, err := api.DB.GetAccountByID(r.Context(), accountID)
+	if err != nil || account.DeletedAt != nil {
+		logger.WithError(err).Warn("error getting account")
+		utils.WriteError(w, http.StatusNotFound, "account not found", nil)
+		return
+	}
+
+	schedule, err := finance.AmortizationSchedule(account)
+	if err == finance.ErrNoLoanTerms {
+		logger.Warn("account has no loan terms")
+		utils.WriteError(w, http.StatusConflict, "only loan accounts with loanPrincipal, loanTermMonths and loanStartDate have amortization", nil)
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Warn("error computing amortization schedule")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting amortization schedule", nil)
+		return
+	}
+
+	logger.Info("amortization schedule returned")
+
+	utils.WriteJSON(w, http.StatusOK, schedule)
+}
+
+// GET - /users/{userID}/accounts/{accountID}/loan?extra={amount}&extra={amount}&pending={true|false}
+// Permission - MemberIsOwner, AccountViewer, AccountsRead
+// Every extra amount adds payoff scenario with that amount paid on top of each scheduled payment
+func (api *LoanAPI) Status(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "loan.go -> Status()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"accountID": accountID,
+	})
+
+	ctx := r.Context()
+
+	account, err := api.DB.GetAccountByID(ctx, accountID)
+	if err != nil || account.DeletedAt != nil {
+		logger.WithError(err).Warn("error getting account")
+		utils.WriteError(w, http.StatusNotFound, "account not found", nil)
+		return
+	}
+
+	includePending, ok := readIncludePending(w, r)
+	if !ok {
+		return
+	}
+
+	verr := &model.ValidationError{}
+	var extras []model.Money
+	for _, value := range r.URL.Query()["extra"] {
+		extra, err := model.ParseMoney(value)
+		if err != nil || extra.Sign() < 0 {
+			verr.Add("extra", model.ErrCodeInvalid, "extra must be a positive amount")
+			continue
+		}
+		if account.Currency != nil {
+			checkMoney(verr, "extra", &extra, *account.Currency)
+		}
+		extras = append(extras, extra)
+	}
+	if verr.Err() != nil {
+		utils.WriteValidationError(w, verr)
+		return
+	}
+
+	now := time.Now()
+	transactions, err := api.DB.ListTransactionByAccountID(ctx, account.ID, time.Time{}, now.Add(time.Second))
+	if err != nil {
+		logger.WithError(err).Warn("error getting transactions")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting loan", nil)
+		return
+	}
+
+	status, err := finance.ReconcileLoan(account, model.CountedTransactions(transactions, includePending), now, extras)
+	if err == finance.ErrNoLoanTerms {
+		logger.Warn("account has no loan terms")
+		utils.WriteError(w, http.StatusConflict, "only loan accounts with loanPrincipal, loanTermMonths and loanStartDate have amortization", nil)
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Warn("error reconciling loan")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting loan", nil)
+		return
+	}
+
+	logger.Info("loan returned")
+
+	utils.WriteJSON(w, http.StatusOK, status)
+}
@@ -24,11 +24,13 @@ func SetMerchantAPI(db database.Database, router *mux.Router, permissions auth.P
 	}
 
 	apis := []API{
-		NewAPI(http.MethodPost, "/users/{userID}/merchants", api.Create, auth.Admin, auth.MemberIsTarget),                // create merchant for user (Open for admin for now)
-		NewAPI(http.MethodGet, "/users/{userID}/merchants", api.List, auth.Admin, auth.MemberIsTarget),                   // get merchant for user (Open for admin for now)
-		NewAPI(http.MethodPatch, "/users/{userID}/merchants/{merchantID}", api.Update, auth.Admin, auth.MemberIsTarget),  // update merchant for user (Open for admin for now)
-		NewAPI(http.MethodGet, "/users/{userID}/merchants/{merchantID}", api.Get, auth.Admin, auth.MemberIsTarget),       // get merchant by merchant id for user (Open for admin for now)
-		NewAPI(http.MethodDelete, "/users/{userID}/merchants/{merchantID}", api.Delete, auth.Admin, auth.MemberIsTarget), // delete merchant by merchant id for user (Open for admin for now)
+		NewAPI(http.MethodPost, "/users/{userID}/merchants", api.Create, auth.Admin, auth.MemberIsOwner, auth.MerchantsWrite),                       // create merchant for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/users/{userID}/merchants", api.List, auth.Admin, auth.MemberIsOwner, auth.MerchantsRead),                           // get merchant for user (Open for admin for now)
+		NewAPI(http.MethodPatch, "/users/{userID}/merchants/{merchantID}", api.Update, auth.Admin, auth.MemberIsOwner, auth.MerchantsWrite),         // update merchant for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/users/{userID}/merchants/{merchantID}", api.Get, auth.Admin, auth.MemberIsOwner, auth.MerchantsRead),               // get merchant by merchant id for user (Open for admin for now)
+		NewAPI(http.MethodDelete, "/users/{userID}/merchants/{merchantID}", api.Delete, auth.Admin, auth.MemberIsOwner, auth.MerchantsWrite),        // delete merchant by merchant id for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/users/{userID}/merchants/{merchantID}/history", api.History, auth.Admin, auth.MemberIsOwner, auth.MerchantsRead),   // list revisions of merchant
+		NewAPI(http.MethodPost, "/users/{userID}/merchants/{merchantID}/restore", api.Restore, auth.Admin, auth.MemberIsOwner, auth.MerchantsWrite), // restore revision or undelete merchant
 	}
 
 	for _, api := range apis {
@@ -37,7 +39,7 @@ func SetMerchantAPI(db database.Database, router *mux.Router, permissions auth.P
 }
 
 // POST - /users/{userID}/merchants
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, MerchantsWrite
 func (api *MerchantAPI) Create(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "merchant.go -> Create()")
@@ -85,7 +87,7 @@ func (api *MerchantAPI) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 // PATCH - /users/{userID}/merchants/{merchantID}
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, MerchantsWrite
 func (api *MerchantAPI) Update(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "merchant.go -> Update()")
@@ -122,7 +124,7 @@ func (api *MerchantAPI) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if merchantRequest.Name != nil || len(*merchantRequest.Name) != 0 {
+	if merchantRequest.Name != nil && len(*merchantRequest.Name) != 0 {
 		merchant.Name = merchantRequest.Name
 	}
 
@@ -140,7 +142,7 @@ func (api *MerchantAPI) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 // GET - /users/{userID}/merchants
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, MerchantsRead
 func (api *MerchantAPI) List(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "merchant.go -> List()")
@@ -169,7 +171,7 @@ func (api *MerchantAPI) List(w http.ResponseWriter, r *http.Request) {
 }
 
 // GET - /users/{userID}/merchants/{MerchantID}
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, MerchantsRead
 func (api *MerchantAPI) Get(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "merchant.go -> Get()")
@@ -200,7 +202,7 @@ func (api *MerchantAPI) Get(w http.ResponseWriter, r *http.Request) {
 }
 
 // DELETE - /users/{userID}/merchants/{merchantID}
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, MerchantsWrite
 func (api *MerchantAPI) Delete(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "merchant.go -> Delete()")
@@ -231,3 +233,68 @@ func (api *MerchantAPI) Delete(w http.ResponseWriter, r *http.Request) {
 		Deleted: true,
 	})
 }
+
+// GET - /users/{userID}/merchants/{merchantID}/history
+// Permission - MemberIsOwner, MerchantsRead
+func (api *MerchantAPI) History(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "merchant.go -> History()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	merchantID := model.MerchantID(vars["merchantID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":     userID,
+		"principal":  principal,
+		"merchantID": merchantID,
+	})
+
+	writeHistory(w, r, api.DB, logger, model.AuditEntityMerchant, string(merchantID))
+}
+
+// POST - /users/{userID}/merchants/{merchantID}/restore
+// Permission - MemberIsOwner, MerchantsWrite
+func (api *MerchantAPI) Restore(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "merchant.go -> Restore()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	merchantID := model.MerchantID(vars["merchantID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":     userID,
+		"principal":  principal,
+		"merchantID": merchantID,
+	})
+
+	_, revision, ok := readRestoreRevision(w, r, api.DB, logger, model.AuditEntityMerchant, string(merchantID))
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	// nil state only undeletes merchant
+	var state *model.Merchant
+	if revision != nil {
+		state = &model.Merchant{}
+		if ok := decodeRevision(w, logger, revision, state); !ok {
+			return
+		}
+	}
+
+	merchant, err := api.DB.RestoreMerchant(ctx, merchantID, state)
+	if err != nil {
+		logger.WithError(err).Warn("error restoring merchant")
+		utils.WriteError(w, http.StatusConflict, "error restoring merchant", nil)
+		return
+	}
+
+	logger.Info("merchant restored")
+
+	utils.WriteJSON(w, http.StatusOK, &merchant)
+}
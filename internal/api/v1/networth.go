@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/finance"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// NetWorthAPI - provides REST for net worth of user, it's computed from all his accounts
+type NetWorthAPI struct {
+	DB database.Database
+}
+
+func SetNetWorthAPI(db database.Database, router *mux.Router, permissions auth.Permissions) {
+	api := &NetWorthAPI{
+		DB: db,
+	}
+
+	apis := []API{
+		NewAPI(http.MethodGet, "/users/{userID}/networth", api.Get, auth.Admin, auth.MemberIsOwner, auth.AccountsRead), // net worth by currency with value of every account
+	}
+
+	for _, api := range apis {
+		router.HandleFunc(api.Path, permissions.Wrap(api.Func, api.permissionTypes...)).Methods(api.Method)
+	}
+}
+
+// accountValue is balance of account, investment account adds market value of holdings
+// and loan with terms is worth minus its remaining principal
+func accountValue(ctx context.Context, db database.Database, account *model.Account, now time.Time, includePending bool) (model.Money, error) {
+	switch {
+	case account.Type != nil && account.Type.HasHoldings():
+		holdings, err := accountHoldings(ctx, db, account, now, includePending)
+		if err != nil {
+			return model.Money{}, err
+		}
+		return holdings.Value, nil
+
+	case account.Type != nil && account.Type.HasLoanTerms() && account.LoanPrincipal != nil && account.LoanTermMonths != nil && account.LoanStartDate != nil:
+		transactions, err := db.ListTransactionByAccountID(ctx, account.ID, time.Time{}, now.Add(time.Second))
+		if err != nil {
+			return model.Money{}, err
+		}
+		status, err := finance.ReconcileLoan(account, model.CountedTransactions(transactions, includePending), now, nil)
+		if err != nil {
+			return model.Money{}, err
+		}
+		return model.Money{Amount: -status.RemainingPrincipal.Amount, Exponent: status.RemainingPrincipal.Exponent}, nil
+	}
+
+	return db.GetAccountBalance(ctx, account.ID, includePending)
+}
+
+// GET - /users/{userID}/networth?pending={true|false}
+// Permission - MemberIsOwner, AccountsRead
+// Accounts in different currencies are summed separately, there are no exchange rates
+func (api *NetWorthAPI) Get(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "networth.go -> Get()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	includePending, ok := readIncludePending(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	accounts, err := api.DB.ListAccountsByUserID(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting accounts")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting net worth", nil)
+		return
+	}
+
+	now := time.Now()
+	values := make([]*model.AccountValue, 0, len(accounts))
+	for _, account := range accounts {
+		value, err := accountValue(ctx, api.DB, account, now, includePending)
+		if errors.Cause(err) == finance.ErrOversold {
+			logger.WithError(err).WithField("accountID", account.ID).Warn("account sells more than it holds")
+			utils.WriteError(w, http.StatusConflict, err.Error(), nil)
+			return
+		}
+		if err != nil {
+			logger.WithError(err).WithField("accountID", account.ID).Warn("error computing account value")
+			utils.WriteError(w, http.StatusInternalServerError, "error getting net worth", nil)
+			return
+		}
+
+		values = append(values, &model.AccountValue{
+			AccountID: account.ID,
+			Name:      *account.Name,
+			Type:      *account.Type,
+			Currency:  *account.Currency,
+			Value:     value,
+		})
+	}
+
+	netWorth, err := finance.NetWorth(values)
+	if err != nil {
+		logger.WithError(err).Warn("error summing net worth")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting net worth", nil)
+		return
+	}
+
+	logger.Info("net worth returned")
+
+	utils.WriteJSON(w, http.StatusOK, netWorth)
+}
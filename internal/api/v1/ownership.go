@@ -0,0 +1,152 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/finance"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// Resources referenced in request body (transaction.accountID, category.parentID...) must belong
+// to the same user as resource which is created or updated. Resources of other users and deleted
+// ones are reported as not found, so client can't find out what IDs exist.
+
+// checkAccountOwner returns account when it can be referenced
+func checkAccountOwner(ctx context.Context, db database.Database, verr *model.ValidationError, field string, userID model.UserID, accountID model.AccountID) (*model.Account, error) {
+	account, err := db.GetAccountByID(ctx, accountID)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			verr.Add(field, model.ErrCodeInvalid, "account not found")
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if account.UserID == nil || *account.UserID != userID || account.DeletedAt != nil {
+		verr.Add(field, model.ErrCodeInvalid, "account not found")
+		return nil, nil
+	}
+	return account, nil
+}
+
+func checkCategoryOwner(ctx context.Context, db database.Database, verr *model.ValidationError, field string, userID model.UserID, categoryID model.CategoryID) error {
+	category, err := db.GetCategoryByID(ctx, categoryID)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			verr.Add(field, model.ErrCodeInvalid, "category not found")
+			return nil
+		}
+		return err
+	}
+
+	if category.UserID == nil || *category.UserID != userID || category.DeletedAt != nil {
+		verr.Add(field, model.ErrCodeInvalid, "category not found")
+	}
+	return nil
+}
+
+// verifyTransaction checks fields of transaction and that its account and category belong to user of transaction,
+// amount is converted to currency of account. Investment transactions must not sell more than account holds.
+func verifyTransaction(ctx context.Context, db database.Database, transaction *model.Transaction) (*model.ValidationError, error) {
+	verr := &model.ValidationError{}
+	if err, ok := transaction.Verify().(*model.ValidationError); ok {
+		verr = err
+	}
+
+	if transaction.UserID == nil {
+		return verr, nil
+	}
+
+	if transaction.AccountID != nil {
+		account, err := checkAccountOwner(ctx, db, verr, "accountID", *transaction.UserID, *transaction.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		if account != nil && account.Currency != nil && transaction.Amount != nil {
+			checkMoney(verr, "amount", transaction.Amount, *account.Currency)
+		}
+		if account != nil && transaction.Type != nil && transaction.Type.IsInvestment() && (account.Type == nil || !account.Type.HasHoldings()) {
+			verr.Add("type", model.ErrCodeInvalid, "buy, sell and dividend are only for investment accounts")
+		}
+		if account != nil && account.Type != nil && account.Type.HasHoldings() && verr.Err() == nil {
+			if err := checkHoldings(ctx, db, verr, account, transaction); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if transaction.CategoryID != nil {
+		if err := checkCategoryOwner(ctx, db, verr, "categoryID", *transaction.UserID, *transaction.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	return verr, nil
+}
+
+// checkHoldings computes positions of investment account with transaction created or changed,
+// sell of more than is held on its date is invalid
+func checkHoldings(ctx context.Context, db database.Database, verr *model.ValidationError, account *model.Account, transaction *model.Transaction) error {
+	// transactions can be dated up to MaxTransactionDaysAhead, all of them are counted
+	end := time.Now().AddDate(0, 0, model.MaxTransactionDaysAhead+1)
+	transactions, err := db.ListTransactionByAccountID(ctx, account.ID, time.Time{}, end)
+	if err != nil {
+		return err
+	}
+
+	changed := make([]*model.Transaction, 0, len(transactions)+1)
+	for _, stored := range transactions {
+		if stored.ID != transaction.ID {
+			changed = append(changed, stored)
+		}
+	}
+	changed = append(changed, transaction)
+
+	// pending sell can't be posted when it sells more than is held, so it counts too
+	_, err = finance.Holdings(account, model.Money{}, model.CountedTransactions(changed, true), nil, end)
+	if errors.Cause(err) == finance.ErrOversold {
+		verr.Add("quantity", model.ErrCodeInvalid, err.Error())
+		return nil
+	}
+	return err
+}
+
+// verifyAccount checks fields of account, its amounts are converted to currency of account
+func verifyAccount(account *model.Account) *model.ValidationError {
+	verr := &model.ValidationError{}
+	if err, ok := account.Verify().(*model.ValidationError); ok {
+		verr = err
+	}
+
+	if account.Currency != nil && model.IsCurrencyCode(*account.Currency) {
+		if account.StartBalance != nil {
+			checkMoney(verr, "startBalance", account.StartBalance, *account.Currency)
+		}
+		if account.CreditLimit != nil {
+			checkMoney(verr, "creditLimit", account.CreditLimit, *account.Currency)
+		}
+		if account.MinPaymentAmount != nil {
+			checkMoney(verr, "minPaymentAmount", account.MinPaymentAmount, *account.Currency)
+		}
+		if account.LoanPrincipal != nil {
+			checkMoney(verr, "loanPrincipal", account.LoanPrincipal, *account.Currency)
+		}
+	}
+	return verr
+}
+
+// checkMoney converts amount to minor units of currency, amount user entered is never rounded,
+// amount with more decimal places than currency has is invalid
+func checkMoney(verr *model.ValidationError, field string, money *model.Money, currency string) {
+	converted, err := money.InCurrency(currency)
+	if err != nil {
+		verr.Add(field, model.ErrCodeInvalid, err.Error())
+		return
+	}
+	*money = converted
+}
@@ -0,0 +1,528 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/finance"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// ReconciliationAPI - provides REST for reconciliation of account with bank statement. User clears transactions
+// he finds on statement until cleared balance matches ending balance, then locks reconciliation.
+type ReconciliationAPI struct {
+	DB database.Database
+}
+
+// ClearTransactionsRequest - transactions of account to mark cleared or uncleared, status is cleared by default
+type ClearTransactionsRequest struct {
+	TransactionIDs []model.TransactionID `json:"transactionIDs"`
+	Status         model.ClearedStatus   `json:"status"`
+}
+
+func SetReconciliationAPI(db database.Database, router *mux.Router, permissions auth.Permissions) {
+	api := &ReconciliationAPI{
+		DB: db,
+	}
+
+	apis := []API{
+		NewAPI(http.MethodPost, "/users/{userID}/accounts/{accountID}/reconciliations", api.Create, auth.Admin, auth.MemberIsOwner, auth.AccountsWrite),                                   // start reconciliation with statement
+		NewAPI(http.MethodGet, "/users/{userID}/accounts/{accountID}/reconciliations", api.List, auth.Admin, auth.MemberIsOwner, auth.AccountViewer, auth.AccountsRead),                   // list reconciliations of account
+		NewAPI(http.MethodGet, "/users/{userID}/accounts/{accountID}/reconciliations/{reconciliationID}", api.Get, auth.Admin, auth.MemberIsOwner, auth.AccountViewer, auth.AccountsRead), // uncleared transactions and difference
+		NewAPI(http.MethodPost, "/users/{userID}/accounts/{accountID}/reconciliations/{reconciliationID}/clear", api.Clear, auth.Admin, auth.MemberIsOwner, auth.AccountsWrite),           // mark transactions cleared or uncleared
+		NewAPI(http.MethodPost, "/users/{userID}/accounts/{accountID}/reconciliations/{reconciliationID}/lock", api.Lock, auth.Admin, auth.MemberIsOwner, auth.AccountsWrite),             // lock reconciliation without difference
+	}
+
+	for _, api := range apis {
+		router.HandleFunc(api.Path, permissions.Wrap(api.Func, api.permissionTypes...)).Methods(api.Method)
+	}
+}
+
+// lockedUntil returns end of statement date of the latest locked reconciliation of account,
+// zero time when account was never reconciled
+func lockedUntil(ctx context.Context, db database.Database, accountID model.AccountID) (time.Time, error) {
+	reconciliations, err := db.ListReconciliationsByAccountID(ctx, accountID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// the latest statement is first
+	for _, reconciliation := range reconciliations {
+		if reconciliation.IsLocked() {
+			return reconciliation.PeriodEnd(), nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+// readForce reads ?force=true which allows change of locked reconciliation period,
+// response is written when it returns false
+func readForce(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	force, err := utils.BoolParam(r.URL.Query(), "force", false)
+	if err != nil {
+		verr := &model.ValidationError{}
+		verr.Add("force", model.ErrCodeInvalid, "force must be true or false")
+		utils.WriteValidationError(w, verr)
+		return false, false
+	}
+	return force, true
+}
+
+// checkUnlocked refuses change of reconciled transaction or transaction dated in locked reconciliation
+// unless request has ?force=true. Transactions are states before and after change, nil ones are skipped.
+// Response is written when it returns false.
+func checkUnlocked(w http.ResponseWriter, r *http.Request, db database.Database, logger *logrus.Entry, transactions ...*model.Transaction) bool {
+	force, ok := readForce(w, r)
+	if !ok || force {
+		return ok
+	}
+
+	for _, transaction := range transactions {
+		if transaction == nil || transaction.AccountID == nil || transaction.Date == nil {
+			continue
+		}
+
+		until, err := lockedUntil(r.Context(), db, *transaction.AccountID)
+		if err != nil {
+			logger.WithError(err).Warn("error getting reconciliations")
+			utils.WriteError(w, http.StatusInternalServerError, "error checking reconciliations", nil)
+			return false
+		}
+
+		reconciled := transaction.ClearedStatus != nil && *transaction.ClearedStatus == model.Reconciled
+		if reconciled || transaction.Date.Before(until) {
+			logger.WithField("transactionID", transaction.ID).Warn("transaction is in locked reconciliation")
+			utils.WriteError(w, http.StatusConflict, "transaction is in locked reconciliation, use force=true to change it", nil)
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkAccountUnlocked refuses change of accounts which have locked reconciliation or cleared transactions
+// (delete, moving their transactions, start balance or currency), it would change balance which was reconciled.
+// Request with ?force=true is allowed. Response is written when it returns false.
+func checkAccountUnlocked(w http.ResponseWriter, r *http.Request, db database.Database, logger *logrus.Entry, accountIDs ...model.AccountID) bool {
+	force, ok := readForce(w, r)
+	if !ok || force {
+		return ok
+	}
+
+	ctx := r.Context()
+	// transactions can be dated up to MaxTransactionDaysAhead, all of them are checked
+	end := time.Now().AddDate(0, 0, model.MaxTransactionDaysAhead+1)
+	for _, accountID := range accountIDs {
+		until, err := lockedUntil(ctx, db, accountID)
+		if err != nil {
+			logger.WithError(err).Warn("error getting reconciliations")
+			utils.WriteError(w, http.StatusInternalServerError, "error checking reconciliations", nil)
+			return false
+		}
+
+		cleared := !until.IsZero()
+		if !cleared {
+			transactions, err := db.ListTransactionByAccountID(ctx, accountID, time.Time{}, end)
+			if err != nil {
+				logger.WithError(err).Warn("error getting transactions")
+				utils.WriteError(w, http.StatusInternalServerError, "error checking reconciliations", nil)
+				return false
+			}
+			for _, transaction := range transactions {
+				if transaction.ClearedStatus != nil && transaction.ClearedStatus.IsCleared() {
+					cleared = true
+					break
+				}
+			}
+		}
+
+		if cleared {
+			logger.WithField("reconciledAccountID", accountID).Warn("account is reconciled")
+			utils.WriteError(w, http.StatusConflict, "account has locked reconciliation or cleared transactions, use force=true to change it", nil)
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkClearedStatus - user clears transactions, only locked reconciliation makes them reconciled
+func checkClearedStatus(verr *model.ValidationError, status *model.ClearedStatus) {
+	if status != nil && *status == model.Reconciled {
+		verr.Add("clearedStatus", model.ErrCodeInvalid, "transactions are reconciled by locking reconciliation")
+	}
+}
+
+// readReconciliation returns account and its reconciliation from path, response is written when it returns false
+func (api *ReconciliationAPI) readReconciliation(w http.ResponseWriter, r *http.Request, logger *logrus.Entry) (*model.Account, *model.Reconciliation, bool) {
+	vars := mux.Vars(r)
+	accountID := model.AccountID(vars["accountID"])
+	reconciliationID := model.ReconciliationID(vars["reconciliationID"])
+
+	ctx := r.Context()
+
+	account, err := api.DB.GetAccountByID(ctx, accountID)
+	if err != nil || account.DeletedAt != nil {
+		logger.WithError(err).Warn("error getting account")
+		utils.WriteError(w, http.StatusNotFound, "account not found", nil)
+		return nil, nil, false
+	}
+
+	reconciliation, err := api.DB.GetReconciliationByID(ctx, reconciliationID)
+	if err != nil || *reconciliation.AccountID != accountID {
+		logger.WithError(err).Warn("error getting reconciliation")
+		utils.WriteError(w, http.StatusNotFound, "reconciliation not found", nil)
+		return nil, nil, false
+	}
+
+	return account, reconciliation, true
+}
+
+// report computes cleared balance of account at statement date of reconciliation
+func (api *ReconciliationAPI) report(ctx context.Context, account *model.Account, reconciliation *model.Reconciliation) (*model.ReconciliationReport, error) {
+	transactions, err := api.DB.ListTransactionByAccountID(ctx, account.ID, time.Time{}, reconciliation.PeriodEnd())
+	if err != nil {
+		return nil, err
+	}
+
+	// pending and void transactions aren't on bank statement
+	return finance.Reconcile(account, reconciliation, model.CountedTransactions(transactions, false))
+}
+
+// POST - /users/{userID}/accounts/{accountID}/reconciliations
+// Permission - MemberIsOwner, AccountsWrite
+// Statement has to end after the last locked one, account has at most one open reconciliation
+func (api *ReconciliationAPI) Create(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "reconciliation.go -> Create()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"accountID": accountID,
+	})
+
+	// Decode paramters
+	var reconciliation model.Reconciliation
+	if err := json.NewDecoder(r.Body).Decode(&reconciliation); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	ctx := r.Context()
+
+	account, err := api.DB.GetAccountByID(ctx, accountID)
+	if err != nil || account.DeletedAt != nil {
+		logger.WithError(err).Warn("error getting account")
+		utils.WriteError(w, http.StatusNotFound, "account not found", nil)
+		return
+	}
+
+	reconciliation.UserID = account.UserID
+	reconciliation.AccountID = &accountID
+	reconciliation.LockedAt = nil
+
+	verr := &model.ValidationError{}
+	if err, ok := reconciliation.Verify().(*model.ValidationError); ok {
+		verr = err
+	}
+	if reconciliation.EndingBalance != nil {
+		checkMoney(verr, "endingBalance", reconciliation.EndingBalance, *account.Currency)
+	}
+
+	reconciliations, err := api.DB.ListReconciliationsByAccountID(ctx, accountID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting reconciliations")
+		utils.WriteError(w, http.StatusInternalServerError, "error creating reconciliation", nil)
+		return
+	}
+	for _, stored := range reconciliations {
+		if !stored.IsLocked() {
+			logger.WithField("reconciliationID", stored.ID).Warn("account has open reconciliation")
+			utils.WriteError(w, http.StatusConflict, "account has open reconciliation, lock it first", nil)
+			return
+		}
+		if reconciliation.StatementDate != nil && !reconciliation.StatementDate.After(*stored.StatementDate) {
+			verr.Add("statementDate", model.ErrCodeInvalid, "statementDate must be after the last locked reconciliation")
+			break
+		}
+	}
+
+	if verr.Err() != nil {
+		logger.WithError(verr).Warn("invalid reconciliation")
+		utils.WriteValidationError(w, verr)
+		return
+	}
+
+	if err := api.DB.CreateReconciliation(ctx, &reconciliation); err != nil {
+		logger.WithError(err).Warn("error creating reconciliation")
+		utils.WriteError(w, http.StatusInternalServerError, "error creating reconciliation", nil)
+		return
+	}
+
+	report, err := api.report(ctx, account, &reconciliation)
+	if err != nil {
+		logger.WithError(err).Warn("error computing reconciliation")
+		utils.WriteError(w, http.StatusInternalServerError, "error creating reconciliation", nil)
+		return
+	}
+
+	logger.WithField("reconciliationID", reconciliation.ID).Info("reconciliation created")
+
+	utils.WriteJSON(w, http.StatusCreated, report)
+}
+
+// GET - /users/{userID}/accounts/{accountID}/reconciliations
+// Permission - MemberIsOwner, AccountViewer, AccountsRead
+// Reconciliations are sorted from the latest statement
+func (api *ReconciliationAPI) List(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "reconciliation.go -> List()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"accountID": accountID,
+	})
+
+	ctx := r.Context()
+
+	account, err := api.DB.GetAccountByID(ctx, accountID)
+	if err != nil || account.DeletedAt != nil {
+		logger.WithError(err).Warn("error getting account")
+		utils.WriteError(w, http.StatusNotFound, "account not found", nil)
+		return
+	}
+
+	reconciliations, err := api.DB.ListReconciliationsByAccountID(ctx, accountID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting reconciliations")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting reconciliations", nil)
+		return
+	}
+	if reconciliations == nil {
+		reconciliations = make([]*model.Reconciliation, 0)
+	}
+
+	logger.Info("reconciliations returned")
+
+	utils.WriteJSON(w, http.StatusOK, reconciliations)
+}
+
+// GET - /users/{userID}/accounts/{accountID}/reconciliations/{reconciliationID}
+// Permission - MemberIsOwner, AccountViewer, AccountsRead
+func (api *ReconciliationAPI) Get(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "reconciliation.go -> Get()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":           userID,
+		"principal":        principal,
+		"accountID":        vars["accountID"],
+		"reconciliationID": vars["reconciliationID"],
+	})
+
+	account, reconciliation, ok := api.readReconciliation(w, r, logger)
+	if !ok {
+		return
+	}
+
+	report, err := api.report(r.Context(), account, reconciliation)
+	if err != nil {
+		logger.WithError(err).Warn("error computing reconciliation")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting reconciliation", nil)
+		return
+	}
+
+	logger.Info("reconciliation returned")
+
+	utils.WriteJSON(w, http.StatusOK, report)
+}
+
+// POST - /users/{userID}/accounts/{accountID}/reconciliations/{reconciliationID}/clear
+// Permission - MemberIsOwner, AccountsWrite
+// Only transactions on statement of open reconciliation can be cleared, reconciled ones can't be changed.
+// Response is reconciliation with new difference.
+func (api *ReconciliationAPI) Clear(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "reconciliation.go -> Clear()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":           userID,
+		"principal":        principal,
+		"accountID":        vars["accountID"],
+		"reconciliationID": vars["reconciliationID"],
+	})
+
+	// Decode paramters
+	var request ClearTransactionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	account, reconciliation, ok := api.readReconciliation(w, r, logger)
+	if !ok {
+		return
+	}
+	if reconciliation.IsLocked() {
+		logger.Warn("reconciliation is locked")
+		utils.WriteError(w, http.StatusConflict, "reconciliation is locked", nil)
+		return
+	}
+
+	ctx := r.Context()
+
+	if request.Status == "" {
+		request.Status = model.Cleared
+	}
+
+	verr := &model.ValidationError{}
+	if request.Status != model.Cleared && request.Status != model.Uncleared {
+		verr.Add("status", model.ErrCodeInvalid, "status must be cleared or uncleared")
+	}
+	if len(request.TransactionIDs) == 0 {
+		verr.Add("transactionIDs", model.ErrCodeRequired, "transactionIDs are required")
+	}
+	for i, transactionID := range request.TransactionIDs {
+		field := fmt.Sprintf("transactionIDs[%d]", i)
+
+		transaction, err := api.DB.GetTransactionByID(ctx, transactionID)
+		if errors.Cause(err) == sql.ErrNoRows || (err == nil && !isInAccount(transaction, account.ID)) {
+			verr.Add(field, model.ErrCodeInvalid, "transaction not found")
+			continue
+		}
+		if err != nil {
+			logger.WithError(err).Warn("error getting transaction")
+			utils.WriteError(w, http.StatusInternalServerError, "error clearing transactions", nil)
+			return
+		}
+
+		if !transaction.IsCounted(false) {
+			verr.Add(field, model.ErrCodeInvalid, "only posted transactions can be cleared")
+		} else if !transaction.Date.Before(reconciliation.PeriodEnd()) {
+			verr.Add(field, model.ErrCodeInvalid, "transaction is after statement date")
+		} else if transaction.ClearedStatus != nil && *transaction.ClearedStatus == model.Reconciled {
+			verr.Add(field, model.ErrCodeInvalid, "transaction is reconciled")
+		}
+	}
+
+	if verr.Err() != nil {
+		logger.WithError(verr).Warn("invalid transactions")
+		utils.WriteValidationError(w, verr)
+		return
+	}
+
+	if err := api.DB.SetTransactionsCleared(ctx, account.ID, request.TransactionIDs, request.Status); err != nil {
+		logger.WithError(err).Warn("error clearing transactions")
+		utils.WriteError(w, http.StatusInternalServerError, "error clearing transactions", nil)
+		return
+	}
+
+	report, err := api.report(ctx, account, reconciliation)
+	if err != nil {
+		logger.WithError(err).Warn("error computing reconciliation")
+		utils.WriteError(w, http.StatusInternalServerError, "error clearing transactions", nil)
+		return
+	}
+
+	logger.WithField("transactions", len(request.TransactionIDs)).Info("transactions cleared")
+
+	utils.WriteJSON(w, http.StatusOK, report)
+}
+
+// POST - /users/{userID}/accounts/{accountID}/reconciliations/{reconciliationID}/lock
+// Permission - MemberIsOwner, AccountsWrite
+// Reconciliation is locked only when cleared balance matches ending balance, cleared transactions become
+// reconciled and transactions until end of statement date can't be changed without force.
+func (api *ReconciliationAPI) Lock(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "reconciliation.go -> Lock()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":           userID,
+		"principal":        principal,
+		"accountID":        vars["accountID"],
+		"reconciliationID": vars["reconciliationID"],
+	})
+
+	account, reconciliation, ok := api.readReconciliation(w, r, logger)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	report, err := api.report(ctx, account, reconciliation)
+	if err != nil {
+		logger.WithError(err).Warn("error computing reconciliation")
+		utils.WriteError(w, http.StatusInternalServerError, "error locking reconciliation", nil)
+		return
+	}
+	if !reconciliation.IsLocked() && report.Difference.Sign() != 0 {
+		logger.WithField("difference", report.Difference).Warn("reconciliation has difference")
+		utils.WriteError(w, http.StatusConflict, "cleared balance doesn't match ending balance", report)
+		return
+	}
+
+	locked, err := api.DB.LockReconciliation(ctx, reconciliation.ID)
+	if err == database.ErrReconciliationLocked {
+		logger.Warn("reconciliation is locked")
+		utils.WriteError(w, http.StatusConflict, "reconciliation is locked", nil)
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Warn("error locking reconciliation")
+		utils.WriteError(w, http.StatusInternalServerError, "error locking reconciliation", nil)
+		return
+	}
+
+	if report, err = api.report(ctx, account, locked); err != nil {
+		logger.WithError(err).Warn("error computing reconciliation")
+		utils.WriteError(w, http.StatusInternalServerError, "error locking reconciliation", nil)
+		return
+	}
+
+	logger.Info("reconciliation locked")
+
+	utils.WriteJSON(w, http.StatusOK, report)
+}
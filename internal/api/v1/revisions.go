@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// Accounts, categories, merchants and transactions keep every revision:
+// GET .../{id}/history lists them, POST .../{id}/restore with {"revision": n} brings one back,
+// POST .../{id}/restore without revision only undeletes entity, it is how items are restored from trash.
+
+// writeHistory writes revisions of entity, newest first
+func writeHistory(w http.ResponseWriter, r *http.Request, db database.Database, logger *logrus.Entry, entity model.AuditEntity, entityID string) {
+	revisions, err := db.ListRevisions(r.Context(), entity, entityID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting revisions")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting history", nil)
+		return
+	}
+	if revisions == nil {
+		revisions = make([]*model.Revision, 0)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, &revisions)
+}
+
+// readRestoreRevision returns request and revision user wants to restore, nil revision only undeletes entity.
+// Response is written when it returns false.
+func readRestoreRevision(w http.ResponseWriter, r *http.Request, db database.Database, logger *logrus.Entry, entity model.AuditEntity, entityID string) (*model.RestoreRequest, *model.Revision, bool) {
+	// body is optional
+	var request model.RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, nil, false
+	}
+
+	if request.Revision == 0 {
+		return &request, nil, true
+	}
+
+	revision, err := db.GetRevision(r.Context(), entity, entityID, request.Revision)
+	if err != nil {
+		if err == database.ErrRevisionNotFound {
+			verr := &model.ValidationError{}
+			verr.Add("revision", model.ErrCodeInvalid, "revision not found")
+			utils.WriteValidationError(w, verr)
+			return nil, nil, false
+		}
+		logger.WithError(err).Warn("error getting revision")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting revision", nil)
+		return nil, nil, false
+	}
+
+	return &request, revision, true
+}
+
+// decodeRevision reads state of entity from revision
+func decodeRevision(w http.ResponseWriter, logger *logrus.Entry, revision *model.Revision, state interface{}) bool {
+	if err := json.Unmarshal(revision.Data, state); err != nil {
+		logger.WithError(err).Warn("could not decode revision")
+		utils.WriteError(w, http.StatusInternalServerError, "error restoring revision", nil)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,243 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// RoleAPI - provides REST for roles and their permissions
+type RoleAPI struct {
+	DB          database.Database
+	Permissions auth.Permissions // to forget cached permissions when role is changed
+}
+
+func SetRoleAPI(db database.Database, router *mux.Router, permissions auth.Permissions) {
+	api := &RoleAPI{
+		DB:          db,
+		Permissions: permissions,
+	}
+
+	apis := []API{
+		NewAPI(http.MethodPost, "/roles", api.Create, auth.Admin),                               // create role
+		NewAPI(http.MethodGet, "/roles", api.List, auth.Admin, auth.RolesRead),                  // list roles
+		NewAPI(http.MethodGet, "/roles/{role}", api.Get, auth.Admin, auth.RolesRead),            // get role with permissions
+		NewAPI(http.MethodPut, "/roles/{role}", api.Update, auth.Admin),                         // replace role permissions
+		NewAPI(http.MethodDelete, "/roles/{role}", api.Delete, auth.Admin),                      // delete role
+		NewAPI(http.MethodGet, "/permissions", api.ListPermissions, auth.Admin, auth.RolesRead), // list permissions which can be assigned
+	}
+
+	for _, api := range apis {
+		router.HandleFunc(api.Path, permissions.Wrap(api.Func, api.permissionTypes...)).Methods(api.Method)
+	}
+}
+
+// POST - /roles
+// Permission - Admin
+func (api *RoleAPI) Create(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "roles.go -> Create()")
+
+	principal := auth.GetPrincipal(r)
+	logger = logger.WithField("principal", principal)
+
+	// Decode paramters
+	var role model.RoleDefinition
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := role.Verify(); err != nil {
+		logger.WithError(err).Warn("invalid role")
+		utils.WriteValidationError(w, err.(*model.ValidationError))
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := api.DB.CreateRole(ctx, &role); err != nil {
+		if err == database.ErrRoleExists {
+			logger.WithError(err).Warn("role already exists")
+			utils.WriteError(w, http.StatusConflict, "role already exists", nil)
+			return
+		}
+		logger.WithError(err).Warn("error creating role")
+		utils.WriteError(w, http.StatusInternalServerError, "error creating role", nil)
+		return
+	}
+
+	logger.WithField("role", role.Name).Info("role created")
+
+	utils.WriteJSON(w, http.StatusCreated, &role)
+}
+
+// GET - /roles
+// Permission - Admin, RolesRead
+func (api *RoleAPI) List(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "roles.go -> List()")
+
+	principal := auth.GetPrincipal(r)
+	logger = logger.WithField("principal", principal)
+
+	ctx := r.Context()
+
+	roles, err := api.DB.ListRoles(ctx)
+	if err != nil {
+		logger.WithError(err).Warn("error getting roles")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting roles", nil)
+		return
+	}
+	if roles == nil {
+		roles = make([]*model.RoleDefinition, 0)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, &roles)
+}
+
+// GET - /roles/{role}
+// Permission - Admin, RolesRead
+func (api *RoleAPI) Get(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "roles.go -> Get()")
+
+	name := model.Role(mux.Vars(r)["role"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"role":      name,
+		"principal": principal,
+	})
+
+	ctx := r.Context()
+
+	role, err := api.DB.GetRole(ctx, name)
+	if err != nil {
+		if err == database.ErrRoleNotFound {
+			utils.WriteError(w, http.StatusNotFound, "role not found", nil)
+			return
+		}
+		logger.WithError(err).Warn("error getting role")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting role", nil)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, role)
+}
+
+// PUT - /roles/{role}
+// Permission - Admin
+// Description and permissions are replaced, role can't be renamed
+func (api *RoleAPI) Update(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "roles.go -> Update()")
+
+	name := model.Role(mux.Vars(r)["role"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"role":      name,
+		"principal": principal,
+	})
+
+	if name == model.RoleAdmin {
+		utils.WriteError(w, http.StatusBadRequest, "admin role can't be changed", nil)
+		return
+	}
+
+	// Decode paramters
+	var role model.RoleDefinition
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	role.Name = name
+
+	if err := role.Verify(); err != nil {
+		logger.WithError(err).Warn("invalid role")
+		utils.WriteValidationError(w, err.(*model.ValidationError))
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := api.DB.UpdateRole(ctx, &role); err != nil {
+		if err == database.ErrRoleNotFound {
+			utils.WriteError(w, http.StatusNotFound, "role not found", nil)
+			return
+		}
+		logger.WithError(err).Warn("error updating role")
+		utils.WriteError(w, http.StatusInternalServerError, "error updating role", nil)
+		return
+	}
+
+	api.Permissions.Purge()
+
+	logger.Info("role updated")
+
+	utils.WriteJSON(w, http.StatusOK, &ActUpdated{
+		Updated: true,
+	})
+}
+
+// DELETE - /roles/{role}
+// Permission - Admin
+// Role is revoked from all users
+func (api *RoleAPI) Delete(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "roles.go -> Delete()")
+
+	name := model.Role(mux.Vars(r)["role"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"role":      name,
+		"principal": principal,
+	})
+
+	if name == model.RoleAdmin {
+		utils.WriteError(w, http.StatusBadRequest, "admin role can't be deleted", nil)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := api.DB.DeleteRole(ctx, name); err != nil {
+		if err == database.ErrRoleNotFound {
+			utils.WriteError(w, http.StatusNotFound, "role not found", nil)
+			return
+		}
+		logger.WithError(err).Warn("error deleting role")
+		utils.WriteError(w, http.StatusInternalServerError, "error deleting role", nil)
+		return
+	}
+
+	api.Permissions.Purge()
+
+	logger.Info("role deleted")
+
+	utils.WriteJSON(w, http.StatusOK, &ActDeleted{
+		Deleted: true,
+	})
+}
+
+// GET - /permissions
+// Permission - Admin, RolesRead
+func (api *RoleAPI) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, model.Permissions)
+}
@@ -0,0 +1,161 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/finance"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// defaultUpcomingDays - upcoming statements are due in next 7 days by default
+const defaultUpcomingDays = 7
+
+// StatementAPI - provides REST for statements of credit accounts, they are computed from transactions
+type StatementAPI struct {
+	DB database.Database
+}
+
+func SetStatementAPI(db database.Database, router *mux.Router, permissions auth.Permissions) {
+	api := &StatementAPI{
+		DB: db,
+	}
+
+	apis := []API{
+		NewAPI(http.MethodGet, "/users/{userID}/accounts/{accountID}/statements", api.List, auth.Admin, auth.MemberIsOwner, auth.AccountViewer, auth.AccountsRead), // list past and current statements of credit account
+		NewAPI(http.MethodGet, "/users/{userID}/statements/upcoming", api.Upcoming, auth.Admin, auth.MemberIsOwner, auth.AccountsRead),                             // list unpaid statements due soon, for reminders
+	}
+
+	for _, api := range apis {
+		router.HandleFunc(api.Path, permissions.Wrap(api.Func, api.permissionTypes...)).Methods(api.Method)
+	}
+}
+
+// accountStatements computes statements of account until now
+func (api *StatementAPI) accountStatements(ctx context.Context, account *model.Account, now time.Time, includePending bool) ([]*model.Statement, error) {
+	transactions, err := api.DB.ListTransactionByAccountID(ctx, account.ID, time.Time{}, now.Add(time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	return finance.Statements(account, model.CountedTransactions(transactions, includePending), now)
+}
+
+// GET - /users/{userID}/accounts/{accountID}/statements?pending={true|false}
+// Permission - MemberIsOwner, AccountViewer, AccountsRead
+// Statements are sorted from the newest, first one is current cycle
+func (api *StatementAPI) List(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "statement.go -> List()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+		"accountID": accountID,
+	})
+
+	includePending, ok := readIncludePending(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	account, err := api.DB.GetAccountByID(ctx, accountID)
+	if err != nil || account.DeletedAt != nil {
+		logger.WithError(err).Warn("error getting account")
+		utils.WriteError(w, http.StatusNotFound, "account not found", nil)
+		return
+	}
+
+	statements, err := api.accountStatements(ctx, account, time.Now(), includePending)
+	if err == finance.ErrNoStatementCycle {
+		logger.Warn("account has no statement cycle")
+		utils.WriteError(w, http.StatusConflict, "only credit accounts with statementDay have statements", nil)
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Warn("error computing statements")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting statements", nil)
+		return
+	}
+
+	for i, j := 0, len(statements)-1; i < j; i, j = i+1, j-1 {
+		statements[i], statements[j] = statements[j], statements[i]
+	}
+
+	logger.Info("statements returned")
+
+	utils.WriteJSON(w, http.StatusOK, statements)
+}
+
+// GET - /users/{userID}/statements/upcoming?days={days}&pending={true|false}
+// Permission - MemberIsOwner, AccountsRead
+// Unpaid statements of all credit accounts of user which are due in days (7 by default) or overdue
+func (api *StatementAPI) Upcoming(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "statement.go -> Upcoming()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	days, err := utils.IntParam(r.URL.Query(), "days", defaultUpcomingDays)
+	if err != nil || days < 0 {
+		verr := &model.ValidationError{}
+		verr.Add("days", model.ErrCodeInvalid, "days must be a positive number")
+		utils.WriteValidationError(w, verr)
+		return
+	}
+
+	includePending, ok := readIncludePending(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	accounts, err := api.DB.ListAccountsByUserID(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting accounts")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting statements", nil)
+		return
+	}
+
+	now := time.Now()
+	var statements []*model.Statement
+	for _, account := range accounts {
+		if account.Type == nil || !account.Type.HasCreditLimit() || account.StatementDay == nil {
+			continue
+		}
+
+		accountStatements, err := api.accountStatements(ctx, account, now, includePending)
+		if err != nil {
+			logger.WithError(err).WithField("accountID", account.ID).Warn("error computing statements")
+			utils.WriteError(w, http.StatusInternalServerError, "error getting statements", nil)
+			return
+		}
+		statements = append(statements, accountStatements...)
+	}
+
+	logger.Info("upcoming statements returned")
+
+	utils.WriteJSON(w, http.StatusOK, finance.Upcoming(statements, now, time.Duration(days)*24*time.Hour))
+}
@@ -2,6 +2,7 @@ package v1
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -24,13 +25,18 @@ func SetTransactionAPI(db database.Database, router *mux.Router, permissions aut
 	}
 
 	apis := []API{
-		NewAPI(http.MethodPost, "/users/{userID}/transactions", api.Create, auth.Admin, auth.MemberIsTarget),                   // create transaction for user (Open for admin for now)
-		NewAPI(http.MethodGet, "/users/{userID}/transactions", api.ListByUser, auth.Admin, auth.MemberIsTarget),                // get transaction for user (Open for admin for now)
-		NewAPI(http.MethodGet, "/accounts/{accountID}/transactions", api.ListByAccount, auth.Admin, auth.MemberIsTarget),       // get transaction for account (Open for admin for now)
-		NewAPI(http.MethodGet, "/categories/{categoryID}/transactions", api.ListByCategory, auth.Admin, auth.MemberIsTarget),   // get transaction for category (Open for admin for now)
-		NewAPI(http.MethodPatch, "/users/{userID}/transactions/{transactionID}", api.Update, auth.Admin, auth.MemberIsTarget),  // update transaction for user (Open for admin for now)
-		NewAPI(http.MethodGet, "/users/{userID}/transactions/{transactionID}", api.Get, auth.Admin, auth.MemberIsTarget),       // get transaction by transaction id for user (Open for admin for now)
-		NewAPI(http.MethodDelete, "/users/{userID}/transactions/{transactionID}", api.Delete, auth.Admin, auth.MemberIsTarget), // delete transaction by transaction id for user (Open for admin for now)
+		NewAPI(http.MethodPost, "/users/{userID}/transactions", api.Create, auth.Admin, auth.MemberIsOwner, auth.TransactionsWrite),                                // create transaction for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/users/{userID}/transactions", api.ListByUser, auth.Admin, auth.MemberIsOwner, auth.TransactionsRead),                              // get transaction for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/accounts/{accountID}/transactions", api.ListByAccount, auth.Admin, auth.MemberIsOwner, auth.AccountViewer, auth.TransactionsRead), // get transaction for account (Open for admin for now)
+		NewAPI(http.MethodPost, "/accounts/{accountID}/transactions", api.CreateForAccount, auth.Admin, auth.AccountEditor),                                        // create transaction in own or shared account
+		NewAPI(http.MethodPatch, "/accounts/{accountID}/transactions/{transactionID}", api.Update, auth.Admin, auth.AccountEditor),                                 // update transaction in own or shared account
+		NewAPI(http.MethodDelete, "/accounts/{accountID}/transactions/{transactionID}", api.Delete, auth.Admin, auth.AccountEditor),                                // delete transaction in own or shared account
+		NewAPI(http.MethodGet, "/categories/{categoryID}/transactions", api.ListByCategory, auth.Admin, auth.MemberIsOwner, auth.TransactionsRead),                 // get transaction for category (Open for admin for now)
+		NewAPI(http.MethodPatch, "/users/{userID}/transactions/{transactionID}", api.Update, auth.Admin, auth.MemberIsOwner, auth.TransactionsWrite),               // update transaction for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/users/{userID}/transactions/{transactionID}", api.Get, auth.Admin, auth.MemberIsOwner, auth.TransactionsRead),                     // get transaction by transaction id for user (Open for admin for now)
+		NewAPI(http.MethodDelete, "/users/{userID}/transactions/{transactionID}", api.Delete, auth.Admin, auth.MemberIsOwner, auth.TransactionsWrite),              // delete transaction by transaction id for user (Open for admin for now)
+		NewAPI(http.MethodGet, "/users/{userID}/transactions/{transactionID}/history", api.History, auth.Admin, auth.MemberIsOwner, auth.TransactionsRead),         // list revisions of transaction
+		NewAPI(http.MethodPost, "/users/{userID}/transactions/{transactionID}/restore", api.Restore, auth.Admin, auth.MemberIsOwner, auth.TransactionsWrite),       // restore revision or undelete transaction
 	}
 
 	for _, api := range apis {
@@ -39,7 +45,7 @@ func SetTransactionAPI(db database.Database, router *mux.Router, permissions aut
 }
 
 // POST - /users/{userID}/transactions
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, TransactionsWrite
 func (api *TransactionAPI) Create(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "transaction.go -> Create()")
@@ -57,24 +63,97 @@ func (api *TransactionAPI) Create(w http.ResponseWriter, r *http.Request) {
 	var transaction model.Transaction
 	if err := json.NewDecoder(r.Body).Decode(&transaction); err != nil {
 		logger.WithError(err).Warn("could not decode parameters")
-		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
-			"error": err.Error(),
-		})
+		utils.WriteDecodeError(w, err)
 		return
 	}
 
 	transaction.UserID = &userID
 
-	if err := transaction.Verify(); err != nil {
-		logger.WithError(err).Warn("not all fields found") // I will hide this error in future, it isn't secure to show what fields are missing...
-		utils.WriteError(w, http.StatusBadRequest, "not all fields found", map[string]string{
-			"error": err.Error(),
-		})
+	ctx := r.Context()
+
+	verr, err := verifyTransaction(ctx, api.DB, &transaction)
+	if err != nil {
+		logger.WithError(err).Warn("error verifying transaction")
+		utils.WriteError(w, http.StatusInternalServerError, "error creating transaction", nil)
+		return
+	}
+	checkClearedStatus(verr, transaction.ClearedStatus)
+	checkStatus(verr, nil, &transaction, transaction.ClearedStatus)
+	if verr.Err() != nil {
+		logger.WithError(verr).Warn("invalid transaction")
+		utils.WriteValidationError(w, verr)
+		return
+	}
+
+	if !checkUnlocked(w, r, api.DB, logger, &transaction) {
+		return
+	}
+
+	if err := api.DB.CreateTransaction(ctx, &transaction); err != nil {
+		logger.WithError(err).Warn("error creating transaction")
+		utils.WriteError(w, http.StatusInternalServerError, "error creating transaction", nil)
+		return
+	}
+
+	logger.WithField("TransactionID", transaction.ID).Info("transaction created")
+
+	utils.WriteJSON(w, http.StatusCreated, &transaction)
+}
+
+// POST - /accounts/{accountID}/transactions
+// Permission - AccountEditor
+// Transaction belongs to owner of account, category has to be owner's category too
+func (api *TransactionAPI) CreateForAccount(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "transaction.go -> CreateForAccount()")
+
+	vars := mux.Vars(r)
+	accountID := model.AccountID(vars["accountID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"accountID": accountID,
+		"principal": principal,
+	})
+
+	// Decode paramters
+	var transaction model.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&transaction); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteDecodeError(w, err)
 		return
 	}
 
 	ctx := r.Context()
 
+	account, err := api.DB.GetAccountByID(ctx, accountID)
+	if err != nil || account.DeletedAt != nil {
+		logger.WithError(err).Warn("error getting account")
+		utils.WriteError(w, http.StatusNotFound, "account not found", nil)
+		return
+	}
+
+	transaction.UserID = account.UserID
+	transaction.AccountID = &accountID
+
+	verr, err := verifyTransaction(ctx, api.DB, &transaction)
+	if err != nil {
+		logger.WithError(err).Warn("error verifying transaction")
+		utils.WriteError(w, http.StatusInternalServerError, "error creating transaction", nil)
+		return
+	}
+	checkClearedStatus(verr, transaction.ClearedStatus)
+	checkStatus(verr, nil, &transaction, transaction.ClearedStatus)
+	if verr.Err() != nil {
+		logger.WithError(verr).Warn("invalid transaction")
+		utils.WriteValidationError(w, verr)
+		return
+	}
+
+	if !checkUnlocked(w, r, api.DB, logger, &transaction) {
+		return
+	}
+
 	if err := api.DB.CreateTransaction(ctx, &transaction); err != nil {
 		logger.WithError(err).Warn("error creating transaction")
 		utils.WriteError(w, http.StatusInternalServerError, "error creating transaction", nil)
@@ -87,7 +166,10 @@ func (api *TransactionAPI) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 // PATCH - /users/{userID}/transactions/{transactionID}
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, TransactionsWrite
+// PATCH - /accounts/{accountID}/transactions/{transactionID}
+// Permission - AccountEditor
+// Transaction in shared account can't be moved to other account
 func (api *TransactionAPI) Update(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "transaction.go -> Update()")
@@ -107,9 +189,7 @@ func (api *TransactionAPI) Update(w http.ResponseWriter, r *http.Request) {
 	var transactionRequest model.Transaction
 	if err := json.NewDecoder(r.Body).Decode(&transactionRequest); err != nil {
 		logger.WithError(err).Warn("could not decode parameters")
-		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
-			"error": err.Error(),
-		})
+		utils.WriteDecodeError(w, err)
 		return
 	}
 
@@ -124,11 +204,25 @@ func (api *TransactionAPI) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if transactionRequest.AccountID != nil || *transactionRequest.AccountID != model.NilAccountID {
+	accountID, inAccount := vars["accountID"]
+	if inAccount && !isInAccount(transaction, model.AccountID(accountID)) {
+		utils.WriteError(w, http.StatusNotFound, "transaction not found", nil)
+		return
+	}
+
+	before := *transaction
+
+	if transactionRequest.AccountID != nil && *transactionRequest.AccountID != model.NilAccountID {
+		if inAccount && *transactionRequest.AccountID != model.AccountID(accountID) {
+			verr := &model.ValidationError{}
+			verr.Add("accountID", model.ErrCodeInvalid, "transaction can't be moved to other account")
+			utils.WriteValidationError(w, verr)
+			return
+		}
 		transaction.AccountID = transactionRequest.AccountID
 	}
 
-	if transactionRequest.CategoryID != nil || *transactionRequest.CategoryID != model.NilCategoryID {
+	if transactionRequest.CategoryID != nil && *transactionRequest.CategoryID != model.NilCategoryID {
 		transaction.CategoryID = transactionRequest.CategoryID
 	}
 
@@ -136,8 +230,9 @@ func (api *TransactionAPI) Update(w http.ResponseWriter, r *http.Request) {
 		transaction.Date = transactionRequest.Date
 	}
 
-	if transactionRequest.Type != nil || *transactionRequest.Type != "" {
+	if transactionRequest.Type != nil && *transactionRequest.Type != "" {
 		transaction.Type = transactionRequest.Type
+		transaction.ClearTypeDetails()
 	}
 
 	if transactionRequest.Amount != nil {
@@ -148,6 +243,41 @@ func (api *TransactionAPI) Update(w http.ResponseWriter, r *http.Request) {
 		transaction.Notes = transactionRequest.Notes
 	}
 
+	if transactionRequest.Symbol != nil {
+		transaction.Symbol = transactionRequest.Symbol
+	}
+
+	if transactionRequest.Quantity != nil {
+		transaction.Quantity = transactionRequest.Quantity
+	}
+
+	if transactionRequest.Status != nil {
+		transaction.Status = transactionRequest.Status
+	}
+
+	if transactionRequest.ClearedStatus != nil {
+		transaction.ClearedStatus = transactionRequest.ClearedStatus
+	}
+
+	verr, err := verifyTransaction(ctx, api.DB, transaction)
+	if err != nil {
+		logger.WithError(err).Warn("error verifying transaction")
+		utils.WriteError(w, http.StatusInternalServerError, "error updating transaction", nil)
+		return
+	}
+	checkClearedStatus(verr, transactionRequest.ClearedStatus)
+	checkStatus(verr, before.Status, transaction, transactionRequest.ClearedStatus)
+	if verr.Err() != nil {
+		logger.WithError(verr).Warn("invalid transaction")
+		utils.WriteValidationError(w, verr)
+		return
+	}
+
+	// transaction can't be moved into or out of locked reconciliation
+	if !checkUnlocked(w, r, api.DB, logger, &before, transaction) {
+		return
+	}
+
 	if err := api.DB.UpdateTransaction(ctx, transaction); err != nil {
 		logger.WithError(err).Warn("error updating transaction")
 		utils.WriteError(w, http.StatusInternalServerError, "error updating transaction", nil)
@@ -162,7 +292,7 @@ func (api *TransactionAPI) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 // GET - /users/{userID}/transactions?from={from}&to={to}
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, TransactionsRead
 func (api *TransactionAPI) ListByUser(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "transaction.go -> ListByUser()")
@@ -212,7 +342,7 @@ func (api *TransactionAPI) ListByUser(w http.ResponseWriter, r *http.Request) {
 }
 
 // GET - /categories/{categoryID}/transactions?from={from}&to={to}
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, TransactionsRead
 func (api *TransactionAPI) ListByCategory(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "transaction.go -> ListByCategory()")
@@ -262,7 +392,7 @@ func (api *TransactionAPI) ListByCategory(w http.ResponseWriter, r *http.Request
 }
 
 // GET - /accounts/{accountID}/transactions?from={from}&to={to}
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, AccountViewer, TransactionsRead
 func (api *TransactionAPI) ListByAccount(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "transaction.go -> ListByAccount()")
@@ -312,7 +442,7 @@ func (api *TransactionAPI) ListByAccount(w http.ResponseWriter, r *http.Request)
 }
 
 // GET - /users/{userID}/transactions/{transactionID}
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, TransactionsRead
 func (api *TransactionAPI) Get(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "transaction.go -> Get()")
@@ -343,7 +473,9 @@ func (api *TransactionAPI) Get(w http.ResponseWriter, r *http.Request) {
 }
 
 // DELETE - /users/{userID}/transactions/{transactionID}
-// Permission - MemberIsTarget
+// Permission - MemberIsOwner, TransactionsWrite
+// DELETE - /accounts/{accountID}/transactions/{transactionID}
+// Permission - AccountEditor
 func (api *TransactionAPI) Delete(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "transaction.go -> Delete()")
@@ -361,6 +493,16 @@ func (api *TransactionAPI) Delete(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
+	transaction, err := api.DB.GetTransactionByID(ctx, transactionID)
+	if accountID, ok := vars["accountID"]; ok && (err != nil || !isInAccount(transaction, model.AccountID(accountID))) {
+		logger.WithError(err).Warn("error getting transaction")
+		utils.WriteError(w, http.StatusNotFound, "transaction not found", nil)
+		return
+	}
+	if err == nil && !checkUnlocked(w, r, api.DB, logger, transaction) {
+		return
+	}
+
 	ok, err := api.DB.DeleteTransaction(ctx, transactionID)
 	if !ok && err != nil {
 		logger.WithError(err).Warn("error deleting transaction")
@@ -374,3 +516,134 @@ func (api *TransactionAPI) Delete(w http.ResponseWriter, r *http.Request) {
 		Deleted: true,
 	})
 }
+
+func isInAccount(transaction *model.Transaction, accountID model.AccountID) bool {
+	return transaction.AccountID != nil && *transaction.AccountID == accountID
+}
+
+// GET - /users/{userID}/transactions/{transactionID}/history
+// Permission - MemberIsOwner, TransactionsRead
+func (api *TransactionAPI) History(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "transaction.go -> History()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	transactionID := model.TransactionID(vars["transactionID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":        userID,
+		"principal":     principal,
+		"transactionID": transactionID,
+	})
+
+	writeHistory(w, r, api.DB, logger, model.AuditEntityTransaction, string(transactionID))
+}
+
+// POST - /users/{userID}/transactions/{transactionID}/restore
+// Permission - MemberIsOwner, TransactionsWrite
+func (api *TransactionAPI) Restore(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "transaction.go -> Restore()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	transactionID := model.TransactionID(vars["transactionID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":        userID,
+		"principal":     principal,
+		"transactionID": transactionID,
+	})
+
+	_, revision, ok := readRestoreRevision(w, r, api.DB, logger, model.AuditEntityTransaction, string(transactionID))
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+
+	// nil state only undeletes transaction
+	var state *model.Transaction
+	if revision != nil {
+		state = &model.Transaction{}
+		if ok := decodeRevision(w, logger, revision, state); !ok {
+			return
+		}
+
+		// revision can reference account or category which was deleted since
+		verr, err := verifyTransaction(ctx, api.DB, state)
+		if err != nil {
+			logger.WithError(err).Warn("error verifying transaction")
+			utils.WriteError(w, http.StatusInternalServerError, "error restoring transaction", nil)
+			return
+		}
+		if verr.Err() != nil {
+			logger.WithError(verr).Warn("invalid transaction")
+			utils.WriteValidationError(w, verr)
+			return
+		}
+	}
+
+	// current state is in the latest revision, deleted transaction has it too
+	var current *model.Transaction
+	if revisions, err := api.DB.ListRevisions(ctx, model.AuditEntityTransaction, string(transactionID)); err == nil && len(revisions) > 0 {
+		current = &model.Transaction{}
+		if ok := decodeRevision(w, logger, revisions[0], current); !ok {
+			return
+		}
+	}
+	if !checkUnlocked(w, r, api.DB, logger, current, state) {
+		return
+	}
+
+	transaction, err := api.DB.RestoreTransaction(ctx, transactionID, state)
+	if err != nil {
+		logger.WithError(err).Warn("error restoring transaction")
+		utils.WriteError(w, http.StatusConflict, "error restoring transaction", nil)
+		return
+	}
+
+	logger.Info("transaction restored")
+
+	utils.WriteJSON(w, http.StatusOK, &transaction)
+}
+
+// readIncludePending reads ?pending=true which counts pending transactions to balances and reports,
+// only posted ones count by default and void ones never do. Response is written when it returns false.
+func readIncludePending(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	includePending, err := utils.BoolParam(r.URL.Query(), "pending", false)
+	if err != nil {
+		verr := &model.ValidationError{}
+		verr.Add("pending", model.ErrCodeInvalid, "pending must be true or false")
+		utils.WriteValidationError(w, verr)
+		return false, false
+	}
+	return includePending, true
+}
+
+// checkStatus checks change of status from before, which is nil for new transaction. Transaction can't be
+// created void and only posted transaction can be cleared, pending one is cleared after it posts.
+// Cleared transaction has to be uncleared before it's voided, void one would leave cleared balance.
+func checkStatus(verr *model.ValidationError, before *model.TransactionStatus, transaction *model.Transaction, clearedStatus *model.ClearedStatus) {
+	if transaction.Status == nil || !transaction.Status.IsValid() {
+		return
+	}
+
+	if before == nil && *transaction.Status == model.Void {
+		verr.Add("status", model.ErrCodeInvalid, "transaction can't be created void")
+	}
+	if before != nil && !before.CanBecome(*transaction.Status) {
+		verr.Add("status", model.ErrCodeInvalid, fmt.Sprintf("%s transaction can't become %s", *before, *transaction.Status))
+	}
+	if before != nil && *before != model.Void && *transaction.Status == model.Void &&
+		transaction.ClearedStatus != nil && transaction.ClearedStatus.IsCleared() {
+		verr.Add("status", model.ErrCodeInvalid, "cleared transaction must be uncleared before it's voided")
+	}
+
+	if clearedStatus != nil && clearedStatus.IsCleared() && *transaction.Status != model.Posted {
+		verr.Add("clearedStatus", model.ErrCodeInvalid, "only posted transactions can be cleared")
+	}
+}
@@ -0,0 +1,118 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// TrashAPI - provides REST for deleted records. Items are restored with POST .../{id}/restore of their API.
+type TrashAPI struct {
+	DB database.Database
+}
+
+func SetTrashAPI(db database.Database, router *mux.Router, permissions auth.Permissions) {
+	api := &TrashAPI{
+		DB: db,
+	}
+
+	apis := []API{
+		NewAPI(http.MethodGet, "/users/{userID}/trash", api.List, auth.Admin, auth.MemberIsTarget),                                                         // list deleted records
+		NewAPI(http.MethodDelete, "/users/{userID}/trash/accounts/{accountID}", api.Purge, auth.Admin, auth.MemberIsOwner, auth.AccountsWrite),             // purge deleted account
+		NewAPI(http.MethodDelete, "/users/{userID}/trash/categories/{categoryID}", api.Purge, auth.Admin, auth.MemberIsOwner, auth.CategoriesWrite),        // purge deleted category
+		NewAPI(http.MethodDelete, "/users/{userID}/trash/merchants/{merchantID}", api.Purge, auth.Admin, auth.MemberIsOwner, auth.MerchantsWrite),          // purge deleted merchant
+		NewAPI(http.MethodDelete, "/users/{userID}/trash/transactions/{transactionID}", api.Purge, auth.Admin, auth.MemberIsOwner, auth.TransactionsWrite), // purge deleted transaction
+	}
+
+	for _, api := range apis {
+		router.HandleFunc(api.Path, permissions.Wrap(api.Func, api.permissionTypes...)).Methods(api.Method)
+	}
+}
+
+// GET - /users/{userID}/trash
+// Permission - MemberIsTarget, Admin
+func (api *TrashAPI) List(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "trash.go -> List()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	trash, err := api.DB.ListTrash(r.Context(), userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting trash")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting trash", nil)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, trash)
+}
+
+// trashVars maps path variables of purge routes to entities
+var trashVars = map[string]model.AuditEntity{
+	"accountID":     model.AuditEntityAccount,
+	"categoryID":    model.AuditEntityCategory,
+	"merchantID":    model.AuditEntityMerchant,
+	"transactionID": model.AuditEntityTransaction,
+}
+
+// DELETE - /users/{userID}/trash/accounts/{accountID}
+// DELETE - /users/{userID}/trash/categories/{categoryID}
+// DELETE - /users/{userID}/trash/merchants/{merchantID}
+// DELETE - /users/{userID}/trash/transactions/{transactionID}
+// Permission - MemberIsOwner, Admin, AccountsWrite/CategoriesWrite/MerchantsWrite/TransactionsWrite
+func (api *TrashAPI) Purge(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "trash.go -> Purge()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	principal := auth.GetPrincipal(r)
+
+	var entity model.AuditEntity
+	var id string
+	for name, e := range trashVars {
+		if value, ok := vars[name]; ok {
+			entity, id = e, value
+		}
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":     userID,
+		"principal":  principal,
+		"entityType": entity,
+		"entityID":   id,
+	})
+
+	if err := api.DB.PurgeDeleted(r.Context(), entity, id); err != nil {
+		switch err {
+		case database.ErrNotInTrash:
+			utils.WriteError(w, http.StatusNotFound, "item is not in trash", nil)
+		case database.ErrHasDependents:
+			utils.WriteError(w, http.StatusConflict, "item has transactions, delete them first", nil)
+		case database.ErrHasChildren:
+			utils.WriteError(w, http.StatusConflict, "category has child categories, delete them first", nil)
+		default:
+			logger.WithError(err).Warn("error purging item")
+			utils.WriteError(w, http.StatusInternalServerError, "error purging item", nil)
+		}
+		return
+	}
+
+	logger.Info("item purged")
+
+	utils.WriteJSON(w, http.StatusOK, &ActDeleted{
+		Deleted: true,
+	})
+}
@@ -3,7 +3,10 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
@@ -12,26 +15,58 @@ import (
 	"github.com/startdusk/finance-app-backend/internal/api/utils"
 	"github.com/startdusk/finance-app-backend/internal/database"
 	"github.com/startdusk/finance-app-backend/internal/model"
+	"github.com/startdusk/finance-app-backend/internal/oidc"
+	"github.com/startdusk/finance-app-backend/internal/password"
 )
 
 // UserAPI - providers REST for users
 type UserAPI struct {
-	DB database.Database // will represent all database interface
+	DB        database.Database   // will represent all database interface
+	Providers oidc.Providers      // external login providers
+	Throttle  *auth.LoginThrottle // failed logins counter
+	Passwords *password.Policy    // new passwords rules
+
+	states *oidc.StateStore // pending external logins
 }
 
-func SetUserAPI(db database.Database, router *mux.Router, permissions auth.Permissions) {
+func SetUserAPI(db database.Database, router *mux.Router, permissions auth.Permissions, providers oidc.Providers, passwords *password.Policy) {
 	api := &UserAPI{
-		DB: db,
+		DB:        db,
+		Providers: providers,
+		Passwords: passwords,
+		Throttle:  auth.NewLoginThrottle(db),
+		states:    oidc.NewStateStore(),
 	}
 
 	apis := []API{
 		// ---------------USER-------------------
-		NewAPI(http.MethodPost, "/users", api.Create, auth.Any),                                   // Create user
-		NewAPI(http.MethodGet, "/users", api.List, auth.Admin, auth.MemberIsTarget),               // list all user
-		NewAPI(http.MethodGet, "/users/{userID}", api.Get, auth.Admin, auth.MemberIsTarget),       // get user by id
-		NewAPI(http.MethodPatch, "/users/{userID}", api.Update, auth.Admin, auth.MemberIsTarget),  // update user by id
-		NewAPI(http.MethodDelete, "/users/{userID}", api.Delete, auth.Admin, auth.MemberIsTarget), // delete user by id
-		NewAPI(http.MethodPost, "/login", api.Login, auth.Any),                                    // Login user
+		NewAPI(http.MethodPost, "/users", api.Create, auth.Any),                                                                                                 // Create user
+		NewAPI(http.MethodGet, "/users", api.List, auth.Admin, auth.MemberIsTarget, auth.UsersRead),                                                             // list all user
+		NewAPI(http.MethodGet, "/users/{userID}", api.Get, auth.Admin, auth.MemberIsTarget, auth.UsersRead),                                                     // get user by id
+		NewAPI(http.MethodPatch, "/users/{userID}", api.Update, auth.Admin, auth.MemberIsTarget, auth.UsersWrite),                                               // update user by id
+		NewAPI(http.MethodDelete, "/users/{userID}", api.Delete, auth.Admin, auth.MemberIsTarget, auth.UsersWrite, auth.RefuseImpersonation, auth.RefuseAPIKey), // delete user by id
+		NewAPI(http.MethodPost, "/login", api.Login, auth.Any),                                                                                                  // Login user
+		NewAPI(http.MethodDelete, "/users/{userID}/lockout", api.Unlock, auth.Admin),                                                                            // unlock user locked by failed logins
+		NewAPI(http.MethodPost, "/users/{userID}/impersonate", api.Impersonate, auth.Admin, auth.RefuseImpersonation),                                           // get token of user for support
+
+		// ---------------PRIVACY-----------------
+		NewAPI(http.MethodGet, "/users/{userID}/export", api.Export, auth.Admin, auth.MemberIsTarget, auth.UsersRead, auth.RefuseImpersonation),                               // download user's data
+		NewAPI(http.MethodPost, "/users/{userID}/erasure", api.RequestErasure, auth.Admin, auth.MemberIsTarget, auth.UsersWrite, auth.RefuseImpersonation, auth.RefuseAPIKey), // erase user after grace period
+		NewAPI(http.MethodGet, "/users/{userID}/erasure", api.GetErasure, auth.Admin, auth.MemberIsTarget, auth.UsersRead),                                                    // get erasure request
+		NewAPI(http.MethodDelete, "/users/{userID}/erasure", api.CancelErasure, auth.Admin),                                                                                   // cancel erasure in grace period
+
+		// ---------------EXTERNAL LOGIN----------
+		NewAPI(http.MethodGet, "/login/{provider}", api.OIDCLogin, auth.Any),                                                      // Start login with provider
+		NewAPI(http.MethodGet, "/login/{provider}/callback", api.OIDCCallback, auth.Any),                                          // Finish login with provider
+		NewAPI(http.MethodGet, "/users/{userID}/identities", api.ListIdentities, auth.Admin, auth.MemberIsTarget, auth.UsersRead), // list user's linked providers
+
+		// ---------------TWO-FACTOR--------------
+		NewAPI(http.MethodPost, "/login/2fa", api.LoginTwoFactor, auth.Any),                                                                       // Finish login with two-factor code
+		NewAPI(http.MethodGet, "/users/{userID}/2fa", api.GetTwoFactor, auth.Admin, auth.MemberIsTarget, auth.UsersRead),                          // get two-factor status
+		NewAPI(http.MethodPost, "/users/{userID}/2fa", api.EnrollTwoFactor, auth.MemberIsTarget, auth.RefuseImpersonation),                        // start two-factor enrollment
+		NewAPI(http.MethodPost, "/users/{userID}/2fa/confirm", api.ConfirmTwoFactor, auth.MemberIsTarget, auth.RefuseImpersonation),               // enable two-factor with first code
+		NewAPI(http.MethodPost, "/users/{userID}/2fa/recovery-codes", api.RegenerateRecoveryCodes, auth.MemberIsTarget, auth.RefuseImpersonation), // generate new recovery codes
+		NewAPI(http.MethodDelete, "/users/{userID}/2fa", api.DisableTwoFactor, auth.Admin, auth.MemberIsTarget, auth.RefuseImpersonation),         // disable two-factor
 
 		// ---------------TOKENS------------------
 		NewAPI(http.MethodPost, "/refresh", api.RefreshToken, auth.Any), // Refresh token
@@ -46,7 +81,16 @@ type UserParameters struct {
 	model.User
 	model.SessionData
 
-	Password string `json:"password"` // Password must be 8 characters or longer!
+	Password string `json:"password"` // Password must pass password.Policy (length, strength, not breached)
+}
+
+// verifyPassword checks new password against policy, user's email must not be part of it
+func (api *UserAPI) verifyPassword(verr *model.ValidationError, user *model.User, password string) {
+	var userInputs []string
+	if user.Email != nil {
+		userInputs = append(userInputs, *user.Email)
+	}
+	api.Passwords.Check(verr, "password", password, userInputs...)
 }
 
 func (api *UserAPI) Create(w http.ResponseWriter, r *http.Request) {
@@ -62,15 +106,22 @@ func (api *UserAPI) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logger = logger.WithFields(logrus.Fields{
-		"email": *userParameters.Email,
-	})
-
-	if err := userParameters.User.Verify(); err != nil {
-		logger.WithError(err).Warn("not all fields found") // I will hide this error in future, it isn't secure to show what fields are missing...
-		utils.WriteError(w, http.StatusBadRequest, "not all fields found", map[string]string{
-			"error": err.Error(),
+	if userParameters.Email != nil {
+		logger = logger.WithFields(logrus.Fields{
+			"email": *userParameters.Email,
 		})
+	}
+
+	verr := &model.ValidationError{}
+	if err, ok := userParameters.User.Verify().(*model.ValidationError); ok {
+		verr = err
+	}
+
+	api.verifyPassword(verr, &userParameters.User, userParameters.Password)
+
+	if len(verr.Fields) != 0 {
+		logger.WithError(verr).Warn("invalid fields")
+		utils.WriteValidationError(w, verr)
 		return
 	}
 
@@ -143,9 +194,25 @@ func (api *UserAPI) Login(w http.ResponseWriter, r *http.Request) {
 	})
 
 	ctx := r.Context()
+	ip := utils.ClientIP(r)
+	throttleKeys := []auth.ThrottleKey{auth.AccountKey(credantials.Email), auth.IPKey(ip)}
+
+	if retryAfter, err := api.Throttle.Check(ctx, throttleKeys...); err != nil {
+		logger.WithError(err).Warn("error checking login attempts")
+		utils.WriteError(w, http.StatusInternalServerError, "error login in", nil)
+		return
+	} else if retryAfter > 0 {
+		logger.WithField("retryAfter", retryAfter).Warn("too many login attempts")
+		writeTooManyAttempts(w, retryAfter)
+		return
+	}
+
 	user, err := api.DB.GetUserByEmail(ctx, credantials.Email)
 	if err != nil {
 		logger.WithError(err).Warn("error login in")
+		if err := api.Throttle.Failure(ctx, model.NilUserID, ip, throttleKeys...); err != nil {
+			logger.WithError(err).Warn("error recording login failure")
+		}
 		utils.WriteError(w, http.StatusConflict, "invalid email or password", nil)
 		return
 	}
@@ -153,13 +220,66 @@ func (api *UserAPI) Login(w http.ResponseWriter, r *http.Request) {
 	// Checking if password is correct
 	if err := user.CheckPassword(credantials.Password); err != nil {
 		logger.WithError(err).Warn("error login in")
+		if err := api.Throttle.Failure(ctx, user.ID, ip, throttleKeys...); err != nil {
+			logger.WithError(err).Warn("error recording login failure")
+		}
 		utils.WriteError(w, http.StatusUnauthorized, "invalid email or password", nil)
 		return
 	}
 
+	if err := api.Throttle.Success(ctx, auth.AccountKey(credantials.Email)); err != nil {
+		logger.WithError(err).Warn("error resetting login attempts")
+	}
+
 	logger.WithField("userID", user.ID).Info("user login in")
 
-	api.writeTokenResponse(ctx, w, http.StatusOK, user, &credantials.SessionData, true)
+	api.writeLoginResponse(ctx, w, user, &credantials.SessionData)
+}
+
+// writeTooManyAttempts - client has to wait before next login attempt
+func writeTooManyAttempts(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	utils.WriteError(w, http.StatusTooManyRequests, "too many login attempts", map[string]int64{
+		"retryAfter": seconds,
+	})
+}
+
+// DELETE - /users/{userID}/lockout
+// Permission - Admin
+func (api *UserAPI) Unlock(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user.go -> Unlock()")
+
+	vars := mux.Vars(r)
+	userID := model.UserID(vars["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	ctx := r.Context()
+
+	user, err := api.DB.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting user")
+		utils.WriteError(w, http.StatusConflict, "error getting user", nil)
+		return
+	}
+
+	if err := api.Throttle.Unlock(ctx, user, principal, utils.ClientIP(r)); err != nil {
+		logger.WithError(err).Warn("error unlocking user")
+		utils.WriteError(w, http.StatusInternalServerError, "error unlocking user", nil)
+		return
+	}
+
+	logger.Info("user unlocked")
+
+	utils.WriteJSON(w, http.StatusOK, &ActDeleted{
+		Deleted: true,
+	})
 }
 
 func (api *UserAPI) Get(w http.ResponseWriter, r *http.Request) {
@@ -242,8 +362,9 @@ func (api *UserAPI) RefreshToken(w http.ResponseWriter, r *http.Request) {
 }
 
 type TokenResponse struct {
-	Tokens *auth.Tokens `json:"tokens,omitempty"` // this will insert all tokens struct fields
-	User   *model.User  `json:"user,omitempty"`
+	Tokens    *auth.Tokens    `json:"tokens,omitempty"` // this will insert all tokens struct fields
+	User      *model.User     `json:"user,omitempty"`
+	Challenge *auth.Challenge `json:"challenge,omitempty"` // returned instead of tokens when user has to enter two-factor code
 }
 
 // writeTokenResponse - Generate Access and Refresh token are return them to user. Refresh token is stored in database as session
@@ -256,7 +377,7 @@ func (api *UserAPI) writeTokenResponse(
 	cookie bool) {
 	// Issue token:
 	// TODO: add user role to Principal
-	tokens, err := auth.IssueToken(model.Principal{UserID: user.ID})
+	tokens, err := auth.IssueToken(model.Principal{UserID: user.ID, DeviceID: sessionData.DeviceID})
 	if err != nil && tokens == nil {
 		logrus.WithError(err).Warn("error issuing token")
 		utils.WriteError(w, http.StatusConflict, "error issuing token", nil)
@@ -290,7 +411,7 @@ func (api *UserAPI) writeTokenResponse(
 }
 
 // GET - /users
-// Permission - MemberIsTarget, Admin
+// Permission - MemberIsTarget, Admin, UsersRead
 func (api *UserAPI) List(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "user.go -> List()")
@@ -316,7 +437,7 @@ func (api *UserAPI) List(w http.ResponseWriter, r *http.Request) {
 }
 
 // DELETE - /users/{userID}
-// Permission - MemberIsTarget, Admin
+// Permission - MemberIsTarget, Admin, UsersWrite, RefuseImpersonation, RefuseAPIKey
 func (api *UserAPI) Delete(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "user.go -> Delete()")
@@ -353,7 +474,7 @@ func (api *UserAPI) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 // PATCH - /users/{userID}
-// Permission - MemberIsTarget, Admin
+// Permission - MemberIsTarget, Admin, UsersWrite
 func (api *UserAPI) Update(w http.ResponseWriter, r *http.Request) {
 	// show function name to track error faster
 	logger := logrus.WithField("func", "user.go -> Update()")
@@ -387,6 +508,26 @@ func (api *UserAPI) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(userRequest.Password) != 0 {
+		// other fields can be fixed by support, password only by user himself
+		if principal.IsImpersonated() {
+			logger.Warn("password change while impersonating")
+			utils.WriteError(w, http.StatusForbidden, "not allowed while impersonating user", nil)
+			return
+		}
+		// leaked key must not be enough to take over account
+		if principal.IsAPIKey() {
+			logger.Warn("password change with api key")
+			utils.WriteError(w, http.StatusForbidden, "not allowed with api key", nil)
+			return
+		}
+
+		verr := &model.ValidationError{}
+		if api.verifyPassword(verr, user, userRequest.Password); len(verr.Fields) != 0 {
+			logger.WithError(verr).Warn("invalid fields")
+			utils.WriteValidationError(w, verr)
+			return
+		}
+
 		if err := user.SetPassword(userRequest.Password); err != nil {
 			logger.WithError(err).Warn("error setting password")
 			utils.WriteError(w, http.StatusInternalServerError, "error setting password", nil)
@@ -0,0 +1,420 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/config"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// Two-factor authentication (TOTP):
+// 1. POST /users/{userID}/2fa - returns secret and otpauth:// URI for authenticator app
+// 2. POST /users/{userID}/2fa/confirm - user sends first code, 2FA is enabled and recovery codes are returned
+// 3. POST /login returns challenge token instead of tokens, POST /login/2fa exchanges it with code for tokens
+
+// TwoFactorCodeRequest - code from authenticator app or one of recovery codes
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorLoginRequest - data user sends to finish login
+type TwoFactorLoginRequest struct {
+	model.SessionData
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+}
+
+// TwoFactorEnrollment - data user needs to add account to authenticator app
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningURI"`
+}
+
+// RecoveryCodes are shown to user only once
+type RecoveryCodes struct {
+	Enabled       bool     `json:"enabled"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// writeLoginResponse - user passed first factor, if 2FA is enabled we return challenge, otherwise tokens
+func (api *UserAPI) writeLoginResponse(ctx context.Context, w http.ResponseWriter, user *model.User, sessionData *model.SessionData) {
+	totp, err := api.DB.GetTOTP(ctx, user.ID)
+	if err != nil && err != database.ErrTOTPNotFound {
+		logrus.WithError(err).Warn("error getting two-factor authentication")
+		utils.WriteError(w, http.StatusInternalServerError, "error login in", nil)
+		return
+	}
+
+	if err == nil && totp.Enabled {
+		challenge, err := auth.IssueChallengeToken(user.ID)
+		if err != nil {
+			logrus.WithError(err).Warn("error issuing challenge token")
+			utils.WriteError(w, http.StatusConflict, "error issuing token", nil)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, &TokenResponse{
+			Challenge: challenge,
+		})
+		return
+	}
+
+	api.writeTokenResponse(ctx, w, http.StatusOK, user, sessionData, true)
+}
+
+// checkSecondFactor accepts TOTP code or unused recovery code, both can be used only once
+func (api *UserAPI) checkSecondFactor(ctx context.Context, totp *model.TOTP, code string) (bool, error) {
+	if step, ok := totp.Check(code, time.Now()); ok {
+		return api.DB.UseTOTPStep(ctx, totp.UserID, step)
+	}
+
+	return api.DB.UseRecoveryCode(ctx, totp.UserID, model.HashRecoveryCode(code))
+}
+
+// POST - /login/2fa
+// Permission - Any
+func (api *UserAPI) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_2fa.go -> LoginTwoFactor()")
+
+	var request TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := request.SessionData.Verify(); err != nil {
+		logger.WithError(err).Warn("not all fields found")
+		utils.WriteError(w, http.StatusBadRequest, "not all fields found", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	userID, err := auth.VerifyChallengeToken(request.ChallengeToken)
+	if err != nil {
+		logger.WithError(err).Warn("error verifying challenge token")
+		utils.WriteError(w, http.StatusUnauthorized, "invalid challenge token", nil)
+		return
+	}
+
+	logger = logger.WithField("userID", userID)
+
+	ctx := r.Context()
+	ip := utils.ClientIP(r)
+	throttleKeys := []auth.ThrottleKey{auth.TwoFactorKey(userID), auth.IPKey(ip)}
+
+	// without this 6 digits code could be guessed in few minutes
+	if retryAfter, err := api.Throttle.Check(ctx, throttleKeys...); err != nil {
+		logger.WithError(err).Warn("error checking login attempts")
+		utils.WriteError(w, http.StatusInternalServerError, "error login in", nil)
+		return
+	} else if retryAfter > 0 {
+		logger.WithField("retryAfter", retryAfter).Warn("too many login attempts")
+		writeTooManyAttempts(w, retryAfter)
+		return
+	}
+
+	totp, err := api.DB.GetTOTP(ctx, userID)
+	if err != nil || !totp.Enabled {
+		logger.WithError(err).Warn("two-factor authentication is not enabled")
+		utils.WriteError(w, http.StatusUnauthorized, "invalid code", nil)
+		return
+	}
+
+	if ok, err := api.checkSecondFactor(ctx, totp, request.Code); !ok {
+		logger.WithError(err).Warn("invalid two-factor code")
+		if err := api.Throttle.Failure(ctx, userID, ip, throttleKeys...); err != nil {
+			logger.WithError(err).Warn("error recording login failure")
+		}
+		utils.WriteError(w, http.StatusUnauthorized, "invalid code", nil)
+		return
+	}
+
+	if err := api.Throttle.Success(ctx, auth.TwoFactorKey(userID)); err != nil {
+		logger.WithError(err).Warn("error resetting login attempts")
+	}
+
+	user, err := api.DB.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting user")
+		utils.WriteError(w, http.StatusConflict, "error getting user", nil)
+		return
+	}
+
+	logger.Info("user login in")
+
+	api.writeTokenResponse(ctx, w, http.StatusOK, user, &request.SessionData, true)
+}
+
+// GET - /users/{userID}/2fa
+// Permission - MemberIsTarget, Admin, UsersRead
+func (api *UserAPI) GetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_2fa.go -> GetTwoFactor()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	logger = logger.WithField("userID", userID)
+
+	totp, err := api.DB.GetTOTP(r.Context(), userID)
+	if err == database.ErrTOTPNotFound {
+		totp = &model.TOTP{UserID: userID}
+	} else if err != nil {
+		logger.WithError(err).Warn("error getting two-factor authentication")
+		utils.WriteError(w, http.StatusConflict, "error getting two-factor authentication", nil)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, totp)
+}
+
+// POST - /users/{userID}/2fa
+// Permission - MemberIsTarget, RefuseImpersonation
+func (api *UserAPI) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_2fa.go -> EnrollTwoFactor()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	logger = logger.WithField("userID", userID)
+
+	ctx := r.Context()
+
+	user, err := api.DB.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting user")
+		utils.WriteError(w, http.StatusConflict, "error getting user", nil)
+		return
+	}
+
+	current, err := api.DB.GetTOTP(ctx, userID)
+	if err != nil && err != database.ErrTOTPNotFound {
+		logger.WithError(err).Warn("error getting two-factor authentication")
+		utils.WriteError(w, http.StatusConflict, "error getting two-factor authentication", nil)
+		return
+	}
+
+	// to change device user has to disable 2FA first (with code from old device)
+	if current != nil && current.Enabled {
+		logger.Warn("two-factor authentication already enabled")
+		utils.WriteError(w, http.StatusConflict, "two-factor authentication already enabled", nil)
+		return
+	}
+
+	totp, err := model.NewTOTP(userID)
+	if err != nil {
+		logger.WithError(err).Warn("error generating secret")
+		utils.WriteError(w, http.StatusInternalServerError, "error generating secret", nil)
+		return
+	}
+
+	if err := api.DB.SaveTOTP(ctx, totp); err != nil {
+		logger.WithError(err).Warn("error saving two-factor authentication")
+		utils.WriteError(w, http.StatusInternalServerError, "error saving two-factor authentication", nil)
+		return
+	}
+
+	logger.Info("two-factor enrollment started")
+
+	utils.WriteJSON(w, http.StatusCreated, &TwoFactorEnrollment{
+		Secret:          totp.Secret,
+		ProvisioningURI: totp.ProvisioningURI(*config.TOTPIssuer, *user.Email),
+	})
+}
+
+// POST - /users/{userID}/2fa/confirm
+// Permission - MemberIsTarget, RefuseImpersonation
+func (api *UserAPI) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_2fa.go -> ConfirmTwoFactor()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	logger = logger.WithField("userID", userID)
+
+	var request TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	ctx := r.Context()
+
+	totp, err := api.DB.GetTOTP(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting two-factor enrollment")
+		utils.WriteError(w, http.StatusNotFound, "two-factor enrollment not found", nil)
+		return
+	}
+
+	if totp.Enabled {
+		logger.Warn("two-factor authentication already enabled")
+		utils.WriteError(w, http.StatusConflict, "two-factor authentication already enabled", nil)
+		return
+	}
+
+	now := time.Now()
+	step, ok := totp.Check(request.Code, now)
+	if !ok {
+		logger.Warn("invalid two-factor code")
+		utils.WriteError(w, http.StatusUnauthorized, "invalid code", nil)
+		return
+	}
+
+	totp.Enabled = true
+	totp.LastUsedStep = step
+	totp.ConfirmedAt = &now
+
+	codes, err := api.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error generating recovery codes")
+		utils.WriteError(w, http.StatusInternalServerError, "error generating recovery codes", nil)
+		return
+	}
+
+	if err := api.DB.SaveTOTP(ctx, totp); err != nil {
+		logger.WithError(err).Warn("error saving two-factor authentication")
+		utils.WriteError(w, http.StatusInternalServerError, "error saving two-factor authentication", nil)
+		return
+	}
+
+	logger.Info("two-factor authentication enabled")
+
+	utils.WriteJSON(w, http.StatusOK, &RecoveryCodes{
+		Enabled:       true,
+		RecoveryCodes: codes,
+	})
+}
+
+// POST - /users/{userID}/2fa/recovery-codes
+// Permission - MemberIsTarget, RefuseImpersonation
+func (api *UserAPI) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_2fa.go -> RegenerateRecoveryCodes()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	logger = logger.WithField("userID", userID)
+
+	var request TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.WithError(err).Warn("could not decode parameters")
+		utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	ctx := r.Context()
+
+	totp, err := api.DB.GetTOTP(ctx, userID)
+	if err != nil || !totp.Enabled {
+		logger.WithError(err).Warn("two-factor authentication is not enabled")
+		utils.WriteError(w, http.StatusNotFound, "two-factor authentication is not enabled", nil)
+		return
+	}
+
+	if ok, err := api.checkSecondFactor(ctx, totp, request.Code); !ok {
+		logger.WithError(err).Warn("invalid two-factor code")
+		utils.WriteError(w, http.StatusUnauthorized, "invalid code", nil)
+		return
+	}
+
+	codes, err := api.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error generating recovery codes")
+		utils.WriteError(w, http.StatusInternalServerError, "error generating recovery codes", nil)
+		return
+	}
+
+	logger.Info("recovery codes regenerated")
+
+	utils.WriteJSON(w, http.StatusOK, &RecoveryCodes{
+		Enabled:       true,
+		RecoveryCodes: codes,
+	})
+}
+
+// DELETE - /users/{userID}/2fa
+// Permission - MemberIsTarget, Admin, RefuseImpersonation
+// User has to send valid code, admin can disable 2FA of other user without code (lost phone)
+func (api *UserAPI) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_2fa.go -> DisableTwoFactor()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	ctx := r.Context()
+
+	totp, err := api.DB.GetTOTP(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("two-factor authentication is not enabled")
+		utils.WriteError(w, http.StatusNotFound, "two-factor authentication is not enabled", nil)
+		return
+	}
+
+	if principal.UserID == userID && totp.Enabled {
+		var request TwoFactorCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			logger.WithError(err).Warn("could not decode parameters")
+			utils.WriteError(w, http.StatusBadRequest, "could not decode parameters", map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if ok, err := api.checkSecondFactor(ctx, totp, request.Code); !ok {
+			logger.WithError(err).Warn("invalid two-factor code")
+			utils.WriteError(w, http.StatusUnauthorized, "invalid code", nil)
+			return
+		}
+	}
+
+	if err := api.DB.DeleteTOTP(ctx, userID); err != nil {
+		logger.WithError(err).Warn("error disabling two-factor authentication")
+		utils.WriteError(w, http.StatusInternalServerError, "error disabling two-factor authentication", nil)
+		return
+	}
+
+	logger.Info("two-factor authentication disabled")
+
+	utils.WriteJSON(w, http.StatusOK, &ActDeleted{
+		Deleted: true,
+	})
+}
+
+func (api *UserAPI) replaceRecoveryCodes(ctx context.Context, userID model.UserID) ([]string, error) {
+	codes, err := model.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([][]byte, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, model.HashRecoveryCode(code))
+	}
+
+	if err := api.DB.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
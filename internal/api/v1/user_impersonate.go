@@ -0,0 +1,95 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// Impersonation lets support see and fix data as user sees it, without user's password.
+// Token is short lived, can't be refreshed, can't change password, login methods or delete user,
+// and every change made with it is audited with admin as impersonator.
+
+// POST - /users/{userID}/impersonate
+// Permission - Admin, RefuseImpersonation
+func (api *UserAPI) Impersonate(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_impersonate.go -> Impersonate()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	// admin acts as himself, impersonation token can't be used as an API key either
+	if userID == principal.UserID || principal.IsAPIKey() {
+		logger.Warn("invalid impersonation")
+		utils.WriteError(w, http.StatusBadRequest, "can't impersonate this user", nil)
+		return
+	}
+
+	ctx := r.Context()
+
+	user, err := api.DB.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting user")
+		utils.WriteError(w, http.StatusConflict, "error getting user", nil)
+		return
+	}
+
+	// token of other admin would give all his permissions without his password
+	roles, err := api.DB.GetRolesByUser(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting user roles")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting user roles", nil)
+		return
+	}
+	for _, role := range roles {
+		if role.Role == model.RoleAdmin {
+			logger.Warn("impersonation of admin")
+			utils.WriteError(w, http.StatusForbidden, "admin can't be impersonated", nil)
+			return
+		}
+	}
+
+	tokens, err := auth.IssueImpersonationToken(principal.UserID, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error issuing token")
+		utils.WriteError(w, http.StatusConflict, "error issuing token", nil)
+		return
+	}
+
+	details, err := json.Marshal(map[string]interface{}{
+		"expiresAt": tokens.AccessTokenExpiresAt,
+	})
+	if err != nil {
+		logger.WithError(err).Warn("could not encode audit details")
+	}
+
+	// admin has to be traceable, we don't give token without audit entry
+	if err := api.DB.CreateAuditEntry(ctx, &model.AuditEntry{
+		Action:  model.AuditUserImpersonated,
+		UserID:  &userID,
+		Details: details,
+	}); err != nil {
+		logger.WithError(err).Warn("error writing audit entry")
+		utils.WriteError(w, http.StatusInternalServerError, "error issuing token", nil)
+		return
+	}
+
+	logger.Info("user impersonated")
+
+	utils.WriteJSON(w, http.StatusOK, &TokenResponse{
+		Tokens: tokens,
+		User:   user,
+	})
+}
@@ -0,0 +1,204 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/model"
+	"github.com/startdusk/finance-app-backend/internal/oidc"
+)
+
+// Login with OpenID Connect providers (authorization code flow with PKCE):
+// 1. client opens GET /login/{provider}?deviceID={deviceID} in browser, we redirect it to provider
+// 2. provider redirects back to GET /login/{provider}/callback?code={code}&state={state}
+// 3. we exchange code for ID token, find (or create) user and return tokens same as /login
+
+// GET - /login/{provider}?deviceID={deviceID}
+// Permission - Any
+func (api *UserAPI) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_oidc.go -> OIDCLogin()")
+
+	providerName := mux.Vars(r)["provider"]
+	sessionData := model.SessionData{
+		DeviceID: model.DeviceID(r.URL.Query().Get("deviceID")),
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"provider": providerName,
+		"deviceID": sessionData.DeviceID,
+	})
+
+	provider, ok := api.Providers.Get(providerName)
+	if !ok {
+		logger.Warn("unknown login provider")
+		utils.WriteError(w, http.StatusNotFound, "unknown login provider", nil)
+		return
+	}
+
+	if err := sessionData.Verify(); err != nil {
+		logger.WithError(err).Warn("not all fields found")
+		utils.WriteError(w, http.StatusBadRequest, "not all fields found", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	state, request, err := api.states.Start(providerName, string(sessionData.DeviceID))
+	if err != nil {
+		logger.WithError(err).Warn("error starting login")
+		utils.WriteError(w, http.StatusInternalServerError, "error starting login", nil)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, request.Nonce, request.CodeVerifier)
+	if err != nil {
+		logger.WithError(err).Warn("error starting login")
+		utils.WriteError(w, http.StatusBadGateway, "error starting login", nil)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// GET - /login/{provider}/callback?code={code}&state={state}
+// Permission - Any
+func (api *UserAPI) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_oidc.go -> OIDCCallback()")
+
+	providerName := mux.Vars(r)["provider"]
+	logger = logger.WithField("provider", providerName)
+
+	provider, ok := api.Providers.Get(providerName)
+	if !ok {
+		logger.Warn("unknown login provider")
+		utils.WriteError(w, http.StatusNotFound, "unknown login provider", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		logger.WithField("error", providerError).Warn("provider returned error")
+		utils.WriteError(w, http.StatusUnauthorized, "login failed", nil)
+		return
+	}
+
+	request, ok := api.states.Take(query.Get("state"))
+	if !ok || request.Provider != providerName {
+		logger.Warn("invalid login state")
+		utils.WriteError(w, http.StatusUnauthorized, "invalid login state", nil)
+		return
+	}
+
+	ctx := r.Context()
+
+	idToken, err := provider.Exchange(ctx, query.Get("code"), request.CodeVerifier, request.Nonce)
+	if err != nil {
+		logger.WithError(err).Warn("error verifying login")
+		utils.WriteError(w, http.StatusUnauthorized, "login failed", nil)
+		return
+	}
+
+	logger = logger.WithField("subject", idToken.Subject)
+
+	user, err := api.userByIdentity(ctx, providerName, idToken)
+	if err != nil {
+		logger.WithError(err).Warn("error getting user")
+		utils.WriteError(w, http.StatusConflict, "login failed", nil)
+		return
+	}
+
+	logger.WithField("userID", user.ID).Info("user login in")
+
+	api.writeLoginResponse(ctx, w, user, &model.SessionData{DeviceID: model.DeviceID(request.DeviceID)})
+}
+
+// userByIdentity returns user linked with provider identity.
+// First login links identity to user with the same (verified) email or creates new user without password.
+func (api *UserAPI) userByIdentity(ctx context.Context, provider string, idToken *oidc.IDToken) (*model.User, error) {
+	identity, err := api.DB.GetUserIdentity(ctx, provider, idToken.Subject)
+	if err == nil {
+		return api.DB.GetUserByID(ctx, identity.UserID)
+	}
+	if err != database.ErrIdentityNotFound {
+		return nil, err
+	}
+
+	// we can trust email only if provider verified it, otherwise anyone could take over account
+	if idToken.Email == "" || !idToken.EmailVerified {
+		return nil, errors.New("provider did not return verified email")
+	}
+
+	// user created without identity could not log in with provider again
+	var user *model.User
+	err = api.DB.WithTx(ctx, func(db database.Database) error {
+		var err error
+		user, err = db.GetUserByEmail(ctx, idToken.Email)
+		if err == sql.ErrNoRows {
+			email := idToken.Email
+			newUser := &model.User{
+				Email: &email,
+			}
+			if err := db.CreateUser(ctx, newUser); err != nil {
+				return err
+			}
+
+			if user, err = db.GetUserByID(ctx, newUser.ID); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		email := idToken.Email
+		identity := &model.UserIdentity{
+			Provider: provider,
+			Subject:  idToken.Subject,
+			UserID:   user.ID,
+			Email:    &email,
+		}
+		if err := identity.Verify(); err != nil {
+			return err
+		}
+
+		return db.CreateUserIdentity(ctx, identity)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GET - /users/{userID}/identities, UsersRead
+// Permission - MemberIsTarget, Admin
+func (api *UserAPI) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_oidc.go -> ListIdentities()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	logger = logger.WithField("userID", userID)
+
+	identities, err := api.DB.ListUserIdentities(r.Context(), userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting identities")
+		utils.WriteError(w, http.StatusConflict, "error getting identities", nil)
+		return
+	}
+
+	if identities == nil {
+		identities = make([]*model.UserIdentity, 0)
+	}
+
+	logger.Info("identities returned")
+
+	utils.WriteJSON(w, http.StatusOK, &identities)
+}
@@ -0,0 +1,178 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/api/auth"
+	"github.com/startdusk/finance-app-backend/internal/api/utils"
+	"github.com/startdusk/finance-app-backend/internal/config"
+	"github.com/startdusk/finance-app-backend/internal/database"
+	"github.com/startdusk/finance-app-backend/internal/export"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// User can download everything we store about him and ask to erase it.
+// Erased user is deleted right away and his data is erased after grace period,
+// until then admin can cancel the erasure.
+
+// GET - /users/{userID}/export
+// Permission - Admin, MemberIsTarget, UsersRead, RefuseImpersonation
+func (api *UserAPI) Export(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_privacy.go -> Export()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	ctx := r.Context()
+
+	data, err := api.DB.GetUserExport(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("error getting user data")
+		utils.WriteError(w, http.StatusConflict, "error getting user data", nil)
+		return
+	}
+
+	// archive is built before headers are sent, so error can still be reported
+	var archive bytes.Buffer
+	if err := export.WriteArchive(&archive, data); err != nil {
+		logger.WithError(err).Warn("error building archive")
+		utils.WriteError(w, http.StatusInternalServerError, "error building archive", nil)
+		return
+	}
+
+	if err := api.DB.CreateAuditEntry(ctx, &model.AuditEntry{
+		Action: model.AuditUserExported,
+		UserID: &userID,
+	}); err != nil {
+		logger.WithError(err).Warn("error writing audit entry")
+		utils.WriteError(w, http.StatusInternalServerError, "error building archive", nil)
+		return
+	}
+
+	logger.Info("user exported")
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s.zip"`, userID))
+	w.WriteHeader(http.StatusOK)
+	if _, err := archive.WriteTo(w); err != nil {
+		logger.WithError(err).Warn("error writing archive")
+	}
+}
+
+// POST - /users/{userID}/erasure
+// Permission - Admin, MemberIsTarget, UsersWrite, RefuseImpersonation, RefuseAPIKey
+func (api *UserAPI) RequestErasure(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_privacy.go -> RequestErasure()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	request := &model.ErasureRequest{
+		UserID:      userID,
+		RequestedBy: &principal.UserID,
+		ScheduledAt: time.Now().UTC().Add(time.Duration(*config.ErasureGraceDays) * 24 * time.Hour),
+	}
+
+	ctx := r.Context()
+
+	if err := api.DB.CreateErasureRequest(ctx, request); err != nil {
+		if err == database.ErrErasureExists {
+			logger.WithError(err).Warn("erasure already requested")
+			utils.WriteError(w, http.StatusConflict, "erasure already requested", nil)
+			return
+		}
+		logger.WithError(err).Warn("error requesting erasure")
+		utils.WriteError(w, http.StatusInternalServerError, "error requesting erasure", nil)
+		return
+	}
+
+	logger.Info("erasure requested")
+
+	utils.WriteJSON(w, http.StatusCreated, request)
+}
+
+// GET - /users/{userID}/erasure
+// Permission - Admin, MemberIsTarget, UsersRead
+func (api *UserAPI) GetErasure(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_privacy.go -> GetErasure()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	ctx := r.Context()
+
+	request, err := api.DB.GetErasureRequest(ctx, userID)
+	if err != nil {
+		if err == database.ErrErasureNotFound {
+			utils.WriteError(w, http.StatusNotFound, "erasure not requested", nil)
+			return
+		}
+		logger.WithError(err).Warn("error getting erasure request")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting erasure request", nil)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, request)
+}
+
+// DELETE - /users/{userID}/erasure
+// Permission - Admin
+func (api *UserAPI) CancelErasure(w http.ResponseWriter, r *http.Request) {
+	// show function name to track error faster
+	logger := logrus.WithField("func", "user_privacy.go -> CancelErasure()")
+
+	userID := model.UserID(mux.Vars(r)["userID"])
+	principal := auth.GetPrincipal(r)
+
+	logger = logger.WithFields(logrus.Fields{
+		"userID":    userID,
+		"principal": principal,
+	})
+
+	ctx := r.Context()
+
+	if err := api.DB.CancelErasureRequest(ctx, userID); err != nil {
+		switch err {
+		case database.ErrErasureNotFound:
+			// request which is completed can't be canceled, data is gone
+			utils.WriteError(w, http.StatusNotFound, "no pending erasure", nil)
+		case database.ErrUserExist:
+			logger.WithError(err).Warn("email of user is taken")
+			utils.WriteError(w, http.StatusConflict, "email of user is used by other user", nil)
+		default:
+			logger.WithError(err).Warn("error canceling erasure")
+			utils.WriteError(w, http.StatusInternalServerError, "error canceling erasure", nil)
+		}
+		return
+	}
+
+	logger.Info("erasure canceled")
+
+	utils.WriteJSON(w, http.StatusOK, &ActDeleted{
+		Deleted: true,
+	})
+}
@@ -15,18 +15,20 @@ import (
 
 // UserRoleAPI - providers REST for user role
 type UserRoleAPI struct {
-	DB database.Database
+	DB          database.Database
+	Permissions auth.Permissions // to forget cached roles when they are changed
 }
 
 func SetUserRoleAPI(db database.Database, router *mux.Router, permissions auth.Permissions) {
 	api := &UserRoleAPI{
-		DB: db,
+		DB:          db,
+		Permissions: permissions,
 	}
 
 	apis := []API{
-		NewAPI(http.MethodPost, "/users/{userID}/roles", api.GrantRole, auth.Admin),    // Create role
-		NewAPI(http.MethodDelete, "/users/{userID}/roles", api.RevokeRole, auth.Admin), // Revoke role
-		NewAPI(http.MethodGet, "/users/{userID}/roles", api.GetRoleList, auth.Admin),   // Get all role
+		NewAPI(http.MethodPost, "/users/{userID}/roles", api.GrantRole, auth.Admin, auth.RefuseImpersonation),    // Create role
+		NewAPI(http.MethodDelete, "/users/{userID}/roles", api.RevokeRole, auth.Admin, auth.RefuseImpersonation), // Revoke role
+		NewAPI(http.MethodGet, "/users/{userID}/roles", api.GetRoleList, auth.Admin, auth.UsersRead),             // Get all role
 	}
 
 	for _, api := range apis {
@@ -59,6 +61,16 @@ func (api *UserRoleAPI) GrantRole(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
+	if _, err := api.DB.GetRole(ctx, userRole.Role); err != nil {
+		if err == database.ErrRoleNotFound {
+			utils.WriteError(w, http.StatusBadRequest, "role not found", nil)
+			return
+		}
+		logger.WithError(err).Warn("error getting role")
+		utils.WriteError(w, http.StatusInternalServerError, "error getting role", nil)
+		return
+	}
+
 	// store role in database
 	if err := api.DB.GrantRole(ctx, userID, userRole.Role); err != nil {
 		logger.WithError(err).Warn("error granting role")
@@ -66,6 +78,8 @@ func (api *UserRoleAPI) GrantRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	api.Permissions.Purge()
+
 	utils.WriteJSON(w, http.StatusCreated, &ActCreated{
 		Created: true,
 	})
@@ -103,6 +117,8 @@ func (api *UserRoleAPI) RevokeRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	api.Permissions.Purge()
+
 	utils.WriteJSON(w, http.StatusCreated, &ActDeleted{
 		Deleted: true,
 	})
@@ -6,3 +6,12 @@ import (
 
 // DataDirectory is the path used for loading templates/database migrations
 var DataDirectory = flag.String("data-directory", "", "Path for loading templates and migration scripts.")
+
+// TOTPIssuer is shown in authenticator apps next to user's email
+var TOTPIssuer = flag.String("totp-issuer", "FinanceApp", "Issuer name used in two-factor authentication apps.")
+
+// TrashRetentionDays is how long deleted records can be restored, 0 keeps them forever
+var TrashRetentionDays = flag.Int("trash-retention-days", 30, "Days after which deleted records are purged, 0 disables purging.")
+
+// ErasureGraceDays is how long erasure of user's data can be canceled
+var ErasureGraceDays = flag.Int("erasure-grace-days", 30, "Days after which data of user who asked for erasure is erased.")
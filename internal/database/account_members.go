@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// AccountMemberDB persist users who have access to accounts of other users
+type AccountMemberDB interface {
+	CreateAccountMember(ctx context.Context, member *model.AccountMember) error
+	GetAccountMember(ctx context.Context, accountID model.AccountID, userID model.UserID) (*model.AccountMember, error)
+	ListAccountMembers(ctx context.Context, accountID model.AccountID) ([]*model.AccountMember, error)
+	ListInvitations(ctx context.Context, userID model.UserID) ([]*model.AccountMember, error)
+	AcceptAccountMember(ctx context.Context, accountID model.AccountID, userID model.UserID) error
+	UpdateAccountMemberAccess(ctx context.Context, accountID model.AccountID, userID model.UserID, access model.AccountAccess) error
+	DeleteAccountMember(ctx context.Context, accountID model.AccountID, userID model.UserID) error
+	ListSharedAccounts(ctx context.Context, userID model.UserID) ([]*model.Account, error)
+}
+
+var (
+	ErrMemberNotFound = errors.New("account member not found")
+	ErrMemberExists   = errors.New("account member already exists")
+)
+
+const createAccountMemberQuery = `
+	INSERT INTO account_members (account_id, user_id, access, invited_by) 
+		VALUES (:account_id, :user_id, :access, :invited_by) 
+	RETURNING status, created_at;
+`
+
+func (d *database) CreateAccountMember(ctx context.Context, member *model.AccountMember) error {
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		rows, err := sqlx.NamedQueryContext(ctx, tx, createAccountMemberQuery, member)
+		if err != nil {
+			if pqError, ok := err.(*pq.Error); ok {
+				if pqError.Code.Name() == UniqueViolation {
+					return nil, ErrMemberExists
+				}
+			}
+			return nil, errors.Wrap(err, "could not create account member")
+		}
+
+		for rows.Next() {
+			if err := rows.Scan(&member.Status, &member.CreatedAt); err != nil {
+				rows.Close()
+				return nil, errors.Wrap(err, "could not get created account member")
+			}
+		}
+		rows.Close()
+
+		return newAuditEntry(model.AuditEntityAccountMember, model.AuditCreated, memberEntityID(member.AccountID, member.UserID), &member.UserID, nil, member)
+	})
+}
+
+// memberEntityID - member is identified by account and user
+func memberEntityID(accountID model.AccountID, userID model.UserID) string {
+	return string(accountID) + "/" + string(userID)
+}
+
+// changeAccountMember runs query which changes member and logs state before and after it
+func (d *database) changeAccountMember(ctx context.Context, verb string, accountID model.AccountID, userID model.UserID, query string, args ...interface{}) error {
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		before, err := getAccountMember(ctx, tx, accountID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not change account member")
+		}
+
+		if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+			return nil, ErrMemberNotFound
+		}
+
+		var after *model.AccountMember
+		if verb != model.AuditDeleted {
+			if after, err = getAccountMember(ctx, tx, accountID, userID); err != nil {
+				return nil, err
+			}
+		}
+
+		return newAuditEntry(model.AuditEntityAccountMember, verb, memberEntityID(accountID, userID), &userID, before, after)
+	})
+}
+
+const getAccountMemberQuery = `
+	SELECT m.account_id, m.user_id, u.email, m.access, m.status, m.invited_by, m.created_at, m.accepted_at 
+	FROM account_members m 
+		JOIN users u ON u.user_id = m.user_id 
+	WHERE m.account_id = $1 AND m.user_id = $2;
+`
+
+func (d *database) GetAccountMember(ctx context.Context, accountID model.AccountID, userID model.UserID) (*model.AccountMember, error) {
+	return getAccountMember(ctx, d.conn, accountID, userID)
+}
+
+func getAccountMember(ctx context.Context, conn sqlx.QueryerContext, accountID model.AccountID, userID model.UserID) (*model.AccountMember, error) {
+	var member model.AccountMember
+	if err := sqlx.GetContext(ctx, conn, &member, getAccountMemberQuery, accountID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMemberNotFound
+		}
+		return nil, errors.Wrap(err, "could not get account member")
+	}
+
+	return &member, nil
+}
+
+const listAccountMembersQuery = `
+	SELECT m.account_id, m.user_id, u.email, m.access, m.status, m.invited_by, m.created_at, m.accepted_at 
+	FROM account_members m 
+		JOIN users u ON u.user_id = m.user_id 
+	WHERE m.account_id = $1 
+	ORDER BY m.created_at;
+`
+
+func (d *database) ListAccountMembers(ctx context.Context, accountID model.AccountID) ([]*model.AccountMember, error) {
+	var members []*model.AccountMember
+	if err := d.conn.SelectContext(ctx, &members, listAccountMembersQuery, accountID); err != nil {
+		return nil, errors.Wrap(err, "could not list account members")
+	}
+
+	return members, nil
+}
+
+// invitations of deleted accounts are not shown
+const listInvitationsQuery = `
+	SELECT m.account_id, m.user_id, u.email, m.access, m.status, m.invited_by, m.created_at, m.accepted_at 
+	FROM account_members m 
+		JOIN users u ON u.user_id = m.user_id 
+		JOIN accounts a ON a.account_id = m.account_id 
+	WHERE m.user_id = $1 
+		AND m.status = 'pending' 
+		AND a.deleted_at IS NULL 
+	ORDER BY m.created_at;
+`
+
+func (d *database) ListInvitations(ctx context.Context, userID model.UserID) ([]*model.AccountMember, error) {
+	var invitations []*model.AccountMember
+	if err := d.conn.SelectContext(ctx, &invitations, listInvitationsQuery, userID); err != nil {
+		return nil, errors.Wrap(err, "could not list invitations")
+	}
+
+	return invitations, nil
+}
+
+const acceptAccountMemberQuery = `
+	UPDATE account_members 
+	SET status = 'accepted', 
+		accepted_at = NOW() 
+	WHERE account_id = $1 AND user_id = $2 AND status = 'pending';
+`
+
+func (d *database) AcceptAccountMember(ctx context.Context, accountID model.AccountID, userID model.UserID) error {
+	return d.changeAccountMember(ctx, model.AuditUpdated, accountID, userID, acceptAccountMemberQuery, accountID, userID)
+}
+
+const updateAccountMemberAccessQuery = `
+	UPDATE account_members 
+	SET access = $3 
+	WHERE account_id = $1 AND user_id = $2;
+`
+
+func (d *database) UpdateAccountMemberAccess(ctx context.Context, accountID model.AccountID, userID model.UserID, access model.AccountAccess) error {
+	return d.changeAccountMember(ctx, model.AuditUpdated, accountID, userID, updateAccountMemberAccessQuery, accountID, userID, access)
+}
+
+const deleteAccountMemberQuery = `
+	DELETE FROM account_members 
+	WHERE account_id = $1 AND user_id = $2;
+`
+
+func (d *database) DeleteAccountMember(ctx context.Context, accountID model.AccountID, userID model.UserID) error {
+	return d.changeAccountMember(ctx, model.AuditDeleted, accountID, userID, deleteAccountMemberQuery, accountID, userID)
+}
+
+const listSharedAccountsQuery = `
+	SELECT a.account_id, a.user_id, a.start_balance, a.account_type, a.account_name, a.currency, a.institution, a.masked_number, a.credit_limit, a.statement_day, a.interest_rate, a.payment_due_day, a.min_payment_percent, a.min_payment_amount, a.loan_principal, a.loan_term_months, a.loan_start_date, a.created_at, a.deleted_at, m.access 
+	FROM accounts a 
+		JOIN account_members m ON m.account_id = a.account_id 
+	WHERE m.user_id = $1 
+		AND m.status = 'accepted' 
+		AND a.deleted_at IS NULL;
+`
+
+// ListSharedAccounts returns accounts of other users where user is member
+func (d *database) ListSharedAccounts(ctx context.Context, userID model.UserID) ([]*model.Account, error) {
+	var accounts []*model.Account
+	if err := d.conn.SelectContext(ctx, &accounts, listSharedAccountsQuery, userID); err != nil {
+		return nil, errors.Wrap(err, "could not list shared accounts")
+	}
+
+	return accounts, nil
+}
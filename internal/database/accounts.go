@@ -2,7 +2,9 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 
 	"github.com/startdusk/finance-app-backend/internal/model"
@@ -12,29 +14,34 @@ type AccountDB interface {
 	CreateAccount(ctx context.Context, account *model.Account) error
 	UpdateAccount(ctx context.Context, account *model.Account) error
 	GetAccountByID(ctx context.Context, accountID model.AccountID) (*model.Account, error)
+	GetAccountBalance(ctx context.Context, accountID model.AccountID, includePending bool) (model.Money, error)
 	ListAccountsByUserID(ctx context.Context, userID model.UserID) ([]*model.Account, error)
-	DeleteAccount(ctx context.Context, accountID model.AccountID) (bool, error)
+	DeleteAccount(ctx context.Context, accountID model.AccountID, options model.DeleteOptions) (*model.DeleteResult, error)
+	RestoreAccount(ctx context.Context, accountID model.AccountID, state *model.Account, withTransactions bool) (*model.Account, int, error)
 }
 
 const createAccountQuery = `
-	INSERT INTO accounts (user_id, start_balance, account_type, account_name, currency) 
-		VALUES (:user_id, :start_balance, :account_type, :account_name, :currency) 
+	INSERT INTO accounts (user_id, start_balance, account_type, account_name, currency, institution, masked_number, credit_limit, statement_day, interest_rate, payment_due_day, min_payment_percent, min_payment_amount, loan_principal, loan_term_months, loan_start_date) 
+		VALUES (:user_id, :start_balance, :account_type, :account_name, :currency, :institution, :masked_number, :credit_limit, :statement_day, :interest_rate, :payment_due_day, :min_payment_percent, :min_payment_amount, :loan_principal, :loan_term_months, :loan_start_date) 
 	RETURNING account_id;
 `
 
 func (d *database) CreateAccount(ctx context.Context, account *model.Account) error {
-	rows, err := d.conn.NamedQueryContext(ctx, createAccountQuery, account)
-	if err != nil {
-		return err
-	}
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		rows, err := sqlx.NamedQueryContext(ctx, tx, createAccountQuery, account)
+		if err != nil {
+			return nil, err
+		}
 
-	defer rows.Close()
-	rows.Next()
-	if err := rows.Scan(&account.ID); err != nil {
-		return err
-	}
+		defer rows.Close()
+		rows.Next()
+		if err := rows.Scan(&account.ID); err != nil {
+			return nil, err
+		}
+		rows.Close()
 
-	return nil
+		return newAuditEntry(model.AuditEntityAccount, model.AuditCreated, string(account.ID), account.UserID, nil, account)
+	})
 }
 
 const updateAccountQuery = `
@@ -42,26 +49,41 @@ const updateAccountQuery = `
 	SET start_balance = :start_balance, 
 		account_type = :account_type,
 		account_name = :account_name,
-		currency = :currency 
+		currency = :currency, 
+		institution = :institution, 
+		masked_number = :masked_number, 
+		credit_limit = :credit_limit, 
+		statement_day = :statement_day, 
+		interest_rate = :interest_rate, 
+		payment_due_day = :payment_due_day, 
+		min_payment_percent = :min_payment_percent, 
+		min_payment_amount = :min_payment_amount, 
+		loan_principal = :loan_principal, 
+		loan_term_months = :loan_term_months, 
+		loan_start_date = :loan_start_date 
 	WHERE account_id = :account_id;
 `
 
 func (d *database) UpdateAccount(ctx context.Context, account *model.Account) error {
-	result, err := d.conn.NamedExecContext(ctx, updateAccountQuery, account)
-	if err != nil {
-		return err
-	}
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Account
+		if err := tx.GetContext(ctx, &before, getAccountByIDQuery, account.ID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("account not found")
+			}
+			return nil, err
+		}
 
-	rows, err := result.RowsAffected()
-	if err != nil || rows == 0 {
-		return errors.New("account not found")
-	}
+		if _, err := tx.NamedExecContext(ctx, updateAccountQuery, account); err != nil {
+			return nil, err
+		}
 
-	return nil
+		return newAuditEntry(model.AuditEntityAccount, model.AuditUpdated, string(account.ID), before.UserID, &before, account)
+	})
 }
 
 const getAccountByIDQuery = `
-	SELECT account_id, user_id, start_balance, account_type, account_name, currency, created_at, deleted_at 
+	SELECT account_id, user_id, start_balance, account_type, account_name, currency, institution, masked_number, credit_limit, statement_day, interest_rate, payment_due_day, min_payment_percent, min_payment_amount, loan_principal, loan_term_months, loan_start_date, created_at, deleted_at 
 	FROM accounts 
 	WHERE account_id = $1;
 `
@@ -75,8 +97,30 @@ func (d *database) GetAccountByID(ctx context.Context, accountID model.AccountID
 	return &account, nil
 }
 
+// balance is start balance with income added and expenses subtracted, deleted and void transactions don't count,
+// pending ones count when includePending is set (see Transaction.IsCounted).
+// Sell and dividend bring money to investment account like income, buy spends it like expense (see TransactionType.IsInflow).
+const getAccountBalanceQuery = `
+	SELECT a.start_balance + COALESCE(SUM(CASE WHEN t.type IN ('income', 'sell', 'dividend') THEN t.amount ELSE -t.amount END), 0) 
+	FROM accounts a 
+		LEFT JOIN transactions t ON t.account_id = a.account_id 
+			AND t.deleted_at IS NULL 
+			AND (t.status = 'posted' OR ($2 AND t.status = 'pending')) 
+	WHERE a.account_id = $1 
+	GROUP BY a.account_id;
+`
+
+func (d *database) GetAccountBalance(ctx context.Context, accountID model.AccountID, includePending bool) (model.Money, error) {
+	var balance model.Money
+	if err := d.conn.GetContext(ctx, &balance, getAccountBalanceQuery, accountID, includePending); err != nil {
+		return balance, errors.Wrap(err, "could not get account balance")
+	}
+
+	return balance, nil
+}
+
 const listAccountByUserIDQuery = `
-	SELECT account_id, user_id, start_balance, account_type, account_name, currency, created_at, deleted_at 
+	SELECT account_id, user_id, start_balance, account_type, account_name, currency, institution, masked_number, credit_limit, statement_day, interest_rate, payment_due_day, min_payment_percent, min_payment_amount, loan_principal, loan_term_months, loan_start_date, created_at, deleted_at 
 	FROM accounts 
 	WHERE user_id = $1 AND deleted_at IS NULL;
 `
@@ -97,16 +141,170 @@ const deleteAccountQuery = `
 	WHERE account_id = $1;
 `
 
-func (d *database) DeleteAccount(ctx context.Context, accountID model.AccountID) (bool, error) {
-	result, err := d.conn.ExecContext(ctx, deleteAccountQuery, accountID)
+// ErrInvalidTarget - account or category can't pass its transactions to itself (or category to its child)
+var ErrInvalidTarget = errors.New("invalid target of reassign")
+
+const listAccountTransactionsQuery = `
+	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, status, symbol, quantity, cleared_status, created_at, deleted_at 
+	FROM transactions 
+	WHERE account_id = $1 AND deleted_at IS NULL;
+`
+
+// DeleteAccount deletes account and handles its transactions by strategy of options.
+// Refused delete returns ErrHasDependents with count of transactions. Target of reassign must be checked by caller.
+func (d *database) DeleteAccount(ctx context.Context, accountID model.AccountID, options model.DeleteOptions) (*model.DeleteResult, error) {
+	result := &model.DeleteResult{}
+	err := d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Account
+		if err := tx.GetContext(ctx, &before, getAccountByIDQuery, accountID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		// deleting again would change deleted_at, transactions deleted with account couldn't be restored with it
+		if before.DeletedAt != nil {
+			return nil, nil
+		}
+
+		var transactions []*model.Transaction
+		if err := tx.SelectContext(ctx, &transactions, listAccountTransactionsQuery, accountID); err != nil {
+			return nil, err
+		}
+		result.Transactions = int64(len(transactions))
+
+		switch options.Strategy {
+		case model.DeleteCascade:
+			if err := deleteTransactionsInTx(ctx, tx, transactions); err != nil {
+				return nil, err
+			}
+		case model.DeleteReassign:
+			if model.AccountID(options.Target) == accountID {
+				return nil, ErrInvalidTarget
+			}
+			target := model.AccountID(options.Target)
+			if err := moveTransactionsInTx(ctx, tx, transactions, func(transaction *model.Transaction) {
+				transaction.AccountID = &target
+			}); err != nil {
+				return nil, err
+			}
+		default:
+			if len(transactions) > 0 {
+				return nil, ErrHasDependents
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteAccountQuery, accountID); err != nil {
+			return nil, err
+		}
+		result.Deleted = true
+
+		return newAuditEntry(model.AuditEntityAccount, model.AuditDeleted, string(accountID), before.UserID, &before, nil)
+	})
 	if err != nil {
-		return false, err
+		return result, err
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil || rows == 0 {
-		return false, err
+	return result, nil
+}
+
+// restored account gets data of revision back and isn't deleted anymore
+const restoreAccountQuery = `
+	UPDATE accounts 
+	SET start_balance = :start_balance, 
+		account_type = :account_type, 
+		account_name = :account_name, 
+		currency = :currency, 
+		institution = :institution, 
+		masked_number = :masked_number, 
+		credit_limit = :credit_limit, 
+		statement_day = :statement_day, 
+		interest_rate = :interest_rate, 
+		payment_due_day = :payment_due_day, 
+		min_payment_percent = :min_payment_percent, 
+		min_payment_amount = :min_payment_amount, 
+		loan_principal = :loan_principal, 
+		loan_term_months = :loan_term_months, 
+		loan_start_date = :loan_start_date, 
+		deleted_at = NULL 
+	WHERE account_id = :account_id;
+`
+
+// transactions deleted together with account have the same deleted_at, NOW() is the same in one database transaction
+const listTransactionsDeletedWithAccountQuery = `
+	SELECT t.transaction_id, t.user_id, t.account_id, t.category_id, t.date, t.type, t.amount, t.notes, t.status, t.symbol, t.quantity, t.cleared_status, t.created_at, t.deleted_at 
+	FROM transactions t 
+		JOIN accounts a ON a.account_id = t.account_id 
+	WHERE t.account_id = $1 
+		AND t.deleted_at = a.deleted_at;
+`
+
+const undeleteTransactionQuery = `
+	UPDATE transactions 
+	SET deleted_at = NULL 
+	WHERE transaction_id = $1;
+`
+
+// RestoreAccount undeletes account and sets its data to state, nil state keeps current data. Owner is never changed.
+// withTransactions also undeletes transactions which were deleted together with account, it returns their count.
+func (d *database) RestoreAccount(ctx context.Context, accountID model.AccountID, state *model.Account, withTransactions bool) (*model.Account, int, error) {
+	var restored model.Account
+	restoredTransactions := 0
+	err := d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Account
+		if err := tx.GetContext(ctx, &before, getAccountByIDQuery, accountID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("account not found")
+			}
+			return nil, err
+		}
+
+		// has to be read before account's deleted_at is cleared
+		var transactions []*model.Transaction
+		if withTransactions && before.DeletedAt != nil {
+			if err := tx.SelectContext(ctx, &transactions, listTransactionsDeletedWithAccountQuery, accountID); err != nil {
+				return nil, err
+			}
+		}
+
+		next := before
+		if state != nil {
+			next = *state
+		}
+		next.ID = before.ID
+		next.UserID = before.UserID
+
+		if _, err := tx.NamedExecContext(ctx, restoreAccountQuery, &next); err != nil {
+			return nil, err
+		}
+
+		for _, transaction := range transactions {
+			if _, err := tx.ExecContext(ctx, undeleteTransactionQuery, transaction.ID); err != nil {
+				return nil, err
+			}
+
+			after := *transaction
+			after.DeletedAt = nil
+			entry, err := newAuditEntry(model.AuditEntityTransaction, model.AuditRestored, string(transaction.ID), transaction.UserID, transaction, &after)
+			if err != nil {
+				return nil, err
+			}
+			if err := writeAudit(ctx, tx, entry); err != nil {
+				return nil, err
+			}
+			restoredTransactions++
+		}
+
+		if err := tx.GetContext(ctx, &restored, getAccountByIDQuery, accountID); err != nil {
+			return nil, err
+		}
+
+		return newAuditEntry(model.AuditEntityAccount, model.AuditRestored, string(accountID), before.UserID, &before, &restored)
+	})
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "could not restore account")
 	}
 
-	return true, nil
+	return &restored, restoredTransactions, nil
 }
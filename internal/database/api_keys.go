@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// APIKeyDB persist personal API keys
+type APIKeyDB interface {
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+	UpdateAPIKey(ctx context.Context, key *model.APIKey) error
+	GetAPIKey(ctx context.Context, userID model.UserID, keyID model.APIKeyID) (*model.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash []byte) (*model.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID model.UserID) ([]*model.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID model.UserID, keyID model.APIKeyID) error
+	TouchAPIKey(ctx context.Context, keyID model.APIKeyID) error
+}
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+const createAPIKeyQuery = `
+	INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at) 
+		VALUES (:user_id, :name, :prefix, :key_hash, :scopes, :expires_at) 
+	RETURNING api_key_id, created_at;
+`
+
+func (d *database) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		rows, err := sqlx.NamedQueryContext(ctx, tx, createAPIKeyQuery, key)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create api key")
+		}
+
+		for rows.Next() {
+			if err := rows.Scan(&key.ID, &key.CreatedAt); err != nil {
+				rows.Close()
+				return nil, errors.Wrap(err, "could not get created api key")
+			}
+		}
+		rows.Close()
+
+		return newAuditEntry(model.AuditEntityAPIKey, model.AuditCreated, string(key.ID), &key.UserID, nil, key)
+	})
+}
+
+const updateAPIKeyQuery = `
+	UPDATE api_keys 
+	SET name = :name, 
+		scopes = :scopes, 
+		expires_at = :expires_at 
+	WHERE api_key_id = :api_key_id AND user_id = :user_id;
+`
+
+func (d *database) UpdateAPIKey(ctx context.Context, key *model.APIKey) error {
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		before, err := getAPIKey(ctx, tx, key.UserID, key.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.NamedExecContext(ctx, updateAPIKeyQuery, key); err != nil {
+			return nil, errors.Wrap(err, "could not update api key")
+		}
+
+		after, err := getAPIKey(ctx, tx, key.UserID, key.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return newAuditEntry(model.AuditEntityAPIKey, model.AuditUpdated, string(key.ID), &key.UserID, before, after)
+	})
+}
+
+const getAPIKeyQuery = `
+	SELECT api_key_id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at 
+	FROM api_keys 
+	WHERE api_key_id = $1 AND user_id = $2;
+`
+
+func (d *database) GetAPIKey(ctx context.Context, userID model.UserID, keyID model.APIKeyID) (*model.APIKey, error) {
+	return getAPIKey(ctx, d.conn, userID, keyID)
+}
+
+func getAPIKey(ctx context.Context, conn sqlx.QueryerContext, userID model.UserID, keyID model.APIKeyID) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := sqlx.GetContext(ctx, conn, &key, getAPIKeyQuery, keyID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, errors.Wrap(err, "could not get api key")
+	}
+
+	return &key, nil
+}
+
+// keys of deleted users can't be used
+const getAPIKeyByHashQuery = `
+	SELECT k.api_key_id, k.user_id, k.name, k.prefix, k.key_hash, k.scopes, k.expires_at, k.last_used_at, k.created_at 
+	FROM api_keys k 
+		JOIN users u ON u.user_id = k.user_id 
+	WHERE k.key_hash = $1 AND u.deleted_at IS NULL;
+`
+
+func (d *database) GetAPIKeyByHash(ctx context.Context, keyHash []byte) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := d.conn.GetContext(ctx, &key, getAPIKeyByHashQuery, keyHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, errors.Wrap(err, "could not get api key")
+	}
+
+	return &key, nil
+}
+
+const listAPIKeysQuery = `
+	SELECT api_key_id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at 
+	FROM api_keys 
+	WHERE user_id = $1 
+	ORDER BY created_at;
+`
+
+func (d *database) ListAPIKeys(ctx context.Context, userID model.UserID) ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	if err := d.conn.SelectContext(ctx, &keys, listAPIKeysQuery, userID); err != nil {
+		return nil, errors.Wrap(err, "could not list api keys")
+	}
+
+	return keys, nil
+}
+
+const deleteAPIKeyQuery = `
+	DELETE FROM api_keys 
+	WHERE api_key_id = $1 AND user_id = $2;
+`
+
+func (d *database) DeleteAPIKey(ctx context.Context, userID model.UserID, keyID model.APIKeyID) error {
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		before, err := getAPIKey(ctx, tx, userID, keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteAPIKeyQuery, keyID, userID); err != nil {
+			return nil, errors.Wrap(err, "could not delete api key")
+		}
+
+		return newAuditEntry(model.AuditEntityAPIKey, model.AuditDeleted, string(keyID), &userID, before, nil)
+	})
+}
+
+// scripts can send many requests, we don't need to write last use time more often than once a minute
+const touchAPIKeyQuery = `
+	UPDATE api_keys 
+	SET last_used_at = NOW() 
+	WHERE api_key_id = $1 
+		AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+`
+
+func (d *database) TouchAPIKey(ctx context.Context, keyID model.APIKeyID) error {
+	if _, err := d.conn.ExecContext(ctx, touchAPIKeyQuery, keyID); err != nil {
+		return errors.Wrap(err, "could not update api key last use")
+	}
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// AuditDB persist audit log
+type AuditDB interface {
+	CreateAuditEntry(ctx context.Context, entry *model.AuditEntry) error
+	ListAuditEntries(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEntry, error)
+}
+
+// AuditActor is who makes changes, API puts it to context of request
+type AuditActor struct {
+	UserID         model.UserID
+	IP             string
+	DeviceID       model.DeviceID
+	ImpersonatorID model.UserID
+}
+
+type auditActorContextKeyType struct{}
+
+var auditActorContextKey auditActorContextKeyType
+
+// WithAuditActor returns context with actor, every change made with this context is logged with actor
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorContextKey, actor)
+}
+
+func auditActorFromContext(ctx context.Context) AuditActor {
+	actor, _ := ctx.Value(auditActorContextKey).(AuditActor)
+	return actor
+}
+
+const createAuditEntryQuery = `
+	INSERT INTO audit_log (action, user_id, actor_id, ip, device_id, impersonator_id, details, entity_type, entity_id, before, after, diff)
+		VALUES (:action, :user_id, :actor_id, :ip, :device_id, :impersonator_id, :details, :entity_type, :entity_id, :before, :after, :diff)
+	RETURNING audit_id, created_at;
+`
+
+// CreateAuditEntry writes entry, actor fields which are not set are taken from context
+func (d *database) CreateAuditEntry(ctx context.Context, entry *model.AuditEntry) error {
+	fillAuditActor(ctx, entry)
+	return insertAuditEntry(ctx, d.conn, entry)
+}
+
+func fillAuditActor(ctx context.Context, entry *model.AuditEntry) {
+	actor := auditActorFromContext(ctx)
+	if entry.ActorID == nil && actor.UserID != model.NilUserID {
+		entry.ActorID = &actor.UserID
+	}
+	if entry.IP == nil && actor.IP != "" {
+		entry.IP = &actor.IP
+	}
+	if entry.DeviceID == nil && actor.DeviceID != model.NilDeviceID {
+		entry.DeviceID = &actor.DeviceID
+	}
+	if entry.ImpersonatorID == nil && actor.ImpersonatorID != model.NilUserID {
+		entry.ImpersonatorID = &actor.ImpersonatorID
+	}
+}
+
+func insertAuditEntry(ctx context.Context, conn sqlx.ExtContext, entry *model.AuditEntry) error {
+	rows, err := sqlx.NamedQueryContext(ctx, conn, createAuditEntryQuery, entry)
+	if err != nil {
+		return errors.Wrap(err, "could not create audit entry")
+	}
+
+	defer rows.Close()
+	rows.Next()
+	if err := rows.Scan(&entry.ID, &entry.CreatedAt); err != nil {
+		return errors.Wrap(err, "could not get created audit entry id")
+	}
+
+	return nil
+}
+
+// audited runs change in transaction and writes audit entry returned by change in the same transaction,
+// so we never have change without audit entry or entry of change which was rolled back.
+// Changes of versioned entities also get their revision.
+// change returns nil entry when nothing was changed.
+func (d *database) audited(ctx context.Context, change func(tx *sqlx.Tx) (*model.AuditEntry, error)) error {
+	return d.transact(ctx, nil, func(tx *sqlx.Tx) error {
+		entry, err := change(tx)
+		if err != nil || entry == nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, entry)
+	})
+}
+
+// writeAudit writes entry with actor from context and revision of versioned entity,
+// changes which touch more entities in one transaction use it for every other entity
+func writeAudit(ctx context.Context, tx *sqlx.Tx, entry *model.AuditEntry) error {
+	fillAuditActor(ctx, entry)
+	if err := insertAuditEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return insertRevision(ctx, tx, entry)
+}
+
+// newAuditEntry creates entry of entity change, before is nil for created entity and after is nil for deleted one
+func newAuditEntry(entity model.AuditEntity, verb string, entityID string, userID *model.UserID, before, after interface{}) (*model.AuditEntry, error) {
+	entry := &model.AuditEntry{
+		Action:     entity.Action(verb),
+		UserID:     userID,
+		EntityType: &entity,
+		EntityID:   &entityID,
+	}
+
+	var err error
+	if entry.Before, err = marshalAuditState(before); err != nil {
+		return nil, err
+	}
+	if entry.After, err = marshalAuditState(after); err != nil {
+		return nil, err
+	}
+	if entry.Diff, err = auditDiff(entry.Before, entry.After); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func marshalAuditState(state interface{}) (model.JSON, error) {
+	if state == nil || reflect.ValueOf(state).Kind() == reflect.Ptr && reflect.ValueOf(state).IsNil() {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode audit state")
+	}
+	return data, nil
+}
+
+// auditDiff compares top level fields of JSON objects
+func auditDiff(before, after model.JSON) (model.JSON, error) {
+	beforeFields := make(map[string]json.RawMessage)
+	afterFields := make(map[string]json.RawMessage)
+
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &beforeFields); err != nil {
+			return nil, errors.Wrap(err, "could not decode audit state")
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &afterFields); err != nil {
+			return nil, errors.Wrap(err, "could not decode audit state")
+		}
+	}
+
+	type change struct {
+		Before json.RawMessage `json:"before,omitempty"`
+		After  json.RawMessage `json:"after,omitempty"`
+	}
+
+	diff := make(map[string]change)
+	for field, value := range beforeFields {
+		if afterValue, ok := afterFields[field]; !ok || string(afterValue) != string(value) {
+			diff[field] = change{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			diff[field] = change{After: value}
+		}
+	}
+
+	data, err := json.Marshal(diff)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode audit diff")
+	}
+	return data, nil
+}
+
+const listAuditEntriesQuery = `
+	SELECT audit_id, created_at, action, user_id, actor_id, ip, device_id, impersonator_id, details, entity_type, entity_id, before, after, diff
+	FROM audit_log
+`
+
+// default and maximum number of entries returned by ListAuditEntries
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// ListAuditEntries returns newest entries first
+func (d *database) ListAuditEntries(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.UserID != model.NilUserID {
+		where("user_id = $%d", filter.UserID)
+	}
+	if filter.ActorID != model.NilUserID {
+		where("actor_id = $%d", filter.ActorID)
+	}
+	if filter.ImpersonatorID != model.NilUserID {
+		where("impersonator_id = $%d", filter.ImpersonatorID)
+	}
+	if filter.EntityType != "" {
+		where("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		where("entity_id = $%d", filter.EntityID)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at < $%d", filter.To)
+	}
+
+	query := listAuditEntriesQuery
+	if len(conditions) > 0 {
+		query += "WHERE " + strings.Join(conditions, " AND ") + " "
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	query += fmt.Sprintf("ORDER BY created_at DESC, audit_id LIMIT %d OFFSET %d;", limit, offset)
+
+	var entries []*model.AuditEntry
+	if err := d.conn.SelectContext(ctx, &entries, query, args...); err != nil {
+		return nil, errors.Wrap(err, "could not list audit entries")
+	}
+
+	return entries, nil
+}
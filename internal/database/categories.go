@@ -2,7 +2,10 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 
 	"github.com/startdusk/finance-app-backend/internal/model"
@@ -13,7 +16,8 @@ type CategoryDB interface {
 	UpdateCategory(ctx context.Context, category *model.Category) error
 	GetCategoryByID(ctx context.Context, categoryID model.CategoryID) (*model.Category, error)
 	ListCategoriesByUserID(ctx context.Context, userID model.UserID) ([]*model.Category, error)
-	DeleteCategory(ctx context.Context, categoryID model.CategoryID) (bool, error)
+	DeleteCategory(ctx context.Context, categoryID model.CategoryID, options model.DeleteOptions) (*model.DeleteResult, error)
+	RestoreCategory(ctx context.Context, categoryID model.CategoryID, state *model.Category) (*model.Category, error)
 }
 
 const createCategoryQuery = `
@@ -23,18 +27,21 @@ const createCategoryQuery = `
 `
 
 func (d *database) CreateCategory(ctx context.Context, category *model.Category) error {
-	rows, err := d.conn.NamedQueryContext(ctx, createCategoryQuery, category)
-	if err != nil {
-		return err
-	}
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		rows, err := sqlx.NamedQueryContext(ctx, tx, createCategoryQuery, category)
+		if err != nil {
+			return nil, err
+		}
 
-	defer rows.Close()
-	rows.Next()
-	if err := rows.Scan(&category.ID); err != nil {
-		return err
-	}
+		defer rows.Close()
+		rows.Next()
+		if err := rows.Scan(&category.ID); err != nil {
+			return nil, err
+		}
+		rows.Close()
 
-	return nil
+		return newAuditEntry(model.AuditEntityCategory, model.AuditCreated, string(category.ID), category.UserID, nil, category)
+	})
 }
 
 const updateCategoryQuery = `
@@ -45,17 +52,21 @@ const updateCategoryQuery = `
 `
 
 func (d *database) UpdateCategory(ctx context.Context, category *model.Category) error {
-	result, err := d.conn.NamedExecContext(ctx, updateCategoryQuery, category)
-	if err != nil {
-		return err
-	}
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Category
+		if err := tx.GetContext(ctx, &before, getCategoryByIDQuery, category.ID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("category not found")
+			}
+			return nil, err
+		}
 
-	rows, err := result.RowsAffected()
-	if err != nil || rows == 0 {
-		return errors.New("category not found")
-	}
+		if _, err := tx.NamedExecContext(ctx, updateCategoryQuery, category); err != nil {
+			return nil, err
+		}
 
-	return nil
+		return newAuditEntry(model.AuditEntityCategory, model.AuditUpdated, string(category.ID), before.UserID, &before, category)
+	})
 }
 
 const getCategoryByIDQuery = `
@@ -95,16 +106,197 @@ const deleteCategoryQuery = `
 	WHERE category_id = $1;
 `
 
-func (d *database) DeleteCategory(ctx context.Context, categoryID model.CategoryID) (bool, error) {
-	result, err := d.conn.ExecContext(ctx, deleteCategoryQuery, categoryID)
+// all not deleted categories under category, UNION stops on cycles of parents
+const listCategoryDescendantsQuery = `
+	WITH RECURSIVE tree AS (
+		SELECT category_id FROM categories WHERE category_id = $1 
+		UNION 
+		SELECT c.category_id 
+		FROM categories c 
+			JOIN tree ON c.parent_id = tree.category_id::text 
+		WHERE c.deleted_at IS NULL 
+	) 
+	SELECT category_id, parent_id, user_id, name, created_at, deleted_at 
+	FROM categories 
+	WHERE category_id IN (SELECT category_id FROM tree) AND category_id <> $1;
+`
+
+const listCategoriesTransactionsQuery = `
+	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, status, symbol, quantity, cleared_status, created_at, deleted_at 
+	FROM transactions 
+	WHERE category_id = ANY($1) AND deleted_at IS NULL;
+`
+
+const moveCategoryQuery = `
+	UPDATE categories 
+	SET parent_id = $2 
+	WHERE category_id = $1;
+`
+
+// DeleteCategory deletes category and handles its transactions and child categories by strategy of options:
+// cascade deletes whole subtree with transactions, reassign moves transactions and direct children to target.
+// Refused delete returns ErrHasDependents with counts. Target of reassign must be checked by caller.
+func (d *database) DeleteCategory(ctx context.Context, categoryID model.CategoryID, options model.DeleteOptions) (*model.DeleteResult, error) {
+	result := &model.DeleteResult{}
+	err := d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Category
+		if err := tx.GetContext(ctx, &before, getCategoryByIDQuery, categoryID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		var descendants []*model.Category
+		if err := tx.SelectContext(ctx, &descendants, listCategoryDescendantsQuery, categoryID); err != nil {
+			return nil, err
+		}
+
+		var children []*model.Category
+		for _, category := range descendants {
+			if category.ParentID == categoryID {
+				children = append(children, category)
+			}
+		}
+
+		// only cascade touches transactions of whole subtree
+		categoryIDs := []string{string(categoryID)}
+		if options.Strategy == model.DeleteCascade {
+			for _, category := range descendants {
+				categoryIDs = append(categoryIDs, string(category.ID))
+			}
+		}
+
+		var transactions []*model.Transaction
+		if err := tx.SelectContext(ctx, &transactions, listCategoriesTransactionsQuery, pq.Array(categoryIDs)); err != nil {
+			return nil, err
+		}
+		result.Transactions = int64(len(transactions))
+
+		switch options.Strategy {
+		case model.DeleteCascade:
+			result.Categories = int64(len(descendants))
+			if err := deleteTransactionsInTx(ctx, tx, transactions); err != nil {
+				return nil, err
+			}
+			for _, category := range descendants {
+				if err := changeCategoryInTx(ctx, tx, category, model.AuditDeleted, deleteCategoryQuery, category.ID); err != nil {
+					return nil, err
+				}
+			}
+		case model.DeleteReassign:
+			target := model.CategoryID(options.Target)
+			// category can't be moved under itself
+			if target == categoryID {
+				return nil, ErrInvalidTarget
+			}
+			for _, category := range descendants {
+				if category.ID == target {
+					return nil, ErrInvalidTarget
+				}
+			}
+
+			result.Categories = int64(len(children))
+			if err := moveTransactionsInTx(ctx, tx, transactions, func(transaction *model.Transaction) {
+				transaction.CategoryID = &target
+			}); err != nil {
+				return nil, err
+			}
+			for _, category := range children {
+				if err := changeCategoryInTx(ctx, tx, category, model.AuditUpdated, moveCategoryQuery, category.ID, target); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			result.Categories = int64(len(children))
+			if len(transactions) > 0 || len(children) > 0 {
+				return nil, ErrHasDependents
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteCategoryQuery, categoryID); err != nil {
+			return nil, err
+		}
+		result.Deleted = true
+
+		return newAuditEntry(model.AuditEntityCategory, model.AuditDeleted, string(categoryID), before.UserID, &before, nil)
+	})
 	if err != nil {
-		return false, err
+		return result, err
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil || rows == 0 {
-		return false, err
+	return result, nil
+}
+
+// changeCategoryInTx runs query which deletes or moves child category of deleted category and audits it
+func changeCategoryInTx(ctx context.Context, tx *sqlx.Tx, category *model.Category, verb string, query string, args ...interface{}) error {
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "could not change child category")
+	}
+
+	var after *model.Category
+	if verb != model.AuditDeleted {
+		after = &model.Category{}
+		if err := tx.GetContext(ctx, after, getCategoryByIDQuery, category.ID); err != nil {
+			return errors.Wrap(err, "could not get child category")
+		}
+	}
+
+	entry, err := newAuditEntry(model.AuditEntityCategory, verb, string(category.ID), category.UserID, category, after)
+	if err != nil {
+		return err
+	}
+	return writeAudit(ctx, tx, entry)
+}
+
+// deleted categorys can be restored
+const getCategoryWithDeletedQuery = `
+	SELECT category_id, parent_id, user_id, name, created_at, deleted_at 
+	FROM categories 
+	WHERE category_id = $1;
+`
+
+// restored category gets data of revision back and isn't deleted anymore
+const restoreCategoryQuery = `
+	UPDATE categories 
+	SET parent_id = :parent_id, 
+		name = :name, 
+		deleted_at = NULL 
+	WHERE category_id = :category_id;
+`
+
+// RestoreCategory undeletes category and sets its data to state, nil state keeps current data. Owner is never changed.
+func (d *database) RestoreCategory(ctx context.Context, categoryID model.CategoryID, state *model.Category) (*model.Category, error) {
+	var restored model.Category
+	err := d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Category
+		if err := tx.GetContext(ctx, &before, getCategoryWithDeletedQuery, categoryID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("category not found")
+			}
+			return nil, err
+		}
+
+		next := before
+		if state != nil {
+			next = *state
+		}
+		next.ID = before.ID
+		next.UserID = before.UserID
+
+		if _, err := tx.NamedExecContext(ctx, restoreCategoryQuery, &next); err != nil {
+			return nil, err
+		}
+
+		if err := tx.GetContext(ctx, &restored, getCategoryWithDeletedQuery, categoryID); err != nil {
+			return nil, err
+		}
+
+		return newAuditEntry(model.AuditEntityCategory, model.AuditRestored, string(categoryID), before.UserID, &before, &restored)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not restore category")
 	}
 
-	return true, nil
+	return &restored, nil
 }
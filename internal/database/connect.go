@@ -13,19 +13,40 @@ const UniqueViolation = "unique_violation"
 type Database interface {
 	UsersDB
 	SessionDB
+	APIKeyDB
 	UserRoleDB
+	RoleDB
+	UserIdentityDB
+	TwoFactorDB
+	LoginAttemptDB
 	AccountDB
+	AccountMemberDB
 	CategoryDB
 	MerchantDB
 	TransactionDB
+	PriceDB
+	ReconciliationDB
+	AuditDB
+	RevisionDB
+	OwnerDB
+	TrashDB
+	ExportDB
+	ErasureDB
+	TxDB
 
 	io.Closer
 }
 
 type database struct {
-	conn *sqlx.DB
+	db   *sqlx.DB
+	conn conn     // db or tx when database is in transaction
+	tx   *sqlx.Tx // nil outside of transaction
 }
 
+// Close closes connection pool, database in transaction belongs to WithTx and is not closed
 func (d *database) Close() error {
-	return d.conn.Close()
+	if d.tx != nil {
+		return nil
+	}
+	return d.db.Close()
 }
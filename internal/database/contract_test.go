@@ -0,0 +1,670 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/config"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// Contract tests run against every implementation of Database, so in-memory one used by API tests
+// behaves like Postgres. Postgres is tested only when TEST_DATABASE_URL is set, its data is not removed.
+func contractDatabases(t *testing.T) map[string]Database {
+	databases := map[string]Database{
+		"memory": NewMemory(),
+	}
+
+	if url := os.Getenv("TEST_DATABASE_URL"); url != "" {
+		*databaseURL = url
+		if *config.DataDirectory == "" {
+			root, err := filepath.Abs("../..")
+			if err != nil {
+				t.Fatal(err)
+			}
+			*config.DataDirectory = root
+		}
+
+		db, err := New()
+		if err != nil {
+			t.Fatalf("could not connect to Postgres: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		databases["postgres"] = db
+	}
+
+	return databases
+}
+
+func TestContract(t *testing.T) {
+	tests := map[string]func(t *testing.T, db Database){
+		"users":                testUsers,
+		"sessions":             testSessions,
+		"roles":                testRoles,
+		"accounts":             testAccounts,
+		"account balance":      testAccountBalance,
+		"categories":           testCategories,
+		"merchants":            testMerchants,
+		"transactions":         testTransactions,
+		"transaction window":   testTransactionWindow,
+		"delete strategies":    testDeleteStrategies,
+		"transaction rollback": testRollback,
+		"prices":               testPrices,
+		"reconciliations":      testReconciliations,
+	}
+
+	for name, db := range contractDatabases(t) {
+		db := db
+		t.Run(name, func(t *testing.T) {
+			for name, test := range tests {
+				test := test
+				t.Run(name, func(t *testing.T) {
+					test(t, db)
+				})
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// cents is amount in minor units of currency with 2 decimal places
+func cents(amount int64) *model.Money {
+	return &model.Money{Amount: amount, Exponent: 2}
+}
+
+// newTestUser creates user with unique email, tests share database
+func newTestUser(t *testing.T, db Database) *model.User {
+	t.Helper()
+
+	user := &model.User{
+		Email:        stringPtr(newMemoryID() + "@example.com"),
+		PasswordHash: &[]byte{1, 2, 3},
+	}
+	if err := db.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("could not create user: %v", err)
+	}
+	return user
+}
+
+func newTestAccount(t *testing.T, db Database, userID model.UserID) *model.Account {
+	t.Helper()
+
+	accountType := model.Cash
+	account := &model.Account{
+		UserID:       &userID,
+		Name:         stringPtr("Wallet"),
+		Type:         &accountType,
+		StartBalance: cents(100),
+		Currency:     stringPtr("USD"),
+	}
+	if err := db.CreateAccount(context.Background(), account); err != nil {
+		t.Fatalf("could not create account: %v", err)
+	}
+	return account
+}
+
+func newTestCategory(t *testing.T, db Database, userID model.UserID, parentID model.CategoryID) *model.Category {
+	t.Helper()
+
+	category := &model.Category{
+		UserID:   &userID,
+		ParentID: parentID,
+		Name:     stringPtr("Food"),
+	}
+	if err := db.CreateCategory(context.Background(), category); err != nil {
+		t.Fatalf("could not create category: %v", err)
+	}
+	return category
+}
+
+func newTestTransaction(t *testing.T, db Database, account *model.Account, category *model.Category, date time.Time) *model.Transaction {
+	t.Helper()
+
+	transactionType := model.Expense
+	transaction := &model.Transaction{
+		UserID:     account.UserID,
+		AccountID:  &account.ID,
+		CategoryID: &category.ID,
+		Date:       &date,
+		Type:       &transactionType,
+		Amount:     cents(25),
+		Notes:      stringPtr("lunch"),
+	}
+	if err := db.CreateTransaction(context.Background(), transaction); err != nil {
+		t.Fatalf("could not create transaction: %v", err)
+	}
+	return transaction
+}
+
+func testUsers(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+
+	got, err := db.GetUserByEmail(ctx, *user.Email)
+	if err != nil || got.ID != user.ID {
+		t.Fatalf("GetUserByEmail() = %v, %v; want user %s", got, err, user.ID)
+	}
+
+	if err := db.CreateUser(ctx, &model.User{Email: user.Email, PasswordHash: &[]byte{1}}); err != ErrUserExist {
+		t.Fatalf("CreateUser() with used email = %v; want ErrUserExist", err)
+	}
+
+	deleted, err := db.DeleteUser(ctx, user.ID)
+	if err != nil || !deleted {
+		t.Fatalf("DeleteUser() = %v, %v; want true", deleted, err)
+	}
+	if deleted, err := db.DeleteUser(ctx, user.ID); err != nil || deleted {
+		t.Fatalf("DeleteUser() of deleted user = %v, %v; want false", deleted, err)
+	}
+	if _, err := db.GetUserByID(ctx, user.ID); errors.Cause(err) != sql.ErrNoRows {
+		t.Fatalf("GetUserByID() of deleted user = %v; want sql.ErrNoRows", err)
+	}
+
+	// deleted user frees his email
+	again := &model.User{Email: user.Email, PasswordHash: &[]byte{1}}
+	if err := db.CreateUser(ctx, again); err != nil {
+		t.Fatalf("CreateUser() with email of deleted user = %v", err)
+	}
+	if again.ID == user.ID {
+		t.Fatal("CreateUser() reused id of deleted user")
+	}
+}
+
+func testSessions(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+
+	active := model.Session{UserID: user.ID, DeviceID: "phone", RefreshToken: "active", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	expired := model.Session{UserID: user.ID, DeviceID: "laptop", RefreshToken: "expired", ExpiresAt: time.Now().Add(-time.Hour).Unix()}
+	for _, session := range []model.Session{active, expired} {
+		if err := db.SaveRefreshToken(ctx, session); err != nil {
+			t.Fatalf("SaveRefreshToken() = %v", err)
+		}
+	}
+
+	if session, err := db.GetSession(ctx, active); err != nil || session.RefreshToken != active.RefreshToken {
+		t.Fatalf("GetSession() = %v, %v; want active session", session, err)
+	}
+	if _, err := db.GetSession(ctx, expired); err != sql.ErrNoRows {
+		t.Fatalf("GetSession() of expired session = %v; want sql.ErrNoRows", err)
+	}
+
+	// saving session of the same device replaces refresh token
+	active.RefreshToken = "rotated"
+	if err := db.SaveRefreshToken(ctx, active); err != nil {
+		t.Fatalf("SaveRefreshToken() = %v", err)
+	}
+	if _, err := db.GetSession(ctx, model.Session{UserID: user.ID, DeviceID: "phone", RefreshToken: "active"}); err != sql.ErrNoRows {
+		t.Fatalf("GetSession() with old token = %v; want sql.ErrNoRows", err)
+	}
+}
+
+func testRoles(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+
+	if err := db.GrantRole(ctx, user.ID, model.RoleAdmin); err != nil {
+		t.Fatalf("GrantRole() = %v", err)
+	}
+	roles, err := db.GetRolesByUser(ctx, user.ID)
+	if err != nil || len(roles) != 1 || roles[0].Role != model.RoleAdmin {
+		t.Fatalf("GetRolesByUser() = %v, %v; want admin", roles, err)
+	}
+
+	if err := db.RevokeRole(ctx, user.ID, model.RoleAdmin); err != nil {
+		t.Fatalf("RevokeRole() = %v", err)
+	}
+	if roles, err := db.GetRolesByUser(ctx, user.ID); err != nil || len(roles) != 0 {
+		t.Fatalf("GetRolesByUser() after revoke = %v, %v; want none", roles, err)
+	}
+}
+
+func testAccounts(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+	account := newTestAccount(t, db, user.ID)
+
+	accountType := model.Credit
+	statementDay := 15
+	account.Name = stringPtr("Bank")
+	account.Type = &accountType
+	account.CreditLimit = cents(100000)
+	account.StatementDay = &statementDay
+	if err := db.UpdateAccount(ctx, account); err != nil {
+		t.Fatalf("UpdateAccount() = %v", err)
+	}
+
+	result, err := db.DeleteAccount(ctx, account.ID, model.DeleteOptions{Strategy: model.DeleteRefuse})
+	if err != nil || !result.Deleted {
+		t.Fatalf("DeleteAccount() = %v, %v; want deleted", result, err)
+	}
+
+	// deleted account can be read, but it is not listed
+	got, err := db.GetAccountByID(ctx, account.ID)
+	if err != nil || got.DeletedAt == nil || *got.Name != "Bank" || *got.CreditLimit != *cents(100000) || *got.StatementDay != 15 {
+		t.Fatalf("GetAccountByID() of deleted account = %+v, %v", got, err)
+	}
+	if accounts, err := db.ListAccountsByUserID(ctx, user.ID); err != nil || len(accounts) != 0 {
+		t.Fatalf("ListAccountsByUserID() = %v, %v; want none", accounts, err)
+	}
+
+	restored, _, err := db.RestoreAccount(ctx, account.ID, nil, true)
+	if err != nil || restored.DeletedAt != nil {
+		t.Fatalf("RestoreAccount() = %v, %v", restored, err)
+	}
+	if accounts, err := db.ListAccountsByUserID(ctx, user.ID); err != nil || len(accounts) != 1 {
+		t.Fatalf("ListAccountsByUserID() after restore = %v, %v; want 1", accounts, err)
+	}
+
+	revisions, err := db.ListRevisions(ctx, model.AuditEntityAccount, string(account.ID))
+	if err != nil || len(revisions) != 4 || revisions[0].Operation != model.AuditRestored {
+		t.Fatalf("ListRevisions() = %v, %v; want 4 revisions, restored first", revisions, err)
+	}
+}
+
+func testAccountBalance(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+	account := newTestAccount(t, db, user.ID)
+	category := newTestCategory(t, db, user.ID, model.NilCategoryID)
+
+	newTestTransaction(t, db, account, category, time.Now())
+	deleted := newTestTransaction(t, db, account, category, time.Now())
+	if ok, err := db.DeleteTransaction(ctx, deleted.ID); err != nil || !ok {
+		t.Fatalf("DeleteTransaction() = %v, %v", ok, err)
+	}
+
+	// start balance 1.00 minus expense 0.25, deleted transaction doesn't count
+	balance, err := db.GetAccountBalance(ctx, account.ID, false)
+	if err != nil || balance.String() != "0.75" {
+		t.Fatalf("GetAccountBalance() = %v, %v; want 0.75", balance, err)
+	}
+
+	// pending transaction counts only when asked, void one never does
+	pending := newTestTransaction(t, db, account, category, time.Now())
+	pendingStatus := model.Pending
+	pending.Status = &pendingStatus
+	if err := db.UpdateTransaction(ctx, pending); err != nil {
+		t.Fatalf("UpdateTransaction() = %v", err)
+	}
+	if got, err := db.GetTransactionByID(ctx, pending.ID); err != nil || got.Status == nil || *got.Status != model.Pending {
+		t.Fatalf("GetTransactionByID() = %+v, %v; want pending", got, err)
+	}
+	if balance, err := db.GetAccountBalance(ctx, account.ID, false); err != nil || balance.String() != "0.75" {
+		t.Fatalf("GetAccountBalance(false) = %v, %v; want 0.75", balance, err)
+	}
+	if balance, err := db.GetAccountBalance(ctx, account.ID, true); err != nil || balance.String() != "0.50" {
+		t.Fatalf("GetAccountBalance(true) = %v, %v; want 0.50", balance, err)
+	}
+
+	voidStatus := model.Void
+	pending.Status = &voidStatus
+	if err := db.UpdateTransaction(ctx, pending); err != nil {
+		t.Fatalf("UpdateTransaction() = %v", err)
+	}
+	if balance, err := db.GetAccountBalance(ctx, account.ID, true); err != nil || balance.String() != "0.75" {
+		t.Fatalf("GetAccountBalance(true) = %v, %v; want 0.75", balance, err)
+	}
+
+	// sell brings money like income
+	sell := newTestTransaction(t, db, account, category, time.Now())
+	sellType := model.Sell
+	sell.Type = &sellType
+	sell.Symbol = stringPtr("AAPL")
+	sell.Quantity = &model.Money{Amount: 15, Exponent: 1}
+	if err := db.UpdateTransaction(ctx, sell); err != nil {
+		t.Fatalf("UpdateTransaction() = %v", err)
+	}
+	if got, err := db.GetTransactionByID(ctx, sell.ID); err != nil || got.Symbol == nil || *got.Symbol != "AAPL" || got.Quantity.String() != "1.5" {
+		t.Fatalf("GetTransactionByID() = %+v, %v; want 1.5 of AAPL", got, err)
+	}
+	if balance, err := db.GetAccountBalance(ctx, account.ID, false); err != nil || balance.String() != "1.00" {
+		t.Fatalf("GetAccountBalance() = %v, %v; want 1.00", balance, err)
+	}
+}
+
+func testCategories(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+	category := newTestCategory(t, db, user.ID, model.NilCategoryID)
+
+	if _, err := db.DeleteCategory(ctx, category.ID, model.DeleteOptions{Strategy: model.DeleteRefuse}); err != nil {
+		t.Fatalf("DeleteCategory() = %v", err)
+	}
+	if _, err := db.GetCategoryByID(ctx, category.ID); errors.Cause(err) != sql.ErrNoRows {
+		t.Fatalf("GetCategoryByID() of deleted category = %v; want sql.ErrNoRows", err)
+	}
+
+	restored, err := db.RestoreCategory(ctx, category.ID, nil)
+	if err != nil || restored.DeletedAt != nil {
+		t.Fatalf("RestoreCategory() = %v, %v", restored, err)
+	}
+	if categories, err := db.ListCategoriesByUserID(ctx, user.ID); err != nil || len(categories) != 1 {
+		t.Fatalf("ListCategoriesByUserID() = %v, %v; want 1", categories, err)
+	}
+
+	// restored child keeps parent in trash, parent can't be purged until child is deleted again
+	child := newTestCategory(t, db, user.ID, category.ID)
+	if _, err := db.DeleteCategory(ctx, category.ID, model.DeleteOptions{Strategy: model.DeleteCascade}); err != nil {
+		t.Fatalf("DeleteCategory() cascade = %v", err)
+	}
+	if _, err := db.RestoreCategory(ctx, child.ID, nil); err != nil {
+		t.Fatalf("RestoreCategory() of child = %v", err)
+	}
+	if err := db.PurgeDeleted(ctx, model.AuditEntityCategory, string(category.ID)); err != ErrHasChildren {
+		t.Fatalf("PurgeDeleted() of parent = %v; want ErrHasChildren", err)
+	}
+	if _, err := db.DeleteCategory(ctx, child.ID, model.DeleteOptions{Strategy: model.DeleteRefuse}); err != nil {
+		t.Fatalf("DeleteCategory() of child = %v", err)
+	}
+	if err := db.PurgeDeleted(ctx, model.AuditEntityCategory, string(category.ID)); err != nil {
+		t.Fatalf("PurgeDeleted() of parent = %v", err)
+	}
+}
+
+func testMerchants(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+
+	merchant := &model.Merchant{UserID: &user.ID, Name: stringPtr("Shop")}
+	if err := db.CreateMerchant(ctx, merchant); err != nil {
+		t.Fatalf("CreateMerchant() = %v", err)
+	}
+
+	if deleted, err := db.DeleteMerchant(ctx, merchant.ID); err != nil || !deleted {
+		t.Fatalf("DeleteMerchant() = %v, %v; want true", deleted, err)
+	}
+	if deleted, err := db.DeleteMerchant(ctx, merchant.ID); err != nil || deleted {
+		t.Fatalf("DeleteMerchant() of deleted merchant = %v, %v; want false", deleted, err)
+	}
+
+	trash, err := db.ListTrash(ctx, user.ID)
+	if err != nil || len(trash.Merchants) != 1 {
+		t.Fatalf("ListTrash() = %v, %v; want deleted merchant", trash, err)
+	}
+
+	if err := db.PurgeDeleted(ctx, model.AuditEntityMerchant, string(merchant.ID)); err != nil {
+		t.Fatalf("PurgeDeleted() = %v", err)
+	}
+	if revisions, err := db.ListRevisions(ctx, model.AuditEntityMerchant, string(merchant.ID)); err != nil || len(revisions) != 0 {
+		t.Fatalf("ListRevisions() of purged merchant = %v, %v; want none", revisions, err)
+	}
+	if err := db.PurgeDeleted(ctx, model.AuditEntityMerchant, string(merchant.ID)); err != ErrNotInTrash {
+		t.Fatalf("PurgeDeleted() of purged merchant = %v; want ErrNotInTrash", err)
+	}
+}
+
+func testTransactions(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+	account := newTestAccount(t, db, user.ID)
+	category := newTestCategory(t, db, user.ID, model.NilCategoryID)
+	transaction := newTestTransaction(t, db, account, category, time.Date(2020, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	transaction.Amount = cents(40)
+	if err := db.UpdateTransaction(ctx, transaction); err != nil {
+		t.Fatalf("UpdateTransaction() = %v", err)
+	}
+	got, err := db.GetTransactionByID(ctx, transaction.ID)
+	if err != nil || *got.Amount != *cents(40) {
+		t.Fatalf("GetTransactionByID() = %v, %v; want amount 40", got, err)
+	}
+
+	if deleted, err := db.DeleteTransaction(ctx, transaction.ID); err != nil || !deleted {
+		t.Fatalf("DeleteTransaction() = %v, %v; want true", deleted, err)
+	}
+	if _, err := db.GetTransactionByID(ctx, transaction.ID); errors.Cause(err) != sql.ErrNoRows {
+		t.Fatalf("GetTransactionByID() of deleted transaction = %v; want sql.ErrNoRows", err)
+	}
+
+	// restore to first revision brings back old amount
+	restored, err := db.RestoreTransaction(ctx, transaction.ID, &model.Transaction{
+		AccountID:  &account.ID,
+		CategoryID: &category.ID,
+		Date:       transaction.Date,
+		Type:       transaction.Type,
+		Amount:     cents(25),
+		Notes:      transaction.Notes,
+	})
+	if err != nil || restored.DeletedAt != nil || *restored.Amount != *cents(25) {
+		t.Fatalf("RestoreTransaction() = %v, %v; want amount 25", restored, err)
+	}
+}
+
+func testTransactionWindow(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+	account := newTestAccount(t, db, user.ID)
+	category := newTestCategory(t, db, user.ID, model.NilCategoryID)
+
+	from := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	newTestTransaction(t, db, account, category, from)
+	inside := newTestTransaction(t, db, account, category, from.Add(time.Hour))
+	newTestTransaction(t, db, account, category, to)
+
+	lists := map[string]func() ([]*model.Transaction, error){
+		"user": func() ([]*model.Transaction, error) {
+			return db.ListTransactionByUserID(ctx, user.ID, from, to)
+		},
+		"account": func() ([]*model.Transaction, error) {
+			return db.ListTransactionByAccountID(ctx, account.ID, from, to)
+		},
+		"category": func() ([]*model.Transaction, error) {
+			return db.ListTransactionByCategoryID(ctx, category.ID, from, to)
+		},
+	}
+
+	// both ends of window are excluded
+	for name, list := range lists {
+		transactions, err := list()
+		if err != nil || len(transactions) != 1 || transactions[0].ID != inside.ID {
+			t.Errorf("list by %s = %v, %v; want only transaction inside window", name, transactions, err)
+		}
+	}
+}
+
+func testDeleteStrategies(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+	date := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	account := newTestAccount(t, db, user.ID)
+	other := newTestAccount(t, db, user.ID)
+	parent := newTestCategory(t, db, user.ID, model.NilCategoryID)
+	child := newTestCategory(t, db, user.ID, parent.ID)
+	newTestTransaction(t, db, account, child, date)
+
+	result, err := db.DeleteAccount(ctx, account.ID, model.DeleteOptions{Strategy: model.DeleteRefuse})
+	if err != ErrHasDependents || result.Deleted || result.Transactions != 1 {
+		t.Fatalf("DeleteAccount() refuse = %v, %v; want ErrHasDependents", result, err)
+	}
+
+	if _, err := db.DeleteAccount(ctx, account.ID, model.DeleteOptions{Strategy: model.DeleteReassign, Target: string(account.ID)}); err != ErrInvalidTarget {
+		t.Fatalf("DeleteAccount() reassign to itself = %v; want ErrInvalidTarget", err)
+	}
+
+	result, err = db.DeleteAccount(ctx, account.ID, model.DeleteOptions{Strategy: model.DeleteReassign, Target: string(other.ID)})
+	if err != nil || !result.Deleted || result.Transactions != 1 {
+		t.Fatalf("DeleteAccount() reassign = %v, %v", result, err)
+	}
+	if transactions, err := db.ListTransactionByAccountID(ctx, other.ID, date.Add(-time.Hour), date.Add(time.Hour)); err != nil || len(transactions) != 1 {
+		t.Fatalf("transactions of target account = %v, %v; want 1", transactions, err)
+	}
+
+	if _, err := db.DeleteCategory(ctx, parent.ID, model.DeleteOptions{Strategy: model.DeleteReassign, Target: string(child.ID)}); err != ErrInvalidTarget {
+		t.Fatalf("DeleteCategory() reassign to child = %v; want ErrInvalidTarget", err)
+	}
+
+	// cascade deletes child categories and their transactions with the same time, so account restore finds them
+	result, err = db.DeleteCategory(ctx, parent.ID, model.DeleteOptions{Strategy: model.DeleteCascade})
+	if err != nil || !result.Deleted || result.Categories != 1 || result.Transactions != 1 {
+		t.Fatalf("DeleteCategory() cascade = %v, %v", result, err)
+	}
+	if _, err := db.GetCategoryByID(ctx, child.ID); errors.Cause(err) != sql.ErrNoRows {
+		t.Fatalf("GetCategoryByID() of child = %v; want sql.ErrNoRows", err)
+	}
+
+	result, err = db.DeleteAccount(ctx, other.ID, model.DeleteOptions{Strategy: model.DeleteCascade})
+	if err != nil || !result.Deleted || result.Transactions != 0 {
+		t.Fatalf("DeleteAccount() cascade = %v, %v; want no transactions left", result, err)
+	}
+}
+
+func testRollback(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+	failure := errors.New("failure")
+
+	var account *model.Account
+	err := db.WithTx(ctx, func(tx Database) error {
+		account = newTestAccount(t, tx, user.ID)
+		if _, err := tx.GetAccountByID(ctx, account.ID); err != nil {
+			t.Errorf("GetAccountByID() in transaction = %v", err)
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("WithTx() = %v; want error of fn", err)
+	}
+
+	if _, err := db.GetAccountByID(ctx, account.ID); errors.Cause(err) != sql.ErrNoRows {
+		t.Fatalf("GetAccountByID() after rollback = %v; want sql.ErrNoRows", err)
+	}
+	entries, err := db.ListAuditEntries(ctx, model.AuditFilter{EntityType: model.AuditEntityAccount, EntityID: string(account.ID)})
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("ListAuditEntries() after rollback = %v, %v; want none", entries, err)
+	}
+}
+
+func testPrices(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+	other := newTestUser(t, db)
+
+	price := func(userID model.UserID, symbol string, date time.Time, amount int64) *model.Price {
+		return &model.Price{UserID: &userID, Symbol: &symbol, Date: &date, Price: cents(amount)}
+	}
+	day := func(day int) time.Time {
+		return time.Date(2020, 1, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	if err := db.SetPrices(ctx, []*model.Price{
+		price(user.ID, "VTI", day(2), 15000),
+		price(user.ID, "AAPL", day(3), 30000),
+		price(user.ID, "AAPL", day(1), 29000),
+		price(other.ID, "AAPL", day(5), 1),
+	}); err != nil {
+		t.Fatalf("SetPrices() = %v", err)
+	}
+
+	// price on the same day is replaced
+	if err := db.SetPrices(ctx, []*model.Price{price(user.ID, "AAPL", day(3), 31000)}); err != nil {
+		t.Fatalf("SetPrices() = %v", err)
+	}
+
+	prices, err := db.ListPrices(ctx, user.ID, "AAPL")
+	if err != nil || len(prices) != 2 || !prices[0].Date.Equal(day(1)) || prices[1].Price.String() != "310.00" {
+		t.Fatalf("ListPrices() = %v, %v; want 2 prices of AAPL by date", prices, err)
+	}
+	if prices, err := db.ListPrices(ctx, user.ID, ""); err != nil || len(prices) != 3 {
+		t.Fatalf("ListPrices() of all symbols = %v, %v; want 3", prices, err)
+	}
+
+	latest, err := db.ListLatestPrices(ctx, user.ID, day(2))
+	if err != nil || len(latest) != 2 || *latest[0].Symbol != "AAPL" || latest[0].Price.String() != "290.00" || *latest[1].Symbol != "VTI" {
+		t.Fatalf("ListLatestPrices() = %v, %v; want AAPL of day 1 and VTI", latest, err)
+	}
+
+	// nothing is saved when one of prices fails
+	unknown := model.UserID(newMemoryID())
+	if err := db.SetPrices(ctx, []*model.Price{price(user.ID, "MSFT", day(1), 100), price(unknown, "MSFT", day(1), 100)}); err == nil {
+		t.Fatal("SetPrices() of unknown user succeeded")
+	}
+	if prices, err := db.ListPrices(ctx, user.ID, "MSFT"); err != nil || len(prices) != 0 {
+		t.Fatalf("ListPrices() after failed SetPrices() = %v, %v; want none", prices, err)
+	}
+}
+
+func testReconciliations(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := newTestUser(t, db)
+	account := newTestAccount(t, db, user.ID)
+	other := newTestAccount(t, db, user.ID)
+	category := newTestCategory(t, db, user.ID, model.NilCategoryID)
+
+	statementDate := time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)
+	onStatement := newTestTransaction(t, db, account, category, statementDate.Add(12*time.Hour))
+	afterStatement := newTestTransaction(t, db, account, category, statementDate.AddDate(0, 0, 1))
+	inOther := newTestTransaction(t, db, other, category, statementDate)
+	if onStatement.ClearedStatus == nil || *onStatement.ClearedStatus != model.Uncleared {
+		t.Fatalf("CreateTransaction() cleared status = %v; want uncleared", onStatement.ClearedStatus)
+	}
+
+	reconciliation := &model.Reconciliation{UserID: &user.ID, AccountID: &account.ID, StatementDate: &statementDate, EndingBalance: cents(75)}
+	if err := db.CreateReconciliation(ctx, reconciliation); err != nil || reconciliation.ID == model.NilReconciliationID {
+		t.Fatalf("CreateReconciliation() = %v", err)
+	}
+	if err := db.CreateReconciliation(ctx, &model.Reconciliation{UserID: &user.ID, AccountID: &account.ID, StatementDate: &statementDate, EndingBalance: cents(0)}); err == nil {
+		t.Fatal("CreateReconciliation() of second open reconciliation succeeded")
+	}
+
+	// nothing is cleared when one of transactions is in other account
+	if err := db.SetTransactionsCleared(ctx, account.ID, []model.TransactionID{onStatement.ID, inOther.ID}, model.Cleared); err == nil {
+		t.Fatal("SetTransactionsCleared() of transaction in other account succeeded")
+	}
+	if got, err := db.GetTransactionByID(ctx, onStatement.ID); err != nil || *got.ClearedStatus != model.Uncleared {
+		t.Fatalf("GetTransactionByID() after failed SetTransactionsCleared() = %+v, %v; want uncleared", got, err)
+	}
+
+	if err := db.SetTransactionsCleared(ctx, account.ID, []model.TransactionID{onStatement.ID, afterStatement.ID}, model.Cleared); err != nil {
+		t.Fatalf("SetTransactionsCleared() = %v", err)
+	}
+
+	locked, err := db.LockReconciliation(ctx, reconciliation.ID)
+	if err != nil || !locked.IsLocked() {
+		t.Fatalf("LockReconciliation() = %+v, %v; want locked", locked, err)
+	}
+	if _, err := db.LockReconciliation(ctx, reconciliation.ID); err != ErrReconciliationLocked {
+		t.Fatalf("LockReconciliation() again = %v; want ErrReconciliationLocked", err)
+	}
+
+	// only transaction on statement is reconciled
+	if got, err := db.GetTransactionByID(ctx, onStatement.ID); err != nil || *got.ClearedStatus != model.Reconciled {
+		t.Fatalf("GetTransactionByID() = %+v, %v; want reconciled", got, err)
+	}
+	if got, err := db.GetTransactionByID(ctx, afterStatement.ID); err != nil || *got.ClearedStatus != model.Cleared {
+		t.Fatalf("GetTransactionByID() = %+v, %v; want cleared", got, err)
+	}
+
+	nextDate := statementDate.AddDate(0, 1, 0)
+	next := &model.Reconciliation{UserID: &user.ID, AccountID: &account.ID, StatementDate: &nextDate, EndingBalance: cents(50)}
+	if err := db.CreateReconciliation(ctx, next); err != nil {
+		t.Fatalf("CreateReconciliation() after lock = %v", err)
+	}
+
+	reconciliations, err := db.ListReconciliationsByAccountID(ctx, account.ID)
+	if err != nil || len(reconciliations) != 2 || reconciliations[0].ID != next.ID || reconciliations[1].EndingBalance.String() != "0.75" {
+		t.Fatalf("ListReconciliationsByAccountID() = %v, %v; want the latest statement first", reconciliations, err)
+	}
+
+	// purged account takes its reconciliations with it
+	if _, err := db.DeleteAccount(ctx, account.ID, model.DeleteOptions{Strategy: model.DeleteCascade}); err != nil {
+		t.Fatalf("DeleteAccount() of reconciled account = %v", err)
+	}
+	if err := db.PurgeDeleted(ctx, model.AuditEntityAccount, string(account.ID)); err != nil {
+		t.Fatalf("PurgeDeleted() of reconciled account = %v", err)
+	}
+	if reconciliations, err := db.ListReconciliationsByAccountID(ctx, account.ID); err != nil || len(reconciliations) != 0 {
+		t.Fatalf("ListReconciliationsByAccountID() of purged account = %v, %v; want none", reconciliations, err)
+	}
+}
@@ -49,6 +49,7 @@ func New() (Database, error) {
 	}
 
 	d := &database{
+		db:   conn,
 		conn: conn,
 	}
 	return d, nil
@@ -0,0 +1,218 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// ErasureDB persist erasure requests and erases users
+type ErasureDB interface {
+	CreateErasureRequest(ctx context.Context, request *model.ErasureRequest) error
+	GetErasureRequest(ctx context.Context, userID model.UserID) (*model.ErasureRequest, error)
+	CancelErasureRequest(ctx context.Context, userID model.UserID) error
+	ListDueErasureRequests(ctx context.Context, now time.Time) ([]*model.ErasureRequest, error)
+	EraseUser(ctx context.Context, userID model.UserID) error
+}
+
+var (
+	ErrErasureNotFound = errors.New("erasure request not found")
+	ErrErasureExists   = errors.New("erasure request already exists")
+)
+
+const createErasureRequestQuery = `
+	INSERT INTO erasure_requests (user_id, requested_by, scheduled_at) 
+		VALUES (:user_id, :requested_by, :scheduled_at) 
+	RETURNING created_at;
+`
+
+// user can be already deleted by admin, deleteUserQuery would change his email again
+const deleteUserIfActiveQuery = `
+	UPDATE users 
+	SET deleted_at = NOW(), 
+		email = CONCAT(email, '-DELETE-', uuid_generate_v4()) 
+	WHERE user_id = $1 AND deleted_at IS NULL;
+`
+
+// user has to log in again, but he can't - he is deleted
+const deleteUserSessionsQuery = `
+	DELETE FROM sessions 
+	WHERE user_id = $1;
+`
+
+// CreateErasureRequest deletes user right away (he can't log in) and schedules erasure of his data
+func (d *database) CreateErasureRequest(ctx context.Context, request *model.ErasureRequest) error {
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		rows, err := sqlx.NamedQueryContext(ctx, tx, createErasureRequestQuery, request)
+		if err != nil {
+			if pqError, ok := err.(*pq.Error); ok {
+				if pqError.Code.Name() == UniqueViolation {
+					return nil, ErrErasureExists
+				}
+			}
+			return nil, errors.Wrap(err, "could not create erasure request")
+		}
+
+		for rows.Next() {
+			if err := rows.Scan(&request.CreatedAt); err != nil {
+				rows.Close()
+				return nil, errors.Wrap(err, "could not get created erasure request")
+			}
+		}
+		rows.Close()
+
+		if _, err := tx.ExecContext(ctx, deleteUserIfActiveQuery, request.UserID); err != nil {
+			return nil, errors.Wrap(err, "could not delete user")
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteUserSessionsQuery, request.UserID); err != nil {
+			return nil, errors.Wrap(err, "could not delete sessions")
+		}
+
+		return erasureAuditEntry(model.AuditErasureRequested, request)
+	})
+}
+
+func erasureAuditEntry(action model.AuditAction, request *model.ErasureRequest) (*model.AuditEntry, error) {
+	entry := &model.AuditEntry{
+		Action: action,
+		UserID: &request.UserID,
+	}
+
+	var err error
+	if entry.Details, err = marshalAuditState(map[string]interface{}{
+		"scheduledAt": request.ScheduledAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+const getErasureRequestQuery = `
+	SELECT user_id, requested_by, created_at, scheduled_at, completed_at 
+	FROM erasure_requests 
+	WHERE user_id = $1;
+`
+
+func (d *database) GetErasureRequest(ctx context.Context, userID model.UserID) (*model.ErasureRequest, error) {
+	return getErasureRequest(ctx, d.conn, userID)
+}
+
+func getErasureRequest(ctx context.Context, conn sqlx.QueryerContext, userID model.UserID) (*model.ErasureRequest, error) {
+	var request model.ErasureRequest
+	if err := sqlx.GetContext(ctx, conn, &request, getErasureRequestQuery, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrErasureNotFound
+		}
+		return nil, errors.Wrap(err, "could not get erasure request")
+	}
+
+	return &request, nil
+}
+
+const deleteErasureRequestQuery = `
+	DELETE FROM erasure_requests 
+	WHERE user_id = $1 AND completed_at IS NULL;
+`
+
+// deleteUserQuery added suffix to email so it can be used by new user
+const undeleteUserQuery = `
+	UPDATE users 
+	SET deleted_at = NULL, 
+		email = split_part(email, '-DELETE-', 1) 
+	WHERE user_id = $1;
+`
+
+// CancelErasureRequest undeletes user in grace period, erased user can't be brought back
+func (d *database) CancelErasureRequest(ctx context.Context, userID model.UserID) error {
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		request, err := getErasureRequest(ctx, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := tx.ExecContext(ctx, deleteErasureRequestQuery, userID)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not delete erasure request")
+		}
+		if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+			return nil, ErrErasureNotFound
+		}
+
+		if _, err := tx.ExecContext(ctx, undeleteUserQuery, userID); err != nil {
+			if pqError, ok := err.(*pq.Error); ok {
+				if pqError.Code.Name() == UniqueViolation {
+					return nil, ErrUserExist
+				}
+			}
+			return nil, errors.Wrap(err, "could not undelete user")
+		}
+
+		return erasureAuditEntry(model.AuditErasureCanceled, request)
+	})
+}
+
+const listDueErasureRequestsQuery = `
+	SELECT user_id, requested_by, created_at, scheduled_at, completed_at 
+	FROM erasure_requests 
+	WHERE completed_at IS NULL AND scheduled_at <= $1 
+	ORDER BY scheduled_at;
+`
+
+func (d *database) ListDueErasureRequests(ctx context.Context, now time.Time) ([]*model.ErasureRequest, error) {
+	var requests []*model.ErasureRequest
+	if err := d.conn.SelectContext(ctx, &requests, listDueErasureRequestsQuery, now); err != nil {
+		return nil, errors.Wrap(err, "could not list erasure requests")
+	}
+
+	return requests, nil
+}
+
+// eraseUserQueries hard-delete user's data in order of foreign keys. User row stays, because audit log
+// and erasure request point to it, but it has nothing personal. Audit log keeps only what happened, not data.
+var eraseUserQueries = []string{
+	`DELETE FROM entity_revisions WHERE user_id = $1;`,
+	`DELETE FROM transactions WHERE user_id = $1 OR account_id IN (SELECT account_id FROM accounts WHERE user_id = $1);`,
+	`DELETE FROM reconciliations WHERE user_id = $1 OR account_id IN (SELECT account_id FROM accounts WHERE user_id = $1);`,
+	`DELETE FROM account_members WHERE user_id = $1 OR invited_by = $1 OR account_id IN (SELECT account_id FROM accounts WHERE user_id = $1);`,
+	`DELETE FROM accounts WHERE user_id = $1;`,
+	`DELETE FROM categories WHERE user_id = $1;`,
+	`DELETE FROM merchants WHERE user_id = $1;`,
+	`DELETE FROM prices WHERE user_id = $1;`,
+	`DELETE FROM sessions WHERE user_id = $1;`,
+	`DELETE FROM user_roles WHERE user_id = $1;`,
+	`DELETE FROM user_identities WHERE user_id = $1;`,
+	`DELETE FROM user_recovery_codes WHERE user_id = $1;`,
+	`DELETE FROM user_totp WHERE user_id = $1;`,
+	`DELETE FROM api_keys WHERE user_id = $1;`,
+	`DELETE FROM login_attempts 
+		WHERE attempt_key = '2fa:' || $1::text 
+			OR attempt_key IN (SELECT 'account:' || lower(split_part(email, '-DELETE-', 1)) FROM users WHERE user_id = $1::uuid);`,
+	`UPDATE audit_log SET ip = NULL, device_id = NULL, details = NULL, before = NULL, after = NULL, diff = NULL WHERE user_id = $1;`,
+	`UPDATE audit_log SET ip = NULL, device_id = NULL WHERE actor_id = $1;`,
+	`UPDATE users SET email = 'erased-' || user_id || '@invalid', password_hash = NULL, deleted_at = COALESCE(deleted_at, NOW()) WHERE user_id = $1;`,
+	`UPDATE erasure_requests SET completed_at = NOW() WHERE user_id = $1;`,
+}
+
+// EraseUser removes all data of user in one transaction
+func (d *database) EraseUser(ctx context.Context, userID model.UserID) error {
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		for _, query := range eraseUserQueries {
+			if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+				return nil, errors.Wrap(err, "could not erase user")
+			}
+		}
+
+		return &model.AuditEntry{
+			Action: model.AuditUserErased,
+			UserID: &userID,
+		}, nil
+	})
+}
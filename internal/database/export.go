@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// ExportDB reads everything stored about user
+type ExportDB interface {
+	GetUserExport(ctx context.Context, userID model.UserID) (*model.UserExport, error)
+}
+
+// deleted records are exported too, user can still restore them
+const (
+	exportUserQuery = `
+	SELECT user_id, email, created_at, deleted_at 
+	FROM users 
+	WHERE user_id = $1;
+`
+	exportSessionsQuery = `
+	SELECT device_id, expires_at 
+	FROM sessions 
+	WHERE user_id = $1;
+`
+	exportRolesQuery = `
+	SELECT role 
+	FROM user_roles 
+	WHERE user_id = $1;
+`
+	exportIdentitiesQuery = `
+	SELECT provider, subject, user_id, email, created_at 
+	FROM user_identities 
+	WHERE user_id = $1;
+`
+	exportAPIKeysQuery = `
+	SELECT api_key_id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at 
+	FROM api_keys 
+	WHERE user_id = $1;
+`
+	exportAccountsQuery = `
+	SELECT account_id, user_id, start_balance, account_type, account_name, currency, institution, masked_number, credit_limit, statement_day, interest_rate, payment_due_day, min_payment_percent, min_payment_amount, loan_principal, loan_term_months, loan_start_date, created_at, deleted_at 
+	FROM accounts 
+	WHERE user_id = $1 
+	ORDER BY created_at;
+`
+	exportCategoriesQuery = `
+	SELECT category_id, parent_id, user_id, name, created_at, deleted_at 
+	FROM categories 
+	WHERE user_id = $1 
+	ORDER BY created_at;
+`
+	exportMerchantsQuery = `
+	SELECT merchant_id, user_id, name, created_at, deleted_at 
+	FROM merchants 
+	WHERE user_id = $1 
+	ORDER BY created_at;
+`
+	exportTransactionsQuery = `
+	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, status, symbol, quantity, cleared_status, created_at, deleted_at 
+	FROM transactions 
+	WHERE user_id = $1 
+	ORDER BY date;
+`
+	exportPricesQuery = `
+	SELECT user_id, symbol, date, price, created_at 
+	FROM prices 
+	WHERE user_id = $1 
+	ORDER BY symbol, date;
+`
+	exportReconciliationsQuery = `
+	SELECT reconciliation_id, user_id, account_id, statement_date, ending_balance, created_at, locked_at 
+	FROM reconciliations 
+	WHERE user_id = $1 
+	ORDER BY created_at;
+`
+)
+
+// GetUserExport returns all data of user (deleted user too), it is read in one transaction so it is consistent
+func (d *database) GetUserExport(ctx context.Context, userID model.UserID) (*model.UserExport, error) {
+	export := model.UserExport{
+		User:         &model.User{},
+		Sessions:     make([]*model.SessionInfo, 0),
+		Roles:        make([]*model.UserRole, 0),
+		Identities:   make([]*model.UserIdentity, 0),
+		APIKeys:      make([]*model.APIKey, 0),
+		Accounts:     make([]*model.Account, 0),
+		Categories:   make([]*model.Category, 0),
+		Merchants:    make([]*model.Merchant, 0),
+		Transactions: make([]*model.Transaction, 0),
+		Prices:       make([]*model.Price, 0),
+
+		Reconciliations: make([]*model.Reconciliation, 0),
+	}
+
+	err := d.transact(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead}, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, export.User, exportUserQuery, userID); err != nil {
+			return errors.Wrap(err, "could not get user")
+		}
+
+		lists := []struct {
+			dest  interface{}
+			query string
+			name  string
+		}{
+			{&export.Sessions, exportSessionsQuery, "sessions"},
+			{&export.Roles, exportRolesQuery, "roles"},
+			{&export.Identities, exportIdentitiesQuery, "identities"},
+			{&export.APIKeys, exportAPIKeysQuery, "api keys"},
+			{&export.Accounts, exportAccountsQuery, "accounts"},
+			{&export.Categories, exportCategoriesQuery, "categories"},
+			{&export.Merchants, exportMerchantsQuery, "merchants"},
+			{&export.Transactions, exportTransactionsQuery, "transactions"},
+			{&export.Prices, exportPricesQuery, "prices"},
+			{&export.Reconciliations, exportReconciliationsQuery, "reconciliations"},
+		}
+		for _, list := range lists {
+			if err := tx.SelectContext(ctx, list.dest, list.query, userID); err != nil {
+				return errors.Wrapf(err, "could not get user's %s", list.name)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// LoginAttemptDB persist failed logins, so locks survive server restart.
+// All times are calculated by database to avoid difference between server and database clocks.
+type LoginAttemptDB interface {
+	GetLoginRetryAfter(ctx context.Context, keys []string) (time.Duration, error)
+	RecordLoginFailure(ctx context.Context, key string, window time.Duration) (*model.LoginAttempt, error)
+	LockLogin(ctx context.Context, key string, duration time.Duration) error
+	ResetLoginAttempts(ctx context.Context, keys []string) error
+}
+
+const getLoginRetryAfterQuery = `
+	SELECT COALESCE(MAX(EXTRACT(EPOCH FROM locked_until - NOW())), 0) 
+	FROM login_attempts 
+	WHERE attempt_key = ANY($1) 
+		AND locked_until > NOW();
+`
+
+// GetLoginRetryAfter returns longest lock of keys, 0 if none of them is locked
+func (d *database) GetLoginRetryAfter(ctx context.Context, keys []string) (time.Duration, error) {
+	var seconds float64
+	if err := d.conn.GetContext(ctx, &seconds, getLoginRetryAfterQuery, pq.Array(keys)); err != nil {
+		return 0, errors.Wrap(err, "could not get login attempts")
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// failures older than window are forgotten, counting starts again
+const recordLoginFailureQuery = `
+	INSERT INTO login_attempts (attempt_key, failures, last_failure_at) 
+		VALUES ($1, 1, NOW()) 
+
+	ON CONFLICT (attempt_key) 
+	DO
+		UPDATE 
+			SET failures = CASE 
+					WHEN login_attempts.last_failure_at < NOW() - $2 * INTERVAL '1 second' THEN 1 
+					ELSE login_attempts.failures + 1 
+				END,
+				last_failure_at = NOW() 
+	RETURNING attempt_key, failures, last_failure_at, locked_until;
+`
+
+func (d *database) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (*model.LoginAttempt, error) {
+	var attempt model.LoginAttempt
+	if err := d.conn.GetContext(ctx, &attempt, recordLoginFailureQuery, key, window.Seconds()); err != nil {
+		return nil, errors.Wrap(err, "could not record login failure")
+	}
+
+	return &attempt, nil
+}
+
+const lockLoginQuery = `
+	UPDATE login_attempts 
+	SET locked_until = NOW() + $2 * INTERVAL '1 second' 
+	WHERE attempt_key = $1;
+`
+
+func (d *database) LockLogin(ctx context.Context, key string, duration time.Duration) error {
+	if _, err := d.conn.ExecContext(ctx, lockLoginQuery, key, duration.Seconds()); err != nil {
+		return errors.Wrap(err, "could not lock login")
+	}
+
+	return nil
+}
+
+const resetLoginAttemptsQuery = `
+	DELETE FROM login_attempts 
+	WHERE attempt_key = ANY($1);
+`
+
+func (d *database) ResetLoginAttempts(ctx context.Context, keys []string) error {
+	if _, err := d.conn.ExecContext(ctx, resetLoginAttemptsQuery, pq.Array(keys)); err != nil {
+		return errors.Wrap(err, "could not reset login attempts")
+	}
+
+	return nil
+}
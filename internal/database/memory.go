@@ -0,0 +1,284 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// memory is Database which keeps data in memory, it is used by tests instead of Postgres.
+// It behaves like Postgres one: deleted records are only marked, emails are unique,
+// time windows are checked the same way and every change is audited with revisions.
+type memory struct {
+	state *memoryState
+	tx    bool      // state is locked by WithTx
+	now   time.Time // NOW() of transaction, it is the same for all its changes
+}
+
+// memoryState is shared by database and its transactions
+type memoryState struct {
+	mu    sync.Mutex
+	store *memoryStore
+}
+
+// memoryStore has table per slice, rows are kept in order of insert like Postgres returns them without ORDER BY.
+// Fields are exported so store can be copied by cloneValue.
+type memoryStore struct {
+	Users           []*model.User
+	Sessions        []*model.Session
+	UserRoles       []*memoryUserRole
+	Roles           []*model.RoleDefinition
+	Identities      []*model.UserIdentity
+	TOTPs           []*model.TOTP
+	RecoveryCodes   []*memoryRecoveryCode
+	LoginAttempts   []*model.LoginAttempt
+	APIKeys         []*model.APIKey
+	Accounts        []*model.Account
+	Members         []*model.AccountMember
+	Categories      []*model.Category
+	Merchants       []*model.Merchant
+	Transactions    []*model.Transaction
+	Prices          []*model.Price
+	Reconciliations []*model.Reconciliation
+	Audit           []*model.AuditEntry
+	Revisions       []*model.Revision
+	Erasures        []*model.ErasureRequest
+}
+
+type memoryUserRole struct {
+	UserID model.UserID
+	Role   model.Role
+}
+
+type memoryRecoveryCode struct {
+	UserID   model.UserID
+	CodeHash []byte
+	UsedAt   *time.Time
+}
+
+// errForeignKey - row references row which doesn't exist
+var errForeignKey = errors.New("violates foreign key constraint")
+
+// NewMemory creates empty in-memory database with roles created by migrations
+func NewMemory() Database {
+	now := memoryNow()
+	adminDescription := "Administrator of App, has all permissions"
+	supportDescription := "Support staff, can read users"
+
+	return &memory{
+		state: &memoryState{
+			store: &memoryStore{
+				Roles: []*model.RoleDefinition{
+					{Name: model.RoleAdmin, Description: &adminDescription, Permissions: []model.Permission{}, CreatedAt: &now, UpdatedAt: &now},
+					{Name: "support", Description: &supportDescription, Permissions: []model.Permission{model.PermissionUsersRead}, CreatedAt: &now, UpdatedAt: &now},
+				},
+			},
+		},
+	}
+}
+
+// WithTx runs fn on database which sees and changes the same data, all changes are dropped when fn fails.
+// Other calls wait until transaction ends, so it can't fail on serialization.
+func (m *memory) WithTx(ctx context.Context, fn func(db Database) error) error {
+	if m.tx {
+		return fn(m)
+	}
+
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+
+	snapshot := m.state.store
+	if err := fn(&memory{state: m.state, tx: true, now: memoryNow()}); err != nil {
+		m.state.store = snapshot
+		return err
+	}
+
+	return nil
+}
+
+func (m *memory) Close() error {
+	return nil
+}
+
+// lock locks state outside of transaction, transaction holds the lock already
+func (m *memory) lock() func() {
+	if m.tx {
+		return func() {}
+	}
+
+	m.state.mu.Lock()
+	return m.state.mu.Unlock
+}
+
+// clock returns NOW() of change
+func (m *memory) clock() time.Time {
+	if m.tx {
+		return m.now
+	}
+	return memoryNow()
+}
+
+// read runs fn on current data, fn must return copies of rows
+func (m *memory) read(fn func(s *memoryStore) error) error {
+	defer m.lock()()
+	return fn(m.state.store)
+}
+
+// update runs change on copy of data which replaces data only when change succeeds,
+// so failed change leaves nothing behind like rolled back transaction
+func (m *memory) update(change func(s *memoryStore, now time.Time) error) error {
+	defer m.lock()()
+
+	next := cloneValue(reflect.ValueOf(m.state.store)).Interface().(*memoryStore)
+	if err := change(next, m.clock()); err != nil {
+		return err
+	}
+
+	m.state.store = next
+	return nil
+}
+
+// audited is update which writes audit entry returned by change like database.audited does
+func (m *memory) audited(ctx context.Context, change func(s *memoryStore, now time.Time) (*model.AuditEntry, error)) error {
+	return m.update(func(s *memoryStore, now time.Time) error {
+		entry, err := change(s, now)
+		if err != nil || entry == nil {
+			return err
+		}
+
+		s.writeAudit(ctx, entry, now)
+		return nil
+	})
+}
+
+// writeAudit saves entry with actor from context and revision of versioned entity
+func (s *memoryStore) writeAudit(ctx context.Context, entry *model.AuditEntry, now time.Time) {
+	fillAuditActor(ctx, entry)
+	s.insertAuditEntry(entry, now)
+
+	operation, data, ok := revisionOf(entry)
+	if !ok {
+		return
+	}
+
+	revision := 1
+	for _, r := range s.Revisions {
+		if r.EntityType == *entry.EntityType && r.EntityID == *entry.EntityID && r.Revision >= revision {
+			revision = r.Revision + 1
+		}
+	}
+
+	s.Revisions = append(s.Revisions, &model.Revision{
+		EntityType:     *entry.EntityType,
+		EntityID:       *entry.EntityID,
+		Revision:       revision,
+		Operation:      operation,
+		UserID:         copyUserID(entry.UserID),
+		ActorID:        copyUserID(entry.ActorID),
+		ImpersonatorID: copyUserID(entry.ImpersonatorID),
+		Data:           append(model.JSON(nil), data...),
+		CreatedAt:      &now,
+	})
+}
+
+func (s *memoryStore) insertAuditEntry(entry *model.AuditEntry, now time.Time) {
+	entry.ID = model.AuditID(newMemoryID())
+	entry.CreatedAt = &now
+	s.Audit = append(s.Audit, copyOf(entry).(*model.AuditEntry))
+}
+
+// memoryNow is NOW() as read from TIMESTAMP column, UTC with microseconds
+func memoryNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// memoryTime is time stored in TIMESTAMP column, Postgres drops time zone and keeps wall clock
+func memoryTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC).Truncate(time.Microsecond)
+}
+
+func memoryTimePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	stored := memoryTime(*t)
+	return &stored
+}
+
+// memoryDate is time stored in DATE column, only day of wall clock is kept
+func memoryDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// newMemoryID returns random UUID like uuid_generate_v4()
+func newMemoryID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func copyUserID(userID *model.UserID) *model.UserID {
+	if userID == nil {
+		return nil
+	}
+	id := *userID
+	return &id
+}
+
+// copyOf returns deep copy, rows are copied on the way in and out so callers can't change stored data
+func copyOf(v interface{}) interface{} {
+	return cloneValue(reflect.ValueOf(v)).Interface()
+}
+
+// cloneValue copies pointers, slices and maps, unexported fields (like in time.Time) are copied as they are
+func cloneValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(cloneValue(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(cloneValue(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			c.SetMapIndex(key, cloneValue(v.MapIndex(key)))
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,1260 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// errNotNull - column which is NOT NULL gets nil value
+var errNotNull = errors.New("violates not-null constraint")
+
+// checkNotNull returns errNotNull when one of pointers is nil
+func checkNotNull(values ...interface{}) error {
+	for _, value := range values {
+		if v := reflect.ValueOf(value); !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+			return errNotNull
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) findAccount(accountID model.AccountID) *model.Account {
+	for _, account := range s.Accounts {
+		if account.ID == accountID {
+			return account
+		}
+	}
+	return nil
+}
+
+func (m *memory) CreateAccount(ctx context.Context, account *model.Account) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		if account.UserID == nil || s.findUser(*account.UserID) == nil {
+			return nil, errForeignKey
+		}
+		if err := checkNotNull(account.StartBalance, account.Type, account.Name, account.Currency); err != nil {
+			return nil, err
+		}
+
+		created := copyOf(account).(*model.Account)
+		created.ID = model.AccountID(newMemoryID())
+		created.CreatedAt = &now
+		created.DeletedAt = nil
+		created.Access = nil
+		created.Credit = nil
+		s.Accounts = append(s.Accounts, created)
+
+		account.ID = created.ID
+		return newAuditEntry(model.AuditEntityAccount, model.AuditCreated, string(account.ID), account.UserID, nil, account)
+	})
+}
+
+// setAccount changes columns which updateAccountQuery changes
+func setAccount(stored, account *model.Account) error {
+	if err := checkNotNull(account.StartBalance, account.Type, account.Name, account.Currency); err != nil {
+		return err
+	}
+
+	next := copyOf(account).(*model.Account)
+	stored.StartBalance = next.StartBalance
+	stored.Type = next.Type
+	stored.Name = next.Name
+	stored.Currency = next.Currency
+	stored.Institution = next.Institution
+	stored.MaskedNumber = next.MaskedNumber
+	stored.CreditLimit = next.CreditLimit
+	stored.StatementDay = next.StatementDay
+	stored.InterestRate = next.InterestRate
+	stored.PaymentDueDay = next.PaymentDueDay
+	stored.MinPaymentPercent = next.MinPaymentPercent
+	stored.MinPaymentAmount = next.MinPaymentAmount
+	stored.LoanPrincipal = next.LoanPrincipal
+	stored.LoanTermMonths = next.LoanTermMonths
+	if next.LoanStartDate != nil {
+		date := memoryDate(*next.LoanStartDate)
+		next.LoanStartDate = &date
+	}
+	stored.LoanStartDate = next.LoanStartDate
+	return nil
+}
+
+func (m *memory) UpdateAccount(ctx context.Context, account *model.Account) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.findAccount(account.ID)
+		if stored == nil {
+			return nil, errors.New("account not found")
+		}
+		before := copyOf(stored).(*model.Account)
+
+		if err := setAccount(stored, account); err != nil {
+			return nil, err
+		}
+
+		return newAuditEntry(model.AuditEntityAccount, model.AuditUpdated, string(account.ID), before.UserID, before, account)
+	})
+}
+
+// GetAccountByID returns deleted account too like getAccountByIDQuery
+func (m *memory) GetAccountByID(ctx context.Context, accountID model.AccountID) (*model.Account, error) {
+	var account *model.Account
+	err := m.read(func(s *memoryStore) error {
+		stored := s.findAccount(accountID)
+		if stored == nil {
+			return errors.Wrap(sql.ErrNoRows, "could not get account")
+		}
+		account = copyOf(stored).(*model.Account)
+		return nil
+	})
+	return account, err
+}
+
+func (m *memory) GetAccountBalance(ctx context.Context, accountID model.AccountID, includePending bool) (model.Money, error) {
+	var balance model.Money
+	err := m.read(func(s *memoryStore) error {
+		account := s.findAccount(accountID)
+		if account == nil {
+			return errors.Wrap(sql.ErrNoRows, "could not get account balance")
+		}
+
+		balance = *account.StartBalance
+		for _, transaction := range s.Transactions {
+			if *transaction.AccountID != accountID || transaction.DeletedAt != nil || !transaction.IsCounted(includePending) {
+				continue
+			}
+
+			var err error
+			if transaction.Type.IsInflow() {
+				balance, err = balance.Add(*transaction.Amount)
+			} else {
+				balance, err = balance.Sub(*transaction.Amount)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return balance, err
+}
+
+func (m *memory) ListAccountsByUserID(ctx context.Context, userID model.UserID) ([]*model.Account, error) {
+	var accounts []*model.Account
+	err := m.read(func(s *memoryStore) error {
+		for _, account := range s.Accounts {
+			if *account.UserID == userID && account.DeletedAt == nil {
+				accounts = append(accounts, copyOf(account).(*model.Account))
+			}
+		}
+		return nil
+	})
+	return accounts, err
+}
+
+func (m *memory) DeleteAccount(ctx context.Context, accountID model.AccountID, options model.DeleteOptions) (*model.DeleteResult, error) {
+	result := &model.DeleteResult{}
+	err := m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.findAccount(accountID)
+		// deleting again would change deleted_at, transactions deleted with account couldn't be restored with it
+		if stored == nil || stored.DeletedAt != nil {
+			return nil, nil
+		}
+		before := copyOf(stored).(*model.Account)
+
+		var transactions []*model.Transaction
+		for _, transaction := range s.Transactions {
+			if *transaction.AccountID == accountID && transaction.DeletedAt == nil {
+				transactions = append(transactions, transaction)
+			}
+		}
+		result.Transactions = int64(len(transactions))
+
+		switch options.Strategy {
+		case model.DeleteCascade:
+			s.deleteTransactions(ctx, transactions, now)
+		case model.DeleteReassign:
+			target := model.AccountID(options.Target)
+			if target == accountID {
+				return nil, ErrInvalidTarget
+			}
+			if len(transactions) > 0 && s.findAccount(target) == nil {
+				return nil, errForeignKey
+			}
+			s.moveTransactions(ctx, transactions, now, func(transaction *model.Transaction) {
+				transaction.AccountID = &target
+			})
+		default:
+			if len(transactions) > 0 {
+				return nil, ErrHasDependents
+			}
+		}
+
+		stored.DeletedAt = &now
+		result.Deleted = true
+
+		return newAuditEntry(model.AuditEntityAccount, model.AuditDeleted, string(accountID), before.UserID, before, nil)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (m *memory) RestoreAccount(ctx context.Context, accountID model.AccountID, state *model.Account, withTransactions bool) (*model.Account, int, error) {
+	var restored *model.Account
+	restoredTransactions := 0
+	err := m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.findAccount(accountID)
+		if stored == nil {
+			return nil, errors.New("account not found")
+		}
+		before := copyOf(stored).(*model.Account)
+
+		// transactions deleted together with account have the same deleted_at
+		var transactions []*model.Transaction
+		if withTransactions && before.DeletedAt != nil {
+			for _, transaction := range s.Transactions {
+				if *transaction.AccountID == accountID && transaction.DeletedAt != nil && transaction.DeletedAt.Equal(*before.DeletedAt) {
+					transactions = append(transactions, transaction)
+				}
+			}
+		}
+
+		next := before
+		if state != nil {
+			next = state
+		}
+		if err := setAccount(stored, next); err != nil {
+			return nil, err
+		}
+		stored.DeletedAt = nil
+
+		for _, transaction := range transactions {
+			previous := copyOf(transaction).(*model.Transaction)
+			transaction.DeletedAt = nil
+
+			entry, err := newAuditEntry(model.AuditEntityTransaction, model.AuditRestored, string(transaction.ID), transaction.UserID, previous, transaction)
+			if err != nil {
+				return nil, err
+			}
+			s.writeAudit(ctx, entry, now)
+			restoredTransactions++
+		}
+
+		restored = copyOf(stored).(*model.Account)
+		return newAuditEntry(model.AuditEntityAccount, model.AuditRestored, string(accountID), before.UserID, before, restored)
+	})
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "could not restore account")
+	}
+
+	return restored, restoredTransactions, nil
+}
+
+func (s *memoryStore) findMember(accountID model.AccountID, userID model.UserID) *model.AccountMember {
+	for _, member := range s.Members {
+		if member.AccountID == accountID && member.UserID == userID {
+			return member
+		}
+	}
+	return nil
+}
+
+// memberState is member joined with email of user, nil when user is gone like in JOIN
+func (s *memoryStore) memberState(member *model.AccountMember) *model.AccountMember {
+	user := s.findUser(member.UserID)
+	if user == nil {
+		return nil
+	}
+
+	state := copyOf(member).(*model.AccountMember)
+	state.Email = copyOf(user.Email).(*string)
+	return state
+}
+
+func (m *memory) CreateAccountMember(ctx context.Context, member *model.AccountMember) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		if s.findMember(member.AccountID, member.UserID) != nil {
+			return nil, ErrMemberExists
+		}
+		if s.findAccount(member.AccountID) == nil || s.findUser(member.UserID) == nil || s.findUser(member.InvitedBy) == nil {
+			return nil, errors.Wrap(errForeignKey, "could not create account member")
+		}
+		if !member.Access.IsValid() {
+			return nil, errors.New("could not create account member: invalid access")
+		}
+
+		created := copyOf(member).(*model.AccountMember)
+		created.Email = nil
+		created.Status = model.MemberPending
+		created.CreatedAt = &now
+		created.AcceptedAt = nil
+		s.Members = append(s.Members, created)
+
+		member.Status = created.Status
+		member.CreatedAt = &now
+		return newAuditEntry(model.AuditEntityAccountMember, model.AuditCreated, memberEntityID(member.AccountID, member.UserID), &member.UserID, nil, member)
+	})
+}
+
+// changeAccountMember runs change of member and logs state before and after it, change returns false when member is not changed
+func (m *memory) changeAccountMember(ctx context.Context, verb string, accountID model.AccountID, userID model.UserID, change func(s *memoryStore, member *model.AccountMember, now time.Time) (bool, error)) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.findMember(accountID, userID)
+		if stored == nil {
+			return nil, ErrMemberNotFound
+		}
+		before := s.memberState(stored)
+		if before == nil {
+			return nil, ErrMemberNotFound
+		}
+
+		changed, err := change(s, stored, now)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not change account member")
+		}
+		if !changed {
+			return nil, ErrMemberNotFound
+		}
+
+		var after *model.AccountMember
+		if verb != model.AuditDeleted {
+			after = s.memberState(stored)
+		}
+
+		return newAuditEntry(model.AuditEntityAccountMember, verb, memberEntityID(accountID, userID), &userID, before, after)
+	})
+}
+
+func (m *memory) GetAccountMember(ctx context.Context, accountID model.AccountID, userID model.UserID) (*model.AccountMember, error) {
+	var member *model.AccountMember
+	err := m.read(func(s *memoryStore) error {
+		stored := s.findMember(accountID, userID)
+		if stored == nil {
+			return ErrMemberNotFound
+		}
+		if member = s.memberState(stored); member == nil {
+			return ErrMemberNotFound
+		}
+		return nil
+	})
+	return member, err
+}
+
+func (m *memory) ListAccountMembers(ctx context.Context, accountID model.AccountID) ([]*model.AccountMember, error) {
+	var members []*model.AccountMember
+	err := m.read(func(s *memoryStore) error {
+		for _, stored := range s.Members {
+			if stored.AccountID != accountID {
+				continue
+			}
+			if member := s.memberState(stored); member != nil {
+				members = append(members, member)
+			}
+		}
+		return nil
+	})
+	return members, err
+}
+
+// ListInvitations doesn't show invitations of deleted accounts
+func (m *memory) ListInvitations(ctx context.Context, userID model.UserID) ([]*model.AccountMember, error) {
+	var invitations []*model.AccountMember
+	err := m.read(func(s *memoryStore) error {
+		for _, stored := range s.Members {
+			if stored.UserID != userID || stored.Status != model.MemberPending {
+				continue
+			}
+			if account := s.findAccount(stored.AccountID); account == nil || account.DeletedAt != nil {
+				continue
+			}
+			if member := s.memberState(stored); member != nil {
+				invitations = append(invitations, member)
+			}
+		}
+		return nil
+	})
+	return invitations, err
+}
+
+func (m *memory) AcceptAccountMember(ctx context.Context, accountID model.AccountID, userID model.UserID) error {
+	return m.changeAccountMember(ctx, model.AuditUpdated, accountID, userID, func(s *memoryStore, member *model.AccountMember, now time.Time) (bool, error) {
+		if member.Status != model.MemberPending {
+			return false, nil
+		}
+		member.Status = model.MemberAccepted
+		member.AcceptedAt = &now
+		return true, nil
+	})
+}
+
+func (m *memory) UpdateAccountMemberAccess(ctx context.Context, accountID model.AccountID, userID model.UserID, access model.AccountAccess) error {
+	return m.changeAccountMember(ctx, model.AuditUpdated, accountID, userID, func(s *memoryStore, member *model.AccountMember, now time.Time) (bool, error) {
+		if !access.IsValid() {
+			return false, errors.New("invalid access")
+		}
+		member.Access = access
+		return true, nil
+	})
+}
+
+func (m *memory) DeleteAccountMember(ctx context.Context, accountID model.AccountID, userID model.UserID) error {
+	return m.changeAccountMember(ctx, model.AuditDeleted, accountID, userID, func(s *memoryStore, member *model.AccountMember, now time.Time) (bool, error) {
+		members := s.Members[:0]
+		for _, stored := range s.Members {
+			if stored != member {
+				members = append(members, stored)
+			}
+		}
+		s.Members = members
+		return true, nil
+	})
+}
+
+// ListSharedAccounts returns accounts of other users where user is member
+func (m *memory) ListSharedAccounts(ctx context.Context, userID model.UserID) ([]*model.Account, error) {
+	var accounts []*model.Account
+	err := m.read(func(s *memoryStore) error {
+		for _, member := range s.Members {
+			if member.UserID != userID || member.Status != model.MemberAccepted {
+				continue
+			}
+			stored := s.findAccount(member.AccountID)
+			if stored == nil || stored.DeletedAt != nil {
+				continue
+			}
+
+			account := copyOf(stored).(*model.Account)
+			access := member.Access
+			account.Access = &access
+			accounts = append(accounts, account)
+		}
+		return nil
+	})
+	return accounts, err
+}
+
+func (s *memoryStore) findCategory(categoryID model.CategoryID) *model.Category {
+	for _, category := range s.Categories {
+		if category.ID == categoryID {
+			return category
+		}
+	}
+	return nil
+}
+
+// activeCategory returns category which is not deleted, as selected by getCategoryByIDQuery
+func (s *memoryStore) activeCategory(categoryID model.CategoryID) *model.Category {
+	category := s.findCategory(categoryID)
+	if category == nil || category.DeletedAt != nil {
+		return nil
+	}
+	return category
+}
+
+func (m *memory) CreateCategory(ctx context.Context, category *model.Category) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		if category.UserID == nil || s.findUser(*category.UserID) == nil {
+			return nil, errForeignKey
+		}
+		if err := checkNotNull(category.Name); err != nil {
+			return nil, err
+		}
+
+		created := copyOf(category).(*model.Category)
+		created.ID = model.CategoryID(newMemoryID())
+		created.CreatedAt = &now
+		created.DeletedAt = nil
+		s.Categories = append(s.Categories, created)
+
+		category.ID = created.ID
+		return newAuditEntry(model.AuditEntityCategory, model.AuditCreated, string(category.ID), category.UserID, nil, category)
+	})
+}
+
+func (m *memory) UpdateCategory(ctx context.Context, category *model.Category) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.activeCategory(category.ID)
+		if stored == nil {
+			return nil, errors.New("category not found")
+		}
+		before := copyOf(stored).(*model.Category)
+
+		if err := checkNotNull(category.Name); err != nil {
+			return nil, err
+		}
+		stored.ParentID = category.ParentID
+		stored.Name = copyOf(category.Name).(*string)
+
+		return newAuditEntry(model.AuditEntityCategory, model.AuditUpdated, string(category.ID), before.UserID, before, category)
+	})
+}
+
+func (m *memory) GetCategoryByID(ctx context.Context, categoryID model.CategoryID) (*model.Category, error) {
+	var category *model.Category
+	err := m.read(func(s *memoryStore) error {
+		stored := s.activeCategory(categoryID)
+		if stored == nil {
+			return errors.Wrap(sql.ErrNoRows, "could not get category")
+		}
+		category = copyOf(stored).(*model.Category)
+		return nil
+	})
+	return category, err
+}
+
+func (m *memory) ListCategoriesByUserID(ctx context.Context, userID model.UserID) ([]*model.Category, error) {
+	var categories []*model.Category
+	err := m.read(func(s *memoryStore) error {
+		for _, category := range s.Categories {
+			if *category.UserID == userID && category.DeletedAt == nil {
+				categories = append(categories, copyOf(category).(*model.Category))
+			}
+		}
+		return nil
+	})
+	return categories, err
+}
+
+// categoryDescendants returns not deleted categories under category like listCategoryDescendantsQuery
+func (s *memoryStore) categoryDescendants(categoryID model.CategoryID) []*model.Category {
+	inTree := map[model.CategoryID]bool{categoryID: true}
+	for added := true; added; {
+		added = false
+		for _, category := range s.Categories {
+			if !inTree[category.ID] && category.DeletedAt == nil && inTree[category.ParentID] {
+				inTree[category.ID] = true
+				added = true
+			}
+		}
+	}
+
+	var descendants []*model.Category
+	for _, category := range s.Categories {
+		if inTree[category.ID] && category.ID != categoryID {
+			descendants = append(descendants, category)
+		}
+	}
+	return descendants
+}
+
+func (m *memory) DeleteCategory(ctx context.Context, categoryID model.CategoryID, options model.DeleteOptions) (*model.DeleteResult, error) {
+	result := &model.DeleteResult{}
+	err := m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.activeCategory(categoryID)
+		if stored == nil {
+			return nil, nil
+		}
+		before := copyOf(stored).(*model.Category)
+
+		descendants := s.categoryDescendants(categoryID)
+		var children []*model.Category
+		for _, category := range descendants {
+			if category.ParentID == categoryID {
+				children = append(children, category)
+			}
+		}
+
+		// only cascade touches transactions of whole subtree
+		categoryIDs := map[model.CategoryID]bool{categoryID: true}
+		if options.Strategy == model.DeleteCascade {
+			for _, category := range descendants {
+				categoryIDs[category.ID] = true
+			}
+		}
+
+		var transactions []*model.Transaction
+		for _, transaction := range s.Transactions {
+			if categoryIDs[*transaction.CategoryID] && transaction.DeletedAt == nil {
+				transactions = append(transactions, transaction)
+			}
+		}
+		result.Transactions = int64(len(transactions))
+
+		switch options.Strategy {
+		case model.DeleteCascade:
+			result.Categories = int64(len(descendants))
+			s.deleteTransactions(ctx, transactions, now)
+			for _, category := range descendants {
+				if err := s.changeCategory(ctx, category, model.AuditDeleted, now, func(category *model.Category) {
+					category.DeletedAt = &now
+				}); err != nil {
+					return nil, err
+				}
+			}
+		case model.DeleteReassign:
+			target := model.CategoryID(options.Target)
+			// category can't be moved under itself
+			if target == categoryID {
+				return nil, ErrInvalidTarget
+			}
+			for _, category := range descendants {
+				if category.ID == target {
+					return nil, ErrInvalidTarget
+				}
+			}
+			if len(transactions) > 0 && s.findCategory(target) == nil {
+				return nil, errForeignKey
+			}
+
+			result.Categories = int64(len(children))
+			s.moveTransactions(ctx, transactions, now, func(transaction *model.Transaction) {
+				transaction.CategoryID = &target
+			})
+			for _, category := range children {
+				if err := s.changeCategory(ctx, category, model.AuditUpdated, now, func(category *model.Category) {
+					category.ParentID = target
+				}); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			result.Categories = int64(len(children))
+			if len(transactions) > 0 || len(children) > 0 {
+				return nil, ErrHasDependents
+			}
+		}
+
+		stored.DeletedAt = &now
+		result.Deleted = true
+
+		return newAuditEntry(model.AuditEntityCategory, model.AuditDeleted, string(categoryID), before.UserID, before, nil)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// changeCategory deletes or moves child category of deleted category and audits it
+func (s *memoryStore) changeCategory(ctx context.Context, category *model.Category, verb string, now time.Time, change func(category *model.Category)) error {
+	before := copyOf(category).(*model.Category)
+	change(category)
+
+	var after *model.Category
+	if verb != model.AuditDeleted {
+		after = copyOf(category).(*model.Category)
+	}
+
+	entry, err := newAuditEntry(model.AuditEntityCategory, verb, string(category.ID), category.UserID, before, after)
+	if err != nil {
+		return err
+	}
+	s.writeAudit(ctx, entry, now)
+	return nil
+}
+
+func (m *memory) RestoreCategory(ctx context.Context, categoryID model.CategoryID, state *model.Category) (*model.Category, error) {
+	var restored *model.Category
+	err := m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.findCategory(categoryID)
+		if stored == nil {
+			return nil, errors.New("category not found")
+		}
+		before := copyOf(stored).(*model.Category)
+
+		next := before
+		if state != nil {
+			next = state
+		}
+		if err := checkNotNull(next.Name); err != nil {
+			return nil, err
+		}
+		stored.ParentID = next.ParentID
+		stored.Name = copyOf(next.Name).(*string)
+		stored.DeletedAt = nil
+
+		restored = copyOf(stored).(*model.Category)
+		return newAuditEntry(model.AuditEntityCategory, model.AuditRestored, string(categoryID), before.UserID, before, restored)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not restore category")
+	}
+
+	return restored, nil
+}
+
+func (s *memoryStore) findMerchant(merchantID model.MerchantID) *model.Merchant {
+	for _, merchant := range s.Merchants {
+		if merchant.ID == merchantID {
+			return merchant
+		}
+	}
+	return nil
+}
+
+// activeMerchant returns merchant which is not deleted, as selected by getMerchantByIDQuery
+func (s *memoryStore) activeMerchant(merchantID model.MerchantID) *model.Merchant {
+	merchant := s.findMerchant(merchantID)
+	if merchant == nil || merchant.DeletedAt != nil {
+		return nil
+	}
+	return merchant
+}
+
+func (m *memory) CreateMerchant(ctx context.Context, merchant *model.Merchant) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		if merchant.UserID == nil || s.findUser(*merchant.UserID) == nil {
+			return nil, errForeignKey
+		}
+		if err := checkNotNull(merchant.Name); err != nil {
+			return nil, err
+		}
+
+		created := copyOf(merchant).(*model.Merchant)
+		created.ID = model.MerchantID(newMemoryID())
+		created.CreatedAt = &now
+		created.DeletedAt = nil
+		s.Merchants = append(s.Merchants, created)
+
+		merchant.ID = created.ID
+		return newAuditEntry(model.AuditEntityMerchant, model.AuditCreated, string(merchant.ID), merchant.UserID, nil, merchant)
+	})
+}
+
+func (m *memory) UpdateMerchant(ctx context.Context, merchant *model.Merchant) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.activeMerchant(merchant.ID)
+		if stored == nil {
+			return nil, errors.New("merchant not found")
+		}
+		before := copyOf(stored).(*model.Merchant)
+
+		if err := checkNotNull(merchant.Name); err != nil {
+			return nil, err
+		}
+		stored.Name = copyOf(merchant.Name).(*string)
+
+		return newAuditEntry(model.AuditEntityMerchant, model.AuditUpdated, string(merchant.ID), before.UserID, before, merchant)
+	})
+}
+
+func (m *memory) GetMerchantByID(ctx context.Context, merchantID model.MerchantID) (*model.Merchant, error) {
+	var merchant *model.Merchant
+	err := m.read(func(s *memoryStore) error {
+		stored := s.activeMerchant(merchantID)
+		if stored == nil {
+			return errors.Wrap(sql.ErrNoRows, "could not get merchant")
+		}
+		merchant = copyOf(stored).(*model.Merchant)
+		return nil
+	})
+	return merchant, err
+}
+
+func (m *memory) ListMerchantsByUserID(ctx context.Context, userID model.UserID) ([]*model.Merchant, error) {
+	var merchants []*model.Merchant
+	err := m.read(func(s *memoryStore) error {
+		for _, merchant := range s.Merchants {
+			if *merchant.UserID == userID && merchant.DeletedAt == nil {
+				merchants = append(merchants, copyOf(merchant).(*model.Merchant))
+			}
+		}
+		return nil
+	})
+	return merchants, err
+}
+
+func (m *memory) DeleteMerchant(ctx context.Context, merchantID model.MerchantID) (bool, error) {
+	deleted := false
+	err := m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.activeMerchant(merchantID)
+		if stored == nil {
+			return nil, nil
+		}
+		before := copyOf(stored).(*model.Merchant)
+
+		stored.DeletedAt = &now
+		deleted = true
+
+		return newAuditEntry(model.AuditEntityMerchant, model.AuditDeleted, string(merchantID), before.UserID, before, nil)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+func (m *memory) RestoreMerchant(ctx context.Context, merchantID model.MerchantID, state *model.Merchant) (*model.Merchant, error) {
+	var restored *model.Merchant
+	err := m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.findMerchant(merchantID)
+		if stored == nil {
+			return nil, errors.New("merchant not found")
+		}
+		before := copyOf(stored).(*model.Merchant)
+
+		next := before
+		if state != nil {
+			next = state
+		}
+		if err := checkNotNull(next.Name); err != nil {
+			return nil, err
+		}
+		stored.Name = copyOf(next.Name).(*string)
+		stored.DeletedAt = nil
+
+		restored = copyOf(stored).(*model.Merchant)
+		return newAuditEntry(model.AuditEntityMerchant, model.AuditRestored, string(merchantID), before.UserID, before, restored)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not restore merchant")
+	}
+
+	return restored, nil
+}
+
+func (s *memoryStore) findTransaction(transactionID model.TransactionID) *model.Transaction {
+	for _, transaction := range s.Transactions {
+		if transaction.ID == transactionID {
+			return transaction
+		}
+	}
+	return nil
+}
+
+// activeTransaction returns transaction which is not deleted, as selected by getTransactionByIDQuery
+func (s *memoryStore) activeTransaction(transactionID model.TransactionID) *model.Transaction {
+	transaction := s.findTransaction(transactionID)
+	if transaction == nil || transaction.DeletedAt != nil {
+		return nil
+	}
+	return transaction
+}
+
+// setTransaction changes columns which updateTransactionQuery changes, references and type are checked like by Postgres.
+// Status and cleared status are kept when transaction has none, new transaction is posted and uncleared.
+func (s *memoryStore) setTransaction(stored, transaction *model.Transaction) error {
+	if err := checkNotNull(transaction.AccountID, transaction.CategoryID, transaction.Date, transaction.Type, transaction.Amount, transaction.Notes); err != nil {
+		return err
+	}
+	if s.findAccount(*transaction.AccountID) == nil || s.findCategory(*transaction.CategoryID) == nil {
+		return errForeignKey
+	}
+	if !transaction.Type.IsValid() {
+		return errors.New("invalid input value for enum transaction_type")
+	}
+
+	next := copyOf(transaction).(*model.Transaction)
+	stored.AccountID = next.AccountID
+	stored.CategoryID = next.CategoryID
+	stored.Date = memoryTimePtr(next.Date)
+	stored.Type = next.Type
+	stored.Amount = next.Amount
+	stored.Notes = next.Notes
+	stored.Symbol = next.Symbol
+	stored.Quantity = next.Quantity
+	if next.Status != nil {
+		if !next.Status.IsValid() {
+			return errors.New("invalid input value for enum transaction_status")
+		}
+		stored.Status = next.Status
+	} else if stored.Status == nil {
+		posted := model.Posted
+		stored.Status = &posted
+	}
+	if next.ClearedStatus != nil {
+		if !next.ClearedStatus.IsValid() {
+			return errors.New("invalid input value for enum cleared_status")
+		}
+		stored.ClearedStatus = next.ClearedStatus
+	} else if stored.ClearedStatus == nil {
+		uncleared := model.Uncleared
+		stored.ClearedStatus = &uncleared
+	}
+	return nil
+}
+
+func (m *memory) CreateTransaction(ctx context.Context, transaction *model.Transaction) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		if transaction.UserID == nil || s.findUser(*transaction.UserID) == nil {
+			return nil, errForeignKey
+		}
+
+		created := &model.Transaction{
+			ID:        model.TransactionID(newMemoryID()),
+			UserID:    copyUserID(transaction.UserID),
+			CreatedAt: &now,
+		}
+		if err := s.setTransaction(created, transaction); err != nil {
+			return nil, err
+		}
+		s.Transactions = append(s.Transactions, created)
+
+		transaction.ID = created.ID
+		transaction.Status = copyOf(created.Status).(*model.TransactionStatus)
+		transaction.ClearedStatus = copyOf(created.ClearedStatus).(*model.ClearedStatus)
+		return newAuditEntry(model.AuditEntityTransaction, model.AuditCreated, string(transaction.ID), transaction.UserID, nil, transaction)
+	})
+}
+
+func (m *memory) UpdateTransaction(ctx context.Context, transaction *model.Transaction) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.activeTransaction(transaction.ID)
+		if stored == nil {
+			return nil, errors.New("transaction not found")
+		}
+		before := copyOf(stored).(*model.Transaction)
+
+		if err := s.setTransaction(stored, transaction); err != nil {
+			return nil, err
+		}
+
+		return newAuditEntry(model.AuditEntityTransaction, model.AuditUpdated, string(transaction.ID), before.UserID, before, transaction)
+	})
+}
+
+func (m *memory) GetTransactionByID(ctx context.Context, transactionID model.TransactionID) (*model.Transaction, error) {
+	var transaction *model.Transaction
+	err := m.read(func(s *memoryStore) error {
+		stored := s.activeTransaction(transactionID)
+		if stored == nil {
+			return errors.Wrap(sql.ErrNoRows, "could not get transaction")
+		}
+		transaction = copyOf(stored).(*model.Transaction)
+		return nil
+	})
+	return transaction, err
+}
+
+// listTransactions returns not deleted transactions with date in (from, to), both ends are excluded
+func (m *memory) listTransactions(from, to time.Time, match func(transaction *model.Transaction) bool) ([]*model.Transaction, error) {
+	from, to = memoryTime(from), memoryTime(to)
+
+	var transactions []*model.Transaction
+	err := m.read(func(s *memoryStore) error {
+		for _, transaction := range s.Transactions {
+			if transaction.DeletedAt == nil && match(transaction) && transaction.Date.After(from) && transaction.Date.Before(to) {
+				transactions = append(transactions, copyOf(transaction).(*model.Transaction))
+			}
+		}
+		return nil
+	})
+	return transactions, err
+}
+
+func (m *memory) ListTransactionByUserID(ctx context.Context, userID model.UserID, from, to time.Time) ([]*model.Transaction, error) {
+	return m.listTransactions(from, to, func(transaction *model.Transaction) bool {
+		return *transaction.UserID == userID
+	})
+}
+
+func (m *memory) ListTransactionByCategoryID(ctx context.Context, categoryID model.CategoryID, from, to time.Time) ([]*model.Transaction, error) {
+	return m.listTransactions(from, to, func(transaction *model.Transaction) bool {
+		return *transaction.CategoryID == categoryID
+	})
+}
+
+func (m *memory) ListTransactionByAccountID(ctx context.Context, accountID model.AccountID, from, to time.Time) ([]*model.Transaction, error) {
+	return m.listTransactions(from, to, func(transaction *model.Transaction) bool {
+		return *transaction.AccountID == accountID
+	})
+}
+
+func (m *memory) DeleteTransaction(ctx context.Context, transactionID model.TransactionID) (bool, error) {
+	deleted := false
+	err := m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.activeTransaction(transactionID)
+		if stored == nil {
+			return nil, nil
+		}
+		before := copyOf(stored).(*model.Transaction)
+
+		stored.DeletedAt = &now
+		deleted = true
+
+		return newAuditEntry(model.AuditEntityTransaction, model.AuditDeleted, string(transactionID), before.UserID, before, nil)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+func (m *memory) RestoreTransaction(ctx context.Context, transactionID model.TransactionID, state *model.Transaction) (*model.Transaction, error) {
+	var restored *model.Transaction
+	err := m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.findTransaction(transactionID)
+		if stored == nil {
+			return nil, errors.New("transaction not found")
+		}
+		before := copyOf(stored).(*model.Transaction)
+
+		next := before
+		if state != nil {
+			next = state
+		}
+		if err := s.setTransaction(stored, next); err != nil {
+			return nil, err
+		}
+		stored.DeletedAt = nil
+
+		restored = copyOf(stored).(*model.Transaction)
+		return newAuditEntry(model.AuditEntityTransaction, model.AuditRestored, string(transactionID), before.UserID, before, restored)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not restore transaction")
+	}
+
+	return restored, nil
+}
+
+// deleteTransactions deletes transactions of deleted account or category with the same deleted_at
+func (s *memoryStore) deleteTransactions(ctx context.Context, transactions []*model.Transaction, now time.Time) {
+	for _, transaction := range transactions {
+		before := copyOf(transaction).(*model.Transaction)
+		transaction.DeletedAt = &now
+
+		entry, err := newAuditEntry(model.AuditEntityTransaction, model.AuditDeleted, string(transaction.ID), transaction.UserID, before, nil)
+		if err == nil {
+			s.writeAudit(ctx, entry, now)
+		}
+	}
+}
+
+// moveTransactions changes account or category of transactions, move sets new one
+func (s *memoryStore) moveTransactions(ctx context.Context, transactions []*model.Transaction, now time.Time, move func(transaction *model.Transaction)) {
+	for _, transaction := range transactions {
+		before := copyOf(transaction).(*model.Transaction)
+		move(transaction)
+
+		entry, err := newAuditEntry(model.AuditEntityTransaction, model.AuditUpdated, string(transaction.ID), transaction.UserID, before, transaction)
+		if err == nil {
+			s.writeAudit(ctx, entry, now)
+		}
+	}
+}
+
+// SetPrices saves all prices or none of them like setPriceQuery in transaction
+func (m *memory) SetPrices(ctx context.Context, prices []*model.Price) error {
+	return m.update(func(s *memoryStore, now time.Time) error {
+		for _, price := range prices {
+			if err := checkNotNull(price.UserID, price.Symbol, price.Date, price.Price); err != nil {
+				return errors.Wrap(err, "could not set price")
+			}
+			if s.findUser(*price.UserID) == nil {
+				return errors.Wrap(errForeignKey, "could not set price")
+			}
+
+			next := copyOf(price).(*model.Price)
+			date := memoryDate(*next.Date)
+			next.Date = &date
+			next.CreatedAt = &now
+
+			replaced := false
+			for i, stored := range s.Prices {
+				if *stored.UserID == *next.UserID && *stored.Symbol == *next.Symbol && stored.Date.Equal(date) {
+					s.Prices[i] = next
+					replaced = true
+				}
+			}
+			if !replaced {
+				s.Prices = append(s.Prices, next)
+			}
+		}
+		return nil
+	})
+}
+
+// sortPrices orders prices by symbol and date like ORDER BY symbol, date
+func sortPrices(prices []*model.Price) {
+	sort.SliceStable(prices, func(i, j int) bool {
+		if *prices[i].Symbol != *prices[j].Symbol {
+			return *prices[i].Symbol < *prices[j].Symbol
+		}
+		return prices[i].Date.Before(*prices[j].Date)
+	})
+}
+
+func (m *memory) ListPrices(ctx context.Context, userID model.UserID, symbol string) ([]*model.Price, error) {
+	var prices []*model.Price
+	err := m.read(func(s *memoryStore) error {
+		for _, price := range s.Prices {
+			if *price.UserID == userID && (symbol == "" || *price.Symbol == symbol) {
+				prices = append(prices, copyOf(price).(*model.Price))
+			}
+		}
+		return nil
+	})
+	sortPrices(prices)
+	return prices, err
+}
+
+func (m *memory) ListLatestPrices(ctx context.Context, userID model.UserID, at time.Time) ([]*model.Price, error) {
+	latest := make(map[string]*model.Price)
+	err := m.read(func(s *memoryStore) error {
+		for _, price := range s.Prices {
+			if *price.UserID != userID || price.Date.After(memoryTime(at)) {
+				continue
+			}
+			if found, ok := latest[*price.Symbol]; !ok || price.Date.After(*found.Date) {
+				latest[*price.Symbol] = copyOf(price).(*model.Price)
+			}
+		}
+		return nil
+	})
+
+	prices := make([]*model.Price, 0, len(latest))
+	for _, price := range latest {
+		prices = append(prices, price)
+	}
+	sortPrices(prices)
+	return prices, err
+}
+
+func (s *memoryStore) findReconciliation(reconciliationID model.ReconciliationID) *model.Reconciliation {
+	for _, reconciliation := range s.Reconciliations {
+		if reconciliation.ID == reconciliationID {
+			return reconciliation
+		}
+	}
+	return nil
+}
+
+func (m *memory) CreateReconciliation(ctx context.Context, reconciliation *model.Reconciliation) error {
+	err := m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		if err := checkNotNull(reconciliation.UserID, reconciliation.AccountID, reconciliation.StatementDate, reconciliation.EndingBalance); err != nil {
+			return nil, err
+		}
+		if s.findUser(*reconciliation.UserID) == nil || s.findAccount(*reconciliation.AccountID) == nil {
+			return nil, errForeignKey
+		}
+		// like reconciliations_open_idx
+		for _, stored := range s.Reconciliations {
+			if *stored.AccountID == *reconciliation.AccountID && stored.LockedAt == nil {
+				return nil, errors.New("duplicate key value violates unique constraint")
+			}
+		}
+
+		created := copyOf(reconciliation).(*model.Reconciliation)
+		created.ID = model.ReconciliationID(newMemoryID())
+		date := memoryDate(*created.StatementDate)
+		created.StatementDate = &date
+		created.CreatedAt = &now
+		created.LockedAt = nil
+		s.Reconciliations = append(s.Reconciliations, created)
+
+		reconciliation.ID = created.ID
+		reconciliation.CreatedAt = &now
+		return newAuditEntry(model.AuditEntityReconciliation, model.AuditCreated, string(reconciliation.ID), reconciliation.UserID, nil, reconciliation)
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not create reconciliation")
+	}
+	return nil
+}
+
+func (m *memory) GetReconciliationByID(ctx context.Context, reconciliationID model.ReconciliationID) (*model.Reconciliation, error) {
+	var reconciliation *model.Reconciliation
+	err := m.read(func(s *memoryStore) error {
+		stored := s.findReconciliation(reconciliationID)
+		if stored == nil {
+			return errors.Wrap(sql.ErrNoRows, "could not get reconciliation")
+		}
+		reconciliation = copyOf(stored).(*model.Reconciliation)
+		return nil
+	})
+	return reconciliation, err
+}
+
+func (m *memory) ListReconciliationsByAccountID(ctx context.Context, accountID model.AccountID) ([]*model.Reconciliation, error) {
+	var reconciliations []*model.Reconciliation
+	err := m.read(func(s *memoryStore) error {
+		for _, reconciliation := range s.Reconciliations {
+			if *reconciliation.AccountID == accountID {
+				reconciliations = append(reconciliations, copyOf(reconciliation).(*model.Reconciliation))
+			}
+		}
+		return nil
+	})
+
+	// like ORDER BY statement_date DESC, created_at DESC
+	sort.SliceStable(reconciliations, func(i, j int) bool {
+		if !reconciliations[i].StatementDate.Equal(*reconciliations[j].StatementDate) {
+			return reconciliations[i].StatementDate.After(*reconciliations[j].StatementDate)
+		}
+		return reconciliations[i].CreatedAt.After(*reconciliations[j].CreatedAt)
+	})
+	return reconciliations, err
+}
+
+// setTransactionsCleared changes cleared status of transactions, every change is audited like update of transaction
+func (s *memoryStore) setTransactionsCleared(ctx context.Context, transactions []*model.Transaction, status model.ClearedStatus, now time.Time) {
+	for _, transaction := range transactions {
+		before := copyOf(transaction).(*model.Transaction)
+		cleared := status
+		transaction.ClearedStatus = &cleared
+
+		entry, err := newAuditEntry(model.AuditEntityTransaction, model.AuditUpdated, string(transaction.ID), transaction.UserID, before, transaction)
+		if err == nil {
+			s.writeAudit(ctx, entry, now)
+		}
+	}
+}
+
+func (m *memory) SetTransactionsCleared(ctx context.Context, accountID model.AccountID, transactionIDs []model.TransactionID, status model.ClearedStatus) error {
+	return m.update(func(s *memoryStore, now time.Time) error {
+		if !status.IsValid() {
+			return errors.New("invalid input value for enum cleared_status")
+		}
+
+		transactions := make([]*model.Transaction, 0, len(transactionIDs))
+		for _, transactionID := range transactionIDs {
+			stored := s.activeTransaction(transactionID)
+			if stored == nil || *stored.AccountID != accountID {
+				return errors.Wrap(sql.ErrNoRows, "could not get transaction")
+			}
+			transactions = append(transactions, stored)
+		}
+
+		s.setTransactionsCleared(ctx, transactions, status, now)
+		return nil
+	})
+}
+
+func (m *memory) LockReconciliation(ctx context.Context, reconciliationID model.ReconciliationID) (*model.Reconciliation, error) {
+	var locked *model.Reconciliation
+	err := m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.findReconciliation(reconciliationID)
+		if stored == nil {
+			return nil, sql.ErrNoRows
+		}
+		if stored.LockedAt != nil {
+			return nil, ErrReconciliationLocked
+		}
+		before := copyOf(stored).(*model.Reconciliation)
+
+		stored.LockedAt = &now
+
+		end := stored.PeriodEnd()
+		var transactions []*model.Transaction
+		for _, transaction := range s.Transactions {
+			if *transaction.AccountID == *stored.AccountID && transaction.DeletedAt == nil && transaction.IsCounted(false) &&
+				transaction.ClearedStatus != nil && *transaction.ClearedStatus == model.Cleared && transaction.Date.Before(end) {
+				transactions = append(transactions, transaction)
+			}
+		}
+		s.setTransactionsCleared(ctx, transactions, model.Reconciled, now)
+
+		locked = copyOf(stored).(*model.Reconciliation)
+		return newAuditEntry(model.AuditEntityReconciliation, model.AuditUpdated, string(reconciliationID), before.UserID, before, locked)
+	})
+	if err == ErrReconciliationLocked {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not lock reconciliation")
+	}
+
+	return locked, nil
+}
@@ -0,0 +1,735 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func (m *memory) CreateAuditEntry(ctx context.Context, entry *model.AuditEntry) error {
+	return m.update(func(s *memoryStore, now time.Time) error {
+		fillAuditActor(ctx, entry)
+		s.insertAuditEntry(entry, now)
+		return nil
+	})
+}
+
+// ListAuditEntries returns newest entries first
+func (m *memory) ListAuditEntries(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEntry, error) {
+	from, to := memoryTime(filter.From), memoryTime(filter.To)
+	userIs := func(userID *model.UserID, want model.UserID) bool {
+		return want == model.NilUserID || userID != nil && *userID == want
+	}
+
+	var entries []*model.AuditEntry
+	err := m.read(func(s *memoryStore) error {
+		for _, entry := range s.Audit {
+			if !userIs(entry.UserID, filter.UserID) || !userIs(entry.ActorID, filter.ActorID) || !userIs(entry.ImpersonatorID, filter.ImpersonatorID) {
+				continue
+			}
+			if filter.EntityType != "" && (entry.EntityType == nil || *entry.EntityType != filter.EntityType) {
+				continue
+			}
+			if filter.EntityID != "" && (entry.EntityID == nil || *entry.EntityID != filter.EntityID) {
+				continue
+			}
+			if filter.Action != "" && entry.Action != filter.Action {
+				continue
+			}
+			if !filter.From.IsZero() && entry.CreatedAt.Before(from) {
+				continue
+			}
+			if !filter.To.IsZero() && !entry.CreatedAt.Before(to) {
+				continue
+			}
+			entries = append(entries, copyOf(entry).(*model.AuditEntry))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(*entries[j].CreatedAt) {
+			return entries[i].CreatedAt.After(*entries[j].CreatedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(entries) {
+		offset = len(entries)
+	}
+	entries = entries[offset:]
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries, nil
+}
+
+// ListRevisions returns newest revisions first
+func (m *memory) ListRevisions(ctx context.Context, entity model.AuditEntity, entityID string) ([]*model.Revision, error) {
+	var revisions []*model.Revision
+	err := m.read(func(s *memoryStore) error {
+		for _, revision := range s.Revisions {
+			if revision.EntityType == entity && revision.EntityID == entityID {
+				revisions = append(revisions, copyOf(revision).(*model.Revision))
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+	return revisions, err
+}
+
+func (m *memory) GetRevision(ctx context.Context, entity model.AuditEntity, entityID string, revision int) (*model.Revision, error) {
+	var found *model.Revision
+	err := m.read(func(s *memoryStore) error {
+		for _, r := range s.Revisions {
+			if r.EntityType == entity && r.EntityID == entityID && r.Revision == revision {
+				found = copyOf(r).(*model.Revision)
+				return nil
+			}
+		}
+		return ErrRevisionNotFound
+	})
+	return found, err
+}
+
+// owned returns owner and deleted_at of versioned entity, deleted one too, ok is false when it doesn't exist
+func (s *memoryStore) owned(entity model.AuditEntity, id string) (userID *model.UserID, deletedAt *time.Time, ok bool) {
+	switch entity {
+	case model.AuditEntityAccount:
+		if account := s.findAccount(model.AccountID(id)); account != nil {
+			return account.UserID, account.DeletedAt, true
+		}
+	case model.AuditEntityCategory:
+		if category := s.findCategory(model.CategoryID(id)); category != nil {
+			return category.UserID, category.DeletedAt, true
+		}
+	case model.AuditEntityMerchant:
+		if merchant := s.findMerchant(model.MerchantID(id)); merchant != nil {
+			return merchant.UserID, merchant.DeletedAt, true
+		}
+	case model.AuditEntityTransaction:
+		if transaction := s.findTransaction(model.TransactionID(id)); transaction != nil {
+			return transaction.UserID, transaction.DeletedAt, true
+		}
+	}
+	return nil, nil, false
+}
+
+func (m *memory) GetResourceOwner(ctx context.Context, entity model.AuditEntity, id string) (*model.UserID, error) {
+	if _, ok := resourceOwnerQueries[entity]; !ok {
+		return nil, fmt.Errorf("unknown resource: %s", entity)
+	}
+
+	var userID *model.UserID
+	err := m.read(func(s *memoryStore) error {
+		owner, _, ok := s.owned(entity, id)
+		if !ok {
+			return errors.Wrap(sql.ErrNoRows, "could not get resource owner")
+		}
+		userID = copyUserID(owner)
+		return nil
+	})
+	return userID, err
+}
+
+// ListTrash returns deleted items of user, recently deleted first
+func (m *memory) ListTrash(ctx context.Context, userID model.UserID) (*model.Trash, error) {
+	trash := model.Trash{
+		Accounts:     make([]*model.TrashAccount, 0),
+		Categories:   make([]*model.Category, 0),
+		Merchants:    make([]*model.Merchant, 0),
+		Transactions: make([]*model.Transaction, 0),
+	}
+
+	err := m.read(func(s *memoryStore) error {
+		for _, account := range s.Accounts {
+			if *account.UserID != userID || account.DeletedAt == nil {
+				continue
+			}
+			item := &model.TrashAccount{Account: *copyOf(account).(*model.Account)}
+			for _, transaction := range s.Transactions {
+				if *transaction.AccountID == account.ID && transaction.DeletedAt != nil && transaction.DeletedAt.Equal(*account.DeletedAt) {
+					item.DeletedTransactions++
+				}
+			}
+			trash.Accounts = append(trash.Accounts, item)
+		}
+		for _, category := range s.Categories {
+			if *category.UserID == userID && category.DeletedAt != nil {
+				trash.Categories = append(trash.Categories, copyOf(category).(*model.Category))
+			}
+		}
+		for _, merchant := range s.Merchants {
+			if *merchant.UserID == userID && merchant.DeletedAt != nil {
+				trash.Merchants = append(trash.Merchants, copyOf(merchant).(*model.Merchant))
+			}
+		}
+		for _, transaction := range s.Transactions {
+			if *transaction.UserID == userID && transaction.DeletedAt != nil {
+				trash.Transactions = append(trash.Transactions, copyOf(transaction).(*model.Transaction))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(trash.Accounts, func(i, j int) bool {
+		return trash.Accounts[i].DeletedAt.After(*trash.Accounts[j].DeletedAt)
+	})
+	sort.SliceStable(trash.Categories, func(i, j int) bool {
+		return trash.Categories[i].DeletedAt.After(*trash.Categories[j].DeletedAt)
+	})
+	sort.SliceStable(trash.Merchants, func(i, j int) bool {
+		return trash.Merchants[i].DeletedAt.After(*trash.Merchants[j].DeletedAt)
+	})
+	sort.SliceStable(trash.Transactions, func(i, j int) bool {
+		return trash.Transactions[i].DeletedAt.After(*trash.Transactions[j].DeletedAt)
+	})
+
+	return &trash, nil
+}
+
+// purgedState returns stored entity which is logged as state before purge
+func (s *memoryStore) purgedState(entity model.AuditEntity, id string) interface{} {
+	switch entity {
+	case model.AuditEntityAccount:
+		return copyOf(s.findAccount(model.AccountID(id)))
+	case model.AuditEntityCategory:
+		return copyOf(s.findCategory(model.CategoryID(id)))
+	case model.AuditEntityMerchant:
+		return copyOf(s.findMerchant(model.MerchantID(id)))
+	}
+	return copyOf(s.findTransaction(model.TransactionID(id)))
+}
+
+// PurgeDeleted removes deleted item with its history, account or category which still has transactions can't be purged
+func (m *memory) PurgeDeleted(ctx context.Context, entity model.AuditEntity, id string) error {
+	if _, ok := purges[entity]; !ok {
+		return errors.Errorf("unknown entity: %s", entity)
+	}
+
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		userID, deletedAt, ok := s.owned(entity, id)
+		if !ok || deletedAt == nil {
+			return nil, ErrNotInTrash
+		}
+		before := s.purgedState(entity, id)
+
+		// transactions of account or category go with it
+		var dependent func(transaction *model.Transaction) bool
+		switch entity {
+		case model.AuditEntityAccount:
+			dependent = func(transaction *model.Transaction) bool { return string(*transaction.AccountID) == id }
+		case model.AuditEntityCategory:
+			dependent = func(transaction *model.Transaction) bool { return string(*transaction.CategoryID) == id }
+		}
+
+		if dependent != nil {
+			for _, transaction := range s.Transactions {
+				if dependent(transaction) && transaction.DeletedAt == nil {
+					return nil, ErrHasDependents
+				}
+			}
+
+			// child categories would point to missing parent
+			for _, category := range s.Categories {
+				if entity == model.AuditEntityCategory && string(category.ParentID) == id && category.DeletedAt == nil {
+					return nil, ErrHasChildren
+				}
+			}
+
+			transactions := s.Transactions[:0]
+			for _, transaction := range s.Transactions {
+				if dependent(transaction) {
+					s.deleteRevisions(model.AuditEntityTransaction, string(transaction.ID))
+					continue
+				}
+				transactions = append(transactions, transaction)
+			}
+			s.Transactions = transactions
+		}
+
+		switch entity {
+		case model.AuditEntityAccount:
+			members := s.Members[:0]
+			for _, member := range s.Members {
+				if string(member.AccountID) != id {
+					members = append(members, member)
+				}
+			}
+			s.Members = members
+
+			reconciliations := s.Reconciliations[:0]
+			for _, reconciliation := range s.Reconciliations {
+				if string(*reconciliation.AccountID) != id {
+					reconciliations = append(reconciliations, reconciliation)
+				}
+			}
+			s.Reconciliations = reconciliations
+
+			accounts := s.Accounts[:0]
+			for _, account := range s.Accounts {
+				if string(account.ID) != id {
+					accounts = append(accounts, account)
+				}
+			}
+			s.Accounts = accounts
+		case model.AuditEntityCategory:
+			categories := s.Categories[:0]
+			for _, category := range s.Categories {
+				if string(category.ID) != id {
+					categories = append(categories, category)
+				}
+			}
+			s.Categories = categories
+		case model.AuditEntityMerchant:
+			merchants := s.Merchants[:0]
+			for _, merchant := range s.Merchants {
+				if string(merchant.ID) != id {
+					merchants = append(merchants, merchant)
+				}
+			}
+			s.Merchants = merchants
+		case model.AuditEntityTransaction:
+			transactions := s.Transactions[:0]
+			for _, transaction := range s.Transactions {
+				if string(transaction.ID) != id {
+					transactions = append(transactions, transaction)
+				}
+			}
+			s.Transactions = transactions
+		}
+
+		s.deleteRevisions(entity, id)
+
+		return newAuditEntry(entity, model.AuditPurged, id, userID, before, nil)
+	})
+}
+
+func (s *memoryStore) deleteRevisions(entity model.AuditEntity, id string) {
+	revisions := s.Revisions[:0]
+	for _, revision := range s.Revisions {
+		if revision.EntityType != entity || revision.EntityID != id {
+			revisions = append(revisions, revision)
+		}
+	}
+	s.Revisions = revisions
+}
+
+// ListExpiredTrash returns items deleted before given time in order in which they can be purged
+func (m *memory) ListExpiredTrash(ctx context.Context, deletedBefore time.Time) ([]*model.TrashItem, error) {
+	deletedBefore = memoryTime(deletedBefore)
+	expired := func(deletedAt *time.Time) bool {
+		return deletedAt != nil && deletedAt.Before(deletedBefore)
+	}
+
+	var items []*model.TrashItem
+	err := m.read(func(s *memoryStore) error {
+		for _, transaction := range s.Transactions {
+			if expired(transaction.DeletedAt) {
+				items = append(items, &model.TrashItem{EntityType: model.AuditEntityTransaction, EntityID: string(transaction.ID)})
+			}
+		}
+		for _, merchant := range s.Merchants {
+			if expired(merchant.DeletedAt) {
+				items = append(items, &model.TrashItem{EntityType: model.AuditEntityMerchant, EntityID: string(merchant.ID)})
+			}
+		}
+		for _, category := range s.Categories {
+			if expired(category.DeletedAt) {
+				items = append(items, &model.TrashItem{EntityType: model.AuditEntityCategory, EntityID: string(category.ID)})
+			}
+		}
+		for _, account := range s.Accounts {
+			if expired(account.DeletedAt) {
+				items = append(items, &model.TrashItem{EntityType: model.AuditEntityAccount, EntityID: string(account.ID)})
+			}
+		}
+		return nil
+	})
+	return items, err
+}
+
+// GetUserExport returns all data of user (deleted user too)
+func (m *memory) GetUserExport(ctx context.Context, userID model.UserID) (*model.UserExport, error) {
+	export := model.UserExport{
+		Sessions:     make([]*model.SessionInfo, 0),
+		Roles:        make([]*model.UserRole, 0),
+		Identities:   make([]*model.UserIdentity, 0),
+		APIKeys:      make([]*model.APIKey, 0),
+		Accounts:     make([]*model.Account, 0),
+		Categories:   make([]*model.Category, 0),
+		Merchants:    make([]*model.Merchant, 0),
+		Transactions: make([]*model.Transaction, 0),
+		Prices:       make([]*model.Price, 0),
+
+		Reconciliations: make([]*model.Reconciliation, 0),
+	}
+
+	err := m.read(func(s *memoryStore) error {
+		user := s.findUser(userID)
+		if user == nil {
+			return errors.Wrap(sql.ErrNoRows, "could not get user")
+		}
+		export.User = copyOf(user).(*model.User)
+		export.User.PasswordHash = nil
+
+		for _, session := range s.Sessions {
+			if session.UserID == userID {
+				export.Sessions = append(export.Sessions, &model.SessionInfo{DeviceID: session.DeviceID, ExpiresAt: session.ExpiresAt})
+			}
+		}
+		for _, role := range s.UserRoles {
+			if role.UserID == userID {
+				export.Roles = append(export.Roles, &model.UserRole{Role: role.Role})
+			}
+		}
+		for _, identity := range s.Identities {
+			if identity.UserID == userID {
+				export.Identities = append(export.Identities, copyOf(identity).(*model.UserIdentity))
+			}
+		}
+		for _, key := range s.APIKeys {
+			if key.UserID == userID {
+				exported := copyOf(key).(*model.APIKey)
+				exported.KeyHash = nil
+				export.APIKeys = append(export.APIKeys, exported)
+			}
+		}
+		for _, account := range s.Accounts {
+			if *account.UserID == userID {
+				export.Accounts = append(export.Accounts, copyOf(account).(*model.Account))
+			}
+		}
+		for _, category := range s.Categories {
+			if *category.UserID == userID {
+				export.Categories = append(export.Categories, copyOf(category).(*model.Category))
+			}
+		}
+		for _, merchant := range s.Merchants {
+			if *merchant.UserID == userID {
+				export.Merchants = append(export.Merchants, copyOf(merchant).(*model.Merchant))
+			}
+		}
+		for _, transaction := range s.Transactions {
+			if *transaction.UserID == userID {
+				export.Transactions = append(export.Transactions, copyOf(transaction).(*model.Transaction))
+			}
+		}
+		for _, price := range s.Prices {
+			if *price.UserID == userID {
+				export.Prices = append(export.Prices, copyOf(price).(*model.Price))
+			}
+		}
+		for _, reconciliation := range s.Reconciliations {
+			if *reconciliation.UserID == userID {
+				export.Reconciliations = append(export.Reconciliations, copyOf(reconciliation).(*model.Reconciliation))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(export.Transactions, func(i, j int) bool {
+		return export.Transactions[i].Date.Before(*export.Transactions[j].Date)
+	})
+	sortPrices(export.Prices)
+
+	return &export, nil
+}
+
+func (s *memoryStore) findErasure(userID model.UserID) *model.ErasureRequest {
+	for _, request := range s.Erasures {
+		if request.UserID == userID {
+			return request
+		}
+	}
+	return nil
+}
+
+// CreateErasureRequest deletes user right away and schedules erasure of his data
+func (m *memory) CreateErasureRequest(ctx context.Context, request *model.ErasureRequest) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		if s.findErasure(request.UserID) != nil {
+			return nil, ErrErasureExists
+		}
+		user := s.findUser(request.UserID)
+		if user == nil || request.RequestedBy != nil && s.findUser(*request.RequestedBy) == nil {
+			return nil, errors.Wrap(errForeignKey, "could not create erasure request")
+		}
+
+		created := copyOf(request).(*model.ErasureRequest)
+		created.ScheduledAt = memoryTime(request.ScheduledAt)
+		created.CreatedAt = &now
+		created.CompletedAt = nil
+		s.Erasures = append(s.Erasures, created)
+		request.CreatedAt = &now
+
+		if user.DeletedAt == nil {
+			deleteMemoryUser(user, now)
+		}
+
+		sessions := s.Sessions[:0]
+		for _, session := range s.Sessions {
+			if session.UserID != request.UserID {
+				sessions = append(sessions, session)
+			}
+		}
+		s.Sessions = sessions
+
+		return erasureAuditEntry(model.AuditErasureRequested, request)
+	})
+}
+
+func (m *memory) GetErasureRequest(ctx context.Context, userID model.UserID) (*model.ErasureRequest, error) {
+	var request *model.ErasureRequest
+	err := m.read(func(s *memoryStore) error {
+		stored := s.findErasure(userID)
+		if stored == nil {
+			return ErrErasureNotFound
+		}
+		request = copyOf(stored).(*model.ErasureRequest)
+		return nil
+	})
+	return request, err
+}
+
+// CancelErasureRequest undeletes user in grace period, erased user can't be brought back
+func (m *memory) CancelErasureRequest(ctx context.Context, userID model.UserID) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		request := s.findErasure(userID)
+		if request == nil || request.CompletedAt != nil {
+			return nil, ErrErasureNotFound
+		}
+
+		erasures := s.Erasures[:0]
+		for _, stored := range s.Erasures {
+			if stored != request {
+				erasures = append(erasures, stored)
+			}
+		}
+		s.Erasures = erasures
+
+		if user := s.findUser(userID); user != nil {
+			email := strings.SplitN(*user.Email, "-DELETE-", 2)[0]
+			for _, other := range s.Users {
+				if other != user && *other.Email == email {
+					return nil, ErrUserExist
+				}
+			}
+			user.Email = &email
+			user.DeletedAt = nil
+		}
+
+		return erasureAuditEntry(model.AuditErasureCanceled, request)
+	})
+}
+
+func (m *memory) ListDueErasureRequests(ctx context.Context, now time.Time) ([]*model.ErasureRequest, error) {
+	now = memoryTime(now)
+
+	var requests []*model.ErasureRequest
+	err := m.read(func(s *memoryStore) error {
+		for _, request := range s.Erasures {
+			if request.CompletedAt == nil && !request.ScheduledAt.After(now) {
+				requests = append(requests, copyOf(request).(*model.ErasureRequest))
+			}
+		}
+		return nil
+	})
+
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].ScheduledAt.Before(requests[j].ScheduledAt)
+	})
+	return requests, err
+}
+
+// EraseUser removes all data of user like eraseUserQueries, user row stays without anything personal
+func (m *memory) EraseUser(ctx context.Context, userID model.UserID) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		owned := func(id *model.UserID) bool { return id != nil && *id == userID }
+
+		accounts := make(map[model.AccountID]bool)
+		for _, account := range s.Accounts {
+			if owned(account.UserID) {
+				accounts[account.ID] = true
+			}
+		}
+
+		revisions := s.Revisions[:0]
+		for _, revision := range s.Revisions {
+			if !owned(revision.UserID) {
+				revisions = append(revisions, revision)
+			}
+		}
+		s.Revisions = revisions
+
+		transactions := s.Transactions[:0]
+		for _, transaction := range s.Transactions {
+			if !owned(transaction.UserID) && !accounts[*transaction.AccountID] {
+				transactions = append(transactions, transaction)
+			}
+		}
+		s.Transactions = transactions
+
+		reconciliations := s.Reconciliations[:0]
+		for _, reconciliation := range s.Reconciliations {
+			if !owned(reconciliation.UserID) && !accounts[*reconciliation.AccountID] {
+				reconciliations = append(reconciliations, reconciliation)
+			}
+		}
+		s.Reconciliations = reconciliations
+
+		members := s.Members[:0]
+		for _, member := range s.Members {
+			if member.UserID != userID && member.InvitedBy != userID && !accounts[member.AccountID] {
+				members = append(members, member)
+			}
+		}
+		s.Members = members
+
+		keptAccounts := s.Accounts[:0]
+		for _, account := range s.Accounts {
+			if !accounts[account.ID] {
+				keptAccounts = append(keptAccounts, account)
+			}
+		}
+		s.Accounts = keptAccounts
+
+		categories := s.Categories[:0]
+		for _, category := range s.Categories {
+			if !owned(category.UserID) {
+				categories = append(categories, category)
+			}
+		}
+		s.Categories = categories
+
+		merchants := s.Merchants[:0]
+		for _, merchant := range s.Merchants {
+			if !owned(merchant.UserID) {
+				merchants = append(merchants, merchant)
+			}
+		}
+		s.Merchants = merchants
+
+		prices := s.Prices[:0]
+		for _, price := range s.Prices {
+			if !owned(price.UserID) {
+				prices = append(prices, price)
+			}
+		}
+		s.Prices = prices
+
+		sessions := s.Sessions[:0]
+		for _, session := range s.Sessions {
+			if session.UserID != userID {
+				sessions = append(sessions, session)
+			}
+		}
+		s.Sessions = sessions
+
+		roles := s.UserRoles[:0]
+		for _, role := range s.UserRoles {
+			if role.UserID != userID {
+				roles = append(roles, role)
+			}
+		}
+		s.UserRoles = roles
+
+		identities := s.Identities[:0]
+		for _, identity := range s.Identities {
+			if identity.UserID != userID {
+				identities = append(identities, identity)
+			}
+		}
+		s.Identities = identities
+
+		s.deleteRecoveryCodes(userID)
+
+		totps := s.TOTPs[:0]
+		for _, totp := range s.TOTPs {
+			if totp.UserID != userID {
+				totps = append(totps, totp)
+			}
+		}
+		s.TOTPs = totps
+
+		keys := s.APIKeys[:0]
+		for _, key := range s.APIKeys {
+			if key.UserID != userID {
+				keys = append(keys, key)
+			}
+		}
+		s.APIKeys = keys
+
+		user := s.findUser(userID)
+		attemptKeys := map[string]bool{"2fa:" + string(userID): true}
+		if user != nil {
+			attemptKeys["account:"+strings.ToLower(strings.SplitN(*user.Email, "-DELETE-", 2)[0])] = true
+		}
+		attempts := s.LoginAttempts[:0]
+		for _, attempt := range s.LoginAttempts {
+			if !attemptKeys[attempt.Key] {
+				attempts = append(attempts, attempt)
+			}
+		}
+		s.LoginAttempts = attempts
+
+		for _, entry := range s.Audit {
+			if owned(entry.UserID) {
+				entry.IP, entry.DeviceID, entry.Details, entry.Before, entry.After, entry.Diff = nil, nil, nil, nil, nil, nil
+			}
+			if owned(entry.ActorID) {
+				entry.IP, entry.DeviceID = nil, nil
+			}
+		}
+
+		if user != nil {
+			email := "erased-" + string(userID) + "@invalid"
+			user.Email = &email
+			user.PasswordHash = nil
+			if user.DeletedAt == nil {
+				user.DeletedAt = &now
+			}
+		}
+
+		if request := s.findErasure(userID); request != nil {
+			request.CompletedAt = &now
+		}
+
+		return &model.AuditEntry{
+			Action: model.AuditUserErased,
+			UserID: &userID,
+		}, nil
+	})
+}
@@ -0,0 +1,754 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func (s *memoryStore) findUser(userID model.UserID) *model.User {
+	for _, user := range s.Users {
+		if user.ID == userID {
+			return user
+		}
+	}
+	return nil
+}
+
+// activeUser returns user who is not deleted, as selected by getUserByIDQuery
+func (s *memoryStore) activeUser(userID model.UserID) *model.User {
+	user := s.findUser(userID)
+	if user == nil || user.DeletedAt != nil {
+		return nil
+	}
+	return user
+}
+
+// selectedUser is user without deleted_at, queries of users don't select it
+func selectedUser(user *model.User) *model.User {
+	selected := copyOf(user).(*model.User)
+	selected.DeletedAt = nil
+	return selected
+}
+
+func (m *memory) CreateUser(ctx context.Context, user *model.User) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		if user.Email == nil {
+			return nil, errors.New("could not create user: email is required")
+		}
+		for _, existing := range s.Users {
+			if *existing.Email == *user.Email {
+				return nil, ErrUserExist
+			}
+		}
+
+		created := copyOf(user).(*model.User)
+		created.ID = model.UserID(newMemoryID())
+		created.CreatedAt = &now
+		created.DeletedAt = nil
+		s.Users = append(s.Users, created)
+
+		user.ID = created.ID
+		return newAuditEntry(model.AuditEntityUser, model.AuditCreated, string(user.ID), &user.ID, nil, user)
+	})
+}
+
+func (m *memory) GetUserByID(ctx context.Context, userID model.UserID) (*model.User, error) {
+	var user *model.User
+	err := m.read(func(s *memoryStore) error {
+		found := s.activeUser(userID)
+		if found == nil {
+			return sql.ErrNoRows
+		}
+		user = selectedUser(found)
+		return nil
+	})
+	return user, err
+}
+
+func (m *memory) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	var user *model.User
+	err := m.read(func(s *memoryStore) error {
+		for _, found := range s.Users {
+			if *found.Email == email && found.DeletedAt == nil {
+				user = selectedUser(found)
+				return nil
+			}
+		}
+		return sql.ErrNoRows
+	})
+	return user, err
+}
+
+func (m *memory) ListUsers(ctx context.Context) ([]*model.User, error) {
+	var users []*model.User
+	err := m.read(func(s *memoryStore) error {
+		for _, user := range s.Users {
+			if user.DeletedAt == nil {
+				users = append(users, selectedUser(user))
+			}
+		}
+		return nil
+	})
+	return users, err
+}
+
+func (m *memory) DeleteUser(ctx context.Context, userID model.UserID) (bool, error) {
+	deleted := false
+	err := m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		user := s.activeUser(userID)
+		if user == nil {
+			return nil, nil
+		}
+		before := selectedUser(user)
+
+		deleteMemoryUser(user, now)
+		deleted = true
+
+		return newAuditEntry(model.AuditEntityUser, model.AuditDeleted, string(userID), &userID, before, nil)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+// deleteMemoryUser does what deleteUserQuery does, email gets suffix so it can be used again
+func deleteMemoryUser(user *model.User, now time.Time) {
+	email := *user.Email + "-DELETE-" + newMemoryID()
+	user.Email = &email
+	user.DeletedAt = &now
+}
+
+func (m *memory) UpdateUser(ctx context.Context, user *model.User) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.activeUser(user.ID)
+		if stored == nil {
+			return nil, errors.New("user not found")
+		}
+		before := selectedUser(stored)
+
+		stored.PasswordHash = copyOf(user.PasswordHash).(*[]byte)
+
+		entry, err := newAuditEntry(model.AuditEntityUser, model.AuditUpdated, string(user.ID), &user.ID, before, user)
+		if err != nil {
+			return nil, err
+		}
+
+		if !bytesPtrEqual(before.PasswordHash, user.PasswordHash) {
+			entry.Details = model.JSON(`{"passwordChanged": true}`)
+		}
+		return entry, nil
+	})
+}
+
+func (m *memory) SaveRefreshToken(ctx context.Context, session model.Session) error {
+	return m.update(func(s *memoryStore, now time.Time) error {
+		if s.findUser(session.UserID) == nil {
+			return errForeignKey
+		}
+
+		for _, stored := range s.Sessions {
+			if stored.UserID == session.UserID && stored.DeviceID == session.DeviceID {
+				stored.RefreshToken = session.RefreshToken
+				stored.ExpiresAt = session.ExpiresAt
+				return nil
+			}
+		}
+
+		s.Sessions = append(s.Sessions, &session)
+		return nil
+	})
+}
+
+func (m *memory) GetSession(ctx context.Context, data model.Session) (*model.Session, error) {
+	var session *model.Session
+	err := m.read(func(s *memoryStore) error {
+		now := m.clock()
+		for _, stored := range s.Sessions {
+			if stored.UserID == data.UserID && stored.DeviceID == data.DeviceID &&
+				stored.RefreshToken == data.RefreshToken && time.Unix(stored.ExpiresAt, 0).After(now) {
+				found := *stored
+				session = &found
+				return nil
+			}
+		}
+		return sql.ErrNoRows
+	})
+	return session, err
+}
+
+func (s *memoryStore) findRole(name model.Role) *model.RoleDefinition {
+	for _, role := range s.Roles {
+		if role.Name == name {
+			return role
+		}
+	}
+	return nil
+}
+
+func (m *memory) GrantRole(ctx context.Context, userID model.UserID, role model.Role) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		if s.findUser(userID) == nil || s.findRole(role) == nil {
+			return nil, errors.Wrap(errForeignKey, "could not grant user role")
+		}
+		for _, userRole := range s.UserRoles {
+			if userRole.UserID == userID && userRole.Role == role {
+				return nil, errors.New("could not grant user role: role is granted already")
+			}
+		}
+
+		s.UserRoles = append(s.UserRoles, &memoryUserRole{UserID: userID, Role: role})
+
+		return newAuditEntry(model.AuditEntityUserRole, model.AuditGranted, string(role), &userID, nil, &model.UserRole{Role: role})
+	})
+}
+
+func (m *memory) RevokeRole(ctx context.Context, userID model.UserID, role model.Role) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		revoked := false
+		userRoles := s.UserRoles[:0]
+		for _, userRole := range s.UserRoles {
+			if userRole.UserID == userID && userRole.Role == role {
+				revoked = true
+				continue
+			}
+			userRoles = append(userRoles, userRole)
+		}
+		s.UserRoles = userRoles
+
+		// user didn't have role, nothing changed
+		if !revoked {
+			return nil, nil
+		}
+
+		return newAuditEntry(model.AuditEntityUserRole, model.AuditRevoked, string(role), &userID, &model.UserRole{Role: role}, nil)
+	})
+}
+
+func (m *memory) GetRolesByUser(ctx context.Context, userID model.UserID) ([]*model.UserRole, error) {
+	var roles []*model.UserRole
+	err := m.read(func(s *memoryStore) error {
+		for _, userRole := range s.UserRoles {
+			if userRole.UserID == userID {
+				roles = append(roles, &model.UserRole{Role: userRole.Role})
+			}
+		}
+		return nil
+	})
+	return roles, err
+}
+
+// roleState is role as returned by getRole, permissions are sorted and never nil
+func roleState(role *model.RoleDefinition) *model.RoleDefinition {
+	state := copyOf(role).(*model.RoleDefinition)
+	if state.Permissions == nil {
+		state.Permissions = make([]model.Permission, 0)
+	}
+	sort.Slice(state.Permissions, func(i, j int) bool { return state.Permissions[i] < state.Permissions[j] })
+	return state
+}
+
+// uniquePermissions stores every permission once like primary key of role_permissions
+func uniquePermissions(permissions []model.Permission) []model.Permission {
+	unique := make([]model.Permission, 0, len(permissions))
+	seen := make(map[model.Permission]bool, len(permissions))
+	for _, permission := range permissions {
+		if !seen[permission] {
+			seen[permission] = true
+			unique = append(unique, permission)
+		}
+	}
+	return unique
+}
+
+func (m *memory) CreateRole(ctx context.Context, role *model.RoleDefinition) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		if s.findRole(role.Name) != nil {
+			return nil, ErrRoleExists
+		}
+
+		created := copyOf(role).(*model.RoleDefinition)
+		created.Permissions = uniquePermissions(role.Permissions)
+		created.CreatedAt = &now
+		created.UpdatedAt = &now
+		s.Roles = append(s.Roles, created)
+
+		role.CreatedAt = &now
+		role.UpdatedAt = &now
+		return newAuditEntry(model.AuditEntityRole, model.AuditCreated, string(role.Name), nil, nil, role)
+	})
+}
+
+func (m *memory) UpdateRole(ctx context.Context, role *model.RoleDefinition) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.findRole(role.Name)
+		if stored == nil {
+			return nil, ErrRoleNotFound
+		}
+		before := roleState(stored)
+
+		stored.Description = copyOf(role.Description).(*string)
+		stored.Permissions = uniquePermissions(role.Permissions)
+		stored.UpdatedAt = &now
+
+		return newAuditEntry(model.AuditEntityRole, model.AuditUpdated, string(role.Name), nil, before, roleState(stored))
+	})
+}
+
+func (m *memory) GetRole(ctx context.Context, name model.Role) (*model.RoleDefinition, error) {
+	var role *model.RoleDefinition
+	err := m.read(func(s *memoryStore) error {
+		stored := s.findRole(name)
+		if stored == nil {
+			return ErrRoleNotFound
+		}
+		role = roleState(stored)
+		return nil
+	})
+	return role, err
+}
+
+func (m *memory) ListRoles(ctx context.Context) ([]*model.RoleDefinition, error) {
+	var roles []*model.RoleDefinition
+	err := m.read(func(s *memoryStore) error {
+		for _, role := range s.Roles {
+			roles = append(roles, roleState(role))
+		}
+		return nil
+	})
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, err
+}
+
+func (m *memory) DeleteRole(ctx context.Context, name model.Role) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.findRole(name)
+		if stored == nil {
+			return nil, ErrRoleNotFound
+		}
+		before := roleState(stored)
+
+		roles := s.Roles[:0]
+		for _, role := range s.Roles {
+			if role.Name != name {
+				roles = append(roles, role)
+			}
+		}
+		s.Roles = roles
+
+		// ON DELETE CASCADE
+		userRoles := s.UserRoles[:0]
+		for _, userRole := range s.UserRoles {
+			if userRole.Role != name {
+				userRoles = append(userRoles, userRole)
+			}
+		}
+		s.UserRoles = userRoles
+
+		return newAuditEntry(model.AuditEntityRole, model.AuditDeleted, string(name), nil, before, nil)
+	})
+}
+
+func (m *memory) GetPermissionsByUser(ctx context.Context, userID model.UserID) ([]model.Permission, error) {
+	var permissions []model.Permission
+	err := m.read(func(s *memoryStore) error {
+		seen := make(map[model.Permission]bool)
+		for _, userRole := range s.UserRoles {
+			if userRole.UserID != userID {
+				continue
+			}
+			role := s.findRole(userRole.Role)
+			if role == nil {
+				continue
+			}
+			for _, permission := range role.Permissions {
+				if !seen[permission] {
+					seen[permission] = true
+					permissions = append(permissions, permission)
+				}
+			}
+		}
+		return nil
+	})
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i] < permissions[j] })
+	return permissions, err
+}
+
+func (m *memory) CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	return m.update(func(s *memoryStore, now time.Time) error {
+		if s.findUser(identity.UserID) == nil {
+			return errors.Wrap(errForeignKey, "could not create user identity")
+		}
+		for _, stored := range s.Identities {
+			if stored.Provider == identity.Provider && stored.Subject == identity.Subject {
+				return errors.New("could not create user identity: identity exists")
+			}
+		}
+
+		created := copyOf(identity).(*model.UserIdentity)
+		created.CreatedAt = &now
+		s.Identities = append(s.Identities, created)
+		return nil
+	})
+}
+
+func (m *memory) GetUserIdentity(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	var identity *model.UserIdentity
+	err := m.read(func(s *memoryStore) error {
+		for _, stored := range s.Identities {
+			if stored.Provider == provider && stored.Subject == subject {
+				identity = copyOf(stored).(*model.UserIdentity)
+				return nil
+			}
+		}
+		return ErrIdentityNotFound
+	})
+	return identity, err
+}
+
+func (m *memory) ListUserIdentities(ctx context.Context, userID model.UserID) ([]*model.UserIdentity, error) {
+	var identities []*model.UserIdentity
+	err := m.read(func(s *memoryStore) error {
+		for _, stored := range s.Identities {
+			if stored.UserID == userID {
+				identities = append(identities, copyOf(stored).(*model.UserIdentity))
+			}
+		}
+		return nil
+	})
+	return identities, err
+}
+
+func (s *memoryStore) findTOTP(userID model.UserID) *model.TOTP {
+	for _, totp := range s.TOTPs {
+		if totp.UserID == userID {
+			return totp
+		}
+	}
+	return nil
+}
+
+func (m *memory) SaveTOTP(ctx context.Context, totp *model.TOTP) error {
+	return m.update(func(s *memoryStore, now time.Time) error {
+		if s.findUser(totp.UserID) == nil {
+			return errors.Wrap(errForeignKey, "could not save totp")
+		}
+
+		if stored := s.findTOTP(totp.UserID); stored != nil {
+			stored.Secret = totp.Secret
+			stored.Enabled = totp.Enabled
+			stored.LastUsedStep = totp.LastUsedStep
+			stored.ConfirmedAt = memoryTimePtr(totp.ConfirmedAt)
+			return nil
+		}
+
+		created := copyOf(totp).(*model.TOTP)
+		created.CreatedAt = &now
+		created.ConfirmedAt = memoryTimePtr(totp.ConfirmedAt)
+		s.TOTPs = append(s.TOTPs, created)
+		return nil
+	})
+}
+
+func (m *memory) GetTOTP(ctx context.Context, userID model.UserID) (*model.TOTP, error) {
+	var totp *model.TOTP
+	err := m.read(func(s *memoryStore) error {
+		stored := s.findTOTP(userID)
+		if stored == nil {
+			return ErrTOTPNotFound
+		}
+		totp = copyOf(stored).(*model.TOTP)
+		return nil
+	})
+	return totp, err
+}
+
+func (m *memory) UseTOTPStep(ctx context.Context, userID model.UserID, step int64) (bool, error) {
+	used := false
+	err := m.update(func(s *memoryStore, now time.Time) error {
+		// the same code can't be used twice
+		if stored := s.findTOTP(userID); stored != nil && stored.LastUsedStep < step {
+			stored.LastUsedStep = step
+			used = true
+		}
+		return nil
+	})
+	return used, err
+}
+
+func (m *memory) DeleteTOTP(ctx context.Context, userID model.UserID) error {
+	return m.update(func(s *memoryStore, now time.Time) error {
+		s.deleteRecoveryCodes(userID)
+
+		totps := s.TOTPs[:0]
+		for _, totp := range s.TOTPs {
+			if totp.UserID != userID {
+				totps = append(totps, totp)
+			}
+		}
+		s.TOTPs = totps
+		return nil
+	})
+}
+
+func (s *memoryStore) deleteRecoveryCodes(userID model.UserID) {
+	codes := s.RecoveryCodes[:0]
+	for _, code := range s.RecoveryCodes {
+		if code.UserID != userID {
+			codes = append(codes, code)
+		}
+	}
+	s.RecoveryCodes = codes
+}
+
+func (m *memory) ReplaceRecoveryCodes(ctx context.Context, userID model.UserID, codeHashes [][]byte) error {
+	return m.update(func(s *memoryStore, now time.Time) error {
+		if s.findUser(userID) == nil {
+			return errors.Wrap(errForeignKey, "could not save recovery code")
+		}
+
+		s.deleteRecoveryCodes(userID)
+		for _, codeHash := range codeHashes {
+			for _, code := range s.RecoveryCodes {
+				if code.UserID == userID && bytes.Equal(code.CodeHash, codeHash) {
+					return errors.New("could not save recovery code: code exists")
+				}
+			}
+			s.RecoveryCodes = append(s.RecoveryCodes, &memoryRecoveryCode{
+				UserID:   userID,
+				CodeHash: append([]byte(nil), codeHash...),
+			})
+		}
+		return nil
+	})
+}
+
+func (m *memory) UseRecoveryCode(ctx context.Context, userID model.UserID, codeHash []byte) (bool, error) {
+	used := false
+	err := m.update(func(s *memoryStore, now time.Time) error {
+		for _, code := range s.RecoveryCodes {
+			if code.UserID == userID && bytes.Equal(code.CodeHash, codeHash) && code.UsedAt == nil {
+				code.UsedAt = &now
+				used = true
+				return nil
+			}
+		}
+		return nil
+	})
+	return used, err
+}
+
+func (s *memoryStore) findLoginAttempt(key string) *model.LoginAttempt {
+	for _, attempt := range s.LoginAttempts {
+		if attempt.Key == key {
+			return attempt
+		}
+	}
+	return nil
+}
+
+func (m *memory) GetLoginRetryAfter(ctx context.Context, keys []string) (time.Duration, error) {
+	var retryAfter time.Duration
+	err := m.read(func(s *memoryStore) error {
+		now := m.clock()
+		for _, key := range keys {
+			attempt := s.findLoginAttempt(key)
+			if attempt == nil || attempt.LockedUntil == nil || !attempt.LockedUntil.After(now) {
+				continue
+			}
+			if wait := attempt.LockedUntil.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+		return nil
+	})
+	return retryAfter, err
+}
+
+func (m *memory) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (*model.LoginAttempt, error) {
+	var attempt *model.LoginAttempt
+	err := m.update(func(s *memoryStore, now time.Time) error {
+		stored := s.findLoginAttempt(key)
+		if stored == nil {
+			stored = &model.LoginAttempt{Key: key}
+			s.LoginAttempts = append(s.LoginAttempts, stored)
+		}
+
+		// failures older than window are forgotten, counting starts again
+		if stored.LastFailureAt != nil && stored.LastFailureAt.Before(now.Add(-window)) {
+			stored.Failures = 1
+		} else {
+			stored.Failures++
+		}
+		stored.LastFailureAt = &now
+
+		attempt = copyOf(stored).(*model.LoginAttempt)
+		return nil
+	})
+	return attempt, err
+}
+
+func (m *memory) LockLogin(ctx context.Context, key string, duration time.Duration) error {
+	return m.update(func(s *memoryStore, now time.Time) error {
+		if attempt := s.findLoginAttempt(key); attempt != nil {
+			lockedUntil := now.Add(duration).Truncate(time.Microsecond)
+			attempt.LockedUntil = &lockedUntil
+		}
+		return nil
+	})
+}
+
+func (m *memory) ResetLoginAttempts(ctx context.Context, keys []string) error {
+	return m.update(func(s *memoryStore, now time.Time) error {
+		reset := make(map[string]bool, len(keys))
+		for _, key := range keys {
+			reset[key] = true
+		}
+
+		attempts := s.LoginAttempts[:0]
+		for _, attempt := range s.LoginAttempts {
+			if !reset[attempt.Key] {
+				attempts = append(attempts, attempt)
+			}
+		}
+		s.LoginAttempts = attempts
+		return nil
+	})
+}
+
+func (s *memoryStore) findAPIKey(userID model.UserID, keyID model.APIKeyID) *model.APIKey {
+	for _, key := range s.APIKeys {
+		if key.ID == keyID && key.UserID == userID {
+			return key
+		}
+	}
+	return nil
+}
+
+func (m *memory) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		if s.findUser(key.UserID) == nil {
+			return nil, errors.Wrap(errForeignKey, "could not create api key")
+		}
+		for _, stored := range s.APIKeys {
+			if bytes.Equal(stored.KeyHash, key.KeyHash) {
+				return nil, errors.New("could not create api key: key exists")
+			}
+		}
+
+		created := copyOf(key).(*model.APIKey)
+		created.ID = model.APIKeyID(newMemoryID())
+		created.Scopes = storedScopes(key.Scopes)
+		created.ExpiresAt = memoryTimePtr(key.ExpiresAt)
+		created.LastUsedAt = nil
+		created.CreatedAt = &now
+		s.APIKeys = append(s.APIKeys, created)
+
+		key.ID = created.ID
+		key.CreatedAt = &now
+		return newAuditEntry(model.AuditEntityAPIKey, model.AuditCreated, string(key.ID), &key.UserID, nil, key)
+	})
+}
+
+// storedScopes - nil scopes are stored as empty array
+func storedScopes(scopes model.Scopes) model.Scopes {
+	stored := make(model.Scopes, len(scopes))
+	copy(stored, scopes)
+	return stored
+}
+
+func (m *memory) UpdateAPIKey(ctx context.Context, key *model.APIKey) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.findAPIKey(key.UserID, key.ID)
+		if stored == nil {
+			return nil, ErrAPIKeyNotFound
+		}
+		before := copyOf(stored).(*model.APIKey)
+
+		stored.Name = copyOf(key.Name).(*string)
+		stored.Scopes = storedScopes(key.Scopes)
+		stored.ExpiresAt = memoryTimePtr(key.ExpiresAt)
+
+		return newAuditEntry(model.AuditEntityAPIKey, model.AuditUpdated, string(key.ID), &key.UserID, before, copyOf(stored))
+	})
+}
+
+func (m *memory) GetAPIKey(ctx context.Context, userID model.UserID, keyID model.APIKeyID) (*model.APIKey, error) {
+	var key *model.APIKey
+	err := m.read(func(s *memoryStore) error {
+		stored := s.findAPIKey(userID, keyID)
+		if stored == nil {
+			return ErrAPIKeyNotFound
+		}
+		key = copyOf(stored).(*model.APIKey)
+		return nil
+	})
+	return key, err
+}
+
+func (m *memory) GetAPIKeyByHash(ctx context.Context, keyHash []byte) (*model.APIKey, error) {
+	var key *model.APIKey
+	err := m.read(func(s *memoryStore) error {
+		for _, stored := range s.APIKeys {
+			// keys of deleted users can't be used
+			if bytes.Equal(stored.KeyHash, keyHash) && s.activeUser(stored.UserID) != nil {
+				key = copyOf(stored).(*model.APIKey)
+				return nil
+			}
+		}
+		return ErrAPIKeyNotFound
+	})
+	return key, err
+}
+
+func (m *memory) ListAPIKeys(ctx context.Context, userID model.UserID) ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	err := m.read(func(s *memoryStore) error {
+		for _, stored := range s.APIKeys {
+			if stored.UserID == userID {
+				keys = append(keys, copyOf(stored).(*model.APIKey))
+			}
+		}
+		return nil
+	})
+	return keys, err
+}
+
+func (m *memory) DeleteAPIKey(ctx context.Context, userID model.UserID, keyID model.APIKeyID) error {
+	return m.audited(ctx, func(s *memoryStore, now time.Time) (*model.AuditEntry, error) {
+		stored := s.findAPIKey(userID, keyID)
+		if stored == nil {
+			return nil, ErrAPIKeyNotFound
+		}
+
+		keys := s.APIKeys[:0]
+		for _, key := range s.APIKeys {
+			if key != stored {
+				keys = append(keys, key)
+			}
+		}
+		s.APIKeys = keys
+
+		return newAuditEntry(model.AuditEntityAPIKey, model.AuditDeleted, string(keyID), &userID, stored, nil)
+	})
+}
+
+func (m *memory) TouchAPIKey(ctx context.Context, keyID model.APIKeyID) error {
+	return m.update(func(s *memoryStore, now time.Time) error {
+		for _, key := range s.APIKeys {
+			// last use is written at most once a minute
+			if key.ID == keyID && (key.LastUsedAt == nil || key.LastUsedAt.Before(now.Add(-time.Minute))) {
+				key.LastUsedAt = &now
+			}
+		}
+		return nil
+	})
+}
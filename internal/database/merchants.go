@@ -2,7 +2,9 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 
 	"github.com/startdusk/finance-app-backend/internal/model"
@@ -14,6 +16,7 @@ type MerchantDB interface {
 	GetMerchantByID(ctx context.Context, merchantID model.MerchantID) (*model.Merchant, error)
 	ListMerchantsByUserID(ctx context.Context, userID model.UserID) ([]*model.Merchant, error)
 	DeleteMerchant(ctx context.Context, merchantID model.MerchantID) (bool, error)
+	RestoreMerchant(ctx context.Context, merchantID model.MerchantID, state *model.Merchant) (*model.Merchant, error)
 }
 
 const createMerchantQuery = `
@@ -23,18 +26,21 @@ const createMerchantQuery = `
 `
 
 func (d *database) CreateMerchant(ctx context.Context, merchant *model.Merchant) error {
-	rows, err := d.conn.NamedQueryContext(ctx, createMerchantQuery, merchant)
-	if err != nil {
-		return err
-	}
-
-	defer rows.Close()
-	rows.Next()
-	if err := rows.Scan(&merchant.ID); err != nil {
-		return err
-	}
-
-	return nil
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		rows, err := sqlx.NamedQueryContext(ctx, tx, createMerchantQuery, merchant)
+		if err != nil {
+			return nil, err
+		}
+
+		defer rows.Close()
+		rows.Next()
+		if err := rows.Scan(&merchant.ID); err != nil {
+			return nil, err
+		}
+		rows.Close()
+
+		return newAuditEntry(model.AuditEntityMerchant, model.AuditCreated, string(merchant.ID), merchant.UserID, nil, merchant)
+	})
 }
 
 const updateMerchantQuery = `
@@ -44,17 +50,21 @@ const updateMerchantQuery = `
 `
 
 func (d *database) UpdateMerchant(ctx context.Context, merchant *model.Merchant) error {
-	result, err := d.conn.NamedExecContext(ctx, updateMerchantQuery, merchant)
-	if err != nil {
-		return err
-	}
-
-	rows, err := result.RowsAffected()
-	if err != nil || rows == 0 {
-		return errors.New("merchant not found")
-	}
-
-	return nil
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Merchant
+		if err := tx.GetContext(ctx, &before, getMerchantByIDQuery, merchant.ID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("merchant not found")
+			}
+			return nil, err
+		}
+
+		if _, err := tx.NamedExecContext(ctx, updateMerchantQuery, merchant); err != nil {
+			return nil, err
+		}
+
+		return newAuditEntry(model.AuditEntityMerchant, model.AuditUpdated, string(merchant.ID), before.UserID, &before, merchant)
+	})
 }
 
 const getMerchantByIDQuery = `
@@ -95,15 +105,77 @@ const deleteMerchantQuery = `
 `
 
 func (d *database) DeleteMerchant(ctx context.Context, merchantID model.MerchantID) (bool, error) {
-	result, err := d.conn.ExecContext(ctx, deleteMerchantQuery, merchantID)
+	deleted := false
+	err := d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Merchant
+		if err := tx.GetContext(ctx, &before, getMerchantByIDQuery, merchantID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteMerchantQuery, merchantID); err != nil {
+			return nil, err
+		}
+		deleted = true
+
+		return newAuditEntry(model.AuditEntityMerchant, model.AuditDeleted, string(merchantID), before.UserID, &before, nil)
+	})
 	if err != nil {
 		return false, err
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil || rows == 0 {
-		return false, err
+	return deleted, nil
+}
+
+// deleted merchants can be restored
+const getMerchantWithDeletedQuery = `
+	SELECT merchant_id, user_id, name, created_at, deleted_at 
+	FROM merchants 
+	WHERE merchant_id = $1;
+`
+
+// restored merchant gets data of revision back and isn't deleted anymore
+const restoreMerchantQuery = `
+	UPDATE merchants 
+	SET name = :name, 
+		deleted_at = NULL 
+	WHERE merchant_id = :merchant_id;
+`
+
+// RestoreMerchant undeletes merchant and sets its data to state, nil state keeps current data. Owner is never changed.
+func (d *database) RestoreMerchant(ctx context.Context, merchantID model.MerchantID, state *model.Merchant) (*model.Merchant, error) {
+	var restored model.Merchant
+	err := d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Merchant
+		if err := tx.GetContext(ctx, &before, getMerchantWithDeletedQuery, merchantID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("merchant not found")
+			}
+			return nil, err
+		}
+
+		next := before
+		if state != nil {
+			next = *state
+		}
+		next.ID = before.ID
+		next.UserID = before.UserID
+
+		if _, err := tx.NamedExecContext(ctx, restoreMerchantQuery, &next); err != nil {
+			return nil, err
+		}
+
+		if err := tx.GetContext(ctx, &restored, getMerchantWithDeletedQuery, merchantID); err != nil {
+			return nil, err
+		}
+
+		return newAuditEntry(model.AuditEntityMerchant, model.AuditRestored, string(merchantID), before.UserID, &before, &restored)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not restore merchant")
 	}
 
-	return true, nil
+	return &restored, nil
 }
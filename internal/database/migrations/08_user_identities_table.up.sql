@@ -0,0 +1,13 @@
+-- Users logged in with external OpenID Connect providers.
+-- One user can have many identities, but identity belongs to one user.
+CREATE TABLE user_identities (
+	provider TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	user_id UUID NOT NULL REFERENCES users,
+	email TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX user_identities_user
+	ON user_identities (user_id);
@@ -0,0 +1,18 @@
+-- TOTP second factor, one per user.
+-- Row exists while enrollment is pending (enabled = false) or after it was confirmed.
+CREATE TABLE user_totp (
+	user_id UUID PRIMARY KEY REFERENCES users,
+	secret TEXT NOT NULL,
+	enabled BOOLEAN NOT NULL DEFAULT FALSE,
+	last_used_step BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	confirmed_at TIMESTAMP
+);
+
+-- We store only sha256 of recovery codes
+CREATE TABLE user_recovery_codes (
+	user_id UUID NOT NULL REFERENCES users,
+	code_hash BYTEA NOT NULL,
+	used_at TIMESTAMP,
+	PRIMARY KEY (user_id, code_hash)
+);
@@ -0,0 +1,7 @@
+-- Failed logins by key: 'account:{email}', 'ip:{address}', '2fa:{user_id}'
+CREATE TABLE login_attempts (
+	attempt_key TEXT PRIMARY KEY,
+	failures INTEGER NOT NULL DEFAULT 0,
+	last_failure_at TIMESTAMP,
+	locked_until TIMESTAMP
+);
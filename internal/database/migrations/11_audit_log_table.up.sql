@@ -0,0 +1,13 @@
+-- Audit log is append only, we never update or delete records
+CREATE TABLE audit_log (
+	audit_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	action TEXT NOT NULL,
+	user_id UUID,
+	actor_id UUID,
+	ip TEXT,
+	details JSONB
+);
+
+CREATE INDEX audit_log_user
+	ON audit_log (user_id, created_at);
@@ -0,0 +1,15 @@
+-- Only 'admin' role exists in ENUM, other granted roles are lost
+DELETE FROM user_roles WHERE role <> 'admin';
+
+ALTER TABLE user_roles
+	DROP CONSTRAINT IF EXISTS user_roles_role_fkey;
+
+CREATE TYPE user_role AS ENUM (
+	'admin'
+);
+
+ALTER TABLE user_roles
+	ALTER COLUMN role TYPE user_role USING role::user_role;
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
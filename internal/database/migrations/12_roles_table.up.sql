@@ -0,0 +1,30 @@
+-- Roles are defined in database now, every role has list of named permissions.
+-- Role 'admin' is builtin and has all permissions, we don't store them.
+CREATE TABLE roles (
+	role TEXT PRIMARY KEY,
+	description TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE role_permissions (
+	role TEXT NOT NULL REFERENCES roles ON DELETE CASCADE,
+	permission TEXT NOT NULL,
+	PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (role, description) VALUES
+	('admin', 'Administrator of App, has all permissions'),
+	('support', 'Support staff, can read users');
+
+INSERT INTO role_permissions (role, permission) VALUES
+	('support', 'users:read');
+
+-- user_role ENUM is replaced by reference to roles table
+ALTER TABLE user_roles
+	ALTER COLUMN role TYPE TEXT USING role::TEXT;
+
+ALTER TABLE user_roles
+	ADD CONSTRAINT user_roles_role_fkey FOREIGN KEY (role) REFERENCES roles ON DELETE CASCADE;
+
+DROP TYPE user_role;
@@ -0,0 +1,15 @@
+-- Users who can access account of other user (household accounts).
+-- Invited user becomes member after accepting invitation, declined invitations are deleted.
+CREATE TABLE account_members (
+	account_id UUID NOT NULL REFERENCES accounts,
+	user_id UUID NOT NULL REFERENCES users,
+	access TEXT NOT NULL CHECK (access IN ('viewer', 'editor')),
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted')),
+	invited_by UUID NOT NULL REFERENCES users,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	accepted_at TIMESTAMP,
+	PRIMARY KEY (account_id, user_id)
+);
+
+CREATE INDEX account_members_user
+	ON account_members (user_id);
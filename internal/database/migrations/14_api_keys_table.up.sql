@@ -0,0 +1,18 @@
+-- Personal API keys. We store only sha256 of key, prefix is start of key to recognize it in list.
+CREATE TABLE api_keys (
+	api_key_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	user_id UUID NOT NULL REFERENCES users,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	key_hash BYTEA NOT NULL,
+	scopes TEXT[] NOT NULL,
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX api_keys_key_hash
+	ON api_keys (key_hash);
+
+CREATE INDEX api_keys_user
+	ON api_keys (user_id);
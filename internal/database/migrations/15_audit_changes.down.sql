@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS audit_log_created_at;
+DROP INDEX IF EXISTS audit_log_entity;
+
+ALTER TABLE audit_log
+	DROP COLUMN IF EXISTS diff,
+	DROP COLUMN IF EXISTS after,
+	DROP COLUMN IF EXISTS before,
+	DROP COLUMN IF EXISTS entity_id,
+	DROP COLUMN IF EXISTS entity_type,
+	DROP COLUMN IF EXISTS device_id;
@@ -0,0 +1,14 @@
+-- Audit log records every change of users data with state before and after change
+ALTER TABLE audit_log
+	ADD COLUMN device_id TEXT,
+	ADD COLUMN entity_type TEXT,
+	ADD COLUMN entity_id TEXT,
+	ADD COLUMN before JSONB,
+	ADD COLUMN after JSONB,
+	ADD COLUMN diff JSONB;
+
+CREATE INDEX audit_log_entity
+	ON audit_log (entity_type, entity_id, created_at);
+
+CREATE INDEX audit_log_created_at
+	ON audit_log (created_at);
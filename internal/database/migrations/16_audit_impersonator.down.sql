@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS audit_log_impersonator_id;
+
+ALTER TABLE audit_log
+	DROP COLUMN IF EXISTS impersonator_id;
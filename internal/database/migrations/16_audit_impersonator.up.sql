@@ -0,0 +1,6 @@
+-- Admin who impersonated user, changes made with impersonation token are tagged with him
+ALTER TABLE audit_log
+	ADD COLUMN impersonator_id UUID;
+
+CREATE INDEX audit_log_impersonator_id
+	ON audit_log (impersonator_id, created_at);
@@ -0,0 +1,14 @@
+-- Every state of accounts, categories, merchants and transactions, written in the same transaction as change.
+-- Entities created before this migration get first revision with their first change.
+CREATE TABLE entity_revisions (
+	entity_type TEXT NOT NULL,
+	entity_id UUID NOT NULL,
+	revision INTEGER NOT NULL,
+	operation TEXT NOT NULL,
+	user_id UUID,
+	actor_id UUID,
+	impersonator_id UUID,
+	data JSONB,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (entity_type, entity_id, revision)
+);
@@ -0,0 +1,12 @@
+-- Users who asked to erase their data. User is deleted right away and data is erased after grace period.
+CREATE TABLE erasure_requests (
+	user_id UUID PRIMARY KEY REFERENCES users,
+	requested_by UUID,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	scheduled_at TIMESTAMP NOT NULL,
+	completed_at TIMESTAMP
+);
+
+CREATE INDEX erasure_requests_scheduled_at
+	ON erasure_requests (scheduled_at)
+	WHERE completed_at IS NULL;
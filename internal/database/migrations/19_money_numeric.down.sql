@@ -0,0 +1,37 @@
+-- Amounts go back to INTEGER in minor units of currency
+CREATE FUNCTION pg_temp.currency_exponent(currency TEXT) RETURNS INTEGER AS $$
+	SELECT CASE UPPER(currency)
+		WHEN 'BIF' THEN 0 WHEN 'CLP' THEN 0 WHEN 'DJF' THEN 0 WHEN 'GNF' THEN 0 WHEN 'ISK' THEN 0
+		WHEN 'JPY' THEN 0 WHEN 'KMF' THEN 0 WHEN 'KRW' THEN 0 WHEN 'PYG' THEN 0 WHEN 'RWF' THEN 0
+		WHEN 'UGX' THEN 0 WHEN 'UYI' THEN 0 WHEN 'VND' THEN 0 WHEN 'VUV' THEN 0 WHEN 'XAF' THEN 0
+		WHEN 'XOF' THEN 0 WHEN 'XPF' THEN 0
+		WHEN 'BHD' THEN 3 WHEN 'IQD' THEN 3 WHEN 'JOD' THEN 3 WHEN 'KWD' THEN 3 WHEN 'LYD' THEN 3
+		WHEN 'OMR' THEN 3 WHEN 'TND' THEN 3
+		WHEN 'CLF' THEN 4 WHEN 'UYW' THEN 4
+		ELSE 2
+	END
+$$ LANGUAGE SQL IMMUTABLE;
+
+UPDATE entity_revisions r
+	SET data = jsonb_set(r.data, '{amount}', to_jsonb(ROUND((r.data->>'amount')::NUMERIC * (10::NUMERIC ^ pg_temp.currency_exponent(a.currency)))::BIGINT))
+	FROM accounts a
+	WHERE r.entity_type = 'transaction' AND jsonb_typeof(r.data->'amount') = 'string' AND a.account_id = (r.data->>'accountID')::UUID;
+
+UPDATE entity_revisions
+	SET data = jsonb_set(data, '{startBalance}', to_jsonb(ROUND((data->>'startBalance')::NUMERIC * (10::NUMERIC ^ pg_temp.currency_exponent(data->>'currency')))::BIGINT))
+	WHERE entity_type = 'account' AND jsonb_typeof(data->'startBalance') = 'string';
+
+UPDATE transactions t
+	SET amount = t.amount * (10::NUMERIC ^ pg_temp.currency_exponent(a.currency))
+	FROM accounts a
+	WHERE a.account_id = t.account_id;
+
+ALTER TABLE transactions
+	ALTER COLUMN amount TYPE INTEGER
+	USING ROUND(amount);
+
+ALTER TABLE accounts
+	ALTER COLUMN start_balance TYPE INTEGER
+	USING ROUND(start_balance * (10::NUMERIC ^ pg_temp.currency_exponent(currency)));
+
+DROP FUNCTION pg_temp.currency_exponent(TEXT);
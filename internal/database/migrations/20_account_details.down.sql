@@ -0,0 +1,6 @@
+ALTER TABLE accounts
+	DROP COLUMN IF EXISTS interest_rate,
+	DROP COLUMN IF EXISTS statement_day,
+	DROP COLUMN IF EXISTS credit_limit,
+	DROP COLUMN IF EXISTS masked_number,
+	DROP COLUMN IF EXISTS institution;
@@ -0,0 +1,7 @@
+-- Details of account, which of them account has depends on its type (see model.Account.Verify)
+ALTER TABLE accounts
+	ADD COLUMN institution TEXT,
+	ADD COLUMN masked_number TEXT,
+	ADD COLUMN credit_limit NUMERIC,
+	ADD COLUMN statement_day SMALLINT CHECK (statement_day BETWEEN 1 AND 28),
+	ADD COLUMN interest_rate NUMERIC(7, 4);
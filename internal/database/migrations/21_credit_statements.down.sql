@@ -0,0 +1,4 @@
+ALTER TABLE accounts
+	DROP COLUMN IF EXISTS min_payment_amount,
+	DROP COLUMN IF EXISTS min_payment_percent,
+	DROP COLUMN IF EXISTS payment_due_day;
@@ -0,0 +1,5 @@
+-- Statement rules of credit accounts, statements themselves are computed from transactions
+ALTER TABLE accounts
+	ADD COLUMN payment_due_day SMALLINT CHECK (payment_due_day BETWEEN 1 AND 28),
+	ADD COLUMN min_payment_percent NUMERIC(7, 4),
+	ADD COLUMN min_payment_amount NUMERIC;
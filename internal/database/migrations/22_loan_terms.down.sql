@@ -0,0 +1,4 @@
+ALTER TABLE accounts
+	DROP COLUMN IF EXISTS loan_start_date,
+	DROP COLUMN IF EXISTS loan_term_months,
+	DROP COLUMN IF EXISTS loan_principal;
@@ -0,0 +1,5 @@
+-- Terms of loan accounts, amortization schedule is computed from them and rate in interest_rate
+ALTER TABLE accounts
+	ADD COLUMN loan_principal NUMERIC,
+	ADD COLUMN loan_term_months SMALLINT CHECK (loan_term_months > 0),
+	ADD COLUMN loan_start_date DATE;
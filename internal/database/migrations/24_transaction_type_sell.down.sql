@@ -0,0 +1,16 @@
+-- enum values can't be dropped, type is created again without it;
+-- rollback stops while transactions of the type exist instead of losing them
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM transactions WHERE type::text = 'sell') THEN
+		RAISE EXCEPTION 'transactions of type sell exist, delete them before rollback';
+	END IF;
+END $$;
+ALTER TYPE transaction_type RENAME TO transaction_type_old;
+CREATE TYPE transaction_type AS ENUM (
+	'income',
+	'expense',
+	'buy'
+);
+ALTER TABLE transactions ALTER COLUMN type TYPE transaction_type USING type::text::transaction_type;
+DROP TYPE transaction_type_old;
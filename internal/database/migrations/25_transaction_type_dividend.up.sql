@@ -0,0 +1,3 @@
+-- ADD VALUE can't run inside transaction block, file with more statements runs in one,
+-- so every new value of enum has its own migration
+ALTER TYPE transaction_type ADD VALUE IF NOT EXISTS 'dividend';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS prices;
+
+ALTER TABLE transactions
+	DROP COLUMN IF EXISTS quantity,
+	DROP COLUMN IF EXISTS symbol;
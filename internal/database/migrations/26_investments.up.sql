@@ -0,0 +1,16 @@
+-- Investment transactions change positions of investment account: buy and sell have symbol and quantity,
+-- dividend has only symbol. Amount is cash paid or received, fees included.
+ALTER TABLE transactions
+	ADD COLUMN symbol TEXT,
+	ADD COLUMN quantity NUMERIC CHECK (quantity > 0);
+
+-- Prices of symbols entered by user or imported from CSV, price is in currency of account which holds symbol
+CREATE TABLE prices (
+	user_id UUID NOT NULL REFERENCES users,
+	symbol TEXT NOT NULL,
+	date DATE NOT NULL,
+	price NUMERIC NOT NULL CHECK (price >= 0),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+	PRIMARY KEY (user_id, symbol, date)
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS reconciliations;
+
+ALTER TABLE transactions
+	DROP COLUMN IF EXISTS cleared_status;
+
+DROP TYPE IF EXISTS cleared_status;
@@ -0,0 +1,24 @@
+-- Transactions are cleared when user finds them on bank statement, locked reconciliation makes them reconciled
+CREATE TYPE cleared_status AS ENUM (
+	'uncleared',
+	'cleared',
+	'reconciled'
+);
+
+ALTER TABLE transactions
+	ADD COLUMN cleared_status cleared_status NOT NULL DEFAULT 'uncleared';
+
+-- Reconciliation of account with statement ending on statement_date, locked one locks transactions until end of that day
+CREATE TABLE reconciliations (
+	reconciliation_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	user_id UUID NOT NULL REFERENCES users,
+	account_id UUID NOT NULL REFERENCES accounts,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	locked_at TIMESTAMP,
+
+	statement_date DATE NOT NULL,
+	ending_balance NUMERIC NOT NULL
+);
+
+-- account has at most one open reconciliation
+CREATE UNIQUE INDEX reconciliations_open_idx ON reconciliations (account_id) WHERE locked_at IS NULL;
@@ -0,0 +1,7 @@
+-- void transactions would count again without status
+DELETE FROM transactions WHERE status = 'void';
+
+ALTER TABLE transactions
+	DROP COLUMN IF EXISTS status;
+
+DROP TYPE IF EXISTS transaction_status;
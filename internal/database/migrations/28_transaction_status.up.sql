@@ -0,0 +1,9 @@
+-- Card authorizations are pending until they post, void transactions stay but don't count to balances
+CREATE TYPE transaction_status AS ENUM (
+	'pending',
+	'posted',
+	'void'
+);
+
+ALTER TABLE transactions
+	ADD COLUMN status transaction_status NOT NULL DEFAULT 'posted';
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// OwnerDB finds owners of resources for permission checks
+type OwnerDB interface {
+	GetResourceOwner(ctx context.Context, entity model.AuditEntity, id string) (*model.UserID, error)
+}
+
+// deleted resources still have owner, only owner can see their history and restore them
+var resourceOwnerQueries = map[model.AuditEntity]string{
+	model.AuditEntityAccount:     `SELECT user_id FROM accounts WHERE account_id = $1;`,
+	model.AuditEntityCategory:    `SELECT user_id FROM categories WHERE category_id = $1;`,
+	model.AuditEntityMerchant:    `SELECT user_id FROM merchants WHERE merchant_id = $1;`,
+	model.AuditEntityTransaction: `SELECT user_id FROM transactions WHERE transaction_id = $1;`,
+}
+
+func (d *database) GetResourceOwner(ctx context.Context, entity model.AuditEntity, id string) (*model.UserID, error) {
+	query, ok := resourceOwnerQueries[entity]
+	if !ok {
+		return nil, fmt.Errorf("unknown resource: %s", entity)
+	}
+
+	var userID *model.UserID
+	if err := d.conn.GetContext(ctx, &userID, query, id); err != nil {
+		return nil, errors.Wrap(err, "could not get resource owner")
+	}
+
+	return userID, nil
+}
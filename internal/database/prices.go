@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// PriceDB persist prices of symbols, every user has his own prices
+type PriceDB interface {
+	SetPrices(ctx context.Context, prices []*model.Price) error
+	ListPrices(ctx context.Context, userID model.UserID, symbol string) ([]*model.Price, error)
+	ListLatestPrices(ctx context.Context, userID model.UserID, at time.Time) ([]*model.Price, error)
+}
+
+// symbol has one price per day, imported price replaces the one entered before
+const setPriceQuery = `
+	INSERT INTO prices (user_id, symbol, date, price)
+		VALUES (:user_id, :symbol, :date, :price)
+	ON CONFLICT (user_id, symbol, date) DO UPDATE
+		SET price = EXCLUDED.price,
+			created_at = NOW();
+`
+
+// SetPrices saves all prices or none of them
+func (d *database) SetPrices(ctx context.Context, prices []*model.Price) error {
+	return d.transact(ctx, nil, func(tx *sqlx.Tx) error {
+		for _, price := range prices {
+			if _, err := tx.NamedExecContext(ctx, setPriceQuery, price); err != nil {
+				return errors.Wrap(err, "could not set price")
+			}
+		}
+		return nil
+	})
+}
+
+const listPricesQuery = `
+	SELECT user_id, symbol, date, price, created_at
+	FROM prices
+	WHERE user_id = $1
+		AND ($2::text = '' OR symbol = $2)
+	ORDER BY symbol, date;
+`
+
+// ListPrices returns price history of symbol, empty symbol lists all of them
+func (d *database) ListPrices(ctx context.Context, userID model.UserID, symbol string) ([]*model.Price, error) {
+	var prices []*model.Price
+	if err := d.conn.SelectContext(ctx, &prices, listPricesQuery, userID, symbol); err != nil {
+		return nil, errors.Wrap(err, "could not get prices")
+	}
+
+	return prices, nil
+}
+
+const listLatestPricesQuery = `
+	SELECT DISTINCT ON (symbol) user_id, symbol, date, price, created_at
+	FROM prices
+	WHERE user_id = $1
+		AND date <= $2
+	ORDER BY symbol, date DESC;
+`
+
+// ListLatestPrices returns the latest price of every symbol dated at or before at
+func (d *database) ListLatestPrices(ctx context.Context, userID model.UserID, at time.Time) ([]*model.Price, error) {
+	var prices []*model.Price
+	if err := d.conn.SelectContext(ctx, &prices, listLatestPricesQuery, userID, at); err != nil {
+		return nil, errors.Wrap(err, "could not get latest prices")
+	}
+
+	return prices, nil
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// ErrReconciliationLocked - locked reconciliation can't be locked again
+var ErrReconciliationLocked = errors.New("reconciliation is locked")
+
+// ReconciliationDB persist reconciliations of accounts and cleared status of their transactions
+type ReconciliationDB interface {
+	CreateReconciliation(ctx context.Context, reconciliation *model.Reconciliation) error
+	GetReconciliationByID(ctx context.Context, reconciliationID model.ReconciliationID) (*model.Reconciliation, error)
+	ListReconciliationsByAccountID(ctx context.Context, accountID model.AccountID) ([]*model.Reconciliation, error)
+	SetTransactionsCleared(ctx context.Context, accountID model.AccountID, transactionIDs []model.TransactionID, status model.ClearedStatus) error
+	LockReconciliation(ctx context.Context, reconciliationID model.ReconciliationID) (*model.Reconciliation, error)
+}
+
+const createReconciliationQuery = `
+	INSERT INTO reconciliations (user_id, account_id, statement_date, ending_balance)
+		VALUES (:user_id, :account_id, :statement_date, :ending_balance)
+	RETURNING reconciliation_id, created_at;
+`
+
+func (d *database) CreateReconciliation(ctx context.Context, reconciliation *model.Reconciliation) error {
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		rows, err := sqlx.NamedQueryContext(ctx, tx, createReconciliationQuery, reconciliation)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create reconciliation")
+		}
+
+		defer rows.Close()
+		rows.Next()
+		if err := rows.Scan(&reconciliation.ID, &reconciliation.CreatedAt); err != nil {
+			return nil, errors.Wrap(err, "could not create reconciliation")
+		}
+		rows.Close()
+
+		return newAuditEntry(model.AuditEntityReconciliation, model.AuditCreated, string(reconciliation.ID), reconciliation.UserID, nil, reconciliation)
+	})
+}
+
+const getReconciliationByIDQuery = `
+	SELECT reconciliation_id, user_id, account_id, statement_date, ending_balance, created_at, locked_at
+	FROM reconciliations
+	WHERE reconciliation_id = $1;
+`
+
+func (d *database) GetReconciliationByID(ctx context.Context, reconciliationID model.ReconciliationID) (*model.Reconciliation, error) {
+	var reconciliation model.Reconciliation
+	if err := d.conn.GetContext(ctx, &reconciliation, getReconciliationByIDQuery, reconciliationID); err != nil {
+		return nil, errors.Wrap(err, "could not get reconciliation")
+	}
+
+	return &reconciliation, nil
+}
+
+const listReconciliationsByAccountIDQuery = `
+	SELECT reconciliation_id, user_id, account_id, statement_date, ending_balance, created_at, locked_at
+	FROM reconciliations
+	WHERE account_id = $1
+	ORDER BY statement_date DESC, created_at DESC;
+`
+
+// ListReconciliationsByAccountID returns reconciliations of account, the latest statement is first
+func (d *database) ListReconciliationsByAccountID(ctx context.Context, accountID model.AccountID) ([]*model.Reconciliation, error) {
+	var reconciliations []*model.Reconciliation
+	if err := d.conn.SelectContext(ctx, &reconciliations, listReconciliationsByAccountIDQuery, accountID); err != nil {
+		return nil, errors.Wrap(err, "could not get account's reconciliations")
+	}
+
+	return reconciliations, nil
+}
+
+const setTransactionClearedQuery = `
+	UPDATE transactions
+	SET cleared_status = :cleared_status
+	WHERE transaction_id = :transaction_id;
+`
+
+// setTransactionsClearedInTx changes cleared status of transactions, every change is audited like update of transaction
+func setTransactionsClearedInTx(ctx context.Context, tx *sqlx.Tx, transactions []*model.Transaction, status model.ClearedStatus) error {
+	for _, transaction := range transactions {
+		after := *transaction
+		after.ClearedStatus = &status
+
+		if _, err := tx.NamedExecContext(ctx, setTransactionClearedQuery, &after); err != nil {
+			return errors.Wrap(err, "could not set cleared status of transaction")
+		}
+
+		entry, err := newAuditEntry(model.AuditEntityTransaction, model.AuditUpdated, string(transaction.ID), transaction.UserID, transaction, &after)
+		if err != nil {
+			return err
+		}
+		if err := writeAudit(ctx, tx, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetTransactionsCleared sets status of all transactions or none of them, transactions must be in account
+func (d *database) SetTransactionsCleared(ctx context.Context, accountID model.AccountID, transactionIDs []model.TransactionID, status model.ClearedStatus) error {
+	return d.transact(ctx, nil, func(tx *sqlx.Tx) error {
+		transactions := make([]*model.Transaction, 0, len(transactionIDs))
+		for _, transactionID := range transactionIDs {
+			var transaction model.Transaction
+			if err := tx.GetContext(ctx, &transaction, getTransactionByIDQuery, transactionID); err != nil {
+				return errors.Wrap(err, "could not get transaction")
+			}
+			if transaction.AccountID == nil || *transaction.AccountID != accountID {
+				return errors.Wrap(sql.ErrNoRows, "could not get transaction")
+			}
+			transactions = append(transactions, &transaction)
+		}
+
+		return setTransactionsClearedInTx(ctx, tx, transactions, status)
+	})
+}
+
+const lockReconciliationQuery = `
+	UPDATE reconciliations
+	SET locked_at = NOW()
+	WHERE reconciliation_id = $1
+		AND locked_at IS NULL;
+`
+
+// cleared transactions on statement become reconciled, pending and void ones are left when reconciliation is locked
+const listClearedTransactionsQuery = `
+	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, status, symbol, quantity, cleared_status, created_at, deleted_at
+	FROM transactions
+	WHERE account_id = $1
+		AND deleted_at IS NULL
+		AND status = 'posted'
+		AND cleared_status = 'cleared'
+		AND date < $2;
+`
+
+// LockReconciliation locks open reconciliation and marks cleared transactions until end of its statement date reconciled
+func (d *database) LockReconciliation(ctx context.Context, reconciliationID model.ReconciliationID) (*model.Reconciliation, error) {
+	var locked model.Reconciliation
+	err := d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Reconciliation
+		if err := tx.GetContext(ctx, &before, getReconciliationByIDQuery, reconciliationID); err != nil {
+			return nil, err
+		}
+
+		result, err := tx.ExecContext(ctx, lockReconciliationQuery, reconciliationID)
+		if err != nil {
+			return nil, err
+		}
+		if rows, err := result.RowsAffected(); err != nil {
+			return nil, err
+		} else if rows == 0 {
+			return nil, ErrReconciliationLocked
+		}
+
+		var transactions []*model.Transaction
+		if err := tx.SelectContext(ctx, &transactions, listClearedTransactionsQuery, before.AccountID, before.PeriodEnd()); err != nil {
+			return nil, err
+		}
+		if err := setTransactionsClearedInTx(ctx, tx, transactions, model.Reconciled); err != nil {
+			return nil, err
+		}
+
+		if err := tx.GetContext(ctx, &locked, getReconciliationByIDQuery, reconciliationID); err != nil {
+			return nil, err
+		}
+
+		return newAuditEntry(model.AuditEntityReconciliation, model.AuditUpdated, string(reconciliationID), before.UserID, &before, &locked)
+	})
+	if err == ErrReconciliationLocked {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not lock reconciliation")
+	}
+
+	return &locked, nil
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// RevisionDB persist history of versioned entities (accounts, categories, merchants, transactions)
+type RevisionDB interface {
+	ListRevisions(ctx context.Context, entity model.AuditEntity, entityID string) ([]*model.Revision, error)
+	GetRevision(ctx context.Context, entity model.AuditEntity, entityID string, revision int) (*model.Revision, error)
+}
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// next revision number is computed in insert, primary key stops two transactions from writing the same one
+const createRevisionQuery = `
+	INSERT INTO entity_revisions (entity_type, entity_id, revision, operation, user_id, actor_id, impersonator_id, data) 
+		SELECT $1::text, $2::uuid, COALESCE(MAX(revision), 0) + 1, $3::text, $4::uuid, $5::uuid, $6::uuid, $7::jsonb 
+		FROM entity_revisions 
+		WHERE entity_type = $1 AND entity_id = $2;
+`
+
+// insertRevision saves state of versioned entity from audit entry of its change,
+// so every audited change of entity has its revision.
+func insertRevision(ctx context.Context, tx *sqlx.Tx, entry *model.AuditEntry) error {
+	operation, data, ok := revisionOf(entry)
+	if !ok {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, createRevisionQuery, *entry.EntityType, *entry.EntityID, operation,
+		entry.UserID, entry.ActorID, entry.ImpersonatorID, data); err != nil {
+		return errors.Wrap(err, "could not create revision")
+	}
+
+	return nil
+}
+
+// revisionOf returns operation and state saved in revision, ok is false when change has no revision
+func revisionOf(entry *model.AuditEntry) (string, model.JSON, bool) {
+	if entry.EntityType == nil || entry.EntityID == nil || !entry.EntityType.IsVersioned() {
+		return "", nil, false
+	}
+
+	// deleted entity has no state after change, we keep the last one so it can be restored
+	data := entry.After
+	if data == nil {
+		data = entry.Before
+	}
+
+	operation := strings.TrimPrefix(string(entry.Action), string(*entry.EntityType)+".")
+	// purged entity is gone with its history
+	if operation == model.AuditPurged {
+		return "", nil, false
+	}
+
+	return operation, data, true
+}
+
+const listRevisionsQuery = `
+	SELECT entity_type, entity_id, revision, operation, user_id, actor_id, impersonator_id, data, created_at 
+	FROM entity_revisions 
+	WHERE entity_type = $1 AND entity_id = $2 
+	ORDER BY revision DESC;
+`
+
+// ListRevisions returns newest revisions first
+func (d *database) ListRevisions(ctx context.Context, entity model.AuditEntity, entityID string) ([]*model.Revision, error) {
+	var revisions []*model.Revision
+	if err := d.conn.SelectContext(ctx, &revisions, listRevisionsQuery, entity, entityID); err != nil {
+		return nil, errors.Wrap(err, "could not list revisions")
+	}
+
+	return revisions, nil
+}
+
+const getRevisionQuery = `
+	SELECT entity_type, entity_id, revision, operation, user_id, actor_id, impersonator_id, data, created_at 
+	FROM entity_revisions 
+	WHERE entity_type = $1 AND entity_id = $2 AND revision = $3;
+`
+
+func (d *database) GetRevision(ctx context.Context, entity model.AuditEntity, entityID string, revision int) (*model.Revision, error) {
+	var rev model.Revision
+	if err := d.conn.GetContext(ctx, &rev, getRevisionQuery, entity, entityID, revision); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, errors.Wrap(err, "could not get revision")
+	}
+
+	return &rev, nil
+}
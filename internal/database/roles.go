@@ -0,0 +1,230 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// RoleDB persist roles and their permissions
+type RoleDB interface {
+	CreateRole(ctx context.Context, role *model.RoleDefinition) error
+	UpdateRole(ctx context.Context, role *model.RoleDefinition) error
+	GetRole(ctx context.Context, name model.Role) (*model.RoleDefinition, error)
+	ListRoles(ctx context.Context) ([]*model.RoleDefinition, error)
+	DeleteRole(ctx context.Context, name model.Role) error
+	GetPermissionsByUser(ctx context.Context, userID model.UserID) ([]model.Permission, error)
+}
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrRoleExists   = errors.New("role already exists")
+)
+
+const createRoleQuery = `
+	INSERT INTO roles (role, description) 
+		VALUES (:role, :description) 
+	RETURNING created_at, updated_at;
+`
+
+func (d *database) CreateRole(ctx context.Context, role *model.RoleDefinition) error {
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		rows, err := sqlx.NamedQueryContext(ctx, tx, createRoleQuery, role)
+		if err != nil {
+			if pqError, ok := err.(*pq.Error); ok {
+				if pqError.Code.Name() == UniqueViolation {
+					return nil, ErrRoleExists
+				}
+			}
+			return nil, errors.Wrap(err, "could not create role")
+		}
+
+		for rows.Next() {
+			if err := rows.Scan(&role.CreatedAt, &role.UpdatedAt); err != nil {
+				rows.Close()
+				return nil, errors.Wrap(err, "could not get created role")
+			}
+		}
+		rows.Close()
+
+		if err := insertRolePermissions(ctx, tx, role); err != nil {
+			return nil, err
+		}
+
+		return newAuditEntry(model.AuditEntityRole, model.AuditCreated, string(role.Name), nil, nil, role)
+	})
+}
+
+const updateRoleQuery = `
+	UPDATE roles 
+	SET description = :description,
+		updated_at = NOW() 
+	WHERE role = :role;
+`
+
+const deleteRolePermissionsQuery = `
+	DELETE FROM role_permissions 
+	WHERE role = $1;
+`
+
+// UpdateRole updates description and replaces all permissions of role
+func (d *database) UpdateRole(ctx context.Context, role *model.RoleDefinition) error {
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		before, err := getRole(ctx, tx, role.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.NamedExecContext(ctx, updateRoleQuery, role); err != nil {
+			return nil, errors.Wrap(err, "could not update role")
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteRolePermissionsQuery, role.Name); err != nil {
+			return nil, errors.Wrap(err, "could not delete role permissions")
+		}
+
+		if err := insertRolePermissions(ctx, tx, role); err != nil {
+			return nil, err
+		}
+
+		after, err := getRole(ctx, tx, role.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		return newAuditEntry(model.AuditEntityRole, model.AuditUpdated, string(role.Name), nil, before, after)
+	})
+}
+
+const insertRolePermissionQuery = `
+	INSERT INTO role_permissions (role, permission) 
+		VALUES ($1, $2) 
+	ON CONFLICT DO NOTHING;
+`
+
+func insertRolePermissions(ctx context.Context, tx *sqlx.Tx, role *model.RoleDefinition) error {
+	for _, permission := range role.Permissions {
+		if _, err := tx.ExecContext(ctx, insertRolePermissionQuery, role.Name, permission); err != nil {
+			return errors.Wrap(err, "could not save role permission")
+		}
+	}
+
+	return nil
+}
+
+const getRoleQuery = `
+	SELECT role, description, created_at, updated_at 
+	FROM roles 
+	WHERE role = $1;
+`
+
+const getRolePermissionsQuery = `
+	SELECT permission 
+	FROM role_permissions 
+	WHERE role = $1 
+	ORDER BY permission;
+`
+
+func (d *database) GetRole(ctx context.Context, name model.Role) (*model.RoleDefinition, error) {
+	return getRole(ctx, d.conn, name)
+}
+
+func getRole(ctx context.Context, conn sqlx.QueryerContext, name model.Role) (*model.RoleDefinition, error) {
+	var role model.RoleDefinition
+	if err := sqlx.GetContext(ctx, conn, &role, getRoleQuery, name); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRoleNotFound
+		}
+		return nil, errors.Wrap(err, "could not get role")
+	}
+
+	role.Permissions = make([]model.Permission, 0)
+	if err := sqlx.SelectContext(ctx, conn, &role.Permissions, getRolePermissionsQuery, name); err != nil {
+		return nil, errors.Wrap(err, "could not get role permissions")
+	}
+
+	return &role, nil
+}
+
+const listRolesQuery = `
+	SELECT role, description, created_at, updated_at 
+	FROM roles 
+	ORDER BY role;
+`
+
+const listRolePermissionsQuery = `
+	SELECT role, permission 
+	FROM role_permissions 
+	ORDER BY role, permission;
+`
+
+func (d *database) ListRoles(ctx context.Context) ([]*model.RoleDefinition, error) {
+	var roles []*model.RoleDefinition
+	if err := d.conn.SelectContext(ctx, &roles, listRolesQuery); err != nil {
+		return nil, errors.Wrap(err, "could not list roles")
+	}
+
+	var rolePermissions []struct {
+		Role       model.Role       `db:"role"`
+		Permission model.Permission `db:"permission"`
+	}
+	if err := d.conn.SelectContext(ctx, &rolePermissions, listRolePermissionsQuery); err != nil {
+		return nil, errors.Wrap(err, "could not list role permissions")
+	}
+
+	byName := make(map[model.Role]*model.RoleDefinition, len(roles))
+	for _, role := range roles {
+		role.Permissions = make([]model.Permission, 0)
+		byName[role.Name] = role
+	}
+
+	for _, rolePermission := range rolePermissions {
+		if role, ok := byName[rolePermission.Role]; ok {
+			role.Permissions = append(role.Permissions, rolePermission.Permission)
+		}
+	}
+
+	return roles, nil
+}
+
+// user_roles and role_permissions rows are deleted with role (ON DELETE CASCADE)
+const deleteRoleQuery = `
+	DELETE FROM roles 
+	WHERE role = $1;
+`
+
+func (d *database) DeleteRole(ctx context.Context, name model.Role) error {
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		before, err := getRole(ctx, tx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteRoleQuery, name); err != nil {
+			return nil, errors.Wrap(err, "could not delete role")
+		}
+
+		return newAuditEntry(model.AuditEntityRole, model.AuditDeleted, string(name), nil, before, nil)
+	})
+}
+
+const getPermissionsByUserQuery = `
+	SELECT DISTINCT rp.permission 
+	FROM user_roles ur 
+		JOIN role_permissions rp ON rp.role = ur.role 
+	WHERE ur.user_id = $1;
+`
+
+func (d *database) GetPermissionsByUser(ctx context.Context, userID model.UserID) ([]model.Permission, error) {
+	var permissions []model.Permission
+	if err := d.conn.SelectContext(ctx, &permissions, getPermissionsByUserQuery, userID); err != nil {
+		return nil, errors.Wrap(err, "could not get user permissions")
+	}
+
+	return permissions, nil
+}
@@ -23,7 +23,7 @@ const insertOrUpdateSession = `
 `
 
 func (d *database) SaveRefreshToken(ctx context.Context, session model.Session) error {
-	if _, err := d.conn.NamedQueryContext(ctx, insertOrUpdateSession, session); err != nil {
+	if _, err := d.conn.NamedExecContext(ctx, insertOrUpdateSession, session); err != nil {
 		return err
 	}
 	return nil
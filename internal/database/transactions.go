@@ -2,9 +2,12 @@ package database
 
 import (
 	"context"
-	"github.com/pkg/errors"
+	"database/sql"
 	"time"
 
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
 	"github.com/startdusk/finance-app-backend/internal/model"
 )
 
@@ -16,27 +19,31 @@ type TransactionDB interface {
 	ListTransactionByAccountID(ctx context.Context, accountID model.AccountID, from, to time.Time) ([]*model.Transaction, error)
 	ListTransactionByUserID(ctx context.Context, userID model.UserID, from, to time.Time) ([]*model.Transaction, error)
 	DeleteTransaction(ctx context.Context, transactionID model.TransactionID) (bool, error)
+	RestoreTransaction(ctx context.Context, transactionID model.TransactionID, state *model.Transaction) (*model.Transaction, error)
 }
 
 const createTransactionQuery = `
-	INSERT INTO transactions (user_id, account_id, category_id, date, type, amount, notes) 
-		VALUES (:user_id, :account_id, :category_id, :date, :type, :amount, :notes) 
-	RETURNING transaction_id;
+	INSERT INTO transactions (user_id, account_id, category_id, date, type, amount, notes, status, symbol, quantity, cleared_status) 
+		VALUES (:user_id, :account_id, :category_id, :date, :type, :amount, :notes, COALESCE(CAST(:status AS transaction_status), 'posted'), :symbol, :quantity, COALESCE(CAST(:cleared_status AS cleared_status), 'uncleared')) 
+	RETURNING transaction_id, status, cleared_status;
 `
 
 func (d *database) CreateTransaction(ctx context.Context, transaction *model.Transaction) error {
-	rows, err := d.conn.NamedQueryContext(ctx, createTransactionQuery, transaction)
-	if err != nil {
-		return err
-	}
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		rows, err := sqlx.NamedQueryContext(ctx, tx, createTransactionQuery, transaction)
+		if err != nil {
+			return nil, err
+		}
 
-	defer rows.Close()
-	rows.Next()
-	if err := rows.Scan(&transaction.ID); err != nil {
-		return err
-	}
+		defer rows.Close()
+		rows.Next()
+		if err := rows.Scan(&transaction.ID, &transaction.Status, &transaction.ClearedStatus); err != nil {
+			return nil, err
+		}
+		rows.Close()
 
-	return nil
+		return newAuditEntry(model.AuditEntityTransaction, model.AuditCreated, string(transaction.ID), transaction.UserID, nil, transaction)
+	})
 }
 
 const updateTransactionQuery = `
@@ -46,26 +53,34 @@ const updateTransactionQuery = `
 		date = :date, 
 		type = :type, 
 		amount = :amount, 
-		notes = :notes 
+		notes = :notes, 
+		status = COALESCE(CAST(:status AS transaction_status), status), 
+		symbol = :symbol, 
+		quantity = :quantity, 
+		cleared_status = COALESCE(CAST(:cleared_status AS cleared_status), cleared_status) 
 	WHERE transaction_id = :transaction_id;
 `
 
 func (d *database) UpdateTransaction(ctx context.Context, transaction *model.Transaction) error {
-	result, err := d.conn.NamedExecContext(ctx, updateTransactionQuery, transaction)
-	if err != nil {
-		return err
-	}
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Transaction
+		if err := tx.GetContext(ctx, &before, getTransactionByIDQuery, transaction.ID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("transaction not found")
+			}
+			return nil, err
+		}
 
-	rows, err := result.RowsAffected()
-	if err != nil || rows == 0 {
-		return errors.New("transaction not found")
-	}
+		if _, err := tx.NamedExecContext(ctx, updateTransactionQuery, transaction); err != nil {
+			return nil, err
+		}
 
-	return nil
+		return newAuditEntry(model.AuditEntityTransaction, model.AuditUpdated, string(transaction.ID), before.UserID, &before, transaction)
+	})
 }
 
 const getTransactionByIDQuery = `
-	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, created_at, deleted_at 
+	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, status, symbol, quantity, cleared_status, created_at, deleted_at 
 	FROM transactions   
 	WHERE transaction_id = $1 
 		AND deleted_at IS NULL;
@@ -81,7 +96,7 @@ func (d *database) GetTransactionByID(ctx context.Context, transactionID model.T
 }
 
 const listTransactionByUserIDQuery = `
-	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, created_at, deleted_at 
+	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, status, symbol, quantity, cleared_status, created_at, deleted_at 
 	FROM transactions 
 	WHERE user_id = $1 
 		AND deleted_at IS NULL
@@ -99,7 +114,7 @@ func (d *database) ListTransactionByUserID(ctx context.Context, userID model.Use
 }
 
 const listTransactionByCategoryIDQuery = `
-	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, created_at, deleted_at 
+	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, status, symbol, quantity, cleared_status, created_at, deleted_at 
 	FROM transactions 
 	WHERE category_id = $1 
 		AND deleted_at IS NULL 
@@ -117,7 +132,7 @@ func (d *database) ListTransactionByCategoryID(ctx context.Context, categoryID m
 }
 
 const listTransactionByAccountIDQuery = `
-	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, created_at, deleted_at 
+	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, status, symbol, quantity, cleared_status, created_at, deleted_at 
 	FROM transactions 
 	WHERE account_id = $1 
 		AND deleted_at IS NULL
@@ -142,15 +157,135 @@ const deleteTransactionQuery = `
 `
 
 func (d *database) DeleteTransaction(ctx context.Context, transactionID model.TransactionID) (bool, error) {
-	result, err := d.conn.ExecContext(ctx, deleteTransactionQuery, transactionID)
+	deleted := false
+	err := d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Transaction
+		if err := tx.GetContext(ctx, &before, getTransactionByIDQuery, transactionID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteTransactionQuery, transactionID); err != nil {
+			return nil, err
+		}
+		deleted = true
+
+		return newAuditEntry(model.AuditEntityTransaction, model.AuditDeleted, string(transactionID), before.UserID, &before, nil)
+	})
 	if err != nil {
 		return false, err
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil || rows == 0 {
-		return false, err
+	return deleted, nil
+}
+
+// deleted transactions can be restored
+const getTransactionWithDeletedQuery = `
+	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, status, symbol, quantity, cleared_status, created_at, deleted_at 
+	FROM transactions 
+	WHERE transaction_id = $1;
+`
+
+// restored transaction gets data of revision back and isn't deleted anymore
+const restoreTransactionQuery = `
+	UPDATE transactions 
+	SET account_id = :account_id, 
+		category_id = :category_id, 
+		date = :date, 
+		type = :type, 
+		amount = :amount, 
+		notes = :notes, 
+		status = COALESCE(CAST(:status AS transaction_status), status), 
+		symbol = :symbol, 
+		quantity = :quantity, 
+		cleared_status = COALESCE(CAST(:cleared_status AS cleared_status), cleared_status), 
+		deleted_at = NULL 
+	WHERE transaction_id = :transaction_id;
+`
+
+// RestoreTransaction undeletes transaction and sets its data to state, nil state keeps current data. Owner is never changed.
+func (d *database) RestoreTransaction(ctx context.Context, transactionID model.TransactionID, state *model.Transaction) (*model.Transaction, error) {
+	var restored model.Transaction
+	err := d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.Transaction
+		if err := tx.GetContext(ctx, &before, getTransactionWithDeletedQuery, transactionID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("transaction not found")
+			}
+			return nil, err
+		}
+
+		next := before
+		if state != nil {
+			next = *state
+		}
+		next.ID = before.ID
+		next.UserID = before.UserID
+
+		if _, err := tx.NamedExecContext(ctx, restoreTransactionQuery, &next); err != nil {
+			return nil, err
+		}
+
+		if err := tx.GetContext(ctx, &restored, getTransactionWithDeletedQuery, transactionID); err != nil {
+			return nil, err
+		}
+
+		return newAuditEntry(model.AuditEntityTransaction, model.AuditRestored, string(transactionID), before.UserID, &before, &restored)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not restore transaction")
+	}
+
+	return &restored, nil
+}
+
+const moveTransactionQuery = `
+	UPDATE transactions 
+	SET account_id = :account_id, 
+		category_id = :category_id 
+	WHERE transaction_id = :transaction_id;
+`
+
+// deleteTransactionsInTx soft-deletes transactions of deleted account or category,
+// they get the same deleted_at as their parent so they can be restored with it
+func deleteTransactionsInTx(ctx context.Context, tx *sqlx.Tx, transactions []*model.Transaction) error {
+	for _, transaction := range transactions {
+		if _, err := tx.ExecContext(ctx, deleteTransactionQuery, transaction.ID); err != nil {
+			return errors.Wrap(err, "could not delete transaction")
+		}
+
+		entry, err := newAuditEntry(model.AuditEntityTransaction, model.AuditDeleted, string(transaction.ID), transaction.UserID, transaction, nil)
+		if err != nil {
+			return err
+		}
+		if err := writeAudit(ctx, tx, entry); err != nil {
+			return err
+		}
 	}
 
-	return true, nil
+	return nil
+}
+
+// moveTransactionsInTx changes account or category of transactions, move sets new one
+func moveTransactionsInTx(ctx context.Context, tx *sqlx.Tx, transactions []*model.Transaction, move func(transaction *model.Transaction)) error {
+	for _, transaction := range transactions {
+		after := *transaction
+		move(&after)
+
+		if _, err := tx.NamedExecContext(ctx, moveTransactionQuery, &after); err != nil {
+			return errors.Wrap(err, "could not move transaction")
+		}
+
+		entry, err := newAuditEntry(model.AuditEntityTransaction, model.AuditUpdated, string(transaction.ID), transaction.UserID, transaction, &after)
+		if err != nil {
+			return err
+		}
+		if err := writeAudit(ctx, tx, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
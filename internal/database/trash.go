@@ -0,0 +1,236 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// TrashDB persist soft-deleted records, restore is in RestoreAccount, RestoreCategory...
+type TrashDB interface {
+	ListTrash(ctx context.Context, userID model.UserID) (*model.Trash, error)
+	PurgeDeleted(ctx context.Context, entity model.AuditEntity, id string) error
+	ListExpiredTrash(ctx context.Context, deletedBefore time.Time) ([]*model.TrashItem, error)
+}
+
+var (
+	ErrNotInTrash    = errors.New("item is not in trash")
+	ErrHasDependents = errors.New("item has transactions or child categories which are not deleted")
+	ErrHasChildren   = errors.New("category has child categories which are not deleted")
+)
+
+const listTrashAccountsQuery = `
+	SELECT a.account_id, a.user_id, a.start_balance, a.account_type, a.account_name, a.currency, a.institution, a.masked_number, a.credit_limit, a.statement_day, a.interest_rate, a.payment_due_day, a.min_payment_percent, a.min_payment_amount, a.loan_principal, a.loan_term_months, a.loan_start_date, a.created_at, a.deleted_at, 
+		(SELECT COUNT(*) FROM transactions t WHERE t.account_id = a.account_id AND t.deleted_at = a.deleted_at) AS deleted_transactions 
+	FROM accounts a 
+	WHERE a.user_id = $1 AND a.deleted_at IS NOT NULL 
+	ORDER BY a.deleted_at DESC;
+`
+
+const listTrashCategoriesQuery = `
+	SELECT category_id, parent_id, user_id, name, created_at, deleted_at 
+	FROM categories 
+	WHERE user_id = $1 AND deleted_at IS NOT NULL 
+	ORDER BY deleted_at DESC;
+`
+
+const listTrashMerchantsQuery = `
+	SELECT merchant_id, user_id, name, created_at, deleted_at 
+	FROM merchants 
+	WHERE user_id = $1 AND deleted_at IS NOT NULL 
+	ORDER BY deleted_at DESC;
+`
+
+const listTrashTransactionsQuery = `
+	SELECT transaction_id, user_id, account_id, category_id, date, type, amount, notes, status, symbol, quantity, cleared_status, created_at, deleted_at 
+	FROM transactions 
+	WHERE user_id = $1 AND deleted_at IS NOT NULL 
+	ORDER BY deleted_at DESC;
+`
+
+// ListTrash returns deleted items of user, recently deleted first
+func (d *database) ListTrash(ctx context.Context, userID model.UserID) (*model.Trash, error) {
+	trash := model.Trash{
+		Accounts:     make([]*model.TrashAccount, 0),
+		Categories:   make([]*model.Category, 0),
+		Merchants:    make([]*model.Merchant, 0),
+		Transactions: make([]*model.Transaction, 0),
+	}
+
+	if err := d.conn.SelectContext(ctx, &trash.Accounts, listTrashAccountsQuery, userID); err != nil {
+		return nil, errors.Wrap(err, "could not get deleted accounts")
+	}
+	if err := d.conn.SelectContext(ctx, &trash.Categories, listTrashCategoriesQuery, userID); err != nil {
+		return nil, errors.Wrap(err, "could not get deleted categories")
+	}
+	if err := d.conn.SelectContext(ctx, &trash.Merchants, listTrashMerchantsQuery, userID); err != nil {
+		return nil, errors.Wrap(err, "could not get deleted merchants")
+	}
+	if err := d.conn.SelectContext(ctx, &trash.Transactions, listTrashTransactionsQuery, userID); err != nil {
+		return nil, errors.Wrap(err, "could not get deleted transactions")
+	}
+
+	return &trash, nil
+}
+
+// purge describes how deleted item is removed for good
+type purge struct {
+	get        string   // item including deleted_at, it must be set
+	dependents string   // count of not deleted transactions which need item
+	children   string   // count of not deleted child categories, they would point to missing parent
+	before     []string // deleted rows which reference item and go with it
+	delete     string
+}
+
+var purges = map[model.AuditEntity]purge{
+	model.AuditEntityAccount: {
+		get:        getAccountByIDQuery,
+		dependents: `SELECT COUNT(*) FROM transactions WHERE account_id = $1 AND deleted_at IS NULL;`,
+		before: []string{
+			`DELETE FROM entity_revisions WHERE entity_type = 'transaction' AND entity_id IN (SELECT transaction_id FROM transactions WHERE account_id = $1);`,
+			`DELETE FROM transactions WHERE account_id = $1;`,
+			`DELETE FROM account_members WHERE account_id = $1;`,
+			`DELETE FROM reconciliations WHERE account_id = $1;`,
+		},
+		delete: `DELETE FROM accounts WHERE account_id = $1;`,
+	},
+	model.AuditEntityCategory: {
+		get:        getCategoryWithDeletedQuery,
+		dependents: `SELECT COUNT(*) FROM transactions WHERE category_id = $1 AND deleted_at IS NULL;`,
+		children:   `SELECT COUNT(*) FROM categories WHERE parent_id = $1::text AND deleted_at IS NULL;`,
+		before: []string{
+			`DELETE FROM entity_revisions WHERE entity_type = 'transaction' AND entity_id IN (SELECT transaction_id FROM transactions WHERE category_id = $1);`,
+			`DELETE FROM transactions WHERE category_id = $1;`,
+		},
+		delete: `DELETE FROM categories WHERE category_id = $1;`,
+	},
+	model.AuditEntityMerchant: {
+		get:    getMerchantWithDeletedQuery,
+		delete: `DELETE FROM merchants WHERE merchant_id = $1;`,
+	},
+	model.AuditEntityTransaction: {
+		get:    getTransactionWithDeletedQuery,
+		delete: `DELETE FROM transactions WHERE transaction_id = $1;`,
+	},
+}
+
+// newPurgedState returns empty entity for purge.get query
+func newPurgedState(entity model.AuditEntity) interface{} {
+	switch entity {
+	case model.AuditEntityAccount:
+		return &model.Account{}
+	case model.AuditEntityCategory:
+		return &model.Category{}
+	case model.AuditEntityMerchant:
+		return &model.Merchant{}
+	}
+	return &model.Transaction{}
+}
+
+const deleteRevisionsQuery = `
+	DELETE FROM entity_revisions 
+	WHERE entity_type = $1 AND entity_id = $2;
+`
+
+// PurgeDeleted removes deleted item from database with its history, audit log keeps its last state.
+// Account or category which still has transactions and category with child categories can't be purged.
+func (d *database) PurgeDeleted(ctx context.Context, entity model.AuditEntity, id string) error {
+	p, ok := purges[entity]
+	if !ok {
+		return errors.Errorf("unknown entity: %s", entity)
+	}
+
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		before := newPurgedState(entity)
+		if err := tx.GetContext(ctx, before, p.get, id); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, ErrNotInTrash
+			}
+			return nil, errors.Wrap(err, "could not get deleted item")
+		}
+
+		userID, deletedAt := purgedOwner(before)
+		if deletedAt == nil {
+			return nil, ErrNotInTrash
+		}
+
+		if p.dependents != "" {
+			var count int
+			if err := tx.GetContext(ctx, &count, p.dependents, id); err != nil {
+				return nil, errors.Wrap(err, "could not count dependents")
+			}
+			if count > 0 {
+				return nil, ErrHasDependents
+			}
+		}
+
+		if p.children != "" {
+			var count int
+			if err := tx.GetContext(ctx, &count, p.children, id); err != nil {
+				return nil, errors.Wrap(err, "could not count children")
+			}
+			if count > 0 {
+				return nil, ErrHasChildren
+			}
+		}
+
+		for _, query := range p.before {
+			if _, err := tx.ExecContext(ctx, query, id); err != nil {
+				return nil, errors.Wrap(err, "could not purge dependents")
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, p.delete, id); err != nil {
+			return nil, errors.Wrap(err, "could not purge item")
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteRevisionsQuery, entity, id); err != nil {
+			return nil, errors.Wrap(err, "could not purge revisions")
+		}
+
+		return newAuditEntry(entity, model.AuditPurged, id, userID, before, nil)
+	})
+}
+
+func purgedOwner(state interface{}) (*model.UserID, *time.Time) {
+	switch s := state.(type) {
+	case *model.Account:
+		return s.UserID, s.DeletedAt
+	case *model.Category:
+		return s.UserID, s.DeletedAt
+	case *model.Merchant:
+		return s.UserID, s.DeletedAt
+	case *model.Transaction:
+		return s.UserID, s.DeletedAt
+	}
+	return nil, nil
+}
+
+// transactions go first, accounts and categories can be purged only after their transactions
+const listExpiredTrashQuery = `
+	SELECT entity_type, entity_id FROM (
+		SELECT 'transaction' AS entity_type, transaction_id::text AS entity_id, 1 AS step FROM transactions WHERE deleted_at < $1 
+		UNION ALL 
+		SELECT 'merchant', merchant_id::text, 2 FROM merchants WHERE deleted_at < $1 
+		UNION ALL 
+		SELECT 'category', category_id::text, 3 FROM categories WHERE deleted_at < $1 
+		UNION ALL 
+		SELECT 'account', account_id::text, 4 FROM accounts WHERE deleted_at < $1 
+	) expired 
+	ORDER BY step;
+`
+
+// ListExpiredTrash returns items deleted before given time in order in which they can be purged
+func (d *database) ListExpiredTrash(ctx context.Context, deletedBefore time.Time) ([]*model.TrashItem, error) {
+	var items []*model.TrashItem
+	if err := d.conn.SelectContext(ctx, &items, listExpiredTrashQuery, deletedBefore); err != nil {
+		return nil, errors.Wrap(err, "could not list expired trash")
+	}
+
+	return items, nil
+}
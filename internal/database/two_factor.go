@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// TwoFactorDB persist users second factor (TOTP and recovery codes)
+type TwoFactorDB interface {
+	SaveTOTP(ctx context.Context, totp *model.TOTP) error
+	GetTOTP(ctx context.Context, userID model.UserID) (*model.TOTP, error)
+	UseTOTPStep(ctx context.Context, userID model.UserID, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userID model.UserID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID model.UserID, codeHashes [][]byte) error
+	UseRecoveryCode(ctx context.Context, userID model.UserID, codeHash []byte) (bool, error)
+}
+
+var ErrTOTPNotFound = errors.New("totp not found")
+
+const saveTOTPQuery = `
+	INSERT INTO user_totp (user_id, secret, enabled, last_used_step, confirmed_at) 
+		VALUES (:user_id, :secret, :enabled, :last_used_step, :confirmed_at) 
+
+	ON CONFLICT (user_id) 
+	DO
+		UPDATE 
+			SET secret = :secret,
+				enabled = :enabled,
+				last_used_step = :last_used_step,
+				confirmed_at = :confirmed_at
+`
+
+func (d *database) SaveTOTP(ctx context.Context, totp *model.TOTP) error {
+	if _, err := d.conn.NamedExecContext(ctx, saveTOTPQuery, totp); err != nil {
+		return errors.Wrap(err, "could not save totp")
+	}
+
+	return nil
+}
+
+const getTOTPQuery = `
+	SELECT user_id, secret, enabled, last_used_step, created_at, confirmed_at 
+	FROM user_totp 
+	WHERE user_id = $1;
+`
+
+func (d *database) GetTOTP(ctx context.Context, userID model.UserID) (*model.TOTP, error) {
+	var totp model.TOTP
+	if err := d.conn.GetContext(ctx, &totp, getTOTPQuery, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTOTPNotFound
+		}
+		return nil, errors.Wrap(err, "could not get totp")
+	}
+
+	return &totp, nil
+}
+
+// we update step only if it is newer than last used one,
+// so the same code can't be used twice even by two requests at the same time
+const useTOTPStepQuery = `
+	UPDATE user_totp 
+	SET last_used_step = $2 
+	WHERE user_id = $1 AND last_used_step < $2;
+`
+
+func (d *database) UseTOTPStep(ctx context.Context, userID model.UserID, step int64) (bool, error) {
+	result, err := d.conn.ExecContext(ctx, useTOTPStepQuery, userID, step)
+	if err != nil {
+		return false, errors.Wrap(err, "could not use totp step")
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return false, err
+	}
+
+	return true, nil
+}
+
+const deleteRecoveryCodesQuery = `
+	DELETE FROM user_recovery_codes 
+	WHERE user_id = $1;
+`
+
+const deleteTOTPQuery = `
+	DELETE FROM user_totp 
+	WHERE user_id = $1;
+`
+
+func (d *database) DeleteTOTP(ctx context.Context, userID model.UserID) error {
+	return d.transact(ctx, nil, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, deleteRecoveryCodesQuery, userID); err != nil {
+			return errors.Wrap(err, "could not delete recovery codes")
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteTOTPQuery, userID); err != nil {
+			return errors.Wrap(err, "could not delete totp")
+		}
+
+		return nil
+	})
+}
+
+const insertRecoveryCodeQuery = `
+	INSERT INTO user_recovery_codes (user_id, code_hash) 
+		VALUES ($1, $2);
+`
+
+// ReplaceRecoveryCodes removes old recovery codes (used or not) and stores new ones
+func (d *database) ReplaceRecoveryCodes(ctx context.Context, userID model.UserID, codeHashes [][]byte) error {
+	return d.transact(ctx, nil, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, deleteRecoveryCodesQuery, userID); err != nil {
+			return errors.Wrap(err, "could not delete recovery codes")
+		}
+
+		for _, codeHash := range codeHashes {
+			if _, err := tx.ExecContext(ctx, insertRecoveryCodeQuery, userID, codeHash); err != nil {
+				return errors.Wrap(err, "could not save recovery code")
+			}
+		}
+
+		return nil
+	})
+}
+
+const useRecoveryCodeQuery = `
+	UPDATE user_recovery_codes 
+	SET used_at = NOW() 
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+`
+
+func (d *database) UseRecoveryCode(ctx context.Context, userID model.UserID, codeHash []byte) (bool, error) {
+	result, err := d.conn.ExecContext(ctx, useRecoveryCodeQuery, userID, codeHash)
+	if err != nil {
+		return false, errors.Wrap(err, "could not use recovery code")
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// TxDB runs many database calls as one unit of work
+type TxDB interface {
+	// WithTx runs fn with Database bound to one serializable transaction. Transaction is committed
+	// when fn returns nil and rolled back otherwise. fn is run again when Postgres can't serialize
+	// transaction or finds deadlock, so it should not keep state between runs.
+	// WithTx called inside transaction runs fn in that transaction.
+	WithTx(ctx context.Context, fn func(db Database) error) error
+}
+
+const (
+	// txAttempts - how many times transaction is run before serialization error is returned
+	txAttempts = 5
+	// txRetryDelay - wait before next attempt grows with every attempt
+	txRetryDelay = 10 * time.Millisecond
+)
+
+// conn is implemented by *sqlx.DB and *sqlx.Tx, so methods work the same in and out of transaction
+type conn interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
+
+func (d *database) WithTx(ctx context.Context, fn func(db Database) error) error {
+	if d.tx != nil {
+		return fn(d)
+	}
+
+	var err error
+	for attempt := 1; attempt <= txAttempts; attempt++ {
+		if err = d.runTx(ctx, fn); !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * txRetryDelay):
+		}
+	}
+
+	return err
+}
+
+func (d *database) runTx(ctx context.Context, fn func(db Database) error) error {
+	tx, err := d.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&database{db: d.db, conn: tx, tx: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// transact runs fn in transaction of d or in new one when d is not in transaction
+func (d *database) transact(ctx context.Context, options *sql.TxOptions, fn func(tx *sqlx.Tx) error) error {
+	if d.tx != nil {
+		return fn(d.tx)
+	}
+
+	tx, err := d.db.BeginTxx(ctx, options)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// isRetryable - serialization_failure and deadlock_detected, transaction can succeed when run again
+func isRetryable(err error) bool {
+	pqError, ok := errors.Cause(err).(*pq.Error)
+	if !ok {
+		return false
+	}
+
+	switch pqError.Code {
+	case "40001", "40P01":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+func TestIsRetryable(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("failure"), false},
+		{&pq.Error{Code: "40001"}, true},
+		{&pq.Error{Code: "40P01"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{errors.Wrap(&pq.Error{Code: "40001"}, "could not update"), true},
+	} {
+		if got := isRetryable(test.err); got != test.want {
+			t.Errorf("isRetryable(%v) = %v; want %v", test.err, got, test.want)
+		}
+	}
+}
+
+// TestWithTxRetry makes concurrent update between read and write of transaction, so Postgres can't
+// serialize first attempt. Postgres is tested only when TEST_DATABASE_URL is set.
+func TestWithTxRetry(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	conn, err := sqlx.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE tx_retry_test (id INTEGER PRIMARY KEY, value INTEGER NOT NULL);`); err != nil {
+		t.Fatalf("could not create table: %v", err)
+	}
+	defer conn.ExecContext(ctx, `DROP TABLE tx_retry_test;`)
+	if _, err := conn.ExecContext(ctx, `INSERT INTO tx_retry_test (id, value) VALUES (1, 0);`); err != nil {
+		t.Fatal(err)
+	}
+
+	db := &database{db: conn, conn: conn}
+	attempts := 0
+	err = db.WithTx(ctx, func(tx Database) error {
+		attempts++
+		c := tx.(*database).conn
+
+		var value int
+		if err := c.GetContext(ctx, &value, `SELECT value FROM tx_retry_test WHERE id = 1;`); err != nil {
+			return err
+		}
+
+		if attempts == 1 {
+			if _, err := conn.ExecContext(ctx, `UPDATE tx_retry_test SET value = value + 1 WHERE id = 1;`); err != nil {
+				t.Fatalf("concurrent update = %v", err)
+			}
+		}
+
+		_, err := c.ExecContext(ctx, `UPDATE tx_retry_test SET value = $1 WHERE id = 1;`, value+10)
+		return err
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("WithTx() = %v after %d attempts; want nil after 2", err, attempts)
+	}
+
+	var value int
+	if err := conn.GetContext(ctx, &value, `SELECT value FROM tx_retry_test WHERE id = 1;`); err != nil || value != 11 {
+		t.Fatalf("value = %d, %v; want 11, update of second attempt sees concurrent one", value, err)
+	}
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// UserIdentityDB persist users identities on external login providers
+type UserIdentityDB interface {
+	CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) error
+	GetUserIdentity(ctx context.Context, provider, subject string) (*model.UserIdentity, error)
+	ListUserIdentities(ctx context.Context, userID model.UserID) ([]*model.UserIdentity, error)
+}
+
+var ErrIdentityNotFound = errors.New("user identity not found")
+
+const createUserIdentityQuery = `
+	INSERT INTO user_identities (provider, subject, user_id, email) 
+		VALUES (:provider, :subject, :user_id, :email);
+`
+
+func (d *database) CreateUserIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	if _, err := d.conn.NamedExecContext(ctx, createUserIdentityQuery, identity); err != nil {
+		return errors.Wrap(err, "could not create user identity")
+	}
+
+	return nil
+}
+
+const getUserIdentityQuery = `
+	SELECT provider, subject, user_id, email, created_at 
+	FROM user_identities 
+	WHERE provider = $1 AND subject = $2;
+`
+
+func (d *database) GetUserIdentity(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	if err := d.conn.GetContext(ctx, &identity, getUserIdentityQuery, provider, subject); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, errors.Wrap(err, "could not get user identity")
+	}
+
+	return &identity, nil
+}
+
+const listUserIdentitiesQuery = `
+	SELECT provider, subject, user_id, email, created_at 
+	FROM user_identities 
+	WHERE user_id = $1;
+`
+
+func (d *database) ListUserIdentities(ctx context.Context, userID model.UserID) ([]*model.UserIdentity, error) {
+	var identities []*model.UserIdentity
+	if err := d.conn.SelectContext(ctx, &identities, listUserIdentitiesQuery, userID); err != nil {
+		return nil, errors.Wrap(err, "could not get user identities")
+	}
+
+	return identities, nil
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 
 	"github.com/startdusk/finance-app-backend/internal/model"
@@ -20,11 +21,13 @@ const grantUserRoleQuery = `
 `
 
 func (d *database) GrantRole(ctx context.Context, userID model.UserID, role model.Role) error {
-	if _, err := d.conn.ExecContext(ctx, grantUserRoleQuery, userID, role); err != nil {
-		return errors.Wrap(err, "could not grant user role")
-	}
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		if _, err := tx.ExecContext(ctx, grantUserRoleQuery, userID, role); err != nil {
+			return nil, errors.Wrap(err, "could not grant user role")
+		}
 
-	return nil
+		return newAuditEntry(model.AuditEntityUserRole, model.AuditGranted, string(role), &userID, nil, &model.UserRole{Role: role})
+	})
 }
 
 const revokeUserRoleQuery = `
@@ -33,11 +36,19 @@ const revokeUserRoleQuery = `
 `
 
 func (d *database) RevokeRole(ctx context.Context, userID model.UserID, role model.Role) error {
-	if _, err := d.conn.ExecContext(ctx, revokeUserRoleQuery, userID, role); err != nil {
-		return errors.Wrap(err, "could not revoke user role")
-	}
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		result, err := tx.ExecContext(ctx, revokeUserRoleQuery, userID, role)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not revoke user role")
+		}
+
+		// user didn't have role, nothing changed
+		if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+			return nil, err
+		}
 
-	return nil
+		return newAuditEntry(model.AuditEntityUserRole, model.AuditRevoked, string(role), &userID, &model.UserRole{Role: role}, nil)
+	})
 }
 
 const getRolesByUserIDQuery = `
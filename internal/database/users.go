@@ -1,8 +1,11 @@
 package database
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"github.com/pkg/errors"
 
@@ -32,26 +35,30 @@ const createUserQuery = `
 `
 
 func (d *database) CreateUser(ctx context.Context, user *model.User) error {
-	rows, err := d.conn.NamedQueryContext(ctx, createUserQuery, user)
-	if rows != nil {
-		defer rows.Close()
-	}
-	if err != nil {
-		if pqError, ok := err.(*pq.Error); ok {
-			if pqError.Code.Name() == UniqueViolation {
-				if pqError.Constraint == "user_email" {
-					return ErrUserExist
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		rows, err := sqlx.NamedQueryContext(ctx, tx, createUserQuery, user)
+		if rows != nil {
+			defer rows.Close()
+		}
+		if err != nil {
+			if pqError, ok := err.(*pq.Error); ok {
+				if pqError.Code.Name() == UniqueViolation {
+					if pqError.Constraint == "user_email" {
+						return nil, ErrUserExist
+					}
 				}
 			}
+			return nil, errors.Wrap(err, "could not create user")
 		}
-		return errors.Wrap(err, "could not create user")
-	}
 
-	rows.Next()
-	if err := rows.Scan(&user.ID); err != nil {
-		return errors.Wrap(err, "could not get created user id")
-	}
-	return nil
+		rows.Next()
+		if err := rows.Scan(&user.ID); err != nil {
+			return nil, errors.Wrap(err, "could not get created user id")
+		}
+		rows.Close()
+
+		return newAuditEntry(model.AuditEntityUser, model.AuditCreated, string(user.ID), &user.ID, nil, user)
+	})
 }
 
 const getUserByIDQuery = `
@@ -112,17 +119,28 @@ const deleteUserQuery = `
 `
 
 func (d *database) DeleteUser(ctx context.Context, userID model.UserID) (bool, error) {
-	result, err := d.conn.ExecContext(ctx, deleteUserQuery, userID)
-	if err != nil {
-		return false, err
-	}
+	deleted := false
+	err := d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.User
+		if err := tx.GetContext(ctx, &before, getUserByIDQuery, userID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		if _, err := tx.ExecContext(ctx, deleteUserQuery, userID); err != nil {
+			return nil, err
+		}
+		deleted = true
 
-	rows, err := result.RowsAffected()
-	if err != nil || rows == 0 {
+		return newAuditEntry(model.AuditEntityUser, model.AuditDeleted, string(userID), &userID, &before, nil)
+	})
+	if err != nil {
 		return false, err
 	}
 
-	return true, nil
+	return deleted, nil
 }
 
 const updateUserQuery = `
@@ -132,15 +150,35 @@ const updateUserQuery = `
 `
 
 func (d *database) UpdateUser(ctx context.Context, user *model.User) error {
-	result, err := d.conn.NamedExecContext(ctx, updateUserQuery, user)
-	if err != nil {
-		return err
-	}
+	return d.audited(ctx, func(tx *sqlx.Tx) (*model.AuditEntry, error) {
+		var before model.User
+		if err := tx.GetContext(ctx, &before, getUserByIDQuery, user.ID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, errors.New("user not found")
+			}
+			return nil, err
+		}
 
-	rows, err := result.RowsAffected()
-	if err != nil || rows == 0 {
-		return errors.New("user not found")
-	}
+		if _, err := tx.NamedExecContext(ctx, updateUserQuery, user); err != nil {
+			return nil, err
+		}
 
-	return nil
+		entry, err := newAuditEntry(model.AuditEntityUser, model.AuditUpdated, string(user.ID), &user.ID, &before, user)
+		if err != nil {
+			return nil, err
+		}
+
+		// password hash is not in JSON of user, we log only that it was changed
+		if !bytesPtrEqual(before.PasswordHash, user.PasswordHash) {
+			entry.Details = model.JSON(`{"passwordChanged": true}`)
+		}
+		return entry, nil
+	})
+}
+
+func bytesPtrEqual(a, b *[]byte) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return bytes.Equal(*a, *b)
 }
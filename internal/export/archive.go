@@ -0,0 +1,134 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// WriteArchive writes zip with one JSON file per entity and transactions as CSV
+func WriteArchive(w io.Writer, export *model.UserExport) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"user.json", export.User},
+		{"sessions.json", export.Sessions},
+		{"roles.json", export.Roles},
+		{"identities.json", export.Identities},
+		{"api_keys.json", export.APIKeys},
+		{"accounts.json", export.Accounts},
+		{"categories.json", export.Categories},
+		{"merchants.json", export.Merchants},
+		{"transactions.json", export.Transactions},
+		{"prices.json", export.Prices},
+		{"reconciliations.json", export.Reconciliations},
+	}
+	for _, file := range files {
+		if err := writeJSON(archive, file.name, file.data); err != nil {
+			return err
+		}
+	}
+
+	if err := writeTransactionsCSV(archive, export.Transactions); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func writeJSON(archive *zip.Writer, name string, data interface{}) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return errors.Wrapf(err, "could not create %s", name)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return errors.Wrapf(err, "could not write %s", name)
+	}
+
+	return nil
+}
+
+var transactionsHeader = []string{"id", "date", "type", "amount", "accountID", "categoryID", "notes", "createdAt", "deletedAt", "symbol", "quantity", "clearedStatus", "status"}
+
+func writeTransactionsCSV(archive *zip.Writer, transactions []*model.Transaction) error {
+	file, err := archive.Create("transactions.csv")
+	if err != nil {
+		return errors.Wrap(err, "could not create transactions.csv")
+	}
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(transactionsHeader); err != nil {
+		return errors.Wrap(err, "could not write transactions.csv")
+	}
+
+	for _, t := range transactions {
+		record := []string{
+			string(t.ID),
+			formatTime(t.Date),
+			"",
+			"",
+			"",
+			"",
+			"",
+			formatTime(t.CreatedAt),
+			formatTime(t.DeletedAt),
+			"",
+			"",
+			"",
+			"",
+		}
+		if t.Type != nil {
+			record[2] = string(*t.Type)
+		}
+		if t.Amount != nil {
+			record[3] = t.Amount.String()
+		}
+		if t.AccountID != nil {
+			record[4] = string(*t.AccountID)
+		}
+		if t.CategoryID != nil {
+			record[5] = string(*t.CategoryID)
+		}
+		if t.Notes != nil {
+			record[6] = *t.Notes
+		}
+		if t.Symbol != nil {
+			record[9] = *t.Symbol
+		}
+		if t.Quantity != nil {
+			record[10] = t.Quantity.String()
+		}
+		if t.ClearedStatus != nil {
+			record[11] = string(*t.ClearedStatus)
+		}
+		if t.Status != nil {
+			record[12] = string(*t.Status)
+		}
+
+		if err := writer.Write(record); err != nil {
+			return errors.Wrap(err, "could not write transactions.csv")
+		}
+	}
+
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "could not write transactions.csv")
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,285 @@
+package finance
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+var (
+	// ErrNoHoldings - only investment accounts hold positions
+	ErrNoHoldings = errors.New("account doesn't hold investments")
+	// ErrOversold - sell is larger than quantity held on its date, error is wrapped with symbol and date
+	ErrOversold = errors.New("sell is larger than quantity held")
+)
+
+// position is symbol held while transactions are applied
+type position struct {
+	symbol    string
+	lots      []*model.Lot
+	realized  model.Money
+	dividends model.Money
+}
+
+// Holdings computes positions of investment account from its buy, sell and dividend transactions until now.
+// Sell takes lots first in first out, its realized gain is proceeds minus cost of lots taken. Market value
+// uses the latest price until now, it's rounded half up to minor unit of account currency.
+func Holdings(account *model.Account, balance model.Money, transactions []*model.Transaction, prices []*model.Price, now time.Time) (*model.Holdings, error) {
+	if account.Type == nil || !account.Type.HasHoldings() {
+		return nil, ErrNoHoldings
+	}
+
+	exponent := model.CurrencyExponent(*account.Currency)
+	zero := model.Money{Exponent: exponent}
+
+	sorted := make([]*model.Transaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		if transaction.DeletedAt != nil || transaction.Date.After(now) || !transaction.Type.IsInvestment() || transaction.Symbol == nil {
+			continue
+		}
+		if transaction.Type.HasQuantity() && transaction.Quantity == nil {
+			continue
+		}
+		sorted = append(sorted, transaction)
+	}
+	// buy on the same time as sell comes first, so it can be sold
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Date.Equal(*sorted[j].Date) {
+			return *sorted[i].Type == model.Buy && *sorted[j].Type != model.Buy
+		}
+		return sorted[i].Date.Before(*sorted[j].Date)
+	})
+
+	positions := make(map[string]*position)
+	for _, transaction := range sorted {
+		p, ok := positions[*transaction.Symbol]
+		if !ok {
+			p = &position{symbol: *transaction.Symbol, realized: zero, dividends: zero}
+			positions[p.symbol] = p
+		}
+
+		var err error
+		switch *transaction.Type {
+		case model.Buy:
+			p.lots = append(p.lots, &model.Lot{Date: *transaction.Date, Quantity: *transaction.Quantity, Cost: *transaction.Amount})
+		case model.Sell:
+			err = p.sell(*transaction.Date, *transaction.Quantity, *transaction.Amount)
+		case model.Dividend:
+			p.dividends, err = p.dividends.Add(*transaction.Amount)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	latest := make(map[string]*model.Price)
+	for _, price := range prices {
+		if price.Date.After(now) {
+			continue
+		}
+		if found, ok := latest[*price.Symbol]; !ok || price.Date.After(*found.Date) {
+			latest[*price.Symbol] = price
+		}
+	}
+
+	holdings := &model.Holdings{
+		AccountID:      account.ID,
+		Currency:       *account.Currency,
+		Cash:           balance,
+		MarketValue:    zero,
+		CostBasis:      zero,
+		UnrealizedGain: zero,
+		RealizedGain:   zero,
+		Dividends:      zero,
+		Positions:      make([]*model.Position, 0, len(positions)),
+	}
+
+	for _, p := range positions {
+		result, err := p.value(latest[p.symbol], exponent)
+		if err != nil {
+			return nil, err
+		}
+		holdings.Positions = append(holdings.Positions, result)
+
+		if holdings.CostBasis, err = holdings.CostBasis.Add(result.CostBasis); err != nil {
+			return nil, err
+		}
+		if holdings.RealizedGain, err = holdings.RealizedGain.Add(result.RealizedGain); err != nil {
+			return nil, err
+		}
+		if holdings.Dividends, err = holdings.Dividends.Add(result.Dividends); err != nil {
+			return nil, err
+		}
+		if result.MarketValue == nil {
+			continue
+		}
+		if holdings.MarketValue, err = holdings.MarketValue.Add(*result.MarketValue); err != nil {
+			return nil, err
+		}
+		if holdings.UnrealizedGain, err = holdings.UnrealizedGain.Add(*result.UnrealizedGain); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(holdings.Positions, func(i, j int) bool { return holdings.Positions[i].Symbol < holdings.Positions[j].Symbol })
+
+	// allocation is share of market value, positions without price have none
+	if holdings.MarketValue.Sign() > 0 {
+		for _, result := range holdings.Positions {
+			if result.MarketValue == nil {
+				continue
+			}
+			allocation := math.Round(float64(result.MarketValue.Amount)/float64(holdings.MarketValue.Amount)*10000) / 100
+			result.Allocation = &allocation
+		}
+	}
+
+	var err error
+	if holdings.Value, err = balance.Add(holdings.MarketValue); err != nil {
+		return nil, err
+	}
+
+	return holdings, nil
+}
+
+// sell takes quantity from the oldest lots, cost of lot sold in part is split by quantity
+func (p *position) sell(date time.Time, quantity, proceeds model.Money) error {
+	cost := model.Money{Exponent: proceeds.Exponent}
+	for quantity.Sign() > 0 {
+		if len(p.lots) == 0 {
+			return errors.Wrapf(ErrOversold, "%s sold on %s", p.symbol, date.Format("2006-01-02"))
+		}
+
+		lot := p.lots[0]
+		var err error
+		if lot.Quantity.Cmp(quantity) <= 0 {
+			if cost, err = cost.Add(lot.Cost); err != nil {
+				return err
+			}
+			if quantity, err = quantity.Sub(lot.Quantity); err != nil {
+				return err
+			}
+			p.lots = p.lots[1:]
+			continue
+		}
+
+		part, err := prorate(lot.Cost, quantity, lot.Quantity)
+		if err != nil {
+			return err
+		}
+		if cost, err = cost.Add(part); err != nil {
+			return err
+		}
+		if lot.Cost, err = lot.Cost.Sub(part); err != nil {
+			return err
+		}
+		if lot.Quantity, err = lot.Quantity.Sub(quantity); err != nil {
+			return err
+		}
+		quantity = model.Money{}
+	}
+
+	gain, err := proceeds.Sub(cost)
+	if err != nil {
+		return err
+	}
+	p.realized, err = p.realized.Add(gain)
+	return err
+}
+
+// value sums lots of position and values them with price, nil price leaves market value empty
+func (p *position) value(price *model.Price, exponent int) (*model.Position, error) {
+	result := &model.Position{
+		Symbol:       p.symbol,
+		Quantity:     model.Money{},
+		CostBasis:    model.Money{Exponent: exponent},
+		RealizedGain: p.realized,
+		Dividends:    p.dividends,
+		Lots:         p.lots,
+	}
+	if result.Lots == nil {
+		result.Lots = make([]*model.Lot, 0)
+	}
+
+	var err error
+	for _, lot := range p.lots {
+		if result.Quantity, err = result.Quantity.Add(lot.Quantity); err != nil {
+			return nil, err
+		}
+		if result.CostBasis, err = result.CostBasis.Add(lot.Cost); err != nil {
+			return nil, err
+		}
+	}
+
+	if price == nil {
+		return result, nil
+	}
+
+	marketValue, err := multiply(result.Quantity, *price.Price, exponent)
+	if err != nil {
+		return nil, err
+	}
+	unrealized, err := marketValue.Sub(result.CostBasis)
+	if err != nil {
+		return nil, err
+	}
+	result.Price = price.Price
+	result.PriceDate = price.Date
+	result.MarketValue = &marketValue
+	result.UnrealizedGain = &unrealized
+	return result, nil
+}
+
+// prorate returns amount * part / whole rounded half up to exponent of amount
+func prorate(amount, part, whole model.Money) (model.Money, error) {
+	exponent := part.Exponent
+	if whole.Exponent > exponent {
+		exponent = whole.Exponent
+	}
+	part, err := part.Rescale(exponent, model.RoundExact)
+	if err != nil {
+		return model.Money{}, err
+	}
+	whole, err = whole.Rescale(exponent, model.RoundExact)
+	if err != nil {
+		return model.Money{}, err
+	}
+
+	numerator := new(big.Int).Mul(big.NewInt(amount.Amount), big.NewInt(part.Amount))
+	return divideHalfUp(numerator, big.NewInt(whole.Amount), amount.Exponent)
+}
+
+// multiply returns quantity * price rounded half up to exponent
+func multiply(quantity, price model.Money, exponent int) (model.Money, error) {
+	product := new(big.Int).Mul(big.NewInt(quantity.Amount), big.NewInt(price.Amount))
+
+	// product has quantity.Exponent + price.Exponent digits, it's shifted to exponent
+	shift := quantity.Exponent + price.Exponent - exponent
+	divisor := big.NewInt(1)
+	if shift < 0 {
+		product.Mul(product, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-shift)), nil))
+	} else {
+		divisor.Exp(big.NewInt(10), big.NewInt(int64(shift)), nil)
+	}
+	return divideHalfUp(product, divisor, exponent)
+}
+
+// divideHalfUp divides by positive divisor and rounds half away from zero
+func divideHalfUp(numerator, divisor *big.Int, exponent int) (model.Money, error) {
+	quotient, remainder := new(big.Int).QuoRem(numerator, divisor, new(big.Int))
+	if remainder.Abs(remainder).Mul(remainder, big.NewInt(2)).Cmp(divisor) >= 0 {
+		if numerator.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	if !quotient.IsInt64() {
+		return model.Money{}, model.ErrMoneyOverflow
+	}
+	return model.Money{Amount: quotient.Int64(), Exponent: exponent}, nil
+}
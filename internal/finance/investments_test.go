@@ -0,0 +1,100 @@
+package finance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func trade(transactionType model.TransactionType, on time.Time, symbol, quantity, amount string) *model.Transaction {
+	t := transaction(transactionType, on, amount)
+	t.Symbol = &symbol
+	if quantity != "" {
+		money, err := model.ParseMoney(quantity)
+		if err != nil {
+			panic(err)
+		}
+		t.Quantity = &money
+	}
+	return t
+}
+
+func price(symbol string, on time.Time, amount string) *model.Price {
+	money, err := model.ParseMoney(amount)
+	if err != nil {
+		panic(err)
+	}
+	return &model.Price{Symbol: &symbol, Date: &on, Price: &money}
+}
+
+func TestHoldings(t *testing.T) {
+	accountType := model.Investment
+	currency := "USD"
+	account := &model.Account{Type: &accountType, Currency: &currency}
+
+	transactions := []*model.Transaction{
+		trade(model.Sell, date(time.March, 1), "AAPL", "12", "1500.00"),
+		trade(model.Buy, date(time.January, 5), "AAPL", "10", "1000.00"),
+		trade(model.Buy, date(time.February, 1), "AAPL", "5", "600.00"),
+		trade(model.Dividend, date(time.March, 15), "AAPL", "", "12.34"),
+		trade(model.Buy, date(time.January, 10), "BTC-USD", "0.5", "10000.00"),
+		trade(model.Buy, date(time.January, 10), "VTI", "2", "400.00"),
+		transaction(model.Income, date(time.January, 1), "5000.00"),
+		trade(model.Buy, date(time.April, 15), "AAPL", "1", "200.00"), // after now
+	}
+	prices := []*model.Price{
+		price("AAPL", date(time.March, 10), "150"),
+		price("AAPL", date(time.February, 10), "120"),
+		price("AAPL", date(time.April, 10), "999"), // after now
+		price("VTI", date(time.March, 10), "250.125"),
+	}
+
+	now := date(time.March, 20)
+	holdings, err := Holdings(account, model.Money{Amount: 100000, Exponent: 2}, transactions, prices, now)
+	if err != nil {
+		t.Fatalf("Holdings() = %v", err)
+	}
+
+	if len(holdings.Positions) != 3 {
+		t.Fatalf("expected 3 positions, got %d", len(holdings.Positions))
+	}
+	apple, bitcoin, vti := holdings.Positions[0], holdings.Positions[1], holdings.Positions[2]
+
+	// sell takes whole first lot and 2 of 5 from the second one, they cost 1000.00 + 240.00
+	if apple.Quantity.String() != "3" || apple.CostBasis.String() != "360.00" || apple.RealizedGain.String() != "260.00" {
+		t.Fatalf("unexpected AAPL position %+v", apple)
+	}
+	if len(apple.Lots) != 1 || apple.Lots[0].Quantity.String() != "3" || apple.Lots[0].Cost.String() != "360.00" {
+		t.Fatalf("expected one lot of 3 left, got %+v", apple.Lots)
+	}
+	if apple.MarketValue == nil || apple.MarketValue.String() != "450.00" || apple.UnrealizedGain.String() != "90.00" || apple.Dividends.String() != "12.34" {
+		t.Fatalf("unexpected AAPL valuation %+v", apple)
+	}
+	if apple.Allocation == nil || *apple.Allocation != 47.36 {
+		t.Fatalf("expected AAPL allocation 47.36, got %v", apple.Allocation)
+	}
+
+	if bitcoin.MarketValue != nil || bitcoin.Allocation != nil || bitcoin.CostBasis.String() != "10000.00" {
+		t.Fatalf("expected BTC-USD without price, got %+v", bitcoin)
+	}
+	if vti.MarketValue == nil || vti.MarketValue.String() != "500.25" {
+		t.Fatalf("expected VTI market value 500.25, got %+v", vti)
+	}
+
+	if holdings.MarketValue.String() != "950.25" || holdings.CostBasis.String() != "10760.00" || holdings.Value.String() != "1950.25" {
+		t.Fatalf("unexpected totals %+v", holdings)
+	}
+
+	transactions = append(transactions, trade(model.Sell, date(time.March, 2), "VTI", "2.5", "500.00"))
+	if _, err := Holdings(account, model.Money{}, transactions, prices, now); errors.Cause(err) != ErrOversold {
+		t.Fatalf("expected ErrOversold, got %v", err)
+	}
+
+	accountType = model.Checking
+	if _, err := Holdings(account, model.Money{}, nil, nil, now); err != ErrNoHoldings {
+		t.Fatalf("expected ErrNoHoldings, got %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package finance
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// ErrNoLoanTerms - only loan accounts with principal, term and start date have amortization schedule
+var ErrNoLoanTerms = errors.New("account has no loan terms")
+
+// maxLoanPrincipal keeps sums of loan amounts far from int64 overflow, it's 10^15 minor units
+const maxLoanPrincipal = 1000000000000000
+
+// maxPayoffMonths stops projection of loan which is never paid off
+const maxPayoffMonths = 1200
+
+// loan is terms of loan account with amounts in minor units of its currency
+type loan struct {
+	principal int64
+	months    int
+	start     time.Time
+	rate      int64 // yearly rate in ten-thousandths of percent, 4.5% is 45000
+	exponent  int
+}
+
+func loanOf(account *model.Account) (*loan, error) {
+	if account.Type == nil || !account.Type.HasLoanTerms() || account.LoanPrincipal == nil || account.LoanTermMonths == nil || account.LoanStartDate == nil {
+		return nil, ErrNoLoanTerms
+	}
+
+	exponent := model.CurrencyExponent(*account.Currency)
+	principal, err := account.LoanPrincipal.Rescale(exponent, model.RoundHalfEven)
+	if err != nil {
+		return nil, err
+	}
+	if principal.Amount > maxLoanPrincipal {
+		return nil, model.ErrMoneyOverflow
+	}
+
+	l := &loan{
+		principal: principal.Amount,
+		months:    *account.LoanTermMonths,
+		start:     account.LoanStartDate.UTC(),
+		exponent:  exponent,
+	}
+	if account.InterestRate != nil {
+		l.rate = int64(math.Round(*account.InterestRate * 10000))
+	}
+	return l, nil
+}
+
+func (l *loan) money(amount int64) model.Money {
+	return model.Money{Amount: amount, Exponent: l.exponent}
+}
+
+// interest of one month is balance * yearly rate / 12, rounded half up to minor unit
+func (l *loan) interest(balance int64) int64 {
+	if balance <= 0 {
+		return 0
+	}
+
+	numerator := new(big.Int).Mul(big.NewInt(balance), big.NewInt(l.rate))
+	denominator := big.NewInt(12 * 100 * 10000)
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	if remainder.Mul(remainder, big.NewInt(2)).Cmp(denominator) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	return quotient.Int64()
+}
+
+// payment is fixed monthly payment P*r / (1 - (1+r)^-n) rounded up to minor unit, so loan is paid off in its term.
+// Last payment of schedule is smaller by what rounding overpaid.
+func (l *loan) payment() int64 {
+	principal := float64(l.principal)
+	if l.rate == 0 {
+		return int64(math.Ceil(principal / float64(l.months)))
+	}
+
+	r := float64(l.rate) / (12 * 100 * 10000)
+	payment := principal * r / (1 - math.Pow(1+r, -float64(l.months)))
+	// float error must not add minor unit to payment which is whole
+	return int64(math.Ceil(payment - 1e-6))
+}
+
+// paymentDate is date of payment number, payment 0 is start of loan. Payments of loan started on 31st are
+// on last day of shorter months.
+func (l *loan) paymentDate(number int) time.Time {
+	year, month, day := l.start.Date()
+	first := time.Date(year, month+time.Month(number), 1, 0, 0, 0, 0, time.UTC)
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// AmortizationSchedule returns monthly payments which pay off loan in its term.
+// Interest is rounded half up, payment is rounded up to minor unit and the last one pays what is left.
+func AmortizationSchedule(account *model.Account) ([]*model.AmortizationEntry, error) {
+	l, err := loanOf(account)
+	if err != nil {
+		return nil, err
+	}
+
+	payment := l.payment()
+	balance := l.principal
+	schedule := make([]*model.AmortizationEntry, 0, l.months)
+	for number := 1; number <= l.months && balance > 0; number++ {
+		interest := l.interest(balance)
+		amount := payment
+		if number == l.months || amount > balance+interest {
+			amount = balance + interest
+		}
+		balance -= amount - interest
+
+		schedule = append(schedule, &model.AmortizationEntry{
+			Number:    number,
+			Date:      l.paymentDate(number),
+			Payment:   l.money(amount),
+			Principal: l.money(amount - interest),
+			Interest:  l.money(interest),
+			Balance:   l.money(balance),
+		})
+	}
+
+	return schedule, nil
+}
+
+// ReconcileLoan applies payments made until now to loan month by month. Income to loan account is payment,
+// it pays interest of its month first. Expense is money borrowed on top of principal (fee, another draw).
+// Interest which isn't paid until end of month is added to principal.
+// Remaining principal is projected with scheduled payment and each of extra payments.
+func ReconcileLoan(account *model.Account, transactions []*model.Transaction, now time.Time, extras []model.Money) (*model.LoanStatus, error) {
+	l, err := loanOf(account)
+	if err != nil {
+		return nil, err
+	}
+	now = now.UTC()
+
+	sorted := make([]*model.Transaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		if transaction.DeletedAt == nil && !transaction.Date.After(now) {
+			sorted = append(sorted, transaction)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(*sorted[j].Date) })
+
+	balance := l.principal
+	var principalPaid, interestPaid, accrued int64
+	accruing := false
+	next := 0
+	number := 1
+	for ; l.paymentDate(number - 1).Before(now); number++ {
+		end := l.paymentDate(number)
+		interest := l.interest(balance)
+
+		var paidInterest int64
+		for ; next < len(sorted) && !sorted[next].Date.After(end); next++ {
+			amount, err := sorted[next].Amount.Rescale(l.exponent, model.RoundHalfEven)
+			if err != nil {
+				return nil, err
+			}
+			if amount.Amount > maxLoanPrincipal {
+				return nil, model.ErrMoneyOverflow
+			}
+
+			if *sorted[next].Type == model.Expense {
+				balance += amount.Amount
+				continue
+			}
+
+			toInterest := interest - paidInterest
+			if toInterest > amount.Amount {
+				toInterest = amount.Amount
+			}
+			paidInterest += toInterest
+			balance -= amount.Amount - toInterest
+			principalPaid += amount.Amount - toInterest
+		}
+		interestPaid += paidInterest
+
+		if end.After(now) {
+			accrued = interest - paidInterest
+			accruing = true
+		} else {
+			balance += interest - paidInterest
+		}
+	}
+	if balance < 0 {
+		balance = 0
+	}
+
+	status := &model.LoanStatus{
+		AccountID:          account.ID,
+		RemainingPrincipal: l.money(balance),
+		ScheduledPrincipal: l.money(l.principal),
+		PrincipalPaid:      l.money(principalPaid),
+		InterestPaid:       l.money(interestPaid),
+		AccruedInterest:    l.money(accrued),
+	}
+
+	schedule, err := AmortizationSchedule(account)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range schedule {
+		if entry.Date.After(now) {
+			break
+		}
+		status.ScheduledPrincipal = entry.Balance
+	}
+
+	// payment which ends current month is the next one, interest due with it is accrued so far
+	due := accrued
+	number--
+	if !accruing {
+		number++
+		due = l.interest(balance)
+	}
+	if balance > 0 {
+		date := l.paymentDate(number)
+		status.NextPaymentDate = &date
+	}
+
+	payment := l.payment()
+	base := l.payoff(balance, due, number, payment, 0)
+	status.Scenarios = append(status.Scenarios, base)
+	for _, extra := range extras {
+		extra, err := extra.Rescale(l.exponent, model.RoundHalfEven)
+		if err != nil {
+			return nil, err
+		}
+		if extra.Amount > maxLoanPrincipal {
+			return nil, model.ErrMoneyOverflow
+		}
+
+		scenario := l.payoff(balance, due, number, payment, extra.Amount)
+		scenario.InterestSaved = l.money(base.TotalInterest.Amount - scenario.TotalInterest.Amount)
+		status.Scenarios = append(status.Scenarios, scenario)
+	}
+
+	return status, nil
+}
+
+// payoff projects monthly payments of balance starting with payment number, due is interest of the first one.
+// Payoff date is nil when loan is paid off already or payment doesn't cover interest.
+func (l *loan) payoff(balance, due int64, number int, payment, extra int64) *model.PayoffScenario {
+	scenario := &model.PayoffScenario{
+		ExtraPayment:  l.money(extra),
+		TotalInterest: l.money(0),
+		InterestSaved: l.money(0),
+	}
+
+	var total int64
+	interest := due
+	for months := 0; balance > 0; months++ {
+		if months == maxPayoffMonths || payment+extra <= interest {
+			return &model.PayoffScenario{
+				ExtraPayment:  l.money(extra),
+				TotalInterest: l.money(0),
+				InterestSaved: l.money(0),
+			}
+		}
+
+		amount := payment + extra
+		if amount > balance+interest {
+			amount = balance + interest
+		}
+		balance += interest - amount
+		total += interest
+
+		date := l.paymentDate(number + months)
+		scenario.PayoffDate = &date
+		scenario.Payments = months + 1
+		interest = l.interest(balance)
+	}
+
+	scenario.TotalInterest = l.money(total)
+	return scenario
+}
@@ -0,0 +1,116 @@
+package finance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func loanAccount(principal string, rate float64, months int, start time.Time) *model.Account {
+	accountType := model.Loan
+	currency := "USD"
+	money, err := model.ParseMoney(principal)
+	if err != nil {
+		panic(err)
+	}
+	return &model.Account{
+		Type:           &accountType,
+		Currency:       &currency,
+		InterestRate:   &rate,
+		LoanPrincipal:  &money,
+		LoanTermMonths: &months,
+		LoanStartDate:  &start,
+	}
+}
+
+func TestAmortizationSchedule(t *testing.T) {
+	schedule, err := AmortizationSchedule(loanAccount("10000", 6, 12, date(time.January, 31)))
+	if err != nil {
+		t.Fatalf("AmortizationSchedule() = %v", err)
+	}
+	if len(schedule) != 12 {
+		t.Fatalf("expected 12 payments, got %d", len(schedule))
+	}
+
+	first, last := schedule[0], schedule[11]
+	if first.Payment.String() != "860.67" || first.Interest.String() != "50.00" || first.Principal.String() != "810.67" || first.Balance.String() != "9189.33" {
+		t.Fatalf("unexpected first payment %+v", first)
+	}
+	if !first.Date.Equal(time.Date(2026, time.February, 28, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected first payment on last day of February, got %s", first.Date)
+	}
+	if last.Balance.Sign() != 0 || last.Payment.Cmp(first.Payment) > 0 {
+		t.Fatalf("expected last payment to pay off loan, got %+v", last)
+	}
+
+	var principal model.Money
+	for _, entry := range schedule {
+		principal, _ = principal.Add(entry.Principal)
+	}
+	if principal.String() != "10000.00" {
+		t.Fatalf("expected principal 10000.00 paid, got %s", principal)
+	}
+
+	// without interest principal is split evenly
+	schedule, err = AmortizationSchedule(loanAccount("1200", 0, 12, date(time.January, 15)))
+	if err != nil {
+		t.Fatalf("AmortizationSchedule() = %v", err)
+	}
+	for _, entry := range schedule {
+		if entry.Payment.String() != "100.00" || entry.Interest.Sign() != 0 {
+			t.Fatalf("expected payment 100.00 without interest, got %+v", entry)
+		}
+	}
+
+	accountType := model.Checking
+	account := loanAccount("1200", 0, 12, date(time.January, 15))
+	account.Type = &accountType
+	if _, err := AmortizationSchedule(account); err != ErrNoLoanTerms {
+		t.Fatalf("expected ErrNoLoanTerms, got %v", err)
+	}
+}
+
+func TestReconcileLoan(t *testing.T) {
+	account := loanAccount("1200", 12, 12, time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC))
+
+	deleted := transaction(model.Income, date(time.February, 1), "500")
+	deleted.DeletedAt = &time.Time{}
+	transactions := []*model.Transaction{
+		transaction(model.Income, date(time.March, 14), "50"),
+		deleted,
+		transaction(model.Income, date(time.February, 10), "110"),
+		transaction(model.Income, date(time.May, 1), "1000"), // after now
+	}
+
+	now := date(time.April, 1)
+	status, err := ReconcileLoan(account, transactions, now, []model.Money{{Amount: 10000, Exponent: 2}})
+	if err != nil {
+		t.Fatalf("ReconcileLoan() = %v", err)
+	}
+
+	// February pays 12.00 interest, March pays 11.02 interest of 1102.00
+	if status.InterestPaid.String() != "23.02" || status.PrincipalPaid.String() != "136.98" || status.RemainingPrincipal.String() != "1063.02" {
+		t.Fatalf("unexpected paid amounts %+v", status)
+	}
+	if status.AccruedInterest.String() != "10.63" {
+		t.Fatalf("expected accrued interest 10.63, got %s", status.AccruedInterest)
+	}
+	if status.ScheduledPrincipal.Cmp(status.RemainingPrincipal) >= 0 {
+		t.Fatalf("expected loan behind schedule, scheduled %s remaining %s", status.ScheduledPrincipal, status.RemainingPrincipal)
+	}
+	if status.NextPaymentDate == nil || !status.NextPaymentDate.Equal(time.Date(2026, time.April, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected next payment on April 15, got %v", status.NextPaymentDate)
+	}
+
+	if len(status.Scenarios) != 2 {
+		t.Fatalf("expected 2 scenarios, got %d", len(status.Scenarios))
+	}
+	base, extra := status.Scenarios[0], status.Scenarios[1]
+	if base.ExtraPayment.Sign() != 0 || base.PayoffDate == nil || base.InterestSaved.Sign() != 0 {
+		t.Fatalf("unexpected base scenario %+v", base)
+	}
+	if extra.Payments >= base.Payments || !extra.PayoffDate.Before(*base.PayoffDate) || extra.InterestSaved.Sign() <= 0 {
+		t.Fatalf("expected extra payment to pay off loan sooner, got %+v and %+v", base, extra)
+	}
+}
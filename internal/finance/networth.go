@@ -0,0 +1,47 @@
+package finance
+
+import (
+	"sort"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// NetWorth sums values of accounts by currency, currencies are sorted by code
+func NetWorth(values []*model.AccountValue) (*model.NetWorth, error) {
+	totals := make(map[string]*model.CurrencyNetWorth)
+	for _, value := range values {
+		total, ok := totals[value.Currency]
+		if !ok {
+			zero := model.Money{Exponent: model.CurrencyExponent(value.Currency)}
+			total = &model.CurrencyNetWorth{Currency: value.Currency, Assets: zero, Liabilities: zero, NetWorth: zero}
+			totals[value.Currency] = total
+		}
+
+		var err error
+		if value.Value.Sign() >= 0 {
+			total.Assets, err = total.Assets.Add(value.Value)
+		} else {
+			total.Liabilities, err = total.Liabilities.Add(value.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if total.NetWorth, err = total.NetWorth.Add(value.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	netWorth := &model.NetWorth{
+		Currencies: make([]*model.CurrencyNetWorth, 0, len(totals)),
+		Accounts:   values,
+	}
+	for _, total := range totals {
+		netWorth.Currencies = append(netWorth.Currencies, total)
+	}
+	sort.Slice(netWorth.Currencies, func(i, j int) bool { return netWorth.Currencies[i].Currency < netWorth.Currencies[j].Currency })
+
+	if netWorth.Accounts == nil {
+		netWorth.Accounts = make([]*model.AccountValue, 0)
+	}
+	return netWorth, nil
+}
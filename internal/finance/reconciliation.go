@@ -0,0 +1,56 @@
+package finance
+
+import (
+	"sort"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// Reconcile computes cleared balance of account at end of statement date of reconciliation and lists transactions
+// on statement which aren't cleared yet, oldest first. Cleared balance is start balance of account with cleared
+// and reconciled transactions, difference is what is still missing to match ending balance of statement.
+func Reconcile(account *model.Account, reconciliation *model.Reconciliation, transactions []*model.Transaction) (*model.ReconciliationReport, error) {
+	exponent := model.CurrencyExponent(*account.Currency)
+	end := reconciliation.PeriodEnd()
+
+	cleared, err := account.StartBalance.Rescale(exponent, model.RoundHalfEven)
+	if err != nil {
+		return nil, err
+	}
+
+	uncleared := make([]*model.Transaction, 0)
+	for _, transaction := range transactions {
+		if transaction.DeletedAt != nil || !transaction.Date.Before(end) {
+			continue
+		}
+		if transaction.ClearedStatus == nil || !transaction.ClearedStatus.IsCleared() {
+			uncleared = append(uncleared, transaction)
+			continue
+		}
+
+		if transaction.Type.IsInflow() {
+			cleared, err = cleared.Add(*transaction.Amount)
+		} else {
+			cleared, err = cleared.Sub(*transaction.Amount)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(uncleared, func(i, j int) bool { return uncleared[i].Date.Before(*uncleared[j].Date) })
+
+	if cleared, err = cleared.Rescale(exponent, model.RoundHalfEven); err != nil {
+		return nil, err
+	}
+	difference, err := reconciliation.EndingBalance.Sub(cleared)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ReconciliationReport{
+		Reconciliation: reconciliation,
+		ClearedBalance: cleared,
+		Difference:     difference,
+		Uncleared:      uncleared,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package finance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func cleared(t *model.Transaction, status model.ClearedStatus) *model.Transaction {
+	t.ClearedStatus = &status
+	return t
+}
+
+func TestReconcile(t *testing.T) {
+	currency := "USD"
+	account := &model.Account{Currency: &currency, StartBalance: &model.Money{Amount: 10000, Exponent: 2}}
+
+	statementDate := time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)
+	endingBalance := model.Money{Amount: 115000, Exponent: 2}
+	reconciliation := &model.Reconciliation{StatementDate: &statementDate, EndingBalance: &endingBalance}
+
+	transactions := []*model.Transaction{
+		cleared(transaction(model.Income, date(time.March, 1), "1000.00"), model.Reconciled),
+		cleared(transaction(model.Expense, date(time.March, 10), "25.00"), model.Cleared),
+		transaction(model.Expense, date(time.March, 31), "50.00"),                         // on statement date
+		cleared(transaction(model.Income, date(time.March, 5), "75.00"), model.Uncleared), // uncleared
+		cleared(transaction(model.Income, date(time.April, 1), "500.00"), model.Cleared),  // after statement
+		transaction(model.Expense, date(time.February, 20), "10.00"),                      // older one first
+	}
+
+	report, err := Reconcile(account, reconciliation, transactions)
+	if err != nil {
+		t.Fatalf("Reconcile() = %v", err)
+	}
+
+	if report.ClearedBalance.String() != "1075.00" || report.Difference.String() != "75.00" {
+		t.Fatalf("expected cleared balance 1075.00 and difference 75.00, got %s and %s", report.ClearedBalance, report.Difference)
+	}
+	if len(report.Uncleared) != 3 || !report.Uncleared[0].Date.Equal(date(time.February, 20)) || !report.Uncleared[2].Date.Equal(date(time.March, 31)) {
+		t.Fatalf("expected 3 uncleared transactions by date, got %+v", report.Uncleared)
+	}
+
+	// clearing income of 75.00 matches statement
+	*transactions[3].ClearedStatus = model.Cleared
+	if report, err = Reconcile(account, reconciliation, transactions); err != nil || report.Difference.Sign() != 0 {
+		t.Fatalf("expected no difference, got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,230 @@
+// Package finance computes figures which aren't stored, like statements of credit accounts
+package finance
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+// DefaultGraceDays is time to pay statement of account which has no payment due day
+const DefaultGraceDays = 21
+
+// ErrNoStatementCycle - only credit accounts with statement day have statements
+var ErrNoStatementCycle = errors.New("account has no statement cycle")
+
+// Statements splits transactions of credit account into billing cycles, from cycle of account creation (or of first
+// transaction when it's older) to cycle which contains now. Transactions dated after now are left out.
+// Amounts are rounded half even to currency of account, transactions made before currency was changed can have more digits.
+func Statements(account *model.Account, transactions []*model.Transaction, now time.Time) ([]*model.Statement, error) {
+	if account.Type == nil || !account.Type.HasCreditLimit() || account.StatementDay == nil {
+		return nil, ErrNoStatementCycle
+	}
+
+	now = now.UTC()
+	exponent := model.CurrencyExponent(*account.Currency)
+	zero := model.Money{Exponent: exponent}
+
+	// live transactions until now, oldest first
+	sorted := make([]*model.Transaction, 0, len(transactions))
+	since := now
+	if account.CreatedAt != nil && account.CreatedAt.Before(since) {
+		since = *account.CreatedAt
+	}
+	for _, transaction := range transactions {
+		if transaction.DeletedAt != nil || transaction.Date.After(now) {
+			continue
+		}
+		sorted = append(sorted, transaction)
+		if transaction.Date.Before(since) {
+			since = *transaction.Date
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(*sorted[j].Date) })
+	since = since.UTC()
+
+	// cycle closes at boundary of month, first cycle is the one which ends after since
+	day := *account.StatementDay
+	year, month := since.Year(), since.Month()
+	if !boundary(year, month, day).After(since) {
+		month++
+	}
+
+	balance, err := account.StartBalance.Rescale(exponent, model.RoundHalfEven)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []*model.Statement
+	for cycle := 0; ; cycle++ {
+		start := boundary(year, month+time.Month(cycle)-1, day)
+		end := boundary(year, month+time.Month(cycle), day)
+		if start.After(now) {
+			break
+		}
+
+		statement := &model.Statement{
+			AccountID:      account.ID,
+			PeriodStart:    start,
+			PeriodEnd:      end,
+			DueDate:        dueDate(account, end),
+			OpeningBalance: balance,
+			Charges:        zero,
+			Payments:       zero,
+			PaidAmount:     zero,
+		}
+
+		paidAfterClose := zero
+		for _, transaction := range sorted {
+			var sum *model.Money
+			switch {
+			case transaction.Date.Before(start):
+				continue
+			case transaction.Date.Before(end) && *transaction.Type == model.Expense:
+				sum = &statement.Charges
+			case transaction.Date.Before(end):
+				sum = &statement.Payments
+			case *transaction.Type == model.Income:
+				sum = &paidAfterClose
+			default:
+				continue
+			}
+
+			if *sum, err = sum.Add(*transaction.Amount); err != nil {
+				return nil, err
+			}
+		}
+
+		if statement, err = closeStatement(account, statement, paidAfterClose, now); err != nil {
+			return nil, err
+		}
+		balance = statement.ClosingBalance
+		statements = append(statements, statement)
+	}
+
+	return statements, nil
+}
+
+// closeStatement computes balance and amount due of statement with charges and payments
+func closeStatement(account *model.Account, statement *model.Statement, paidAfterClose model.Money, now time.Time) (*model.Statement, error) {
+	exponent := statement.OpeningBalance.Exponent
+	var err error
+	for _, sum := range []*model.Money{&statement.Charges, &statement.Payments, &paidAfterClose} {
+		if *sum, err = sum.Rescale(exponent, model.RoundHalfEven); err != nil {
+			return nil, err
+		}
+	}
+
+	closing, err := statement.OpeningBalance.Add(statement.Payments)
+	if err != nil {
+		return nil, err
+	}
+	if statement.ClosingBalance, err = closing.Sub(statement.Charges); err != nil {
+		return nil, err
+	}
+
+	statement.AmountDue = model.Money{Exponent: exponent}
+	if statement.ClosingBalance.Sign() < 0 {
+		statement.AmountDue = model.Money{Amount: -statement.ClosingBalance.Amount, Exponent: exponent}
+	}
+	if statement.MinimumPayment, err = minimumPayment(account, statement.AmountDue); err != nil {
+		return nil, err
+	}
+
+	statement.PaidAmount = paidAfterClose
+	if statement.PaidAmount.Cmp(statement.AmountDue) > 0 {
+		statement.PaidAmount = statement.AmountDue
+	}
+
+	switch {
+	case now.Before(statement.PeriodEnd):
+		statement.Status = model.StatementCurrent
+	case statement.PaidAmount.Cmp(statement.AmountDue) >= 0:
+		statement.Status = model.StatementPaid
+	case statement.IsOverdueAt(now):
+		statement.Status = model.StatementOverdue
+	default:
+		statement.Status = model.StatementDue
+	}
+
+	return statement, nil
+}
+
+// minimumPayment is MinPaymentPercent of amount due rounded up to minor unit, at least MinPaymentAmount
+// and at most amount due. Account without minimum payment rule has to pay whole amount due.
+func minimumPayment(account *model.Account, due model.Money) (model.Money, error) {
+	if due.Sign() == 0 || account.MinPaymentPercent == nil && account.MinPaymentAmount == nil {
+		return due, nil
+	}
+
+	minimum := model.Money{Exponent: due.Exponent}
+	if account.MinPaymentPercent != nil {
+		// percent has at most 4 decimal places (NUMERIC(7, 4)), so millionths of amount are exact
+		millionths := int64(math.Round(*account.MinPaymentPercent * 10000))
+		if millionths != 0 && due.Amount > math.MaxInt64/millionths {
+			return model.Money{}, model.ErrMoneyOverflow
+		}
+
+		var err error
+		share := model.Money{Amount: due.Amount * millionths, Exponent: due.Exponent + 6}
+		if minimum, err = share.Rescale(due.Exponent, model.RoundUp); err != nil {
+			return model.Money{}, err
+		}
+	}
+
+	if account.MinPaymentAmount != nil {
+		amount, err := account.MinPaymentAmount.Rescale(due.Exponent, model.RoundHalfEven)
+		if err != nil {
+			return model.Money{}, err
+		}
+		if minimum.Cmp(amount) < 0 {
+			minimum = amount
+		}
+	}
+
+	if minimum.Cmp(due) > 0 {
+		return due, nil
+	}
+	return minimum, nil
+}
+
+// Upcoming returns closed statements which aren't paid and are due before now+within, overdue ones too.
+// Statements are sorted by due date, so they can be used for reminders.
+func Upcoming(statements []*model.Statement, now time.Time, within time.Duration) []*model.Statement {
+	upcoming := make([]*model.Statement, 0)
+	for _, statement := range statements {
+		if statement.Status != model.StatementDue && statement.Status != model.StatementOverdue {
+			continue
+		}
+		if statement.DueDate.After(now.Add(within)) {
+			continue
+		}
+		upcoming = append(upcoming, statement)
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool { return upcoming[i].DueDate.Before(upcoming[j].DueDate) })
+	return upcoming
+}
+
+// boundary is start of day after statement day in month, month out of range goes to other year
+func boundary(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+}
+
+// dueDate is first payment due day after statement day, cycle ends at start of day after statement day
+func dueDate(account *model.Account, end time.Time) time.Time {
+	closeDay := end.AddDate(0, 0, -1)
+	if account.PaymentDueDay == nil {
+		return closeDay.AddDate(0, 0, DefaultGraceDays)
+	}
+
+	due := time.Date(closeDay.Year(), closeDay.Month(), *account.PaymentDueDay, 0, 0, 0, 0, time.UTC)
+	if !due.After(closeDay) {
+		due = time.Date(closeDay.Year(), closeDay.Month()+1, *account.PaymentDueDay, 0, 0, 0, 0, time.UTC)
+	}
+	return due
+}
@@ -0,0 +1,95 @@
+package finance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2026, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+func transaction(transactionType model.TransactionType, on time.Time, amount string) *model.Transaction {
+	money, err := model.ParseMoney(amount)
+	if err != nil {
+		panic(err)
+	}
+	return &model.Transaction{Date: &on, Type: &transactionType, Amount: &money}
+}
+
+func TestStatements(t *testing.T) {
+	accountType := model.Credit
+	currency := "USD"
+	statementDay, dueDay := 15, 10
+	percent := 2.0
+	createdAt := date(time.January, 1)
+	account := &model.Account{
+		Type:              &accountType,
+		Currency:          &currency,
+		StartBalance:      &model.Money{Exponent: 2},
+		CreatedAt:         &createdAt,
+		StatementDay:      &statementDay,
+		PaymentDueDay:     &dueDay,
+		MinPaymentPercent: &percent,
+		MinPaymentAmount:  &model.Money{Amount: 2500, Exponent: 2},
+	}
+
+	deleted := transaction(model.Expense, date(time.January, 6), "500")
+	deleted.DeletedAt = &createdAt
+	transactions := []*model.Transaction{
+		transaction(model.Expense, date(time.February, 20), "10.01"),
+		transaction(model.Expense, date(time.January, 5), "100"),
+		deleted,
+		transaction(model.Expense, date(time.January, 20), "2000"),
+		transaction(model.Income, date(time.February, 3), "100"),
+		transaction(model.Expense, date(time.April, 1), "99"), // after now
+	}
+
+	now := date(time.March, 20)
+	statements, err := Statements(account, transactions, now)
+	if err != nil {
+		t.Fatalf("Statements() = %v", err)
+	}
+
+	want := []struct {
+		end     time.Time
+		due     time.Time
+		closing string
+		minimum string
+		paid    string
+		status  model.StatementStatus
+	}{
+		// first payment covers first statement, minimum of small statement is MinPaymentAmount
+		{time.Date(2026, time.January, 16, 0, 0, 0, 0, time.UTC), time.Date(2026, time.February, 10, 0, 0, 0, 0, time.UTC), "-100.00", "25.00", "100.00", model.StatementPaid},
+		{time.Date(2026, time.February, 16, 0, 0, 0, 0, time.UTC), time.Date(2026, time.March, 10, 0, 0, 0, 0, time.UTC), "-2000.00", "40.00", "0.00", model.StatementOverdue},
+		// 2% of 2010.01 is 40.2002, minimum is rounded up
+		{time.Date(2026, time.March, 16, 0, 0, 0, 0, time.UTC), time.Date(2026, time.April, 10, 0, 0, 0, 0, time.UTC), "-2010.01", "40.21", "0.00", model.StatementDue},
+		{time.Date(2026, time.April, 16, 0, 0, 0, 0, time.UTC), time.Date(2026, time.May, 10, 0, 0, 0, 0, time.UTC), "-2010.01", "40.21", "0.00", model.StatementCurrent},
+	}
+	if len(statements) != len(want) {
+		t.Fatalf("got %d statements, want %d", len(statements), len(want))
+	}
+	for i, w := range want {
+		s := statements[i]
+		if !s.PeriodEnd.Equal(w.end) || !s.DueDate.Equal(w.due) || s.ClosingBalance.String() != w.closing ||
+			s.MinimumPayment.String() != w.minimum || s.PaidAmount.String() != w.paid || s.Status != w.status {
+			t.Errorf("statement %d = %+v; want %+v", i, s, w)
+		}
+	}
+
+	upcoming := Upcoming(statements, now, 30*24*time.Hour)
+	if len(upcoming) != 2 || upcoming[0] != statements[1] || upcoming[1] != statements[2] {
+		t.Fatalf("Upcoming() in 30 days = %+v; want overdue and due statement", upcoming)
+	}
+	if upcoming := Upcoming(statements, now, 7*24*time.Hour); len(upcoming) != 1 {
+		t.Fatalf("Upcoming() in 7 days = %+v; want only overdue statement", upcoming)
+	}
+
+	cash := model.Cash
+	account.Type = &cash
+	if _, err := Statements(account, transactions, now); err != ErrNoStatementCycle {
+		t.Fatalf("Statements() of cash account = %v; want ErrNoStatementCycle", err)
+	}
+}
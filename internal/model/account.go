@@ -1,7 +1,7 @@
 package model
 
 import (
-	"errors"
+	"math"
 	"time"
 )
 
@@ -15,42 +15,277 @@ var NilAccountID AccountID
 type AccountType string
 
 const (
-	Cash   AccountType = "cash"
-	Credit AccountType = "credit"
+	Cash       AccountType = "cash"
+	Credit     AccountType = "credit"
+	Checking   AccountType = "checking"
+	Savings    AccountType = "savings"
+	Investment AccountType = "investment"
+	Loan       AccountType = "loan"
+	Wallet     AccountType = "wallet"
 )
 
+func (t AccountType) IsValid() bool {
+	switch t {
+	case Cash, Credit, Checking, Savings, Investment, Loan, Wallet:
+		return true
+	}
+	return false
+}
+
+// HasCreditLimit - credit limit and statement rules are only for credit cards
+func (t AccountType) HasCreditLimit() bool {
+	return t == Credit
+}
+
+// HasInterestRate - savings account earns interest, loan is charged interest
+func (t AccountType) HasInterestRate() bool {
+	return t == Savings || t == Loan
+}
+
+// HasLoanTerms - principal, term and start date are only for loans
+func (t AccountType) HasLoanTerms() bool {
+	return t == Loan
+}
+
+// HasHoldings - only investment account holds positions bought and sold by investment transactions
+func (t AccountType) HasHoldings() bool {
+	return t == Investment
+}
+
+// MaxLoanTermMonths is the longest loan, 50 years
+const MaxLoanTermMonths = 600
+
+// MaxStatementDay is the last day of month statement can be made on, every month has it
+const MaxStatementDay = 28
+
 // Account is structure for account
 type Account struct {
 	ID           AccountID    `json:"id,omitempty" db:"account_id"`
 	UserID       *UserID      `json:"userID,omitempty" db:"user_id"`
 	Name         *string      `json:"name,omitempty" db:"account_name"`
 	Type         *AccountType `json:"type,omitempty" db:"account_type"`
-	StartBalance *int64       `json:"startBalance,omitempty" db:"start_balance"`
+	StartBalance *Money       `json:"startBalance,omitempty" db:"start_balance"`
 	Currency     *string      `json:"currency,omitempty" db:"currency"`
 	CreatedAt    *time.Time   `json:"-" db:"created_at"`
-	DeletedAt    *time.Time   `json:"-" db:"deleted_at"`
+	DeletedAt    *time.Time   `json:"deletedAt,omitempty" db:"deleted_at"`
+
+	// Institution and MaskedNumber can be set for all types, other details only for some types
+	Institution  *string  `json:"institution,omitempty" db:"institution"`
+	MaskedNumber *string  `json:"maskedNumber,omitempty" db:"masked_number"` // only last 4 digits are kept
+	CreditLimit  *Money   `json:"creditLimit,omitempty" db:"credit_limit"`
+	StatementDay *int     `json:"statementDay,omitempty" db:"statement_day"`
+	InterestRate *float64 `json:"interestRate,omitempty" db:"interest_rate"` // yearly rate in percent, 4.5 is 4.5%
+
+	// Statement rules of credit account, statement closes on StatementDay and is due on PaymentDueDay after it.
+	// Minimum payment is MinPaymentPercent of amount due, but at least MinPaymentAmount.
+	PaymentDueDay     *int     `json:"paymentDueDay,omitempty" db:"payment_due_day"`
+	MinPaymentPercent *float64 `json:"minPaymentPercent,omitempty" db:"min_payment_percent"`
+	MinPaymentAmount  *Money   `json:"minPaymentAmount,omitempty" db:"min_payment_amount"`
+
+	// Terms of loan, it's paid in LoanTermMonths monthly payments starting month after LoanStartDate.
+	// Rate of loan is in InterestRate, StartBalance isn't used by amortization schedule.
+	LoanPrincipal  *Money     `json:"loanPrincipal,omitempty" db:"loan_principal"`
+	LoanTermMonths *int       `json:"loanTermMonths,omitempty" db:"loan_term_months"`
+	LoanStartDate  *time.Time `json:"loanStartDate,omitempty" db:"loan_start_date"`
+
+	// Credit is computed for credit accounts with limit, it isn't stored
+	Credit *CreditStatus `json:"credit,omitempty" db:"-"`
+
+	// Access is set only for accounts shared with user, user is owner of other accounts
+	Access *AccountAccess `json:"access,omitempty" db:"access"`
 }
 
+// Verify checks fields of account and masks its number, start balance can be negative (debt on credit card)
 func (a *Account) Verify() error {
+	verr := &ValidationError{}
+
 	if a.UserID == nil || len(*a.UserID) == 0 {
-		return errors.New("userID is required")
+		verr.Add("userID", ErrCodeRequired, "userID is required")
 	}
 
 	if a.Name == nil || len(*a.Name) == 0 {
-		return errors.New("name is required")
+		verr.Add("name", ErrCodeRequired, "name is required")
 	}
 
 	if a.Type == nil || len(*a.Type) == 0 {
-		return errors.New("type is required")
+		verr.Add("type", ErrCodeRequired, "type is required")
+	} else if !a.Type.IsValid() {
+		verr.Add("type", ErrCodeInvalid, "type must be cash or credit")
 	}
 
 	if a.StartBalance == nil {
-		return errors.New("startBalance is required")
+		verr.Add("startBalance", ErrCodeRequired, "startBalance is required")
 	}
 
 	if a.Currency == nil || len(*a.Currency) == 0 {
-		return errors.New("currency is required")
+		verr.Add("currency", ErrCodeRequired, "currency is required")
+	} else if !IsCurrencyCode(*a.Currency) {
+		verr.Add("currency", ErrCodeInvalid, "currency must be ISO 4217 code like USD")
+	}
+
+	if a.MaskedNumber != nil {
+		masked, ok := MaskAccountNumber(*a.MaskedNumber)
+		if !ok {
+			verr.Add("maskedNumber", ErrCodeInvalid, "maskedNumber must have at least 4 digits")
+		} else {
+			a.MaskedNumber = &masked
+		}
+	}
+
+	hasCreditLimit := a.Type != nil && a.Type.HasCreditLimit()
+	if a.CreditLimit != nil {
+		if !hasCreditLimit {
+			verr.Add("creditLimit", ErrCodeInvalid, "creditLimit is only for credit accounts")
+		} else if a.CreditLimit.Sign() <= 0 {
+			verr.Add("creditLimit", ErrCodeInvalid, "creditLimit must be positive")
+		}
+	}
+
+	if a.StatementDay != nil {
+		if !hasCreditLimit {
+			verr.Add("statementDay", ErrCodeInvalid, "statementDay is only for credit accounts")
+		} else if *a.StatementDay < 1 || *a.StatementDay > MaxStatementDay {
+			verr.Add("statementDay", ErrCodeInvalid, "statementDay must be from 1 to 28")
+		}
+	}
+
+	if a.PaymentDueDay != nil {
+		if !hasCreditLimit {
+			verr.Add("paymentDueDay", ErrCodeInvalid, "paymentDueDay is only for credit accounts")
+		} else if *a.PaymentDueDay < 1 || *a.PaymentDueDay > MaxStatementDay {
+			verr.Add("paymentDueDay", ErrCodeInvalid, "paymentDueDay must be from 1 to 28")
+		}
+	}
+
+	if a.MinPaymentPercent != nil {
+		if !hasCreditLimit {
+			verr.Add("minPaymentPercent", ErrCodeInvalid, "minPaymentPercent is only for credit accounts")
+		} else if *a.MinPaymentPercent <= 0 || *a.MinPaymentPercent > 100 {
+			verr.Add("minPaymentPercent", ErrCodeInvalid, "minPaymentPercent must be above 0 and at most 100 percent")
+		}
+	}
+
+	if a.MinPaymentAmount != nil {
+		if !hasCreditLimit {
+			verr.Add("minPaymentAmount", ErrCodeInvalid, "minPaymentAmount is only for credit accounts")
+		} else if a.MinPaymentAmount.Sign() <= 0 {
+			verr.Add("minPaymentAmount", ErrCodeInvalid, "minPaymentAmount must be positive")
+		}
 	}
 
+	hasLoanTerms := a.Type != nil && a.Type.HasLoanTerms()
+	if a.LoanPrincipal != nil {
+		if !hasLoanTerms {
+			verr.Add("loanPrincipal", ErrCodeInvalid, "loanPrincipal is only for loan accounts")
+		} else if a.LoanPrincipal.Sign() <= 0 {
+			verr.Add("loanPrincipal", ErrCodeInvalid, "loanPrincipal must be positive")
+		}
+	}
+
+	if a.LoanTermMonths != nil {
+		if !hasLoanTerms {
+			verr.Add("loanTermMonths", ErrCodeInvalid, "loanTermMonths is only for loan accounts")
+		} else if *a.LoanTermMonths < 1 || *a.LoanTermMonths > MaxLoanTermMonths {
+			verr.Add("loanTermMonths", ErrCodeInvalid, "loanTermMonths must be from 1 to 600")
+		}
+	}
+
+	if a.LoanStartDate != nil && !hasLoanTerms {
+		verr.Add("loanStartDate", ErrCodeInvalid, "loanStartDate is only for loan accounts")
+	}
+
+	if a.InterestRate != nil {
+		if a.Type == nil || !a.Type.HasInterestRate() {
+			verr.Add("interestRate", ErrCodeInvalid, "interestRate is only for savings and loan accounts")
+		} else if *a.InterestRate < 0 || *a.InterestRate > 100 {
+			verr.Add("interestRate", ErrCodeInvalid, "interestRate must be from 0 to 100 percent")
+		}
+	}
+
+	return verr.Err()
+}
+
+// ClearTypeDetails removes details which type of account doesn't have, it's called when type is changed
+func (a *Account) ClearTypeDetails() {
+	if a.Type == nil || !a.Type.HasCreditLimit() {
+		a.CreditLimit = nil
+		a.StatementDay = nil
+		a.PaymentDueDay = nil
+		a.MinPaymentPercent = nil
+		a.MinPaymentAmount = nil
+	}
+	if a.Type == nil || !a.Type.HasInterestRate() {
+		a.InterestRate = nil
+	}
+	if a.Type == nil || !a.Type.HasLoanTerms() {
+		a.LoanPrincipal = nil
+		a.LoanTermMonths = nil
+		a.LoanStartDate = nil
+	}
+}
+
+// MaskAccountNumber keeps last 4 digits of card or account number, so full number is never stored.
+// Spaces and dashes are ignored, number which is already masked (****1234) is accepted too.
+func MaskAccountNumber(number string) (string, bool) {
+	digits := make([]rune, 0, len(number))
+	for _, c := range number {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case c == ' ' || c == '-' || c == '*':
+		default:
+			return "", false
+		}
+	}
+	if len(digits) < 4 {
+		return "", false
+	}
+	return "****" + string(digits[len(digits)-4:]), true
+}
+
+// CreditStatus of credit account, negative balance is money owed
+type CreditStatus struct {
+	Balance         Money   `json:"balance"`
+	AvailableCredit Money   `json:"availableCredit"`
+	Utilization     float64 `json:"utilization"` // percent of credit limit used, rounded to 2 decimal places
+}
+
+// SetCreditStatus computes credit status from balance (start balance with all transactions).
+// Balance is rounded half even to currency, transactions made before currency was changed can have more digits.
+// Available credit is never below zero, overpayment makes it higher than limit.
+func (a *Account) SetCreditStatus(balance Money) error {
+	if a.Type == nil || !a.Type.HasCreditLimit() || a.CreditLimit == nil || a.Currency == nil {
+		a.Credit = nil
+		return nil
+	}
+
+	exponent := CurrencyExponent(*a.Currency)
+	balance, err := balance.Rescale(exponent, RoundHalfEven)
+	if err != nil {
+		return err
+	}
+	limit, err := a.CreditLimit.Rescale(exponent, RoundHalfEven)
+	if err != nil {
+		return err
+	}
+
+	available, err := limit.Add(balance)
+	if err != nil {
+		return err
+	}
+	if available.Sign() < 0 {
+		available = Money{Exponent: exponent}
+	}
+
+	utilization := 0.0
+	if balance.Sign() < 0 {
+		utilization = math.Round(-float64(balance.Amount)/float64(limit.Amount)*100*100) / 100
+	}
+
+	a.Credit = &CreditStatus{
+		Balance:         balance,
+		AvailableCredit: available,
+		Utilization:     utilization,
+	}
 	return nil
 }
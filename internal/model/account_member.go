@@ -0,0 +1,72 @@
+package model
+
+import (
+	"time"
+)
+
+// AccountAccess is what member of shared account can do
+type AccountAccess string
+
+const (
+	// AccessViewer can see account and its transactions
+	AccessViewer AccountAccess = "viewer"
+	// AccessEditor can also add, change and delete transactions of account
+	AccessEditor AccountAccess = "editor"
+)
+
+// IsValid checks if access is one of known levels
+func (a AccountAccess) IsValid() bool {
+	return a == AccessViewer || a == AccessEditor
+}
+
+// Allows checks if access is enough for required level (editor can do everything viewer can)
+func (a AccountAccess) Allows(required AccountAccess) bool {
+	if required == AccessViewer {
+		return a.IsValid()
+	}
+	return a == required
+}
+
+// MemberStatus - invitation is pending until invited user accepts it
+type MemberStatus string
+
+const (
+	MemberPending  MemberStatus = "pending"
+	MemberAccepted MemberStatus = "accepted"
+)
+
+// AccountMember is user who has access to account of other user
+type AccountMember struct {
+	AccountID  AccountID     `json:"accountID" db:"account_id"`
+	UserID     UserID        `json:"userID" db:"user_id"`
+	Email      *string       `json:"email,omitempty" db:"email"`
+	Access     AccountAccess `json:"access" db:"access"`
+	Status     MemberStatus  `json:"status" db:"status"`
+	InvitedBy  UserID        `json:"invitedBy" db:"invited_by"`
+	CreatedAt  *time.Time    `json:"createdAt,omitempty" db:"created_at"`
+	AcceptedAt *time.Time    `json:"acceptedAt,omitempty" db:"accepted_at"`
+}
+
+// AccountInvitation is request of account owner to share account with other user
+type AccountInvitation struct {
+	Email  string        `json:"email"`
+	Access AccountAccess `json:"access"`
+}
+
+func (i *AccountInvitation) Verify() error {
+	verr := &ValidationError{}
+
+	if len(i.Email) == 0 {
+		verr.Add("email", ErrCodeRequired, "email is required")
+	} else if !IsEmail(i.Email) {
+		verr.Add("email", ErrCodeInvalid, "email is not valid")
+	}
+
+	if len(i.Access) == 0 {
+		verr.Add("access", ErrCodeRequired, "access is required")
+	} else if !i.Access.IsValid() {
+		verr.Add("access", ErrCodeInvalid, "access must be viewer or editor")
+	}
+
+	return verr.Err()
+}
@@ -0,0 +1,172 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKeyID is identifier of API key
+type APIKeyID string
+
+// NilAPIKeyID is empty APIKeyID
+var NilAPIKeyID APIKeyID
+
+// APIKeyPrefix - every API key starts with it, so we know it isn't JWT
+const APIKeyPrefix = "fak_"
+
+// length of random part of key and of its start we show to user to recognize key
+const (
+	apiKeySecretBytes  = 30
+	apiKeyDisplayChars = 8
+)
+
+// Scope allows API key to read or write one resource ("accounts:read", "transactions:write"),
+// "*" means all resources. Write scope allows reading too.
+type Scope string
+
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	// ScopeAllResources is resource part of scope for all resources
+	ScopeAllResources = "*"
+)
+
+// ScopeResources are resources API keys can access
+var ScopeResources = []string{"users", "accounts", "categories", "merchants", "transactions", ScopeAllResources}
+
+func (s Scope) split() (string, string) {
+	parts := strings.SplitN(string(s), ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// IsValid checks format of scope and that resource is known
+func (s Scope) IsValid() bool {
+	resource, access := s.split()
+	if access != ScopeRead && access != ScopeWrite {
+		return false
+	}
+
+	for _, known := range ScopeResources {
+		if resource == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows checks if scope allows access to resource
+func (s Scope) Allows(resource string, write bool) bool {
+	scopeResource, access := s.split()
+	if scopeResource != resource && scopeResource != ScopeAllResources {
+		return false
+	}
+	return access == ScopeWrite || (access == ScopeRead && !write)
+}
+
+// Scopes is list of scopes stored in TEXT[] column
+type Scopes []Scope
+
+func (s Scopes) Value() (driver.Value, error) {
+	values := make(pq.StringArray, 0, len(s))
+	for _, scope := range s {
+		values = append(values, string(scope))
+	}
+	return values.Value()
+}
+
+func (s *Scopes) Scan(src interface{}) error {
+	var values pq.StringArray
+	if err := values.Scan(src); err != nil {
+		return err
+	}
+
+	*s = make(Scopes, 0, len(values))
+	for _, value := range values {
+		*s = append(*s, Scope(value))
+	}
+	return nil
+}
+
+// Allows checks if one of scopes allows access to resource
+func (s Scopes) Allows(resource string, write bool) bool {
+	for _, scope := range s {
+		if scope.Allows(resource, write) {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey is long lived credential user creates for scripts, we store only hash of key
+type APIKey struct {
+	ID         APIKeyID   `json:"id" db:"api_key_id"`
+	UserID     UserID     `json:"userID" db:"user_id"`
+	Name       *string    `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"` // start of key, so user can recognize it
+	KeyHash    []byte     `json:"-" db:"key_hash"`
+	Scopes     Scopes     `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" db:"last_used_at"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty" db:"created_at"`
+}
+
+func (k *APIKey) Verify() error {
+	verr := &ValidationError{}
+
+	if k.Name == nil || len(*k.Name) == 0 {
+		verr.Add("name", ErrCodeRequired, "name is required")
+	}
+
+	if len(k.Scopes) == 0 {
+		verr.Add("scopes", ErrCodeRequired, "at least one scope is required")
+	}
+	for _, scope := range k.Scopes {
+		if !scope.IsValid() {
+			verr.Add("scopes", ErrCodeInvalid, "unknown scope "+string(scope))
+		}
+	}
+
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now()) {
+		verr.Add("expiresAt", ErrCodeInvalid, "expiresAt must be in future")
+	}
+
+	return verr.Err()
+}
+
+// IsExpired checks if key can't be used anymore
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(now)
+}
+
+// GenerateAPIKey creates new random key, sets its prefix and hash. Key is returned to user only once.
+func (k *APIKey) GenerateAPIKey() (string, error) {
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	key := APIKeyPrefix + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret))
+	k.Prefix = key[:len(APIKeyPrefix)+apiKeyDisplayChars]
+	k.KeyHash = HashAPIKey(key)
+	return key, nil
+}
+
+// HashAPIKey - key is random and long so sha256 is enough (no need for bcrypt)
+func HashAPIKey(key string) []byte {
+	hash := sha256.Sum256([]byte(key))
+	return hash[:]
+}
+
+// IsAPIKey checks if token is API key and not JWT
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestScope(t *testing.T) {
+	for _, test := range []struct {
+		scope    Scope
+		valid    bool
+		resource string
+		write    bool
+		allows   bool
+	}{
+		{"accounts:read", true, "accounts", false, true},
+		{"accounts:read", true, "accounts", true, false},
+		{"accounts:write", true, "accounts", true, true},
+		{"accounts:write", true, "transactions", false, false},
+		{"*:read", true, "transactions", false, true},
+		{"*:read", true, "transactions", true, false},
+		{"*:write", true, "users", true, true},
+		{"accounts:delete", false, "accounts", false, false},
+		{"keys:read", false, "keys", false, true},
+		{"accounts", false, "accounts", false, false},
+	} {
+		if got := test.scope.IsValid(); got != test.valid {
+			t.Errorf("Scope(%q).IsValid() = %v; want %v", test.scope, got, test.valid)
+		}
+		if got := test.scope.Allows(test.resource, test.write); got != test.allows {
+			t.Errorf("Scope(%q).Allows(%q, %v) = %v; want %v", test.scope, test.resource, test.write, got, test.allows)
+		}
+	}
+}
+
+func TestAPIKeyVerify(t *testing.T) {
+	name := "script"
+	past := time.Now().Add(-time.Hour)
+
+	for _, test := range []struct {
+		key APIKey
+		ok  bool
+	}{
+		{APIKey{Name: &name, Scopes: Scopes{"accounts:read"}}, true},
+		{APIKey{Scopes: Scopes{"accounts:read"}}, false},
+		{APIKey{Name: &name}, false},
+		{APIKey{Name: &name, Scopes: Scopes{"api-keys:write"}}, false},
+		{APIKey{Name: &name, Scopes: Scopes{"accounts:read"}, ExpiresAt: &past}, false},
+	} {
+		if err := test.key.Verify(); (err == nil) != test.ok {
+			t.Errorf("Verify(%v) = %v; want ok %v", test.key.Scopes, err, test.ok)
+		}
+	}
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	k := &APIKey{}
+	key, err := k.GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() = %v", err)
+	}
+
+	if !IsAPIKey(key) || IsAPIKey("eyJhbGciOiJIUzI1NiJ9.e30.sig") {
+		t.Fatalf("IsAPIKey() doesn't recognize key %q", key)
+	}
+	if len(k.Prefix) <= len(APIKeyPrefix) || key[:len(k.Prefix)] != k.Prefix {
+		t.Fatalf("GenerateAPIKey() prefix = %q of key %q", k.Prefix, key)
+	}
+	if !bytes.Equal(k.KeyHash, HashAPIKey(key)) {
+		t.Fatalf("GenerateAPIKey() hash doesn't match key")
+	}
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AuditID is identifier of audit entry
+type AuditID string
+
+// AuditAction is what happened
+type AuditAction string
+
+const (
+	// AuditLoginLocked - too many failed logins, account or IP was locked
+	AuditLoginLocked AuditAction = "login.locked"
+	// AuditLoginUnlocked - admin removed lock of account
+	AuditLoginUnlocked AuditAction = "login.unlocked"
+	// AuditUserImpersonated - admin got impersonation token of user
+	AuditUserImpersonated AuditAction = "user.impersonated"
+)
+
+// AuditEntity is type of changed entity
+type AuditEntity string
+
+const (
+	AuditEntityUser          AuditEntity = "user"
+	AuditEntityUserRole      AuditEntity = "user_role"
+	AuditEntityRole          AuditEntity = "role"
+	AuditEntityAccount       AuditEntity = "account"
+	AuditEntityAccountMember AuditEntity = "account_member"
+	AuditEntityCategory      AuditEntity = "category"
+	AuditEntityMerchant      AuditEntity = "merchant"
+	AuditEntityTransaction   AuditEntity = "transaction"
+	AuditEntityAPIKey        AuditEntity = "api_key"
+
+	AuditEntityReconciliation AuditEntity = "reconciliation"
+)
+
+// Verbs of entity changes, action is "{entity}.{verb}", for example "account.updated"
+const (
+	AuditCreated  = "created"
+	AuditUpdated  = "updated"
+	AuditDeleted  = "deleted"
+	AuditGranted  = "granted"
+	AuditRevoked  = "revoked"
+	AuditRestored = "restored"
+	AuditPurged   = "purged"
+)
+
+// Action returns action of change of entity
+func (e AuditEntity) Action(verb string) AuditAction {
+	return AuditAction(string(e) + "." + verb)
+}
+
+// AuditEntry is one record in audit log, audit log is append only
+type AuditEntry struct {
+	ID        AuditID     `json:"id" db:"audit_id"`
+	CreatedAt *time.Time  `json:"createdAt,omitempty" db:"created_at"`
+	Action    AuditAction `json:"action" db:"action"`
+	UserID    *UserID     `json:"userID,omitempty" db:"user_id"`   // user whose data or account it is
+	ActorID   *UserID     `json:"actorID,omitempty" db:"actor_id"` // user who did it (empty for system and anonymous)
+	IP        *string     `json:"ip,omitempty" db:"ip"`
+	DeviceID  *DeviceID   `json:"deviceID,omitempty" db:"device_id"`
+
+	// admin who impersonated actor, empty when actor acted himself
+	ImpersonatorID *UserID `json:"impersonatorID,omitempty" db:"impersonator_id"`
+	Details        JSON    `json:"details,omitempty" db:"details"`
+
+	// changed entity, empty for events like login.locked
+	EntityType *AuditEntity `json:"entityType,omitempty" db:"entity_type"`
+	EntityID   *string      `json:"entityID,omitempty" db:"entity_id"`
+	Before     JSON         `json:"before,omitempty" db:"before"` // entity before change, empty for created entities
+	After      JSON         `json:"after,omitempty" db:"after"`   // entity after change, empty for deleted entities
+	Diff       JSON         `json:"diff,omitempty" db:"diff"`     // changed fields: {"name": {"before": "a", "after": "b"}}
+}
+
+// AuditFilter is used to search in audit log, empty fields are not used
+type AuditFilter struct {
+	UserID  UserID
+	ActorID UserID
+	// ImpersonatorID finds everything admin did while impersonating users
+	ImpersonatorID UserID
+	EntityType     AuditEntity
+	EntityID       string
+	Action         AuditAction
+	From           time.Time
+	To             time.Time
+	Limit          int
+	Offset         int
+}
+
+// JSON is raw JSON value stored in JSONB column
+type JSON []byte
+
+// Value - we have to send JSON as string, []byte is sent as bytea
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+func (j *JSON) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], value...)
+	case string:
+		*j = JSON(value)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON", src)
+	}
+	return nil
+}
+
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	if j == nil {
+		return errors.New("JSON: UnmarshalJSON on nil pointer")
+	}
+	*j = append((*j)[:0], data...)
+	return nil
+}
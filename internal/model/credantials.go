@@ -11,18 +11,44 @@ type Credantials struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 
-	// In future we will have google and facebook login as well
+	// Google and other providers login through OpenID Connect (see /login/{provider})
 }
 
 // Principal is an authenticated entity
 type Principal struct {
 	UserID UserID `json:"userID,omitempty"`
+
+	// DeviceID of session which token was issued for, audit log records it with every change
+	DeviceID DeviceID `json:"deviceID,omitempty"`
+
+	// Admin who impersonates user, UserID is impersonated user. Empty for normal login.
+	ImpersonatorID UserID `json:"impersonatorID,omitempty"`
+
+	// API key login, scopes limit what key can do. Empty for JWT login, it can do everything user can.
+	APIKeyID APIKeyID `json:"apiKeyID,omitempty"`
+	Scopes   Scopes   `json:"scopes,omitempty"`
+}
+
+// IsAPIKey - principal was authenticated by API key
+func (p Principal) IsAPIKey() bool {
+	return p.APIKeyID != NilAPIKeyID
+}
+
+// IsImpersonated - principal was authenticated by impersonation token of admin
+func (p Principal) IsImpersonated() bool {
+	return p.ImpersonatorID != NilUserID
 }
 
 // NilPrincipal is an uninitialized Principal
 var NilPrincipal Principal
 
 func (p Principal) String() string {
+	if p.UserID != "" && p.IsImpersonated() {
+		return fmt.Sprintf("UserID[%s] ImpersonatorID[%s]", p.UserID, p.ImpersonatorID)
+	}
+	if p.UserID != "" && p.IsAPIKey() {
+		return fmt.Sprintf("UserID[%s] APIKeyID[%s]", p.UserID, p.APIKeyID)
+	}
 	if p.UserID != "" {
 		return fmt.Sprintf("UserID[%s]", p.UserID)
 	}
@@ -0,0 +1,55 @@
+package model
+
+// DeleteStrategy is what happens with transactions and child categories of deleted account or category
+type DeleteStrategy string
+
+const (
+	// DeleteRefuse - delete fails when account or category has transactions or child categories
+	DeleteRefuse DeleteStrategy = "refuse"
+	// DeleteCascade - transactions and child categories are deleted too
+	DeleteCascade DeleteStrategy = "cascade"
+	// DeleteReassign - transactions and child categories are moved to target
+	DeleteReassign DeleteStrategy = "reassign"
+)
+
+func (s DeleteStrategy) IsValid() bool {
+	switch s {
+	case DeleteRefuse, DeleteCascade, DeleteReassign:
+		return true
+	}
+	return false
+}
+
+// DeleteOptions of account or category delete
+type DeleteOptions struct {
+	Strategy DeleteStrategy
+	// Target is account or category which gets transactions and child categories, only for reassign
+	Target string
+}
+
+// Verify checks options from query parameters, empty strategy is refuse
+func (o *DeleteOptions) Verify() error {
+	verr := &ValidationError{}
+
+	if o.Strategy == "" {
+		o.Strategy = DeleteRefuse
+	}
+
+	if !o.Strategy.IsValid() {
+		verr.Add("strategy", ErrCodeInvalid, "strategy must be refuse, cascade or reassign")
+	}
+
+	if o.Strategy == DeleteReassign && o.Target == "" {
+		verr.Add("target", ErrCodeRequired, "target is required for reassign")
+	}
+
+	return verr.Err()
+}
+
+// DeleteResult counts rows affected by delete of account or category.
+// When delete is refused it counts rows which stopped it.
+type DeleteResult struct {
+	Deleted      bool  `json:"deleted"`
+	Transactions int64 `json:"transactions"` // deleted or moved transactions
+	Categories   int64 `json:"categories"`   // deleted or moved child categories
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// UserIdentity links account on external login provider (google, any OpenID Connect provider) with User
+type UserIdentity struct {
+	Provider  string     `json:"provider" db:"provider"`
+	Subject   string     `json:"subject" db:"subject"` // user id on provider side
+	UserID    UserID     `json:"userID" db:"user_id"`
+	Email     *string    `json:"email,omitempty" db:"email"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" db:"created_at"`
+}
+
+// Verify all required fields before create
+func (i *UserIdentity) Verify() error {
+	if len(i.Provider) == 0 {
+		return errors.New("provider is required")
+	}
+
+	if len(i.Subject) == 0 {
+		return errors.New("subject is required")
+	}
+
+	if len(i.UserID) == 0 {
+		return errors.New("userID is required")
+	}
+
+	return nil
+}
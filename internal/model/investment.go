@@ -0,0 +1,88 @@
+package model
+
+import (
+	"time"
+)
+
+// Price is price of one unit of symbol on date, it's entered by user or imported from CSV.
+// Price has no currency, it's in currency of account which holds symbol.
+type Price struct {
+	UserID    *UserID    `json:"userID" db:"user_id"`
+	Symbol    *string    `json:"symbol" db:"symbol"`
+	Date      *time.Time `json:"date" db:"date"`
+	Price     *Money     `json:"price" db:"price"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" db:"created_at"`
+}
+
+// Verify checks fields of price, symbol is normalized and date is truncated to day
+func (p *Price) Verify() error {
+	verr := &ValidationError{}
+
+	if p.UserID == nil || len(*p.UserID) == 0 {
+		verr.Add("userID", ErrCodeRequired, "userID is required")
+	}
+
+	if p.Symbol == nil || len(NormalizeSymbol(*p.Symbol)) == 0 {
+		verr.Add("symbol", ErrCodeRequired, "symbol is required")
+	} else if symbol := NormalizeSymbol(*p.Symbol); !IsSymbol(symbol) {
+		verr.Add("symbol", ErrCodeInvalid, "symbol must be ticker like AAPL or BRK.B")
+	} else {
+		p.Symbol = &symbol
+	}
+
+	if p.Date == nil {
+		verr.Add("date", ErrCodeRequired, "date is required")
+	} else if p.Date.Before(MinTransactionDate) || p.Date.After(time.Now().AddDate(0, 0, 1)) {
+		verr.Add("date", ErrCodeInvalid, "date must be after 1900 and not in future")
+	} else {
+		year, month, day := p.Date.Date()
+		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		p.Date = &date
+	}
+
+	if p.Price == nil {
+		verr.Add("price", ErrCodeRequired, "price is required")
+	} else if p.Price.Sign() < 0 {
+		verr.Add("price", ErrCodeInvalid, "price can't be negative")
+	}
+
+	return verr.Err()
+}
+
+// Lot is quantity of symbol bought by one buy transaction and not sold yet, lots are sold first in first out
+type Lot struct {
+	Date     time.Time `json:"date"`
+	Quantity Money     `json:"quantity"`
+	Cost     Money     `json:"cost"` // part of buy amount which falls on quantity left
+}
+
+// Position is symbol held in investment account. Market value and unrealized gain are nil
+// when symbol has no price, position which is sold out is kept for its realized gain.
+type Position struct {
+	Symbol         string     `json:"symbol"`
+	Quantity       Money      `json:"quantity"`
+	CostBasis      Money      `json:"costBasis"`
+	Price          *Money     `json:"price,omitempty"` // the latest price until now
+	PriceDate      *time.Time `json:"priceDate,omitempty"`
+	MarketValue    *Money     `json:"marketValue,omitempty"`
+	UnrealizedGain *Money     `json:"unrealizedGain,omitempty"`
+	RealizedGain   Money      `json:"realizedGain"` // proceeds of sells minus cost of lots sold
+	Dividends      Money      `json:"dividends"`
+	Allocation     *float64   `json:"allocation,omitempty"` // percent of market value of account holdings
+	Lots           []*Lot     `json:"lots"`
+}
+
+// Holdings is valuation of investment account. Cash is balance of account, buys are paid from it
+// and sells and dividends are paid to it. Totals count only positions which have price.
+type Holdings struct {
+	AccountID      AccountID   `json:"accountID"`
+	Currency       string      `json:"currency"`
+	Cash           Money       `json:"cash"`
+	MarketValue    Money       `json:"marketValue"`
+	CostBasis      Money       `json:"costBasis"`
+	UnrealizedGain Money       `json:"unrealizedGain"`
+	RealizedGain   Money       `json:"realizedGain"`
+	Dividends      Money       `json:"dividends"`
+	Value          Money       `json:"value"` // cash and market value
+	Positions      []*Position `json:"positions"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+)
+
+// AmortizationEntry is one monthly payment of loan schedule
+type AmortizationEntry struct {
+	Number    int       `json:"number"`
+	Date      time.Time `json:"date"`
+	Payment   Money     `json:"payment"`
+	Principal Money     `json:"principal"` // part of payment which pays principal
+	Interest  Money     `json:"interest"`  // part of payment which pays interest
+	Balance   Money     `json:"balance"`   // principal left after payment
+}
+
+// LoanStatus is loan with payments user actually made, income to loan account is payment.
+// Payment pays interest of its month first, rest of it pays principal.
+type LoanStatus struct {
+	AccountID          AccountID  `json:"accountID"`
+	RemainingPrincipal Money      `json:"remainingPrincipal"`
+	ScheduledPrincipal Money      `json:"scheduledPrincipal"` // principal which should be left by schedule
+	PrincipalPaid      Money      `json:"principalPaid"`
+	InterestPaid       Money      `json:"interestPaid"`
+	AccruedInterest    Money      `json:"accruedInterest"` // interest of current month which isn't paid yet
+	NextPaymentDate    *time.Time `json:"nextPaymentDate,omitempty"`
+
+	// Scenarios of payoff with extra payment every month, the first one is without extra payment
+	Scenarios []*PayoffScenario `json:"scenarios"`
+}
+
+// PayoffScenario is projection of remaining payments when extra payment is added to scheduled one
+type PayoffScenario struct {
+	ExtraPayment  Money      `json:"extraPayment"`
+	Payments      int        `json:"payments"`             // number of remaining payments
+	PayoffDate    *time.Time `json:"payoffDate,omitempty"` // nil when loan is paid off or payments don't cover interest
+	TotalInterest Money      `json:"totalInterest"`        // interest which will be paid
+	InterestSaved Money      `json:"interestSaved"`        // compared to scenario without extra payment
+}
@@ -0,0 +1,13 @@
+package model
+
+import (
+	"time"
+)
+
+// LoginAttempt counts failed logins for one key (account, IP address...)
+type LoginAttempt struct {
+	Key           string     `json:"key" db:"attempt_key"`
+	Failures      int        `json:"failures" db:"failures"`
+	LastFailureAt *time.Time `json:"lastFailureAt,omitempty" db:"last_failure_at"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty" db:"locked_until"`
+}
@@ -15,7 +15,7 @@ type Merchant struct {
 	ID        MerchantID `json:"id,omitempty" db:"merchant_id"`
 	UserID    *UserID    `json:"userID,omitempty" db:"user_id"`
 	CreatedAt *time.Time `json:"createdAt,omitempty" db:"created_at"`
-	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
 	Name      *string    `json:"name,omitempty" db:"name"`
 }
 
@@ -0,0 +1,300 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Money is amount in minor units of currency together with number of minor unit digits,
+// Money{Amount: 1234, Exponent: 2} is 12.34. It's sent to client and stored in NUMERIC column
+// as decimal string, so amount never goes through float.
+type Money struct {
+	Amount   int64
+	Exponent int
+}
+
+// RoundingMode says what to do with digits which don't fit when money is converted to smaller exponent
+type RoundingMode int
+
+const (
+	// RoundExact refuses to drop digits, conversion fails with ErrMoneyPrecision instead
+	RoundExact RoundingMode = iota
+	// RoundHalfEven rounds to nearest, ties to even digit (banker's rounding): 0.125 -> 0.12, 0.135 -> 0.14
+	RoundHalfEven
+	// RoundHalfUp rounds to nearest, ties away from zero: 0.125 -> 0.13, -0.125 -> -0.13
+	RoundHalfUp
+	// RoundDown drops digits, rounds toward zero: 0.129 -> 0.12, -0.129 -> -0.12
+	RoundDown
+	// RoundUp rounds away from zero: 0.121 -> 0.13, -0.121 -> -0.13
+	RoundUp
+)
+
+// MaxMoneyExponent is the most minor unit digits money can have, 10^18 still fits int64
+const MaxMoneyExponent = 18
+
+var (
+	ErrMoneyFormat    = errors.New("amount must be decimal number like 12.34")
+	ErrMoneyOverflow  = errors.New("amount is too large")
+	ErrMoneyPrecision = errors.New("amount has more decimal places than currency allows")
+	// ErrMoneyNumber - amounts used to be JSON numbers in minor units, 2500 was 25.00,
+	// numbers are refused so old clients don't store amount 100 times bigger
+	ErrMoneyNumber = errors.New(`amount must be decimal string like "12.34", not number`)
+)
+
+// currencyExponents are ISO 4217 currencies which don't have 2 minor unit digits
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// CurrencyExponent returns number of minor unit digits of currency, unknown currencies have 2 like most do
+func CurrencyExponent(currency string) int {
+	if exponent, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// IsCurrencyCode checks that currency looks like ISO 4217 code, three upper case letters
+func IsCurrencyCode(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+	for _, c := range currency {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseMoney parses decimal string, exponent of money is number of digits after decimal point
+func ParseMoney(s string) (Money, error) {
+	digits := s
+	negative := false
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		negative = digits[0] == '-'
+		digits = digits[1:]
+	}
+
+	exponent := 0
+	if point := strings.IndexByte(digits, '.'); point >= 0 {
+		exponent = len(digits) - point - 1
+		digits = digits[:point] + digits[point+1:]
+		if exponent == 0 || point == 0 {
+			return Money{}, ErrMoneyFormat
+		}
+	}
+	if len(digits) == 0 {
+		return Money{}, ErrMoneyFormat
+	}
+	if exponent > MaxMoneyExponent {
+		return Money{}, ErrMoneyPrecision
+	}
+
+	// accumulate negative value, so math.MinInt64 can be parsed too
+	var amount int64
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return Money{}, ErrMoneyFormat
+		}
+		digit := int64(c - '0')
+		if amount < (math.MinInt64+digit)/10 {
+			return Money{}, ErrMoneyOverflow
+		}
+		amount = amount*10 - digit
+	}
+	if !negative {
+		if amount == math.MinInt64 {
+			return Money{}, ErrMoneyOverflow
+		}
+		amount = -amount
+	}
+
+	return Money{Amount: amount, Exponent: exponent}, nil
+}
+
+// String returns money as decimal string with all minor unit digits, 12.30 and not 12.3
+func (m Money) String() string {
+	negative := m.Amount < 0
+	abs := uint64(m.Amount)
+	if negative {
+		abs = -abs
+	}
+
+	digits := fmt.Sprintf("%0*d", m.Exponent+1, abs)
+	if m.Exponent > 0 {
+		digits = digits[:len(digits)-m.Exponent] + "." + digits[len(digits)-m.Exponent:]
+	}
+	if negative {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Sign returns -1, 0 or 1
+func (m Money) Sign() int {
+	switch {
+	case m.Amount < 0:
+		return -1
+	case m.Amount > 0:
+		return 1
+	}
+	return 0
+}
+
+// Cmp compares money, it returns -1, 0 or 1 when m is less, equal or greater than other
+func (m Money) Cmp(other Money) int {
+	// comparing rescaled amounts can't overflow like subtraction
+	exponent := m.Exponent
+	if other.Exponent > exponent {
+		exponent = other.Exponent
+	}
+	a, errA := m.Rescale(exponent, RoundExact)
+	b, errB := other.Rescale(exponent, RoundExact)
+	switch {
+	case errA != nil:
+		// m doesn't fit int64 with more digits, so it's further from zero
+		return m.Sign()
+	case errB != nil:
+		return -other.Sign()
+	case a.Amount < b.Amount:
+		return -1
+	case a.Amount > b.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Rescale converts money to other exponent, digits which don't fit are rounded with mode
+func (m Money) Rescale(exponent int, mode RoundingMode) (Money, error) {
+	if exponent < 0 || exponent > MaxMoneyExponent {
+		return Money{}, fmt.Errorf("invalid exponent %d", exponent)
+	}
+
+	if exponent >= m.Exponent {
+		amount := m.Amount
+		for i := m.Exponent; i < exponent; i++ {
+			if amount > math.MaxInt64/10 || amount < math.MinInt64/10 {
+				return Money{}, ErrMoneyOverflow
+			}
+			amount *= 10
+		}
+		return Money{Amount: amount, Exponent: exponent}, nil
+	}
+
+	divisor := int64(1)
+	for i := exponent; i < m.Exponent; i++ {
+		divisor *= 10
+	}
+
+	quotient, remainder := m.Amount/divisor, m.Amount%divisor
+	if remainder != 0 {
+		if remainder < 0 {
+			remainder = -remainder
+		}
+		away := false
+		switch mode {
+		case RoundExact:
+			return Money{}, ErrMoneyPrecision
+		case RoundHalfEven:
+			away = remainder*2 > divisor || remainder*2 == divisor && quotient%2 != 0
+		case RoundHalfUp:
+			away = remainder*2 >= divisor
+		case RoundDown:
+		case RoundUp:
+			away = true
+		default:
+			return Money{}, fmt.Errorf("unknown rounding mode %d", mode)
+		}
+		if away && m.Amount < 0 {
+			quotient--
+		} else if away {
+			quotient++
+		}
+	}
+
+	return Money{Amount: quotient, Exponent: exponent}, nil
+}
+
+// Add returns exact sum, it has exponent of more precise of both
+func (m Money) Add(other Money) (Money, error) {
+	exponent := m.Exponent
+	if other.Exponent > exponent {
+		exponent = other.Exponent
+	}
+
+	a, err := m.Rescale(exponent, RoundExact)
+	if err != nil {
+		return Money{}, err
+	}
+	b, err := other.Rescale(exponent, RoundExact)
+	if err != nil {
+		return Money{}, err
+	}
+
+	if b.Amount > 0 && a.Amount > math.MaxInt64-b.Amount || b.Amount < 0 && a.Amount < math.MinInt64-b.Amount {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: a.Amount + b.Amount, Exponent: exponent}, nil
+}
+
+// Sub returns exact difference, it has exponent of more precise of both
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Amount == math.MinInt64 {
+		return Money{}, ErrMoneyOverflow
+	}
+	return m.Add(Money{Amount: -other.Amount, Exponent: other.Exponent})
+}
+
+// InCurrency converts money to exponent of currency, it fails rather than rounds amount user entered
+func (m Money) InCurrency(currency string) (Money, error) {
+	return m.Rescale(CurrencyExponent(currency), RoundExact)
+}
+
+// MarshalJSON - money is sent as string, JSON numbers are floats for most clients
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalText reads decimal string "12.34". Decoder calls it only for JSON strings,
+// JSON number fails with *json.UnmarshalTypeError which has field of money.
+func (m *Money) UnmarshalText(text []byte) error {
+	money, err := ParseMoney(strings.TrimSpace(string(text)))
+	if err != nil {
+		return err
+	}
+	*m = money
+	return nil
+}
+
+// Value - NUMERIC keeps scale of value, so exponent is stored too
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func (m *Money) Scan(src interface{}) error {
+	var money Money
+	var err error
+	switch value := src.(type) {
+	case []byte:
+		money, err = ParseMoney(string(value))
+	case string:
+		money, err = ParseMoney(value)
+	case int64:
+		money = Money{Amount: value}
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	if err != nil {
+		return err
+	}
+	*m = money
+	return nil
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want Money
+		err  error
+	}{
+		{"12.34", Money{1234, 2}, nil},
+		{"-0.5", Money{-5, 1}, nil},
+		{"+7", Money{7, 0}, nil},
+		{"9223372036854775807", Money{9223372036854775807, 0}, nil},
+		{"-9223372036854775808", Money{-9223372036854775808, 0}, nil},
+		{"9223372036854775808", Money{}, ErrMoneyOverflow},
+		{"12.", Money{}, ErrMoneyFormat},
+		{".5", Money{}, ErrMoneyFormat},
+		{"1e3", Money{}, ErrMoneyFormat},
+		{"", Money{}, ErrMoneyFormat},
+	} {
+		got, err := ParseMoney(test.in)
+		if got != test.want || err != test.err {
+			t.Errorf("ParseMoney(%q) = %v, %v; want %v, %v", test.in, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestMoneyRescale(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		mode RoundingMode
+		want string
+	}{
+		{"0.125", RoundHalfEven, "0.12"},
+		{"0.135", RoundHalfEven, "0.14"},
+		{"-0.125", RoundHalfEven, "-0.12"},
+		{"0.125", RoundHalfUp, "0.13"},
+		{"-0.125", RoundHalfUp, "-0.13"},
+		{"0.129", RoundDown, "0.12"},
+		{"-0.129", RoundDown, "-0.12"},
+		{"0.121", RoundUp, "0.13"},
+		{"-0.121", RoundUp, "-0.13"},
+		{"0.120", RoundExact, "0.12"},
+		{"12", RoundExact, "12.00"},
+	} {
+		money, _ := ParseMoney(test.in)
+		got, err := money.Rescale(2, test.mode)
+		if err != nil || got.String() != test.want {
+			t.Errorf("Rescale(%s, %d) = %v, %v; want %s", test.in, test.mode, got, err, test.want)
+		}
+	}
+
+	if _, err := (Money{Amount: 125, Exponent: 3}).Rescale(2, RoundExact); err != ErrMoneyPrecision {
+		t.Errorf("expected %v, got %v", ErrMoneyPrecision, err)
+	}
+	if _, err := (Money{Amount: 9223372036854775807}).Rescale(2, RoundExact); err != ErrMoneyOverflow {
+		t.Errorf("expected %v, got %v", ErrMoneyOverflow, err)
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var transaction Transaction
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal([]byte(`{"amount": 2500}`), &transaction); !errors.As(err, &typeErr) || typeErr.Field != "amount" {
+		t.Fatalf("number: got %v, %v; want type error of amount", transaction.Amount, err)
+	}
+	if err := json.Unmarshal([]byte(`{"amount": "12.5"}`), &transaction); err != nil || *transaction.Amount != (Money{125, 1}) {
+		t.Fatalf("decimal: got %v, %v", transaction.Amount, err)
+	}
+	if err := json.Unmarshal([]byte(`{"amount": "-0.05"}`), &transaction); err != nil || *transaction.Amount != (Money{-5, 2}) {
+		t.Fatalf("string: got %v, %v", transaction.Amount, err)
+	}
+
+	data, err := json.Marshal(Money{Amount: -5, Exponent: 2})
+	if err != nil || string(data) != `"-0.05"` {
+		t.Fatalf("Marshal() = %s, %v", data, err)
+	}
+}
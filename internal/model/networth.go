@@ -0,0 +1,25 @@
+package model
+
+// AccountValue is what account is worth: balance, with market value of holdings for investment account
+// and minus remaining principal for loan with terms
+type AccountValue struct {
+	AccountID AccountID   `json:"accountID"`
+	Name      string      `json:"name"`
+	Type      AccountType `json:"type"`
+	Currency  string      `json:"currency"`
+	Value     Money       `json:"value"`
+}
+
+// CurrencyNetWorth sums accounts in one currency, there are no exchange rates to sum all of them
+type CurrencyNetWorth struct {
+	Currency    string `json:"currency"`
+	Assets      Money  `json:"assets"`      // accounts worth more than zero
+	Liabilities Money  `json:"liabilities"` // accounts worth less than zero, it's negative
+	NetWorth    Money  `json:"netWorth"`
+}
+
+// NetWorth of user is computed from all his accounts
+type NetWorth struct {
+	Currencies []*CurrencyNetWorth `json:"currencies"`
+	Accounts   []*AccountValue     `json:"accounts"`
+}
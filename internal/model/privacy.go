@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+)
+
+// SessionInfo is session without refresh token
+type SessionInfo struct {
+	DeviceID  DeviceID `json:"deviceID" db:"device_id"`
+	ExpiresAt int64    `json:"expiresAt" db:"expires_at"`
+}
+
+// UserExport is everything we store about user, deleted records included
+type UserExport struct {
+	User         *User           `json:"user"`
+	Sessions     []*SessionInfo  `json:"sessions"`
+	Roles        []*UserRole     `json:"roles"`
+	Identities   []*UserIdentity `json:"identities"`
+	APIKeys      []*APIKey       `json:"apiKeys"`
+	Accounts     []*Account      `json:"accounts"`
+	Categories   []*Category     `json:"categories"`
+	Merchants    []*Merchant     `json:"merchants"`
+	Transactions []*Transaction  `json:"transactions"`
+	Prices       []*Price        `json:"prices"`
+
+	Reconciliations []*Reconciliation `json:"reconciliations"`
+}
+
+// ErasureRequest - user's data is erased when grace period ends, until then request can be canceled
+type ErasureRequest struct {
+	UserID      UserID     `json:"userID" db:"user_id"`
+	RequestedBy *UserID    `json:"requestedBy,omitempty" db:"requested_by"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty" db:"created_at"`
+	ScheduledAt time.Time  `json:"scheduledAt" db:"scheduled_at"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" db:"completed_at"`
+}
+
+const (
+	// AuditUserExported - user's data was downloaded
+	AuditUserExported AuditAction = "user.exported"
+	// AuditErasureRequested - user will be erased after grace period
+	AuditErasureRequested AuditAction = "user.erasure_requested"
+	// AuditErasureCanceled - erasure was canceled in grace period
+	AuditErasureCanceled AuditAction = "user.erasure_canceled"
+	// AuditUserErased - user's data was erased
+	AuditUserErased AuditAction = "user.erased"
+)
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"time"
+)
+
+// ReconciliationID is identifier of Reconciliation
+type ReconciliationID string
+
+// NilReconciliationID is empty identifier for Reconciliation
+var NilReconciliationID ReconciliationID
+
+// ClearedStatus says if transaction was matched with bank statement
+type ClearedStatus string
+
+const (
+	// Uncleared - transaction wasn't found on statement yet, new transactions are uncleared
+	Uncleared ClearedStatus = "uncleared"
+	// Cleared - user found transaction on statement
+	Cleared ClearedStatus = "cleared"
+	// Reconciled - cleared transaction of locked reconciliation, it can be changed only with force
+	Reconciled ClearedStatus = "reconciled"
+)
+
+func (s ClearedStatus) IsValid() bool {
+	switch s {
+	case Uncleared, Cleared, Reconciled:
+		return true
+	}
+	return false
+}
+
+// IsCleared - cleared and reconciled transactions count to cleared balance
+func (s ClearedStatus) IsCleared() bool {
+	return s == Cleared || s == Reconciled
+}
+
+// Reconciliation compares account with bank statement which ends on StatementDate with EndingBalance.
+// Account has at most one open reconciliation, locked reconciliation locks transactions until end of its statement date.
+type Reconciliation struct {
+	ID        ReconciliationID `json:"id" db:"reconciliation_id"`
+	UserID    *UserID          `json:"userID" db:"user_id"`
+	AccountID *AccountID       `json:"accountID" db:"account_id"`
+
+	StatementDate *time.Time `json:"statementDate" db:"statement_date"`
+	EndingBalance *Money     `json:"endingBalance" db:"ending_balance"`
+
+	CreatedAt *time.Time `json:"createdAt,omitempty" db:"created_at"`
+	LockedAt  *time.Time `json:"lockedAt,omitempty" db:"locked_at"`
+}
+
+// Verify checks fields of reconciliation, statement date is truncated to day
+func (r *Reconciliation) Verify() error {
+	verr := &ValidationError{}
+
+	if r.UserID == nil || len(*r.UserID) == 0 {
+		verr.Add("userID", ErrCodeRequired, "userID is required")
+	}
+
+	if r.AccountID == nil || len(*r.AccountID) == 0 {
+		verr.Add("accountID", ErrCodeRequired, "accountID is required")
+	}
+
+	if r.StatementDate == nil {
+		verr.Add("statementDate", ErrCodeRequired, "statementDate is required")
+	} else if r.StatementDate.Before(MinTransactionDate) || r.StatementDate.After(time.Now().AddDate(0, 0, 1)) {
+		verr.Add("statementDate", ErrCodeInvalid, "statementDate must be after 1900 and not in future")
+	} else {
+		year, month, day := r.StatementDate.Date()
+		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		r.StatementDate = &date
+	}
+
+	if r.EndingBalance == nil {
+		verr.Add("endingBalance", ErrCodeRequired, "endingBalance is required")
+	}
+
+	return verr.Err()
+}
+
+// IsLocked - locked reconciliation can't be changed
+func (r *Reconciliation) IsLocked() bool {
+	return r.LockedAt != nil
+}
+
+// PeriodEnd is start of day after statement date, transactions before it are on statement
+func (r *Reconciliation) PeriodEnd() time.Time {
+	return r.StatementDate.AddDate(0, 0, 1)
+}
+
+// ReconciliationReport is reconciliation with cleared balance of account computed from transactions
+type ReconciliationReport struct {
+	*Reconciliation
+
+	ClearedBalance Money          `json:"clearedBalance"` // start balance and cleared transactions until end of statement date
+	Difference     Money          `json:"difference"`     // ending balance minus cleared balance, reconciliation is locked when it's zero
+	Uncleared      []*Transaction `json:"uncleared"`      // transactions until end of statement date which aren't cleared
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+)
+
+// Revision is saved state of entity after each change, revisions are numbered from 1 for every entity
+type Revision struct {
+	EntityType     AuditEntity `json:"entityType" db:"entity_type"`
+	EntityID       string      `json:"entityID" db:"entity_id"`
+	Revision       int         `json:"revision" db:"revision"`
+	Operation      string      `json:"operation" db:"operation"` // AuditCreated, AuditUpdated, AuditDeleted or AuditRestored
+	UserID         *UserID     `json:"userID,omitempty" db:"user_id"`
+	ActorID        *UserID     `json:"actorID,omitempty" db:"actor_id"`
+	ImpersonatorID *UserID     `json:"impersonatorID,omitempty" db:"impersonator_id"`
+	Data           JSON        `json:"data" db:"data"` // entity as returned by API, state before deletion for deleted revision
+	CreatedAt      *time.Time  `json:"createdAt,omitempty" db:"created_at"`
+}
+
+// IsVersioned - entity keeps revisions and can be restored
+func (e AuditEntity) IsVersioned() bool {
+	switch e {
+	case AuditEntityAccount, AuditEntityCategory, AuditEntityMerchant, AuditEntityTransaction:
+		return true
+	}
+	return false
+}
+
+// RestoreRequest - data user sends to restore entity
+type RestoreRequest struct {
+	// Revision to restore, zero only undeletes entity with its current data
+	Revision int `json:"revision"`
+	// Transactions - restore also transactions deleted together with account, only for accounts
+	Transactions bool `json:"transactions"`
+}
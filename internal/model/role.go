@@ -1,5 +1,10 @@
 package model
 
+import (
+	"regexp"
+	"time"
+)
+
 // Role is a function a user can serve
 type Role string
 
@@ -14,3 +19,79 @@ const (
 type UserRole struct {
 	Role Role `json:"role" db:"role"`
 }
+
+// RoleSupport can read users to help them, but can't change anything
+const RoleSupport Role = "support"
+
+// Permission is named right to do something, format is "resource:action"
+type Permission string
+
+const (
+	PermissionUsersRead         Permission = "users:read"
+	PermissionUsersWrite        Permission = "users:write"
+	PermissionRolesRead         Permission = "roles:read"
+	PermissionAccountsRead      Permission = "accounts:read"
+	PermissionAccountsWrite     Permission = "accounts:write"
+	PermissionCategoriesRead    Permission = "categories:read"
+	PermissionCategoriesWrite   Permission = "categories:write"
+	PermissionMerchantsRead     Permission = "merchants:read"
+	PermissionMerchantsWrite    Permission = "merchants:write"
+	PermissionTransactionsRead  Permission = "transactions:read"
+	PermissionTransactionsWrite Permission = "transactions:write"
+)
+
+// Permissions is list of all permissions API checks, roles can have only these
+var Permissions = []Permission{
+	PermissionUsersRead,
+	PermissionUsersWrite,
+	PermissionRolesRead,
+	PermissionAccountsRead,
+	PermissionAccountsWrite,
+	PermissionCategoriesRead,
+	PermissionCategoriesWrite,
+	PermissionMerchantsRead,
+	PermissionMerchantsWrite,
+	PermissionTransactionsRead,
+	PermissionTransactionsWrite,
+}
+
+// IsKnown checks if permission is in Permissions list
+func (p Permission) IsKnown() bool {
+	for _, permission := range Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// roleNamePattern - lowercase names like "support" or "read-only"
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+
+// RoleDefinition is role stored in database with its permissions.
+// Role admin is builtin, it has all permissions and can't be changed.
+type RoleDefinition struct {
+	Name        Role         `json:"name" db:"role"`
+	Description *string      `json:"description,omitempty" db:"description"`
+	Permissions []Permission `json:"permissions" db:"-"`
+	CreatedAt   *time.Time   `json:"createdAt,omitempty" db:"created_at"`
+	UpdatedAt   *time.Time   `json:"updatedAt,omitempty" db:"updated_at"`
+}
+
+func (r *RoleDefinition) Verify() error {
+	verr := &ValidationError{}
+
+	if len(r.Name) == 0 {
+		verr.Add("name", ErrCodeRequired, "name is required")
+	} else if !roleNamePattern.MatchString(string(r.Name)) {
+		verr.Add("name", ErrCodeInvalid, "name must be lowercase letters, digits, '-' or '_'")
+	}
+
+	for _, permission := range r.Permissions {
+		if !permission.IsKnown() {
+			verr.Add("permissions", ErrCodeInvalid, "unknown permission "+string(permission))
+		}
+	}
+
+	return verr.Err()
+}
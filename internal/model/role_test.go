@@ -0,0 +1,23 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestRoleDefinitionVerify(t *testing.T) {
+	for _, test := range []struct {
+		role RoleDefinition
+		ok   bool
+	}{
+		{RoleDefinition{Name: "support", Permissions: []Permission{PermissionUsersRead}}, true},
+		{RoleDefinition{Name: "read-only_2"}, true},
+		{RoleDefinition{Name: ""}, false},
+		{RoleDefinition{Name: "Support"}, false},
+		{RoleDefinition{Name: "2fa"}, false},
+		{RoleDefinition{Name: "support", Permissions: []Permission{"users:delete"}}, false},
+	} {
+		if err := test.role.Verify(); (err == nil) != test.ok {
+			t.Errorf("Verify(%v) = %v; want ok %v", test.role, err, test.ok)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+)
+
+// StatementStatus says if statement of credit account has to be paid
+type StatementStatus string
+
+const (
+	// StatementCurrent - cycle isn't closed yet, amounts are as of now
+	StatementCurrent StatementStatus = "current"
+	// StatementDue - closed statement which isn't paid and its due date didn't pass
+	StatementDue StatementStatus = "due"
+	// StatementOverdue - closed statement which wasn't paid until end of its due date
+	StatementOverdue StatementStatus = "overdue"
+	// StatementPaid - payments made after close cover amount due
+	StatementPaid StatementStatus = "paid"
+)
+
+// Statement is one billing cycle of credit account, it's computed from transactions and isn't stored.
+// Balances are negative when money is owed, income to credit account is payment and expense is charge.
+type Statement struct {
+	AccountID   AccountID `json:"accountID"`
+	PeriodStart time.Time `json:"periodStart"` // first moment of cycle
+	PeriodEnd   time.Time `json:"periodEnd"`   // cycle closes at end of statement day, this is start of next day
+	DueDate     time.Time `json:"dueDate"`     // payment has to be made until end of this day
+
+	OpeningBalance Money `json:"openingBalance"`
+	Charges        Money `json:"charges"`
+	Payments       Money `json:"payments"`
+	ClosingBalance Money `json:"closingBalance"`
+
+	AmountDue      Money           `json:"amountDue"`      // debt at close, zero when balance isn't negative
+	MinimumPayment Money           `json:"minimumPayment"` // by minimum payment rule of account, at most amount due
+	PaidAmount     Money           `json:"paidAmount"`     // payments made after close, at most amount due
+	Status         StatementStatus `json:"status"`
+}
+
+// IsOverdueAt - statement is overdue after its due date ends
+func (s *Statement) IsOverdueAt(now time.Time) bool {
+	return !now.Before(s.DueDate.AddDate(0, 0, 1))
+}
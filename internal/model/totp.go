@@ -0,0 +1,129 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP (RFC 6238) parameters, the same as Google Authenticator and most other apps are using
+const (
+	totpPeriod     = 30 // seconds
+	totpDigits     = 6
+	totpSkew       = 1 // we accept one step before and after current (clock difference of phone)
+	totpSecretSize = 20
+
+	// RecoveryCodesCount is how many recovery codes user gets
+	RecoveryCodesCount = 10
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP is user's time based one time password (second factor)
+type TOTP struct {
+	UserID       UserID     `json:"-" db:"user_id"`
+	Secret       string     `json:"-" db:"secret"` // base32 encoded
+	Enabled      bool       `json:"enabled" db:"enabled"`
+	LastUsedStep int64      `json:"-" db:"last_used_step"` // used to reject code replay
+	CreatedAt    *time.Time `json:"-" db:"created_at"`
+	ConfirmedAt  *time.Time `json:"confirmedAt,omitempty" db:"confirmed_at"`
+}
+
+// NewTOTP generates new random secret for user, 2FA is enabled only after user confirms first code
+func NewTOTP(userID UserID) (*TOTP, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	return &TOTP{
+		UserID: userID,
+		Secret: secretEncoding.EncodeToString(secret),
+	}, nil
+}
+
+// ProvisioningURI is otpauth:// URI, authenticator apps read it from QR code
+func (t *TOTP) ProvisioningURI(issuer, accountName string) string {
+	query := url.Values{}
+	query.Set("secret", t.Secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Check verifies code at given time, it returns time step of matched code.
+// Code of step which was already used (LastUsedStep) is rejected.
+func (t *TOTP) Check(code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	secret, err := secretEncoding.DecodeString(t.Secret)
+	if err != nil {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= t.LastUsedStep {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// totpCode is HOTP (RFC 4226) value for counter
+func totpCode(secret []byte, counter int64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// GenerateRecoveryCodes returns new one time recovery codes in format xxxxx-xxxxx
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, RecoveryCodesCount)
+	for i := 0; i < RecoveryCodesCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(secretEncoding.EncodeToString(b))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode - we store only hashes of recovery codes.
+// Codes are random so we don't need slow hash like for passwords.
+func HashRecoveryCode(code string) []byte {
+	normalized := strings.ToLower(strings.Replace(strings.TrimSpace(code), "-", "", -1))
+	sum := sha256.Sum256([]byte(normalized))
+	return sum[:]
+}
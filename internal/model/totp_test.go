@@ -0,0 +1,94 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// secret and codes of RFC 6238 test vectors (SHA1), truncated to 6 digits
+var rfcSecret = secretEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	for _, test := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	} {
+		if got := totpCode([]byte("12345678901234567890"), test.unix/totpPeriod); got != test.want {
+			t.Errorf("totpCode(%d) = %q; want %q", test.unix, got, test.want)
+		}
+	}
+}
+
+func TestTOTPCheck(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := now.Unix() / totpPeriod
+
+	totp := &TOTP{Secret: rfcSecret}
+	if got, ok := totp.Check(" 081804 ", now); !ok || got != step {
+		t.Fatalf("Check() = %d, %v; want %d, true", got, ok, step)
+	}
+
+	// codes of previous and next step are accepted, older ones are not
+	if _, ok := totp.Check("081804", now.Add(totpPeriod*time.Second)); !ok {
+		t.Fatalf("Check() of previous step = false; want true")
+	}
+	if _, ok := totp.Check("081804", now.Add(-totpPeriod*time.Second)); !ok {
+		t.Fatalf("Check() of next step = false; want true")
+	}
+	if _, ok := totp.Check("081804", now.Add(2*totpPeriod*time.Second)); ok {
+		t.Fatalf("Check() two steps later = true; want false")
+	}
+
+	// code can't be used twice
+	totp.LastUsedStep = step
+	if _, ok := totp.Check("081804", now); ok {
+		t.Fatalf("Check() of used step = true; want false")
+	}
+
+	for _, code := range []string{"", "08180", "0818040", "abcdef"} {
+		if _, ok := (&TOTP{Secret: rfcSecret}).Check(code, now); ok {
+			t.Errorf("Check(%q) = true; want false", code)
+		}
+	}
+}
+
+func TestNewTOTP(t *testing.T) {
+	totp, err := NewTOTP(UserID("user"))
+	if err != nil {
+		t.Fatalf("NewTOTP() = %v", err)
+	}
+	if totp.Enabled {
+		t.Fatalf("NewTOTP() is enabled before confirmation")
+	}
+
+	uri := totp.ProvisioningURI("Finance App", "user@example.com")
+	if !strings.HasPrefix(uri, "otpauth://totp/Finance%20App:user@example.com?") || !strings.Contains(uri, "secret="+totp.Secret) {
+		t.Fatalf("ProvisioningURI() = %q", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil || len(codes) != RecoveryCodesCount {
+		t.Fatalf("GenerateRecoveryCodes() = %v, %v", codes, err)
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || seen[code] {
+			t.Fatalf("GenerateRecoveryCodes() = %v; want unique xxxxx-xxxxx codes", codes)
+		}
+		seen[code] = true
+	}
+
+	if string(HashRecoveryCode(" ABCDE-fghij ")) != string(HashRecoveryCode("abcdefghij")) {
+		t.Fatalf("HashRecoveryCode() depends on case, spaces or dash")
+	}
+}
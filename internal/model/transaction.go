@@ -1,7 +1,7 @@
 package model
 
 import (
-	"errors"
+	"strings"
 	"time"
 )
 
@@ -17,8 +17,94 @@ type TransactionType string
 const (
 	Income  TransactionType = "income"
 	Expense TransactionType = "expense"
+
+	// Buy, Sell and Dividend are transactions of investment account, amount is cash paid or received
+	Buy      TransactionType = "buy"
+	Sell     TransactionType = "sell"
+	Dividend TransactionType = "dividend"
+)
+
+func (t TransactionType) IsValid() bool {
+	switch t {
+	case Income, Expense, Buy, Sell, Dividend:
+		return true
+	}
+	return false
+}
+
+// IsInvestment - buy, sell and dividend are about symbol held in investment account
+func (t TransactionType) IsInvestment() bool {
+	return t == Buy || t == Sell || t == Dividend
+}
+
+// HasQuantity - buy and sell change quantity of symbol held
+func (t TransactionType) HasQuantity() bool {
+	return t == Buy || t == Sell
+}
+
+// IsInflow - money comes to account, balance of account grows by amount
+func (t TransactionType) IsInflow() bool {
+	return t == Income || t == Sell || t == Dividend
+}
+
+// TransactionStatus is lifecycle of transaction: card authorization is pending until bank posts it,
+// void transaction was cancelled, it stays visible but doesn't count
+type TransactionStatus string
+
+const (
+	Pending TransactionStatus = "pending"
+	Posted  TransactionStatus = "posted"
+	Void    TransactionStatus = "void"
 )
 
+func (s TransactionStatus) IsValid() bool {
+	switch s {
+	case Pending, Posted, Void:
+		return true
+	}
+	return false
+}
+
+// CanBecome - pending transaction posts or is voided, posted one can be voided, void is final
+func (s TransactionStatus) CanBecome(next TransactionStatus) bool {
+	switch {
+	case s == next:
+		return true
+	case s == Pending:
+		return next == Posted || next == Void
+	case s == Posted:
+		return next == Void
+	}
+	return false
+}
+
+// MaxSymbolLength is the longest ticker symbol, exchange prefix included
+const MaxSymbolLength = 20
+
+// IsSymbol checks that symbol looks like ticker: upper case letters, digits, '.', '-' and ':'
+func IsSymbol(symbol string) bool {
+	if len(symbol) == 0 || len(symbol) > MaxSymbolLength {
+		return false
+	}
+	for _, c := range symbol {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '.' && c != '-' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeSymbol - symbols are stored upper case, "aapl" and "AAPL" are the same
+func NormalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// Transactions can't be dated before MinTransactionDate or more than MaxTransactionDaysAhead days from now,
+// such dates are typos (0202 instead of 2020) and would be lost in reports
+var MinTransactionDate = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+const MaxTransactionDaysAhead = 366
+
 type Transaction struct {
 	ID         TransactionID `json:"id" db:"transaction_id"`
 	UserID     *UserID       `json:"userID" db:"user_id"`
@@ -26,38 +112,118 @@ type Transaction struct {
 	CategoryID *CategoryID   `json:"categoryID" db:"category_id"`
 
 	CreatedAt *time.Time `json:"createdAt,omitempty" db:"created_at"`
-	DeletedAt *time.Time `json:"-" db:"deleted_at"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
 
 	Date   *time.Time       `json:"date" db:"date"`
 	Type   *TransactionType `json:"type" db:"type"`
-	Amount *int64           `json:"amount" db:"amount"`
+	Amount *Money           `json:"amount" db:"amount"`
 	Notes  *string          `json:"notes" db:"notes"`
+
+	// Status is posted when transaction is created without it, date and amount can change when pending one posts
+	Status *TransactionStatus `json:"status,omitempty" db:"status"`
+
+	// Symbol and Quantity are only for investment transactions, quantity is decimal without currency
+	Symbol   *string `json:"symbol,omitempty" db:"symbol"`
+	Quantity *Money  `json:"quantity,omitempty" db:"quantity"`
+
+	// ClearedStatus is set by reconciliation of account, it's uncleared when transaction is created
+	ClearedStatus *ClearedStatus `json:"clearedStatus,omitempty" db:"cleared_status"`
 }
 
+// Verify checks fields of transaction, amount is always positive and type says if it's income or expense.
+// It doesn't check that account and category belong to user, it needs database for that.
 func (t *Transaction) Verify() error {
+	verr := &ValidationError{}
+
 	if t.UserID == nil || len(*t.UserID) == 0 {
-		return errors.New("userID is required")
+		verr.Add("userID", ErrCodeRequired, "userID is required")
 	}
 
 	if t.AccountID == nil || len(*t.AccountID) == 0 {
-		return errors.New("accountID is required")
+		verr.Add("accountID", ErrCodeRequired, "accountID is required")
 	}
 
 	if t.CategoryID == nil || len(*t.CategoryID) == 0 {
-		return errors.New("categoryID is required")
+		verr.Add("categoryID", ErrCodeRequired, "categoryID is required")
 	}
 
 	if t.Date == nil {
-		return errors.New("date is required")
+		verr.Add("date", ErrCodeRequired, "date is required")
+	} else if t.Date.Before(MinTransactionDate) || t.Date.After(time.Now().AddDate(0, 0, MaxTransactionDaysAhead)) {
+		verr.Add("date", ErrCodeInvalid, "date must be after 1900 and at most a year from now")
 	}
 
 	if t.Type == nil || len(*t.Type) == 0 {
-		return errors.New("type is required")
+		verr.Add("type", ErrCodeRequired, "type is required")
+	} else if !t.Type.IsValid() {
+		verr.Add("type", ErrCodeInvalid, "type must be income, expense, buy, sell or dividend")
 	}
 
 	if t.Amount == nil {
-		return errors.New("amount is required")
+		verr.Add("amount", ErrCodeRequired, "amount is required")
+	} else if t.Amount.Sign() <= 0 {
+		verr.Add("amount", ErrCodeInvalid, "amount must be positive, use type expense for money spent")
+	}
+
+	isInvestment := t.Type != nil && t.Type.IsInvestment()
+	if t.Symbol != nil {
+		symbol := NormalizeSymbol(*t.Symbol)
+		t.Symbol = &symbol
+	}
+	if isInvestment && (t.Symbol == nil || len(*t.Symbol) == 0) {
+		verr.Add("symbol", ErrCodeRequired, "symbol is required for buy, sell and dividend")
+	} else if t.Symbol != nil && !isInvestment {
+		verr.Add("symbol", ErrCodeInvalid, "symbol is only for buy, sell and dividend")
+	} else if t.Symbol != nil && !IsSymbol(*t.Symbol) {
+		verr.Add("symbol", ErrCodeInvalid, "symbol must be ticker like AAPL or BRK.B")
+	}
+
+	hasQuantity := t.Type != nil && t.Type.HasQuantity()
+	if hasQuantity && t.Quantity == nil {
+		verr.Add("quantity", ErrCodeRequired, "quantity is required for buy and sell")
+	} else if t.Quantity != nil && !hasQuantity {
+		verr.Add("quantity", ErrCodeInvalid, "quantity is only for buy and sell")
+	} else if t.Quantity != nil && t.Quantity.Sign() <= 0 {
+		verr.Add("quantity", ErrCodeInvalid, "quantity must be positive, use type sell for shares sold")
+	}
+
+	if t.Status != nil && !t.Status.IsValid() {
+		verr.Add("status", ErrCodeInvalid, "status must be pending, posted or void")
+	}
+
+	if t.ClearedStatus != nil && !t.ClearedStatus.IsValid() {
+		verr.Add("clearedStatus", ErrCodeInvalid, "clearedStatus must be uncleared, cleared or reconciled")
 	}
 
-	return nil
+	return verr.Err()
+}
+
+// ClearTypeDetails removes symbol and quantity which type of transaction doesn't have, it's called when type is changed
+func (t *Transaction) ClearTypeDetails() {
+	if t.Type == nil || !t.Type.IsInvestment() {
+		t.Symbol = nil
+	}
+	if t.Type == nil || !t.Type.HasQuantity() {
+		t.Quantity = nil
+	}
+}
+
+// IsCounted - void transaction never counts to balances and reports, pending one counts only when it's asked for.
+// Transaction without status is posted.
+func (t *Transaction) IsCounted(includePending bool) bool {
+	if t.Status == nil {
+		return true
+	}
+	return *t.Status == Posted || (includePending && *t.Status == Pending)
+}
+
+// CountedTransactions returns transactions which count to balances and reports, see IsCounted
+func CountedTransactions(transactions []*Transaction, includePending bool) []*Transaction {
+	counted := make([]*Transaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		if transaction.IsCounted(includePending) {
+			counted = append(counted, transaction)
+		}
+	}
+	return counted
 }
@@ -0,0 +1,28 @@
+package model
+
+// Trash is everything user deleted, items can be restored until retention worker purges them
+type Trash struct {
+	Accounts     []*TrashAccount `json:"accounts"`
+	Categories   []*Category     `json:"categories"`
+	Merchants    []*Merchant     `json:"merchants"`
+	Transactions []*Transaction  `json:"transactions"`
+}
+
+// TrashAccount is deleted account with number of transactions deleted together with it,
+// they can be restored with account
+type TrashAccount struct {
+	Account
+	DeletedTransactions int `json:"deletedTransactions" db:"deleted_transactions"`
+}
+
+// AccountRestored is returned after account is restored
+type AccountRestored struct {
+	*Account
+	RestoredTransactions int `json:"restoredTransactions"`
+}
+
+// TrashItem identifies item in trash
+type TrashItem struct {
+	EntityType AuditEntity `db:"entity_type"`
+	EntityID   string      `db:"entity_id"`
+}
@@ -2,7 +2,7 @@ package model
 
 import (
 	"errors"
-	"regexp"
+	"net/mail"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -23,18 +23,33 @@ type User struct {
 	DeletedAt    *time.Time `json:"-" db:"deleted_at"`
 }
 
-// isEmail match email format
-var isEmail = regexp.MustCompile(`^([\w\.\_\-]{2,10})@(\w{1,}).([a-z]{2,4})$`)
+// maxEmailLength is maximum length of email address (RFC 5321)
+const maxEmailLength = 254
 
 // Verify all required fields before create or update
 func (u *User) Verify() error {
-	if u.Email == nil || (u.Email != nil && len(*u.Email) == 0) {
-		return errors.New("email is required")
+	verr := &ValidationError{}
+	if u.Email == nil || len(*u.Email) == 0 {
+		verr.Add("email", ErrCodeRequired, "email is required")
+	} else if !IsEmail(*u.Email) {
+		verr.Add("email", ErrCodeInvalid, "email invalid")
 	}
-	if !isEmail.MatchString(*u.Email) {
-		return errors.New("email invalid")
+	return verr.Err()
+}
+
+// IsEmail checks address format (RFC 5322 addr-spec), display names like "Bob <bob@example.com>" are not allowed
+func IsEmail(email string) bool {
+	if len(email) > maxEmailLength {
+		return false
 	}
-	return nil
+
+	address, err := mail.ParseAddress(email)
+	if err != nil {
+		return false
+	}
+
+	// String() quotes local part when needed (`"john doe"@example.com`), so we can compare it with input
+	return address.Name == "" && address.String() == "<"+email+">"
 }
 
 // SetPassword updates user's password
@@ -49,7 +64,7 @@ func (u *User) SetPassword(password string) error {
 
 // CheckPassword verifies user's password
 func (u *User) CheckPassword(password string) error {
-	if u.PasswordHash != nil && len(*u.PasswordHash) == 0 {
+	if u.PasswordHash == nil || len(*u.PasswordHash) == 0 { // users created by external login have no password
 		return errors.New("password not set")
 	}
 	return bcrypt.CompareHashAndPassword(*u.PasswordHash, []byte(password))
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsEmail(t *testing.T) {
+	for _, test := range []struct {
+		email string
+		want  bool
+	}{
+		{"user@example.com", true},
+		{"first.last+tag@sub.example.co.uk", true},
+		{"a@b", true},
+		{`"john doe"@example.com`, true},
+		{"very.long.local.part.of.address@example.com", true},
+		{"", false},
+		{"user", false},
+		{"user@", false},
+		{"@example.com", false},
+		{"two@@example.com", false},
+		{"Bob <bob@example.com>", false},
+		{"john doe@example.com", false},
+		{strings.Repeat("a", 250) + "@b.co", false},
+	} {
+		if got := IsEmail(test.email); got != test.want {
+			t.Errorf("IsEmail(%q) = %v; want %v", test.email, got, test.want)
+		}
+	}
+}
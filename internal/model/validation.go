@@ -0,0 +1,51 @@
+package model
+
+import (
+	"strings"
+)
+
+// Validation error codes, clients can use them to show translated messages
+const (
+	ErrCodeRequired = "required"
+	ErrCodeInvalid  = "invalid"
+	ErrCodeTooShort = "too_short"
+	ErrCodeTooWeak  = "too_weak"
+	ErrCodeBreached = "breached"
+)
+
+// FieldError describes why one field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError is list of invalid fields, we return it to client as it is
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// Add adds invalid field
+func (e *ValidationError) Add(field, code, message string) {
+	e.Fields = append(e.Fields, FieldError{
+		Field:   field,
+		Code:    code,
+		Message: message,
+	})
+}
+
+// Err returns nil when there are no invalid fields, so it can be returned from Verify functions
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+	return strings.Join(messages, ", ")
+}
@@ -0,0 +1,58 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/namsral/flag"
+	"github.com/pkg/errors"
+)
+
+// providersFile is JSON file with list of ProviderConfig, for example:
+//
+//	[{"name": "google", "issuer": "https://accounts.google.com", "clientID": "...", "clientSecret": "...",
+//	  "redirectURL": "https://example.com/api/v1/login/google/callback"}]
+var providersFile = flag.String("oidc-providers-file", "", "Path to JSON file with OpenID Connect providers.")
+
+// Providers is list of configured providers by name
+type Providers map[string]*Provider
+
+// Get returns provider by name
+func (p Providers) Get(name string) (*Provider, bool) {
+	provider, ok := p[name]
+	return provider, ok
+}
+
+// LoadProviders reads providers from file set in flag. Without file OIDC login is disabled.
+func LoadProviders() (Providers, error) {
+	providers := make(Providers)
+	if *providersFile == "" {
+		return providers, nil
+	}
+
+	file, err := os.Open(*providersFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open oidc providers file")
+	}
+	defer file.Close()
+
+	var configs []ProviderConfig
+	if err := json.NewDecoder(file).Decode(&configs); err != nil {
+		return nil, errors.Wrap(err, "could not decode oidc providers file")
+	}
+
+	for _, config := range configs {
+		if _, exists := providers[config.Name]; exists {
+			return nil, fmt.Errorf("oidc provider %q configured twice", config.Name)
+		}
+
+		provider, err := NewProvider(config, nil)
+		if err != nil {
+			return nil, err
+		}
+		providers[config.Name] = provider
+	}
+
+	return providers, nil
+}
@@ -0,0 +1,123 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func testProvider(t *testing.T) (*Provider, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewProvider(ProviderConfig{
+		Name:        "test",
+		Issuer:      "https://issuer.example.com",
+		ClientID:    "client",
+		RedirectURL: "https://app.example.com/api/v1/login/test/callback",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider.keys = newKeySet("https://issuer.example.com/keys", func(ctx context.Context, url string, v interface{}) error {
+		set := v.(*jsonWebKeySet)
+		set.Keys = []jsonWebKey{{
+			Kty: "RSA",
+			Kid: "key-1",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}
+		return nil
+	})
+
+	return provider, key
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	provider, key := testProvider(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	claims := func(change func(c jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss":            "https://issuer.example.com",
+			"sub":            "subject",
+			"aud":            "client",
+			"exp":            now.Add(time.Hour).Unix(),
+			"iat":            now.Unix(),
+			"nonce":          "nonce",
+			"email":          "user@example.com",
+			"email_verified": true,
+			"name":           "User",
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+
+	for name, test := range map[string]struct {
+		token string
+		nonce string
+		ok    bool
+	}{
+		"valid":                 {signToken(t, key, "key-1", claims(nil)), "nonce", true},
+		"audience array":        {signToken(t, key, "key-1", claims(func(c jwt.MapClaims) { c["aud"] = []string{"other", "client"} })), "nonce", true},
+		"verified as string":    {signToken(t, key, "key-1", claims(func(c jwt.MapClaims) { c["email_verified"] = "true" })), "nonce", true},
+		"wrong issuer":          {signToken(t, key, "key-1", claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })), "nonce", false},
+		"wrong audience":        {signToken(t, key, "key-1", claims(func(c jwt.MapClaims) { c["aud"] = "other" })), "nonce", false},
+		"wrong nonce":           {signToken(t, key, "key-1", claims(nil)), "other", false},
+		"empty nonce":           {signToken(t, key, "key-1", claims(func(c jwt.MapClaims) { c["nonce"] = "" })), "", false},
+		"no subject":            {signToken(t, key, "key-1", claims(func(c jwt.MapClaims) { delete(c, "sub") })), "nonce", false},
+		"expired":               {signToken(t, key, "key-1", claims(func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Hour).Unix() })), "nonce", false},
+		"no expiration":         {signToken(t, key, "key-1", claims(func(c jwt.MapClaims) { delete(c, "exp") })), "nonce", false},
+		"issued in future":      {signToken(t, key, "key-1", claims(func(c jwt.MapClaims) { c["iat"] = now.Add(time.Hour).Unix() })), "nonce", false},
+		"signed with other key": {signToken(t, otherKey, "key-1", claims(nil)), "nonce", false},
+		"unknown key":           {signToken(t, key, "key-2", claims(nil)), "nonce", false},
+	} {
+		token, err := provider.verifyIDToken(context.Background(), test.token, test.nonce)
+		if test.ok != (err == nil) {
+			t.Errorf("%s: verifyIDToken() = %v, %v; want ok %v", name, token, err, test.ok)
+			continue
+		}
+		if test.ok && (token.Subject != "subject" || token.Email != "user@example.com" || !token.EmailVerified) {
+			t.Errorf("%s: verifyIDToken() = %+v", name, token)
+		}
+	}
+}
+
+func TestAudienceUnmarshal(t *testing.T) {
+	var a audience
+	if err := a.UnmarshalJSON([]byte(`"client"`)); err != nil || !a.contains("client") {
+		t.Fatalf("UnmarshalJSON() of string = %v, %v", a, err)
+	}
+	if err := a.UnmarshalJSON([]byte(`["a","b"]`)); err != nil || !a.contains("b") || a.contains("client") {
+		t.Fatalf("UnmarshalJSON() of array = %v, %v", a, err)
+	}
+	if err := a.UnmarshalJSON([]byte(`1`)); err == nil {
+		t.Fatalf("UnmarshalJSON() of number = %v; want error", a)
+	}
+}
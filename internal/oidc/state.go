@@ -0,0 +1,92 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"github.com/bluele/gcache"
+)
+
+// how long user has to finish login on provider side
+const authRequestDuration = 10 * time.Minute
+
+// AuthRequest is login started by user, we need it back when provider redirects to callback
+type AuthRequest struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	DeviceID     string
+}
+
+// StateStore keeps pending login requests by state parameter.
+// Every state can be used only once.
+type StateStore struct {
+	cache gcache.Cache
+}
+
+func NewStateStore() *StateStore {
+	return &StateStore{
+		cache: gcache.New(10000).LRU().Build(),
+	}
+}
+
+// Start creates new AuthRequest and returns state for it
+func (s *StateStore) Start(provider, deviceID string) (string, *AuthRequest, error) {
+	state, err := RandomString()
+	if err != nil {
+		return "", nil, err
+	}
+
+	nonce, err := RandomString()
+	if err != nil {
+		return "", nil, err
+	}
+
+	verifier, err := RandomString()
+	if err != nil {
+		return "", nil, err
+	}
+
+	request := &AuthRequest{
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		DeviceID:     deviceID,
+	}
+
+	if err := s.cache.SetWithExpire(state, request, authRequestDuration); err != nil {
+		return "", nil, err
+	}
+
+	return state, request, nil
+}
+
+// Take returns AuthRequest by state and removes it from store
+func (s *StateStore) Take(state string) (*AuthRequest, bool) {
+	value, err := s.cache.Get(state)
+	if err != nil {
+		return nil, false
+	}
+	s.cache.Remove(state)
+
+	request, ok := value.(*AuthRequest)
+	return request, ok
+}
+
+// RandomString returns 32 random bytes encoded with base64url (43 characters),
+// it is long enough to be used as PKCE code verifier
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge is S256 PKCE challenge for code verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
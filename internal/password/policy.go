@@ -0,0 +1,124 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/namsral/flag"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/config"
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+var (
+	minLength    = flag.Int("password-min-length", 8, "Minimum password length.")
+	minStrength  = flag.Int("password-min-strength", 2, "Minimum password strength score (0-4).")
+	breachedFile = flag.String("breached-passwords-file", "", "File with SHA-1 hashes of breached passwords (default is bundled list).")
+)
+
+// bundledList is path of breached passwords list shipped with server, relative to data directory
+const bundledList = "internal/password/breached_passwords.txt"
+
+// Policy checks new passwords
+type Policy struct {
+	MinLength   int
+	MinStrength int
+
+	breached map[[sha1.Size]byte]struct{}
+}
+
+// NewPolicy creates policy from flags and loads breached passwords list
+func NewPolicy() (*Policy, error) {
+	policy := &Policy{
+		MinLength:   *minLength,
+		MinStrength: *minStrength,
+		breached:    make(map[[sha1.Size]byte]struct{}),
+	}
+
+	path := *breachedFile
+	if path == "" {
+		path = filepath.Join(*config.DataDirectory, bundledList)
+		// bundled list is optional, server still checks length and strength without it
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			logrus.WithField("path", path).Warn("breached passwords list not found")
+			return policy, nil
+		}
+	}
+
+	if err := policy.LoadBreached(path); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// LoadBreached reads file with one SHA-1 hash (hex) per line. Lines may have ":count" suffix
+// like in "Have I Been Pwned" downloads, lines starting with # are comments.
+func (p *Policy) LoadBreached(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "could not open breached passwords list")
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if i := strings.IndexByte(text, ':'); i >= 0 {
+			text = text[:i]
+		}
+
+		var hash [sha1.Size]byte
+		decoded, err := hex.DecodeString(text)
+		if err != nil || len(decoded) != sha1.Size {
+			return fmt.Errorf("invalid hash in breached passwords list on line %d", line)
+		}
+		copy(hash[:], decoded)
+		p.breached[hash] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "could not read breached passwords list")
+	}
+
+	logrus.WithField("count", len(p.breached)).Debug("breached passwords list loaded")
+	return nil
+}
+
+// IsBreached checks if password is in breached passwords list
+func (p *Policy) IsBreached(password string) bool {
+	_, ok := p.breached[sha1.Sum([]byte(password))]
+	return ok
+}
+
+// Check adds all problems of password to validation error.
+// userInputs are user's data (email...) which shouldn't be part of password.
+func (p *Policy) Check(verr *model.ValidationError, field, password string, userInputs ...string) {
+	if len(password) == 0 {
+		verr.Add(field, model.ErrCodeRequired, "password is required")
+		return
+	}
+
+	if len([]rune(password)) < p.MinLength {
+		verr.Add(field, model.ErrCodeTooShort, fmt.Sprintf("password must be %d characters or longer", p.MinLength))
+	}
+
+	if p.IsBreached(password) {
+		verr.Add(field, model.ErrCodeBreached, "password was found in data breach, choose another one")
+	} else if Strength(password, userInputs...) < p.MinStrength {
+		verr.Add(field, model.ErrCodeTooWeak, "password is too easy to guess")
+	}
+}
@@ -0,0 +1,102 @@
+package password
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/startdusk/finance-app-backend/internal/model"
+)
+
+func TestStrength(t *testing.T) {
+	for _, test := range []struct {
+		password   string
+		userInputs []string
+		want       int
+	}{
+		{"", nil, 0},
+		{"password", nil, 0},
+		{"aaaaaaaa", nil, 0},
+		{"abcdefgh", nil, 0},
+		{"qwertyuiop", nil, 0},
+		{"summer2020", nil, 1},
+		{"johnsmith99", nil, 4},
+		// the same password is weak when it contains user's email
+		{"johnsmith99", []string{"john.smith@example.com"}, 2},
+		{"Tr0ub4dor&3", nil, 4},
+		{"correct horse battery staple", nil, 4},
+	} {
+		if got := Strength(test.password, test.userInputs...); got != test.want {
+			t.Errorf("Strength(%q, %v) = %d; want %d", test.password, test.userInputs, got, test.want)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// sha1("correct horse battery staple") with count suffix
+	list := filepath.Join(dir, "breached.txt")
+	content := "# comment\n\nABF7AAD6438836DBE526AA231ABDE2D0EEF74D42:3\n"
+	if err := ioutil.WriteFile(list, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := &Policy{MinLength: 8, MinStrength: 2, breached: make(map[[20]byte]struct{})}
+	if err := policy.LoadBreached(list); err != nil {
+		t.Fatalf("LoadBreached() = %v", err)
+	}
+
+	for _, test := range []struct {
+		password string
+		codes    []string
+	}{
+		{"", []string{model.ErrCodeRequired}},
+		{"f8Jk2p", []string{model.ErrCodeTooShort}},
+		{"password", []string{model.ErrCodeTooWeak}},
+		{"pass", []string{model.ErrCodeTooShort, model.ErrCodeTooWeak}},
+		{"correct horse battery staple", []string{model.ErrCodeBreached}},
+		{"Tr0ub4dor&3", nil},
+	} {
+		verr := &model.ValidationError{}
+		policy.Check(verr, "password", test.password, "john.smith@example.com")
+
+		codes := make([]string, 0, len(verr.Fields))
+		for _, field := range verr.Fields {
+			codes = append(codes, field.Code)
+		}
+		if len(codes) != len(test.codes) {
+			t.Errorf("Check(%q) = %v; want %v", test.password, codes, test.codes)
+			continue
+		}
+		for i := range codes {
+			if codes[i] != test.codes[i] {
+				t.Errorf("Check(%q) = %v; want %v", test.password, codes, test.codes)
+				break
+			}
+		}
+	}
+}
+
+func TestLoadBreachedInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	list := filepath.Join(dir, "breached.txt")
+	if err := ioutil.WriteFile(list, []byte("not a hash\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := &Policy{breached: make(map[[20]byte]struct{})}
+	if err := policy.LoadBreached(list); err == nil {
+		t.Fatalf("LoadBreached() of invalid list = nil; want error")
+	}
+}
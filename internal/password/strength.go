@@ -0,0 +1,246 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Password strength estimation in the spirit of zxcvbn:
+// password is split into patterns (dictionary words, user inputs, repeats, sequences, keyboard rows)
+// and every pattern costs few bits of entropy, other characters cost bits of their character set.
+// Result is score 0 (too guessable) ... 4 (very unguessable).
+
+// commonWords are words often used inside passwords
+var commonWords = []string{
+	"password", "passwort", "pass", "admin", "login", "welcome", "letmein", "qwerty", "dragon",
+	"monkey", "master", "shadow", "sunshine", "princess", "football", "baseball", "soccer", "hockey",
+	"iloveyou", "love", "secret", "trustno", "superman", "batman", "starwars", "computer", "internet",
+	"michael", "jordan", "charlie", "freedom", "whatever", "hello", "flower", "summer", "winter",
+	"spring", "autumn", "money", "finance", "bank", "test", "guest", "user", "root", "default",
+	"changeme", "abc", "god", "angel", "jesus", "lucky", "cookie", "pepper", "ginger", "killer",
+	"hunter", "ranger", "buster", "tigger", "mustang", "harley", "maggie", "cheese", "matrix", "thunder",
+}
+
+// keyboardRows for detecting patterns like "qwerty" or "asdf"
+var keyboardRows = []string{
+	"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm", "qwertzuiop", "azertyuiop",
+}
+
+// minimum length of patterns we detect
+const (
+	minWordLength     = 3
+	minRepeatLength   = 3
+	minSequenceLength = 3
+	minKeyboardLength = 4
+	yearLength        = 4
+)
+
+// years from 1900 to 2099 are easy to guess (birth year, current year)
+var yearBits = math.Log2(200)
+
+// Strength returns score 0-4. userInputs (email, name...) are treated as known words.
+func Strength(password string, userInputs ...string) int {
+	return score(entropy(password, userInputs))
+}
+
+// score converts entropy to zxcvbn like score using guesses thresholds 10^3, 10^6, 10^8, 10^10
+func score(bits float64) int {
+	guessesLog10 := bits * math.Log10(2)
+	switch {
+	case guessesLog10 < 3:
+		return 0
+	case guessesLog10 < 6:
+		return 1
+	case guessesLog10 < 8:
+		return 2
+	case guessesLog10 < 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func entropy(password string, userInputs []string) float64 {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	lower := []rune(strings.ToLower(password))
+	charBits := math.Log2(float64(charsetSize(runes)))
+	words := dictionary(userInputs)
+	wordBits := math.Log2(float64(len(words))) + 1 // +1 bit for capitalization and l33t variants
+
+	bits := 0.0
+	for i := 0; i < len(runes); {
+		length, patternBits := longestPattern(lower, i, words, wordBits, charBits)
+		if length > 0 {
+			bits += patternBits
+			i += length
+			continue
+		}
+
+		bits += charBits
+		i++
+	}
+
+	return bits
+}
+
+// longestPattern finds longest pattern starting at position i and its cost in bits
+func longestPattern(lower []rune, i int, words []string, wordBits, charBits float64) (int, float64) {
+	bestLength, bestBits := 0, 0.0
+	consider := func(length int, bits float64) {
+		if length > bestLength {
+			bestLength, bestBits = length, bits
+		}
+	}
+
+	rest := string(lower[i:])
+	for _, word := range words {
+		if strings.HasPrefix(rest, word) {
+			consider(len([]rune(word)), wordBits)
+		}
+	}
+
+	if length := repeatLength(lower, i); length >= minRepeatLength {
+		consider(length, charBits+math.Log2(float64(length)))
+	}
+
+	if length := sequenceLength(lower, i); length >= minSequenceLength {
+		consider(length, charBits+math.Log2(float64(length))+1) // +1 for direction
+	}
+
+	if isYear(lower, i) {
+		consider(yearLength, yearBits)
+	}
+
+	for _, row := range keyboardRows {
+		if length := commonPrefixLength(rest, row); length >= minKeyboardLength {
+			consider(length, math.Log2(float64(len(keyboardRows)*len(row)))+math.Log2(float64(length)))
+		}
+	}
+
+	return bestLength, bestBits
+}
+
+// dictionary returns common words and user inputs split to parts ("john.smith@example.com" -> john, smith, example, com)
+func dictionary(userInputs []string) []string {
+	words := make([]string, 0, len(commonWords)+len(userInputs)*3)
+	words = append(words, commonWords...)
+
+	for _, input := range userInputs {
+		input = strings.ToLower(input)
+		if len([]rune(input)) >= minWordLength {
+			words = append(words, input)
+		}
+
+		parts := strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, part := range parts {
+			if len([]rune(part)) >= minWordLength {
+				words = append(words, part)
+			}
+		}
+	}
+
+	return words
+}
+
+func charsetSize(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if symbol {
+		size += 33
+	}
+	if other {
+		size += 100
+	}
+	return size
+}
+
+// repeatLength - "aaaa"
+func repeatLength(runes []rune, i int) int {
+	length := 1
+	for j := i + 1; j < len(runes) && runes[j] == runes[i]; j++ {
+		length++
+	}
+	return length
+}
+
+// sequenceLength - "abcd", "4321"
+func sequenceLength(runes []rune, i int) int {
+	if i+1 >= len(runes) {
+		return 1
+	}
+
+	step := runes[i+1] - runes[i]
+	if step != 1 && step != -1 {
+		return 1
+	}
+
+	length := 2
+	for j := i + 2; j < len(runes) && runes[j]-runes[j-1] == step; j++ {
+		length++
+	}
+	return length
+}
+
+// isYear - "1987", "2021"
+func isYear(runes []rune, i int) bool {
+	if i+yearLength > len(runes) {
+		return false
+	}
+
+	year := string(runes[i : i+yearLength])
+	if !strings.HasPrefix(year, "19") && !strings.HasPrefix(year, "20") {
+		return false
+	}
+
+	for _, r := range year[2:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func commonPrefixLength(s, row string) int {
+	best := 0
+	for start := 0; start < len(row); start++ {
+		length := 0
+		for length < len(s) && start+length < len(row) && s[length] == row[start+length] {
+			length++
+		}
+		if length > best {
+			best = length
+		}
+	}
+	return best
+}
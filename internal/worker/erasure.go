@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/database"
+)
+
+// erasureInterval - users are erased at most this long after grace period ends
+const erasureInterval = time.Hour
+
+// Erasure erases users whose erasure request grace period ended
+type Erasure struct {
+	DB database.Database
+}
+
+func NewErasure(db database.Database) *Erasure {
+	return &Erasure{DB: db}
+}
+
+// Run erases due users every hour until context is done
+func (e *Erasure) Run(ctx context.Context) {
+	ticker := time.NewTicker(erasureInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := e.Erase(ctx, time.Now()); err != nil {
+			logrus.WithError(err).Warn("could not erase users")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Erase erases users scheduled before now, it returns number of erased users
+func (e *Erasure) Erase(ctx context.Context, now time.Time) (int, error) {
+	logger := logrus.WithField("func", "erasure.go -> Erase()")
+
+	requests, err := e.DB.ListDueErasureRequests(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	erased := 0
+	for _, request := range requests {
+		if err := e.DB.EraseUser(ctx, request.UserID); err != nil {
+			logger.WithError(err).WithField("userID", request.UserID).Warn("could not erase user")
+			continue
+		}
+		erased++
+	}
+
+	if erased > 0 {
+		logger.WithField("erased", erased).Info("users erased")
+	}
+
+	return erased, nil
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/startdusk/finance-app-backend/internal/database"
+)
+
+// trashInterval - items are purged at most this long after retention ends
+const trashInterval = time.Hour
+
+// TrashRetention purges records which are in trash longer than retention
+type TrashRetention struct {
+	DB        database.Database
+	Retention time.Duration
+}
+
+func NewTrashRetention(db database.Database, retentionDays int) *TrashRetention {
+	return &TrashRetention{
+		DB:        db,
+		Retention: time.Duration(retentionDays) * 24 * time.Hour,
+	}
+}
+
+// Run purges expired trash every hour until context is done
+func (t *TrashRetention) Run(ctx context.Context) {
+	ticker := time.NewTicker(trashInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := t.Purge(ctx, time.Now()); err != nil {
+			logrus.WithError(err).Warn("could not purge trash")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge removes items deleted before retention, it returns number of purged items.
+// Item which can't be purged (account with transactions which were restored) stays in trash.
+func (t *TrashRetention) Purge(ctx context.Context, now time.Time) (int, error) {
+	logger := logrus.WithField("func", "trash.go -> Purge()")
+
+	items, err := t.DB.ListExpiredTrash(ctx, now.Add(-t.Retention))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, item := range items {
+		if err := t.DB.PurgeDeleted(ctx, item.EntityType, item.EntityID); err != nil {
+			// item could be restored or purged with its account since we listed it,
+			// category stays in trash until its child categories are deleted
+			if err != database.ErrNotInTrash && err != database.ErrHasDependents && err != database.ErrHasChildren {
+				logger.WithError(err).WithField("item", item).Warn("could not purge item")
+			}
+			continue
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		logger.WithField("purged", purged).Info("trash purged")
+	}
+
+	return purged, nil
+}